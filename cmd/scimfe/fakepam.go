@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"time"
+
+	"github.com/strick-j/scimfe/internal/app"
+	"github.com/strick-j/scimfe/pkg/scim/scimtest"
+	"go.uber.org/zap"
+)
+
+// fakePAM runs in-memory fake PAM SCIM server, see package scimtest
+func fakePAM(args []string) {
+	var (
+		listen, fixture  string
+		opts             scimtest.Options
+		latency          time.Duration
+		errRate, thrRate float64
+	)
+
+	flags := flag.NewFlagSet("fake-pam", flag.ExitOnError)
+	flags.StringVar(&listen, "listen", ":9090", "Listen address")
+	flags.StringVar(&fixture, "fixture", "", "Path to JSON file with users, groups, containers, containerPermissions and privilegedData to seed server with (optional)")
+	flags.StringVar(&opts.ClientID, "client-id", "", "OAuth2 client ID, SCIM requests are not authenticated if empty")
+	flags.StringVar(&opts.ClientSecret, "client-secret", "", "OAuth2 client secret")
+	flags.IntVar(&opts.PageSize, "page-size", scimtest.DefaultPageSize, "Max number of list results per page")
+	flags.DurationVar(&latency, "latency", 0, "Delay of each response")
+	flags.Float64Var(&errRate, "error-rate", 0, "Share of requests failed with 503 Service Unavailable, from 0 to 1")
+	flags.Float64Var(&thrRate, "throttle-rate", 0, "Share of requests failed with 429 Too Many Requests, from 0 to 1")
+	_ = flags.Parse(args)
+
+	logger, err := zap.NewDevelopment()
+	if err != nil {
+		app.Fatal("failed to initialize logger:", err)
+		return
+	}
+	// nolint: errcheck
+	defer logger.Sync()
+
+	h := scimtest.NewHandler(opts)
+	if fixture != "" {
+		f, err := scimtest.ReadFixture(fixture)
+		if err != nil {
+			logger.Fatal("failed to read fixture", zap.Error(err))
+		}
+		if err = h.Load(*f); err != nil {
+			logger.Fatal("failed to load fixture", zap.Error(err))
+		}
+	}
+
+	if latency > 0 {
+		h.Inject(scimtest.Latency(latency))
+	}
+	if thrRate > 0 {
+		h.Inject(scimtest.Fault{Status: http.StatusTooManyRequests, RetryAfter: time.Second, Probability: thrRate})
+	}
+	if errRate > 0 {
+		h.Inject(scimtest.Fault{Status: http.StatusServiceUnavailable, Probability: errRate})
+	}
+
+	ctx := app.ApplicationContext()
+	server := &http.Server{Addr: listen, Handler: logRequests(logger, h)}
+	go func() {
+		<-ctx.Done()
+		if err := server.Shutdown(context.Background()); err != nil {
+			logger.Error("failed to shutdown server", zap.Error(err))
+		}
+	}()
+
+	logger.Info("starting fake PAM SCIM server",
+		zap.String("addr", listen), zap.String("tokenPath", scimtest.TokenPath),
+		zap.Int("users", len(h.Users())), zap.Int("groups", len(h.Groups())),
+		zap.Int("containers", len(h.Containers())))
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Fatal("failed to start server", zap.Error(err))
+	}
+	logger.Info("goodbye")
+}
+
+// logRequests logs served requests
+func logRequests(logger *zap.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		logger.Debug("request",
+			zap.String("method", r.Method), zap.String("uri", r.RequestURI),
+			zap.Int("status", rec.status), zap.Duration("duration", time.Since(start)))
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -2,12 +2,18 @@ package main
 
 import (
 	"flag"
+	"os"
 
 	"github.com/strick-j/scimfe/internal/app"
 	"go.uber.org/zap"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "fake-pam" {
+		fakePAM(os.Args[2:])
+		return
+	}
+
 	var cfgPath string
 	flag.StringVar(&cfgPath, "config", "", "Path to config file (optional)")
 	flag.Parse()
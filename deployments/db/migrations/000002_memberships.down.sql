@@ -0,0 +1,7 @@
+ALTER TABLE pamuser_groups DROP CONSTRAINT IF EXISTS pamuser_groups_pkey;
+ALTER TABLE pamuser_groups DROP CONSTRAINT IF EXISTS fk_user;
+ALTER TABLE pamuser_groups DROP COLUMN IF EXISTS "id";
+
+ALTER TABLE pamgroup_members DROP CONSTRAINT IF EXISTS pamgroup_members_pkey;
+ALTER TABLE pamgroup_members DROP CONSTRAINT IF EXISTS fk_group;
+ALTER TABLE pamgroup_members DROP COLUMN IF EXISTS "id";
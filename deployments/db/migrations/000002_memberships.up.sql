@@ -0,0 +1,28 @@
+-- Memberships ---------------------------------------------------------------------------------------------------
+
+-- Pamgroup_members and pamuser_groups tables had no reference to a resource which owns
+-- the membership, so both tables are extended with "id" column referencing owner resource.
+--
+-- Both tables contain the same set of memberships and are populated during groups synchronization.
+
+-- Pamgroup_members table
+--
+-- "id" references a group, "value" references a group member (user)
+ALTER TABLE pamgroup_members ADD COLUMN IF NOT EXISTS "id" INT NOT NULL;
+ALTER TABLE pamgroup_members
+    ADD CONSTRAINT fk_group
+        FOREIGN KEY(id)
+            REFERENCES pamgroup(id)
+            ON DELETE CASCADE;
+ALTER TABLE pamgroup_members ADD CONSTRAINT pamgroup_members_pkey PRIMARY KEY ("id", "value");
+
+-- Pamuser_groups table
+--
+-- "id" references a user, "value" references a group
+ALTER TABLE pamuser_groups ADD COLUMN IF NOT EXISTS "id" INT NOT NULL;
+ALTER TABLE pamuser_groups
+    ADD CONSTRAINT fk_user
+        FOREIGN KEY(id)
+            REFERENCES pamuser(id)
+            ON DELETE CASCADE;
+ALTER TABLE pamuser_groups ADD CONSTRAINT pamuser_groups_pkey PRIMARY KEY ("id", "value");
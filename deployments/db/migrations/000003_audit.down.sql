@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS actions_resource_idx;
+DROP INDEX IF EXISTS actions_time_idx;
+
+ALTER TABLE actions DROP COLUMN IF EXISTS "duration";
+ALTER TABLE actions DROP COLUMN IF EXISTS "error";
+ALTER TABLE actions DROP COLUMN IF EXISTS "digest";
+ALTER TABLE actions DROP COLUMN IF EXISTS "resourceId";
+ALTER TABLE actions DROP COLUMN IF EXISTS "actor";
+
+-- only the latest record of each action can be kept with unique constraint
+DELETE FROM actions a USING actions b WHERE a."action" = b."action" AND a."time" < b."time";
+DELETE FROM actions a USING actions b WHERE a."action" = b."action" AND a."time" = b."time" AND a."id" < b."id";
+ALTER TABLE actions ADD CONSTRAINT actions_action_key UNIQUE ("action");
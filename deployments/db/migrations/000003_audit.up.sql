@@ -0,0 +1,20 @@
+-- Audit ---------------------------------------------------------------------------------------------------------
+
+-- Actions table
+--
+-- Actions table is used as an audit log of synchronization and provisioning actions,
+-- so "action" column can't be unique anymore.
+--
+-- "actor" is id of user which performed an action, or "system" for background jobs.
+-- "digest" is SHA-256 hex digest of request payload, if any.
+-- "duration" is action duration in milliseconds.
+-- "time" is action start time in UTC.
+ALTER TABLE actions DROP CONSTRAINT IF EXISTS actions_action_key;
+ALTER TABLE actions ADD COLUMN IF NOT EXISTS "actor" VARCHAR(254) NOT NULL DEFAULT 'system';
+ALTER TABLE actions ADD COLUMN IF NOT EXISTS "resourceId" VARCHAR(254);
+ALTER TABLE actions ADD COLUMN IF NOT EXISTS "digest" CHAR(64);
+ALTER TABLE actions ADD COLUMN IF NOT EXISTS "error" TEXT;
+ALTER TABLE actions ADD COLUMN IF NOT EXISTS "duration" BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS actions_time_idx ON actions ("time");
+CREATE INDEX IF NOT EXISTS actions_resource_idx ON actions ("resourceType", "resourceId");
@@ -0,0 +1,7 @@
+-- Users provisioning -----------------------------------------------------------------------------------------
+
+-- Users table
+--
+-- Users can be provisioned by identity provider using SCIM API.
+-- Deactivated users are kept, but can't log in.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS "active" BOOL NOT NULL DEFAULT true;
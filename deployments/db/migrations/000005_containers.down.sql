@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS pamcontainer_permissions;
+DROP TABLE IF EXISTS pamcontainer_meta;
+DROP TABLE IF EXISTS pamcontainer;
@@ -0,0 +1,73 @@
+-- Containers ----------------------------------------------------------------------------------------------------
+
+-- CyberArk PAM SCIM server exposes safes as "Containers" and safe permissions as "ContainerPermissions".
+-- Safes are identified by safe name, so container ids are strings.
+
+-- Pamcontainer table
+--
+-- Used to store safes retrieved from PAM SCIM Server
+-- "owner_value" and "owner_display" describe safe owner reference, if any.
+CREATE TABLE IF NOT EXISTS pamcontainer
+(
+    "id" VARCHAR(100) PRIMARY KEY NOT NULL,
+    "name" VARCHAR(100),
+    "displayname" VARCHAR(100),
+    "description" TEXT,
+    "type" VARCHAR(64),
+    "owner_value" VARCHAR(100),
+    "owner_display" VARCHAR(100),
+    "schemas" TEXT[]
+);
+
+-- Pamcontainer_meta table
+--
+-- References Pamcontainer
+-- Used to store meta information for safes retrieved from PAM SCIM Server
+CREATE TABLE IF NOT EXISTS pamcontainer_meta
+(
+    "id" VARCHAR(100) NOT NULL,
+    "resourceType" VARCHAR(100),
+    "created" TIMESTAMP,
+    "lastModified" TIMESTAMP,
+    "location" VARCHAR(200),
+    CONSTRAINT fk_container
+        FOREIGN KEY(id)
+            REFERENCES pamcontainer(id)
+            ON DELETE CASCADE
+);
+
+-- Pamcontainer_permissions table
+--
+-- References Pamcontainer and either Pamuser or Pamgroup
+-- Used to store safe permissions of users and groups retrieved from PAM SCIM Server
+-- "id" references a safe, "remote_id" is permission id on PAM SCIM Server.
+-- "rights" is list of granted safe rights, like "ListContent" or "RetrieveAccounts".
+CREATE TABLE IF NOT EXISTS pamcontainer_permissions
+(
+    "permission_id" INT PRIMARY KEY NOT NULL GENERATED ALWAYS AS IDENTITY,
+    "id" VARCHAR(100) NOT NULL,
+    "remote_id" VARCHAR(254),
+    "user_id" INT,
+    "group_id" INT,
+    "display" VARCHAR(100),
+    "rights" TEXT[],
+    CONSTRAINT fk_container
+        FOREIGN KEY(id)
+            REFERENCES pamcontainer(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_user
+        FOREIGN KEY(user_id)
+            REFERENCES pamuser(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_group
+        FOREIGN KEY(group_id)
+            REFERENCES pamgroup(id)
+            ON DELETE CASCADE,
+    CONSTRAINT pamcontainer_permissions_principal_check
+        CHECK (("user_id" IS NULL) <> ("group_id" IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS pamcontainer_permissions_user_idx ON pamcontainer_permissions ("id", "user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS pamcontainer_permissions_group_idx ON pamcontainer_permissions ("id", "group_id");
+CREATE INDEX IF NOT EXISTS pamcontainer_permissions_user_id_idx ON pamcontainer_permissions ("user_id");
+CREATE INDEX IF NOT EXISTS pamcontainer_permissions_group_id_idx ON pamcontainer_permissions ("group_id");
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pamaccount_meta;
+DROP TABLE IF EXISTS pamaccount;
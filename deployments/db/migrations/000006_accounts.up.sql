@@ -0,0 +1,48 @@
+-- Accounts --------------------------------------------------------------------------------------------------------
+
+-- CyberArk PAM SCIM server exposes accounts stored in safes as "PrivilegedData".
+-- Account secrets are never stored by scimfe.
+
+-- Pamaccount table
+--
+-- References Pamcontainer
+-- Used to store accounts retrieved from PAM SCIM Server
+-- "container_id" is id of the safe which stores the account.
+-- Platform and address are filtered case-insensitively, so they are indexed in lower case.
+CREATE TABLE IF NOT EXISTS pamaccount
+(
+    "id" VARCHAR(100) PRIMARY KEY NOT NULL,
+    "name" VARCHAR(254),
+    "type" VARCHAR(64),
+    "container_id" VARCHAR(100) NOT NULL,
+    "container_display" VARCHAR(100),
+    "address" VARCHAR(254),
+    "platform" VARCHAR(100),
+    "username" VARCHAR(254),
+    "schemas" TEXT[],
+    CONSTRAINT fk_container
+        FOREIGN KEY(container_id)
+            REFERENCES pamcontainer(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS pamaccount_container_id_idx ON pamaccount ("container_id");
+CREATE INDEX IF NOT EXISTS pamaccount_platform_idx ON pamaccount (lower("platform"));
+CREATE INDEX IF NOT EXISTS pamaccount_address_idx ON pamaccount (lower("address"));
+
+-- Pamaccount_meta table
+--
+-- References Pamaccount
+-- Used to store meta information for accounts retrieved from PAM SCIM Server
+CREATE TABLE IF NOT EXISTS pamaccount_meta
+(
+    "id" VARCHAR(100) NOT NULL,
+    "resourceType" VARCHAR(100),
+    "created" TIMESTAMP,
+    "lastModified" TIMESTAMP,
+    "location" VARCHAR(200),
+    CONSTRAINT fk_account
+        FOREIGN KEY(id)
+            REFERENCES pamaccount(id)
+            ON DELETE CASCADE
+);
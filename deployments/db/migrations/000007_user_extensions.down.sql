@@ -0,0 +1,2 @@
+ALTER TABLE pamuser DROP COLUMN IF EXISTS "extensions";
+DROP TABLE IF EXISTS pamuser_enterprise;
@@ -0,0 +1,28 @@
+-- User schema extensions -----------------------------------------------------------------------------------------
+
+-- Pamuser_enterprise table
+--
+-- References Pamuser
+-- Used to store enterprise user extension (urn:ietf:params:scim:schemas:extension:enterprise:2.0:User)
+-- attributes of users retrieved from PAM SCIM Server.
+-- "manager_value" is id of the manager user, it's not a foreign key since manager can be missing in mirror.
+CREATE TABLE IF NOT EXISTS pamuser_enterprise
+(
+    "id" INT PRIMARY KEY NOT NULL,
+    "employeenumber" VARCHAR(100),
+    "costcenter" VARCHAR(100),
+    "organization" VARCHAR(100),
+    "division" VARCHAR(100),
+    "department" VARCHAR(100),
+    "manager_value" VARCHAR(100),
+    "manager_ref" TEXT,
+    "manager_display" VARCHAR(100),
+    CONSTRAINT fk_user
+        FOREIGN KEY(id)
+            REFERENCES pamuser(id)
+            ON DELETE CASCADE
+);
+
+-- Other schema extensions, like vendor-specific extensions, are stored as is
+-- in a JSON object keyed by schema URI.
+ALTER TABLE pamuser ADD COLUMN IF NOT EXISTS "extensions" JSONB;
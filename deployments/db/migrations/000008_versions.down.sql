@@ -0,0 +1,5 @@
+ALTER TABLE pamaccount_meta DROP COLUMN IF EXISTS "version";
+ALTER TABLE pamcontainer_meta DROP COLUMN IF EXISTS "version";
+ALTER TABLE pamgroup_meta DROP COLUMN IF EXISTS "version";
+ALTER TABLE pamuser_meta DROP COLUMN IF EXISTS "version";
+ALTER TABLE users DROP COLUMN IF EXISTS "version";
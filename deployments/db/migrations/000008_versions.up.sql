@@ -0,0 +1,16 @@
+-- Resource versions ----------------------------------------------------------------------------------------------
+
+-- Users table
+--
+-- Version is incremented on each update and is used as SCIM resource version (ETag),
+-- so concurrent changes of the same user are detected.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS "version" INT NOT NULL DEFAULT 1;
+
+-- Meta tables of mirrored resources
+--
+-- Version is resource version (ETag) provided by PAM SCIM Server.
+-- If server doesn't provide versions, version is computed from resource attributes.
+ALTER TABLE pamuser_meta ADD COLUMN IF NOT EXISTS "version" VARCHAR(200);
+ALTER TABLE pamgroup_meta ADD COLUMN IF NOT EXISTS "version" VARCHAR(200);
+ALTER TABLE pamcontainer_meta ADD COLUMN IF NOT EXISTS "version" VARCHAR(200);
+ALTER TABLE pamaccount_meta ADD COLUMN IF NOT EXISTS "version" VARCHAR(200);
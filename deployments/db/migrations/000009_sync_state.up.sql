@@ -0,0 +1,13 @@
+-- Synchronization state ------------------------------------------------------------------------------------------
+
+-- Pamsync_state table
+--
+-- Stores high-water mark of each synchronized resource type: the latest "meta.lastModified"
+-- of resources retrieved from PAM SCIM Server. Delta synchronization requests only resources
+-- modified after it.
+CREATE TABLE IF NOT EXISTS pamsync_state
+(
+    "resourceType" VARCHAR(64) PRIMARY KEY NOT NULL,
+    "lastModified" TIMESTAMP NOT NULL,
+    "updated" TIMESTAMP NOT NULL
+);
@@ -0,0 +1,34 @@
+-- Synchronization jobs -------------------------------------------------------------------------------------------
+
+-- Pamsync_jobs table
+--
+-- Stores on-demand and scheduled synchronization jobs with their progress.
+--
+-- "resourceType" is synchronized resource type, or "All".
+-- "actor" is id of user which started the job, or "system" for scheduled jobs.
+-- "updated" is time of the latest progress update, it is updated periodically while job is running,
+-- so jobs of stopped replicas can be detected.
+-- Only one job of each resource type can be running at a time.
+CREATE TABLE IF NOT EXISTS pamsync_jobs
+(
+    "id" UUID PRIMARY KEY NOT NULL,
+    "resourceType" VARCHAR(64) NOT NULL,
+    "mode" VARCHAR(16) NOT NULL,
+    "status" VARCHAR(16) NOT NULL,
+    "actor" VARCHAR(254) NOT NULL,
+    "cancelRequested" BOOL NOT NULL DEFAULT FALSE,
+    "error" TEXT,
+    "pages" INT NOT NULL DEFAULT 0,
+    "upserted" INT NOT NULL DEFAULT 0,
+    "deleted" INT NOT NULL DEFAULT 0,
+    "failed" INT NOT NULL DEFAULT 0,
+    "memberships" INT NOT NULL DEFAULT 0,
+    "permissions" INT NOT NULL DEFAULT 0,
+    "unresolved" INT NOT NULL DEFAULT 0,
+    "created" TIMESTAMP NOT NULL,
+    "updated" TIMESTAMP NOT NULL,
+    "finished" TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS pamsync_jobs_running_idx ON pamsync_jobs ("resourceType") WHERE "status" = 'running';
+CREATE INDEX IF NOT EXISTS pamsync_jobs_created_idx ON pamsync_jobs ("created");
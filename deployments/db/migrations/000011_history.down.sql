@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pamhistory_memberships;
+DROP TABLE IF EXISTS pamhistory;
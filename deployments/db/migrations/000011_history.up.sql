@@ -0,0 +1,54 @@
+-- Change history ------------------------------------------------------------------------------------------------
+
+-- Pamhistory table
+--
+-- Change log of mirrored users and groups. Each row stores resource state before and after
+-- the change as SCIM representation without metadata and memberships.
+--
+-- "operation" is "created", "updated" or "deleted".
+-- "jobId" is id of synchronization job which made the change, NULL for provisioning changes.
+-- "time" is change time in UTC.
+CREATE TABLE IF NOT EXISTS pamhistory
+(
+    "id" INT PRIMARY KEY NOT NULL GENERATED ALWAYS AS IDENTITY,
+    "resourceType" VARCHAR(64) NOT NULL,
+    "resourceId" INT NOT NULL,
+    "operation" VARCHAR(16) NOT NULL,
+    "before" JSONB,
+    "after" JSONB,
+    "jobId" UUID,
+    "time" TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS pamhistory_resource_idx ON pamhistory ("resourceType", "resourceId");
+CREATE INDEX IF NOT EXISTS pamhistory_time_idx ON pamhistory ("time");
+
+-- Pamhistory_memberships table
+--
+-- Stores period of each group membership, so memberships can be queried at a point in time.
+-- Membership is valid from "validFrom" inclusive to "validTo" exclusive, current memberships
+-- have no "validTo".
+--
+-- Rows are not referencing users and groups, since history outlives removed resources.
+CREATE TABLE IF NOT EXISTS pamhistory_memberships
+(
+    "id" INT PRIMARY KEY NOT NULL GENERATED ALWAYS AS IDENTITY,
+    "userId" INT NOT NULL,
+    "userDisplay" VARCHAR(254),
+    "groupId" INT NOT NULL,
+    "groupDisplay" VARCHAR(254),
+    "validFrom" TIMESTAMP NOT NULL,
+    "validTo" TIMESTAMP,
+    "jobId" UUID
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS pamhistory_memberships_current_idx
+    ON pamhistory_memberships ("userId", "groupId") WHERE "validTo" IS NULL;
+CREATE INDEX IF NOT EXISTS pamhistory_memberships_user_idx ON pamhistory_memberships ("userId", "validFrom");
+CREATE INDEX IF NOT EXISTS pamhistory_memberships_group_idx ON pamhistory_memberships ("groupId", "validFrom");
+
+-- Memberships stored before history was introduced are valid since migration.
+INSERT INTO pamhistory_memberships ("userId", "userDisplay", "groupId", "groupDisplay", "validFrom")
+SELECT m."value", m."display", m."id", g."displayname", NOW() AT TIME ZONE 'utc'
+FROM pamgroup_members m
+         JOIN pamgroup g ON g."id" = m."id";
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS pamsync_jobs_running_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS pamsync_jobs_running_idx ON pamsync_jobs ("resourceType") WHERE "status" = 'running';
@@ -0,0 +1,9 @@
+-- Synchronization jobs -------------------------------------------------------------------------------------------
+
+-- Pamsync_jobs table
+--
+-- Only one job can be running at a time, since synchronization of all resource types
+-- overlaps with synchronization of each resource type, and jobs of different resource
+-- types modify the same memberships and permissions.
+DROP INDEX IF EXISTS pamsync_jobs_running_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS pamsync_jobs_running_idx ON pamsync_jobs ("status") WHERE "status" = 'running';
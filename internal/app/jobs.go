@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/strick-j/scimfe/internal/config"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/service"
+	"github.com/strick-j/scimfe/pkg/cron"
+)
+
+// NewScheduledJobs returns scheduled jobs from scheduler config
+func NewScheduledJobs(cfg config.Scheduler, syncJobs *service.PamSyncJobService, recorder *service.ActionRecorder) ([]service.ScheduledJob, error) {
+	jobs := make([]service.ScheduledJob, 0, len(cfg.Jobs))
+	names := make(map[string]struct{}, len(cfg.Jobs))
+	for _, jc := range cfg.Jobs {
+		if jc.Name == "" {
+			return nil, fmt.Errorf("scheduled job name is required")
+		}
+		if _, ok := names[jc.Name]; ok {
+			return nil, fmt.Errorf("duplicate scheduled job name %q", jc.Name)
+		}
+		names[jc.Name] = struct{}{}
+
+		schedule, err := cron.Parse(jc.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", jc.Name, err)
+		}
+
+		var run func(ctx context.Context) error
+		switch jc.Task {
+		case config.TaskPamSync:
+			run, err = pamSyncTask(syncJobs, jc)
+		case config.TaskPurgeActions:
+			run, err = purgeActionsTask(recorder, jc)
+		default:
+			err = fmt.Errorf("unknown task %q", jc.Task)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", jc.Name, err)
+		}
+
+		jobs = append(jobs, service.ScheduledJob{
+			Name:     jc.Name,
+			Schedule: schedule,
+			Run:      run,
+		})
+	}
+	return jobs, nil
+}
+
+func pamSyncTask(svc *service.PamSyncJobService, jc config.Job) (func(ctx context.Context) error, error) {
+	req := pam.SyncJobRequest{ResourceType: jc.ResourceType, Mode: pam.SyncMode(jc.Mode)}
+	if _, err := pam.ParseSyncMode(jc.Mode); err != nil {
+		return nil, err
+	}
+	if _, err := service.ParseSyncResourceType(jc.ResourceType); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		_, err := svc.Run(ctx, req)
+		return err
+	}, nil
+}
+
+func purgeActionsTask(recorder *service.ActionRecorder, jc config.Job) (func(ctx context.Context) error, error) {
+	if jc.Retention.Duration <= 0 {
+		return nil, fmt.Errorf("retention period is required")
+	}
+
+	return func(ctx context.Context) error {
+		return recorder.Purge(ctx, jc.Retention.Duration)
+	}, nil
+}
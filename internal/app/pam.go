@@ -0,0 +1,58 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/strick-j/scimfe/internal/config"
+	"github.com/strick-j/scimfe/internal/repository"
+	"github.com/strick-j/scimfe/internal/service"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"go.uber.org/zap"
+)
+
+// NewPAMClient returns PAM SCIM server client and its circuit breaker.
+//
+// Client obtains access tokens using OAuth2 client credentials, tokens are shared
+// between service replicas using database and refreshed under Redis lock.
+//
+// Failed idempotent requests are retried, requests fail fast while PAM SCIM server is unavailable.
+func NewPAMClient(logger *zap.Logger, conn *Connectors, cfg config.PAM) (*scim.Client, *scim.CircuitBreaker) {
+	httpClient := cfg.HTTPClient()
+	tokenSvc := service.NewTokenService(
+		logger,
+		repository.NewTokenRepository(conn.DB),
+		repository.NewLockRepository(conn.Redis),
+		httpClient,
+		tokenParams(cfg),
+	)
+
+	log := logger.Named("pam.transport")
+	breaker := scim.NewCircuitBreaker(cfg.BreakerPolicy())
+	breaker.OnStateChange = func(from, to scim.BreakerState) {
+		log.Warn("PAM SCIM server circuit breaker state changed",
+			zap.String("from", string(from)), zap.String("to", string(to)))
+	}
+
+	transport := scim.NewTransport(nil, cfg.RetryPolicy(), breaker)
+	transport.OnRetry = func(req *http.Request, retry int, delay time.Duration, reason string) {
+		log.Info("retrying PAM SCIM server request",
+			zap.String("method", req.Method), zap.String("path", req.URL.Path),
+			zap.Int("retry", retry), zap.Duration("delay", delay), zap.String("reason", reason))
+	}
+
+	// request timeout is applied to each attempt by transport
+	scimClient := &http.Client{Transport: transport}
+	return scim.NewClient(scimClient, cfg.URL, tokenSvc), breaker
+}
+
+// tokenParams returns OAuth2 client credentials params of PAM token endpoint
+func tokenParams(cfg config.PAM) service.TokenParams {
+	return service.TokenParams{
+		TokenURL:      cfg.TokenURL,
+		ClientID:      cfg.ClientID,
+		ClientSecret:  cfg.ClientSecret,
+		Scope:         cfg.Scope,
+		RefreshMargin: cfg.TokenRefreshMargin.Duration,
+	}
+}
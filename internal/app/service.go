@@ -5,18 +5,23 @@ import (
 	"net/http"
 	"sync"
 
+	"github.com/gorilla/mux"
 	"github.com/strick-j/scimfe/internal/config"
 	"github.com/strick-j/scimfe/internal/repository"
 	"github.com/strick-j/scimfe/internal/service"
 	"github.com/strick-j/scimfe/internal/web"
 	"github.com/strick-j/scimfe/internal/web/handler"
 	"github.com/strick-j/scimfe/internal/web/middleware"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/schema"
 	"go.uber.org/zap"
 )
 
 type Service struct {
-	server *web.Server
-	logger *zap.Logger
+	server    *web.Server
+	logger    *zap.Logger
+	scheduler *service.Scheduler
+	syncJobs  *service.PamSyncJobService
 }
 
 func NewService(baseCtx context.Context, logger *zap.Logger, conn *Connectors, cfg *config.Config) *Service {
@@ -28,6 +33,35 @@ func NewService(baseCtx context.Context, logger *zap.Logger, conn *Connectors, c
 	userSvc := service.NewUsersService(logger, userStore)
 	authSvc := service.NewAuthService(logger, userSvc, sessionStore)
 
+	actionStore := repository.NewActionRepository(conn.DB)
+	recorder := service.NewActionRecorder(logger, actionStore)
+
+	pamClient, pamBreaker := NewPAMClient(logger, conn, cfg.PAM)
+	pamUserStore := repository.NewPamUserRepository(conn.DB)
+	pamGroupStore := repository.NewPamGroupRepository(conn.DB)
+	pamContainerStore := repository.NewPamContainerRepository(conn.DB)
+	pamAccountStore := repository.NewPamAccountRepository(conn.DB)
+	pamSyncStateStore := repository.NewPamSyncStateRepository(conn.DB)
+	pamSyncSvc := service.NewPamSyncService(logger, pamClient, pamUserStore, pamGroupStore,
+		pamContainerStore, pamAccountStore, pamSyncStateStore, recorder, cfg.PAM.PageSize)
+	driftSvc := service.NewPamDriftService(logger, pamClient, pamUserStore, pamGroupStore, recorder, cfg.PAM.PageSize)
+	syncJobSvc := service.NewPamSyncJobService(baseCtx, logger, pamSyncSvc, repository.NewPamSyncJobRepository(conn.DB))
+	pamSvc := service.NewPamService(logger, pamUserStore, pamGroupStore, pamContainerStore, pamAccountStore)
+	historySvc := service.NewPamHistoryService(logger, repository.NewPamHistoryRepository(conn.DB))
+	pamProvSvc := service.NewPamProvisioningService(logger, pamClient, pamUserStore, pamGroupStore, recorder)
+	pamBulkSvc := service.NewPamBulkService(logger, pamProvSvc)
+	scimUsersSvc := service.NewScimUsersService(logger, userSvc, recorder)
+
+	var scheduler *service.Scheduler
+	if !cfg.Scheduler.Disabled {
+		jobs, err := NewScheduledJobs(cfg.Scheduler, syncJobSvc, recorder)
+		if err != nil {
+			logger.Fatal("invalid scheduler config", zap.Error(err))
+		}
+		scheduler = service.NewScheduler(logger, repository.NewLockRepository(conn.Redis),
+			cfg.Scheduler.LeaderTTL.Duration, jobs...)
+	}
+
 	hWrapper := web.NewWrapper(logger.Named("http"))
 	requireAuth := hWrapper.MiddlewareFunc(middleware.NewAuthMiddleware(authSvc))
 
@@ -35,6 +69,9 @@ func NewService(baseCtx context.Context, logger *zap.Logger, conn *Connectors, c
 	srv.Router.Methods(http.MethodGet).
 		Path("/ping").
 		HandlerFunc(hWrapper.WrapResourceHandler(handler.Ping))
+	srv.Router.Methods(http.MethodGet).
+		Path("/health").
+		HandlerFunc(hWrapper.WrapResourceHandler(handler.NewHealthHandler(pamBreaker).GetHealth))
 
 	// Auth
 	authHandler := handler.NewAuthHandler(userSvc, authSvc)
@@ -66,15 +103,139 @@ func NewService(baseCtx context.Context, logger *zap.Logger, conn *Connectors, c
 	usrRouter.Path("/users/{userId}").Methods(http.MethodGet).
 		HandlerFunc(hWrapper.WrapResourceHandler(usrHandler.GetByID))
 
+	// PAM inventory
+	pamHandler := handler.NewPamHandler(pamSvc, pamProvSvc, pamBulkSvc)
+	historyHandler := handler.NewHistoryHandler(historySvc)
+	pamRouter := srv.Router.PathPrefix("/pam").Subrouter()
+	pamRouter.Use(requireAuth)
+	pamRouter.Path("/users").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.GetUsersList))
+	pamRouter.Path("/users").Methods(http.MethodPost).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.CreateUser))
+	pamRouter.Path("/users/{userId}").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.GetUserByID))
+	pamRouter.Path("/users/{userId}").Methods(http.MethodPut).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.ReplaceUser))
+	pamRouter.Path("/users/{userId}").Methods(http.MethodPatch).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.PatchUser))
+	pamRouter.Path("/users/{userId}").Methods(http.MethodDelete).
+		HandlerFunc(hWrapper.WrapHandler(pamHandler.DeactivateUser))
+	pamRouter.Path("/users/{userId}/history").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(historyHandler.GetUserHistory))
+	pamRouter.Path("/users/{userId}/groups").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(historyHandler.GetUserGroups))
+	pamRouter.Path("/groups").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.GetGroupsList))
+	pamRouter.Path("/groups").Methods(http.MethodPost).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.CreateGroup))
+	pamRouter.Path("/groups/{groupId}").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.GetGroupByID))
+	pamRouter.Path("/groups/{groupId}").Methods(http.MethodPut).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.ReplaceGroup))
+	pamRouter.Path("/groups/{groupId}").Methods(http.MethodPatch).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.PatchGroup))
+	pamRouter.Path("/groups/{groupId}").Methods(http.MethodDelete).
+		HandlerFunc(hWrapper.WrapHandler(pamHandler.DeleteGroup))
+	pamRouter.Path("/groups/{groupId}/history").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(historyHandler.GetGroupHistory))
+	pamRouter.Path("/groups/{groupId}/members").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(historyHandler.GetGroupMembers))
+	pamRouter.Path("/containers").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.GetContainersList))
+	pamRouter.Path("/containers/{containerId}").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.GetContainerByID))
+	pamRouter.Path("/containers/{containerId}/permissions").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.GetContainerPermissions))
+	pamRouter.Path("/containers/{containerId}/access").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.GetContainerAccess))
+	pamRouter.Path("/accounts").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.GetAccountsList))
+	pamRouter.Path("/accounts/{accountId}").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.GetAccountByID))
+	pamRouter.Path("/Bulk").Methods(http.MethodPost).
+		HandlerFunc(hWrapper.WrapResourceHandler(pamHandler.Bulk))
+	registerDiscovery(pamRouter, hWrapper, service.PamSchemas)
+
+	// Synchronization jobs
+	syncJobHandler := handler.NewSyncJobHandler(syncJobSvc)
+	syncRouter := srv.Router.PathPrefix("/sync").Subrouter()
+	syncRouter.Use(requireAuth)
+	syncRouter.Path("/jobs").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(syncJobHandler.GetJobsList))
+	syncRouter.Path("/jobs").Methods(http.MethodPost).
+		HandlerFunc(hWrapper.WrapResourceHandler(syncJobHandler.StartJob))
+	syncRouter.Path("/jobs/{jobId}").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(syncJobHandler.GetJobByID))
+	syncRouter.Path("/jobs/{jobId}/cancel").Methods(http.MethodPost).
+		HandlerFunc(hWrapper.WrapResourceHandler(syncJobHandler.CancelJob))
+
+	// Drift report
+	driftHandler := handler.NewDriftHandler(driftSvc)
+	syncRouter.Path("/drift").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(driftHandler.GetDriftReport))
+	syncRouter.Path("/drift.csv").Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapHandler(driftHandler.GetDriftReportCSV))
+
+	// Audit
+	actionHandler := handler.NewActionHandler(recorder)
+	actionRouter := srv.Router.Path("/actions").Subrouter()
+	actionRouter.Use(requireAuth)
+	actionRouter.Methods(http.MethodGet).
+		HandlerFunc(hWrapper.WrapResourceHandler(actionHandler.GetActionsList))
+
+	// SCIM service provider
+	scimWrapper := web.NewSCIMWrapper(logger.Named("scim"))
+	scimUsrHandler := handler.NewScimUserHandler(scimUsersSvc)
+	scimRouter := srv.Router.PathPrefix("/scim/v2").Subrouter()
+	scimRouter.Use(scimWrapper.MiddlewareFunc(middleware.NewBearerAuthMiddleware(cfg.SCIM.Tokens)))
+	scimRouter.Path("/Users").Methods(http.MethodGet).
+		HandlerFunc(scimWrapper.WrapResourceHandler(scimUsrHandler.GetUsersList))
+	scimRouter.Path("/Users").Methods(http.MethodPost).
+		HandlerFunc(scimWrapper.WrapResourceHandler(scimUsrHandler.CreateUser))
+	scimRouter.Path("/Users/{userId}").Methods(http.MethodGet).
+		HandlerFunc(scimWrapper.WrapResourceHandler(scimUsrHandler.GetUserByID))
+	scimRouter.Path("/Users/{userId}").Methods(http.MethodPut).
+		HandlerFunc(scimWrapper.WrapResourceHandler(scimUsrHandler.ReplaceUser))
+	scimRouter.Path("/Users/{userId}").Methods(http.MethodPatch).
+		HandlerFunc(scimWrapper.WrapResourceHandler(scimUsrHandler.PatchUser))
+	scimRouter.Path("/Users/{userId}").Methods(http.MethodDelete).
+		HandlerFunc(scimWrapper.WrapHandler(scimUsrHandler.DeleteUser))
+	registerDiscovery(scimRouter, scimWrapper, service.ScimSchemas)
+
 	return &Service{
-		server: srv,
-		logger: logger,
+		server:    srv,
+		logger:    logger,
+		scheduler: scheduler,
+		syncJobs:  syncJobSvc,
 	}
 }
 
+// registerDiscovery registers SCIM discovery endpoints of schema registry
+func registerDiscovery(router *mux.Router, wrapper *web.Wrapper, reg *schema.Registry) {
+	h := handler.NewDiscoveryHandler(reg)
+	router.Path(scim.EndpointServiceProviderConfig).Methods(http.MethodGet).
+		HandlerFunc(wrapper.WrapResourceHandler(h.GetServiceProviderConfig))
+	router.Path(scim.EndpointResourceTypes).Methods(http.MethodGet).
+		HandlerFunc(wrapper.WrapResourceHandler(h.GetResourceTypes))
+	router.Path(scim.EndpointResourceTypes + "/{id}").Methods(http.MethodGet).
+		HandlerFunc(wrapper.WrapResourceHandler(h.GetResourceType))
+	router.Path(scim.EndpointSchemas).Methods(http.MethodGet).
+		HandlerFunc(wrapper.WrapResourceHandler(h.GetSchemas))
+	router.Path(scim.EndpointSchemas + "/{id}").Methods(http.MethodGet).
+		HandlerFunc(wrapper.WrapResourceHandler(h.GetSchema))
+}
+
 // Start starts the service
 func (s Service) Start(ctx context.Context) {
 	wg := &sync.WaitGroup{}
+	if s.scheduler != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.scheduler.Run(ctx)
+		}()
+	}
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -98,5 +259,6 @@ func (s Service) Start(ctx context.Context) {
 	}()
 
 	wg.Wait()
+	s.syncJobs.Wait()
 	s.logger.Info("goodbye")
 }
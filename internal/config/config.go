@@ -2,12 +2,14 @@ package config
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
 	"gopkg.in/yaml.v2"
 )
 
@@ -64,11 +66,110 @@ func (r Redis) RedisOptions() *redis.Options {
 	}
 }
 
+// PAM is PAM SCIM server connection config
+type PAM struct {
+	URL                string   `envconfig:"SCIMFE_PAM_URL" yaml:"url"`
+	Timeout            Duration `envconfig:"SCIMFE_PAM_TIMEOUT" default:"30s" yaml:"timeout"`
+	TokenURL           string   `envconfig:"SCIMFE_PAM_TOKEN_URL" yaml:"token_url"`
+	ClientID           string   `envconfig:"SCIMFE_PAM_CLIENT_ID" yaml:"client_id"`
+	ClientSecret       string   `envconfig:"SCIMFE_PAM_CLIENT_SECRET" yaml:"client_secret"`
+	Scope              string   `envconfig:"SCIMFE_PAM_SCOPE" yaml:"scope"`
+	TokenRefreshMargin Duration `envconfig:"SCIMFE_PAM_TOKEN_REFRESH_MARGIN" default:"1m" yaml:"token_refresh_margin"`
+	PageSize           int      `envconfig:"SCIMFE_PAM_PAGE_SIZE" default:"100" yaml:"page_size"`
+
+	// Retries of idempotent requests to PAM SCIM server
+	MaxRetries    int      `envconfig:"SCIMFE_PAM_MAX_RETRIES" default:"3" yaml:"max_retries"`
+	MinBackoff    Duration `envconfig:"SCIMFE_PAM_MIN_BACKOFF" default:"500ms" yaml:"min_backoff"`
+	MaxBackoff    Duration `envconfig:"SCIMFE_PAM_MAX_BACKOFF" default:"30s" yaml:"max_backoff"`
+	MaxRetryAfter Duration `envconfig:"SCIMFE_PAM_MAX_RETRY_AFTER" default:"2m" yaml:"max_retry_after"`
+
+	// Circuit breaker of PAM SCIM server requests, breaker is disabled if threshold is zero
+	BreakerThreshold int      `envconfig:"SCIMFE_PAM_BREAKER_THRESHOLD" default:"5" yaml:"breaker_threshold"`
+	BreakerCooldown  Duration `envconfig:"SCIMFE_PAM_BREAKER_COOLDOWN" default:"30s" yaml:"breaker_cooldown"`
+}
+
+// HTTPClient returns HTTP client for PAM SCIM server and token endpoint
+func (p PAM) HTTPClient() *http.Client {
+	return &http.Client{Timeout: p.Timeout.Duration}
+}
+
+// RetryPolicy returns PAM SCIM server requests retry policy.
+//
+// Request timeout limits each attempt.
+func (p PAM) RetryPolicy() scim.RetryPolicy {
+	return scim.RetryPolicy{
+		MaxRetries:    p.MaxRetries,
+		MinBackoff:    p.MinBackoff.Duration,
+		MaxBackoff:    p.MaxBackoff.Duration,
+		MaxRetryAfter: p.MaxRetryAfter.Duration,
+		Timeout:       p.Timeout.Duration,
+	}
+}
+
+// BreakerPolicy returns PAM SCIM server circuit breaker policy
+func (p PAM) BreakerPolicy() scim.BreakerPolicy {
+	return scim.BreakerPolicy{
+		Threshold: p.BreakerThreshold,
+		Cooldown:  p.BreakerCooldown.Duration,
+	}
+}
+
+// SCIM is SCIM service provider config
+type SCIM struct {
+	// Tokens are bearer tokens accepted by SCIM endpoints
+	Tokens []string `envconfig:"SCIMFE_SCIM_TOKENS" yaml:"tokens"`
+}
+
+// Job task names
+const (
+	// TaskPamSync synchronizes PAM mirror
+	TaskPamSync = "pam-sync"
+
+	// TaskPurgeActions removes audit log records older than retention period
+	TaskPurgeActions = "purge-actions"
+)
+
+// Job is scheduled background job config
+type Job struct {
+	// Name is unique job name
+	Name string `yaml:"name"`
+
+	// Schedule is cron expression, like "*/15 * * * *", "@daily" or "@every 1h"
+	Schedule string `yaml:"schedule"`
+
+	// Task is job task, see Task constants
+	Task string `yaml:"task"`
+
+	// Mode is PAM synchronization mode: full, delta or reconcile
+	Mode string `yaml:"mode"`
+
+	// ResourceType limits PAM synchronization to a single resource type, all resource types are synchronized if empty
+	ResourceType string `yaml:"resource_type"`
+
+	// Retention is audit log retention period used by purge-actions task
+	Retention Duration `yaml:"retention"`
+}
+
+// Scheduler is background jobs scheduler config
+type Scheduler struct {
+	// Disabled disables scheduled jobs on this replica
+	Disabled bool `envconfig:"SCIMFE_SCHEDULER_DISABLED" yaml:"disabled"`
+
+	// LeaderTTL is TTL of scheduler leader and job locks
+	LeaderTTL Duration `envconfig:"SCIMFE_SCHEDULER_LEADER_TTL" default:"30s" yaml:"leader_ttl"`
+
+	// Jobs are scheduled jobs, can be set only in config file
+	Jobs []Job `ignored:"true" yaml:"jobs"`
+}
+
 type Config struct {
 	Production bool         `envconfig:"SCIMFE_PRODUCTION" default:"false" yaml:"production"`
 	Server     ServerConfig `yaml:"server"`
 	DB         Database     `yaml:"db"`
 	Redis      Redis        `yaml:"redis"`
+	PAM        PAM          `yaml:"pam"`
+	SCIM       SCIM         `yaml:"scim"`
+	Scheduler  Scheduler    `yaml:"scheduler"`
 }
 
 func FromFile(cfgPath string) (*Config, error) {
@@ -0,0 +1,92 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Action names
+const (
+	ActionSync       = "sync"
+	ActionCreate     = "create"
+	ActionReplace    = "replace"
+	ActionPatch      = "patch"
+	ActionDelete     = "delete"
+	ActionDeactivate = "deactivate"
+	ActionDiff       = "diff"
+)
+
+// ResourceTypeAll is resource type of actions which affect all resource types
+const ResourceTypeAll = "All"
+
+// ResourceTypeOperator is resource type of scimfe users provisioned by identity provider
+const ResourceTypeOperator = "Operator"
+
+// ActorSystem is actor of actions performed by background jobs
+const ActorSystem = "system"
+
+// ContextKey is audit context key
+type ContextKey string
+
+const ctxActorKey ContextKey = "actor"
+
+// Action is audit log record of synchronization or provisioning action
+type Action struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	Action       string    `json:"action" db:"action"`
+	ResourceType string    `json:"resourceType" db:"resourceType"`
+	ResourceID   string    `json:"resourceId,omitempty" db:"resourceId"`
+	Actor        string    `json:"actor" db:"actor"`
+	Success      bool      `json:"success" db:"success"`
+
+	// Digest is SHA-256 hex digest of request payload
+	Digest string `json:"digest,omitempty" db:"digest"`
+
+	// Error is error message of failed action
+	Error string `json:"error,omitempty" db:"error"`
+
+	// Duration is action duration in milliseconds
+	Duration int64 `json:"duration" db:"duration"`
+
+	// Time is action start time
+	Time time.Time `json:"time" db:"time"`
+}
+
+// Actions is list of actions
+type Actions = []Action
+
+// PayloadDigest returns SHA-256 hex digest of payload JSON representation.
+//
+// Returns empty string for nil payload.
+func PayloadDigest(payload interface{}) string {
+	if payload == nil {
+		return ""
+	}
+
+	data, ok := payload.([]byte)
+	if !ok {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return ""
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ContextWithActor returns context with explicitly specified action actor
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, ctxActorKey, actor)
+}
+
+// ActorFromContext returns actor specified by ContextWithActor
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(ctxActorKey).(string)
+	return actor
+}
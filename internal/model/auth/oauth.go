@@ -0,0 +1,20 @@
+package auth
+
+import "time"
+
+// OAuthToken is OAuth2 access token issued to scimfe by PAM token endpoint.
+type OAuthToken struct {
+	ID          int       `db:"id"`
+	AccessToken string    `db:"access_token"`
+	TokenType   string    `db:"token_type"`
+	Expiry      time.Time `db:"expiry"`
+}
+
+// ValidFor reports whether token is not expired and will stay valid for the specified duration.
+func (t OAuthToken) ValidFor(d time.Duration) bool {
+	if t.AccessToken == "" {
+		return false
+	}
+
+	return time.Now().Add(d).Before(t.Expiry)
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+)
+
+const (
+	// DefaultPageSize is number of resources returned when count is not specified
+	DefaultPageSize = 100
+
+	// MaxPageSize is max number of resources returned in a single page
+	MaxPageSize = 1000
+)
+
+// ListQuery is list request parameters
+type ListQuery struct {
+	// Filter is optional filter expression
+	Filter filter.Expression
+
+	// StartIndex is 1-based index of the first result.
+	//
+	// Ignored if cursor pagination is used.
+	StartIndex int
+
+	// Count is max number of results.
+	//
+	// Zero count returns only total number of results.
+	Count int
+
+	// SortBy is optional attribute path to sort results by
+	SortBy string
+
+	// SortOrder is either scim.SortAscending or scim.SortDescending
+	SortOrder string
+
+	// Cursor enables keyset pagination ordered by resource id.
+	//
+	// Points to an empty string for the first page.
+	Cursor *string
+}
+
+// NewListQuery returns list query with default parameters
+func NewListQuery() ListQuery {
+	return ListQuery{StartIndex: 1, Count: DefaultPageSize}
+}
+
+// Offset returns number of results to skip
+func (q ListQuery) Offset() int {
+	if q.StartIndex < 1 {
+		return 0
+	}
+	return q.StartIndex - 1
+}
+
+// Descending reports whether results are sorted in descending order
+func (q ListQuery) Descending() bool {
+	return strings.EqualFold(q.SortOrder, scim.SortDescending)
+}
+
+// Page is list query result info
+type Page struct {
+	// Total is total number of resources matching filter
+	Total int
+
+	// NextCursor is cursor of the next page.
+	//
+	// Empty if there are no more results or cursor pagination is not used.
+	NextCursor string
+}
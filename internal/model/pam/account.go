@@ -0,0 +1,73 @@
+package pam
+
+import (
+	"fmt"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// Accounts is list of accounts
+type Accounts = []Account
+
+// Account is PAM account mirrored from PAM SCIM server PrivilegedData resource.
+//
+// Account secret is never stored.
+type Account struct {
+	ID               string      `db:"id"`
+	Name             string      `db:"name"`
+	Type             string      `db:"type"`
+	ContainerID      string      `db:"container_id"`
+	ContainerDisplay string      `db:"container_display"`
+	Address          string      `db:"address"`
+	Platform         string      `db:"platform"`
+	UserName         string      `db:"username"`
+	Schemas          StringArray `db:"schemas"`
+
+	Meta *Meta
+}
+
+// AccountFromSCIM converts SCIM privileged data resource to PAM account
+func AccountFromSCIM(d scim.PrivilegedData) (*Account, error) {
+	if d.ID == "" {
+		return nil, fmt.Errorf("account %q: empty resource id", d.Name)
+	}
+
+	if d.Container == nil || d.Container.Value == "" {
+		return nil, fmt.Errorf("account %q: empty container reference", d.ID)
+	}
+
+	return &Account{
+		ID:               d.ID,
+		Name:             d.Name,
+		Type:             d.Type,
+		ContainerID:      d.Container.Value,
+		ContainerDisplay: d.Container.Display,
+		Address:          d.Address,
+		Platform:         d.Platform,
+		UserName:         d.UserName,
+		Schemas:          StringArray(d.Schemas),
+		Meta:             metaFromSCIM(d.Meta),
+	}, nil
+}
+
+// SCIM returns SCIM representation of account
+func (a Account) SCIM() scim.PrivilegedData {
+	out := scim.PrivilegedData{
+		Schemas:   a.Schemas,
+		ID:        a.ID,
+		Name:      a.Name,
+		Type:      a.Type,
+		Container: &scim.Reference{Value: a.ContainerID, Display: a.ContainerDisplay},
+		Address:   a.Address,
+		Platform:  a.Platform,
+		UserName:  a.UserName,
+		Meta:      a.Meta.SCIM(),
+	}
+
+	if len(out.Schemas) == 0 {
+		out.Schemas = []string{scim.SchemaPrivilegedData}
+	}
+
+	out.Meta = withVersion(out.Meta, scim.ResourceTypePrivilegedData, out)
+	return out
+}
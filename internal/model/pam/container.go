@@ -0,0 +1,217 @@
+package pam
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// Containers is list of containers
+type Containers = []Container
+
+// Container is PAM safe mirrored from PAM SCIM server
+type Container struct {
+	ID           string      `db:"id"`
+	Name         string      `db:"name"`
+	DisplayName  string      `db:"displayname"`
+	Description  string      `db:"description"`
+	Type         string      `db:"type"`
+	OwnerValue   string      `db:"owner_value"`
+	OwnerDisplay string      `db:"owner_display"`
+	Schemas      StringArray `db:"schemas"`
+
+	Meta *Meta
+}
+
+// ContainerFromSCIM converts SCIM container resource to PAM container
+func ContainerFromSCIM(c scim.Container) (*Container, error) {
+	if c.ID == "" {
+		return nil, fmt.Errorf("container %q: empty resource id", c.Name)
+	}
+
+	out := &Container{
+		ID:          c.ID,
+		Name:        c.Name,
+		DisplayName: c.DisplayName,
+		Description: c.Description,
+		Type:        c.Type,
+		Schemas:     StringArray(c.Schemas),
+		Meta:        metaFromSCIM(c.Meta),
+	}
+
+	if c.Owner != nil {
+		out.OwnerValue = c.Owner.Value
+		out.OwnerDisplay = c.Owner.Display
+	}
+	return out, nil
+}
+
+// SCIM returns SCIM representation of container
+func (c Container) SCIM() scim.Container {
+	out := scim.Container{
+		Schemas:     c.Schemas,
+		ID:          c.ID,
+		Name:        c.Name,
+		DisplayName: c.DisplayName,
+		Description: c.Description,
+		Type:        c.Type,
+		Meta:        c.Meta.SCIM(),
+	}
+
+	if len(out.Schemas) == 0 {
+		out.Schemas = []string{scim.SchemaContainer}
+	}
+
+	if c.OwnerValue != "" {
+		out.Owner = &scim.Reference{Value: c.OwnerValue, Display: c.OwnerDisplay}
+	}
+
+	out.Meta = withVersion(out.Meta, scim.ResourceTypeContainer, out)
+	return out
+}
+
+// ContainerPermission is a set of container rights granted to a user or a group
+type ContainerPermission struct {
+	// ContainerID is ID of container
+	ContainerID string `db:"id"`
+
+	// RemoteID is permission ID on PAM SCIM server
+	RemoteID string `db:"remote_id"`
+
+	// PrincipalType is either scim.ResourceTypeUser or scim.ResourceTypeGroup
+	PrincipalType string `db:"principal_type"`
+
+	// PrincipalID is ID of user or group
+	PrincipalID int `db:"principal_id"`
+
+	// Display is user or group display name
+	Display string `db:"display"`
+
+	Rights StringArray `db:"rights"`
+}
+
+// PermissionFromSCIM converts SCIM container permission resource to PAM container permission
+func PermissionFromSCIM(p scim.ContainerPermission) (*ContainerPermission, error) {
+	out := &ContainerPermission{
+		ContainerID: p.Container.Value,
+		RemoteID:    p.ID,
+		Rights:      StringArray(p.Rights),
+	}
+
+	if out.ContainerID == "" {
+		return nil, fmt.Errorf("permission %q: empty container reference", p.ID)
+	}
+
+	var principal *scim.Reference
+	switch {
+	case p.User != nil && p.Group != nil:
+		return nil, fmt.Errorf("permission %q: both user and group are set", p.ID)
+	case p.User != nil:
+		principal, out.PrincipalType = p.User, scim.ResourceTypeUser
+	case p.Group != nil:
+		principal, out.PrincipalType = p.Group, scim.ResourceTypeGroup
+	default:
+		return nil, fmt.Errorf("permission %q: neither user nor group is set", p.ID)
+	}
+
+	id, err := ParseID(principal.Value)
+	if err != nil {
+		return nil, fmt.Errorf("permission %q: %w", p.ID, err)
+	}
+
+	out.PrincipalID = id
+	out.Display = principal.Display
+	return out, nil
+}
+
+// SCIM returns SCIM representation of container permission
+func (p ContainerPermission) SCIM() scim.ContainerPermission {
+	out := scim.ContainerPermission{
+		Schemas:   []string{scim.SchemaContainerPermission},
+		ID:        p.RemoteID,
+		Container: scim.Reference{Value: p.ContainerID},
+		Rights:    p.Rights,
+	}
+
+	ref := &scim.Reference{Value: FormatID(p.PrincipalID), Display: p.Display}
+	if p.PrincipalType == scim.ResourceTypeGroup {
+		out.Group = ref
+	} else {
+		out.User = ref
+	}
+	return out
+}
+
+// Grant is container rights granted to a user directly or through a group.
+type Grant struct {
+	UserID      int    `db:"user_id"`
+	UserName    string `db:"username"`
+	DisplayName string `db:"displayname"`
+
+	// GroupID is ID of group which grants rights, zero for direct grants
+	GroupID      int    `db:"group_id"`
+	GroupDisplay string `db:"group_display"`
+
+	Rights StringArray `db:"rights"`
+}
+
+// Access is effective access of a user to container
+type Access struct {
+	User scim.Reference `json:"user"`
+
+	// Rights is union of rights granted directly and through groups
+	Rights []string `json:"rights"`
+
+	// Direct is true if user has own container permission
+	Direct bool `json:"direct"`
+
+	// Groups are groups which grant container rights to the user
+	Groups []scim.Reference `json:"groups,omitempty"`
+}
+
+// EffectiveAccess merges grants into effective access per user, ordered by user ID.
+func EffectiveAccess(grants []Grant) []Access {
+	grants = append([]Grant(nil), grants...)
+	sort.SliceStable(grants, func(i, j int) bool {
+		return grants[i].UserID < grants[j].UserID
+	})
+
+	pos := make(map[int]int, len(grants))
+	rights := make([]map[string]bool, 0, len(grants))
+	out := make([]Access, 0, len(grants))
+	for _, g := range grants {
+		i, ok := pos[g.UserID]
+		if !ok {
+			display := g.DisplayName
+			if display == "" {
+				display = g.UserName
+			}
+
+			i = len(out)
+			pos[g.UserID] = i
+			rights = append(rights, make(map[string]bool))
+			out = append(out, Access{
+				User:   scim.Reference{Value: FormatID(g.UserID), Display: display, Type: scim.ResourceTypeUser},
+				Rights: []string{},
+			})
+		}
+
+		a := &out[i]
+		if g.GroupID == 0 {
+			a.Direct = true
+		} else {
+			a.Groups = append(a.Groups, scim.Reference{
+				Value: FormatID(g.GroupID), Display: g.GroupDisplay, Type: scim.ResourceTypeGroup,
+			})
+		}
+
+		for _, r := range g.Rights {
+			if !rights[i][r] {
+				rights[i][r] = true
+				a.Rights = append(a.Rights, r)
+			}
+		}
+	}
+	return out
+}
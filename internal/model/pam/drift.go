@@ -0,0 +1,189 @@
+package pam
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// DriftKind is kind of difference between PAM mirror and PAM SCIM server
+type DriftKind string
+
+// Drift kinds
+const (
+	// DriftAdded is resource or membership which exists on remote but is missing in mirror
+	DriftAdded DriftKind = "added"
+
+	// DriftRemoved is resource or membership which exists in mirror but was removed on remote
+	DriftRemoved DriftKind = "removed"
+
+	// DriftChanged is resource which attributes differ between mirror and remote
+	DriftChanged DriftKind = "changed"
+)
+
+// AttributeDrift is attribute value which differs between mirror and remote.
+//
+// Value is nil if attribute is not set.
+type AttributeDrift struct {
+	Path   string      `json:"path"`
+	Mirror interface{} `json:"mirror"`
+	Remote interface{} `json:"remote"`
+}
+
+// ResourceDrift is resource which differs between mirror and remote
+type ResourceDrift struct {
+	Kind         DriftKind `json:"kind"`
+	ResourceType string    `json:"resourceType"`
+	ID           string    `json:"id"`
+
+	// Name is user name or group display name
+	Name string `json:"name"`
+
+	// Attributes are changed attributes of changed resource
+	Attributes []AttributeDrift `json:"attributes,omitempty"`
+}
+
+// MembershipDrift is group membership which differs between mirror and remote
+type MembershipDrift struct {
+	Kind         DriftKind `json:"kind"`
+	GroupID      int       `json:"groupId"`
+	GroupDisplay string    `json:"groupDisplay,omitempty"`
+	UserID       int       `json:"userId"`
+	UserDisplay  string    `json:"userDisplay,omitempty"`
+}
+
+// DriftSummary is number of differences of each kind
+type DriftSummary struct {
+	Added              int `json:"added"`
+	Removed            int `json:"removed"`
+	Changed            int `json:"changed"`
+	MembershipsAdded   int `json:"membershipsAdded"`
+	MembershipsRemoved int `json:"membershipsRemoved"`
+}
+
+// DriftReport is report of differences between PAM mirror and PAM SCIM server.
+//
+// Changes are described from mirror point of view: "added" resources would be
+// created by the next full synchronization and "removed" resources would be deleted.
+type DriftReport struct {
+	// Generated is report generation time
+	Generated time.Time `json:"generated"`
+
+	Summary     DriftSummary      `json:"summary"`
+	Resources   []ResourceDrift   `json:"resources"`
+	Memberships []MembershipDrift `json:"memberships"`
+}
+
+// AddResource adds resource difference to report
+func (r *DriftReport) AddResource(d ResourceDrift) {
+	switch d.Kind {
+	case DriftAdded:
+		r.Summary.Added++
+	case DriftRemoved:
+		r.Summary.Removed++
+	case DriftChanged:
+		r.Summary.Changed++
+	}
+	r.Resources = append(r.Resources, d)
+}
+
+// AddMembership adds membership difference to report
+func (r *DriftReport) AddMembership(d MembershipDrift) {
+	switch d.Kind {
+	case DriftAdded:
+		r.Summary.MembershipsAdded++
+	case DriftRemoved:
+		r.Summary.MembershipsRemoved++
+	}
+	r.Memberships = append(r.Memberships, d)
+}
+
+// driftCSVHeader is CSV report header.
+//
+// Membership rows describe group "members" attribute, mirror or remote value is member user ID.
+var driftCSVHeader = []string{"kind", "resourceType", "id", "name", "attribute", "mirror", "remote"}
+
+// WriteCSV writes report as CSV, one row per added or removed resource,
+// changed attribute and added or removed membership.
+func (r DriftReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(driftCSVHeader); err != nil {
+		return err
+	}
+
+	for _, d := range r.Resources {
+		if d.Kind != DriftChanged {
+			if err := writeCSVRow(cw, string(d.Kind), d.ResourceType, d.ID, d.Name, "", "", ""); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, a := range d.Attributes {
+			err := writeCSVRow(cw, string(d.Kind), d.ResourceType, d.ID, d.Name, a.Path, csvValue(a.Mirror), csvValue(a.Remote))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, m := range r.Memberships {
+		var mirror, remote string
+		if m.Kind == DriftRemoved {
+			mirror = strconv.Itoa(m.UserID)
+		} else {
+			remote = strconv.Itoa(m.UserID)
+		}
+
+		err := writeCSVRow(cw, string(m.Kind), scim.ResourceTypeGroup, FormatID(m.GroupID), m.GroupDisplay, "members", mirror, remote)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeCSVRow writes CSV row with cells escaped by csvCell
+func writeCSVRow(cw *csv.Writer, cells ...string) error {
+	for i := range cells {
+		cells[i] = csvCell(cells[i])
+	}
+	return cw.Write(cells)
+}
+
+// csvCell escapes cell which spreadsheet applications would evaluate as formula.
+//
+// Names and attribute values come from remote server, so cells starting with
+// "=", "+", "-", "@", tab or carriage return are prefixed with a single quote.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// csvValue formats attribute value, complex values are formatted as JSON
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool, float64:
+		return fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,38 @@
+package pam
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDriftReport_WriteCSV(t *testing.T) {
+	var r DriftReport
+	r.AddResource(ResourceDrift{Kind: DriftAdded, ResourceType: "User", ID: "1", Name: "=HYPERLINK(\"http://evil\")"})
+	r.AddResource(ResourceDrift{Kind: DriftChanged, ResourceType: "User", ID: "2", Name: "bob", Attributes: []AttributeDrift{
+		{Path: "displayName", Mirror: "+1-555", Remote: "@SUM(A1)"},
+		{Path: "title", Mirror: "-x", Remote: "\tcmd"},
+		{Path: "nickName", Mirror: "\rcmd", Remote: "safe = value"},
+		{Path: "active", Mirror: true, Remote: nil},
+		{Path: "emails", Mirror: []interface{}{"a@b.c"}, Remote: nil},
+	}})
+	r.AddMembership(MembershipDrift{Kind: DriftRemoved, GroupID: 10, GroupDisplay: "-admins", UserID: 2})
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, r.WriteCSV(buf))
+
+	rows, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		driftCSVHeader,
+		{"added", "User", "1", "'=HYPERLINK(\"http://evil\")", "", "", ""},
+		{"changed", "User", "2", "bob", "displayName", "'+1-555", "'@SUM(A1)"},
+		{"changed", "User", "2", "bob", "title", "'-x", "'\tcmd"},
+		{"changed", "User", "2", "bob", "nickName", "'\rcmd", "safe = value"},
+		{"changed", "User", "2", "bob", "active", "true", ""},
+		{"changed", "User", "2", "bob", "emails", "[\"a@b.c\"]", ""},
+		{"removed", "Group", "10", "'-admins", "members", "2", ""},
+	}, rows)
+}
@@ -0,0 +1,61 @@
+package pam
+
+import (
+	"fmt"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// Groups is list of groups
+type Groups = []Group
+
+// Group is PAM group mirrored from PAM SCIM server
+type Group struct {
+	ID           int         `db:"id"`
+	DisplayName  string      `db:"displayname"`
+	ExternalID   string      `db:"external_id"`
+	Entitlements StringArray `db:"entitlements"`
+	Schemas      StringArray `db:"schemas"`
+
+	Members []Reference
+	Meta    *Meta
+}
+
+// GroupFromSCIM converts SCIM group resource to PAM group.
+//
+// Group members are not converted, see Membership.
+func GroupFromSCIM(g scim.Group) (*Group, error) {
+	id, err := ParseID(g.ID)
+	if err != nil {
+		return nil, fmt.Errorf("group %q: %w", g.DisplayName, err)
+	}
+
+	return &Group{
+		ID:           id,
+		DisplayName:  g.DisplayName,
+		ExternalID:   g.ExternalID,
+		Entitlements: entitlementsFromSCIM(g.Entitlements),
+		Schemas:      StringArray(g.Schemas),
+		Meta:         metaFromSCIM(g.Meta),
+	}, nil
+}
+
+// SCIM returns SCIM representation of group
+func (g Group) SCIM() scim.Group {
+	out := scim.Group{
+		Schemas:      g.Schemas,
+		ID:           FormatID(g.ID),
+		ExternalID:   g.ExternalID,
+		DisplayName:  g.DisplayName,
+		Members:      referencesToSCIM(g.Members),
+		Entitlements: entitlementsToSCIM(g.Entitlements),
+		Meta:         g.Meta.SCIM(),
+	}
+
+	if len(out.Schemas) == 0 {
+		out.Schemas = []string{scim.SchemaGroup}
+	}
+
+	out.Meta = withVersion(out.Meta, scim.ResourceTypeGroup, out)
+	return out
+}
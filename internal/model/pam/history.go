@@ -0,0 +1,63 @@
+package pam
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChangeOperation is kind of mirrored resource change
+type ChangeOperation string
+
+// Change operations
+const (
+	ChangeCreated ChangeOperation = "created"
+	ChangeUpdated ChangeOperation = "updated"
+	ChangeDeleted ChangeOperation = "deleted"
+)
+
+// Change is a change of mirrored user or group recorded in history.
+//
+// Before and After are SCIM representations of resource without metadata and memberships,
+// memberships history is tracked separately, see MembershipPeriod.
+type Change struct {
+	ID           int             `json:"id" db:"id"`
+	ResourceType string          `json:"resourceType" db:"resourceType"`
+	ResourceID   int             `json:"resourceId" db:"resourceId"`
+	Operation    ChangeOperation `json:"operation" db:"operation"`
+
+	// Before is resource state before change, empty for created resources
+	Before json.RawMessage `json:"before,omitempty" db:"-"`
+
+	// After is resource state after change, empty for deleted resources
+	After json.RawMessage `json:"after,omitempty" db:"-"`
+
+	// JobID is id of synchronization job which made the change, empty for provisioning changes
+	JobID *uuid.UUID `json:"jobId,omitempty" db:"jobId"`
+
+	Time time.Time `json:"time" db:"time"`
+}
+
+// Changes is list of resource changes
+type Changes = []Change
+
+// MembershipPeriod is time period during which user was a member of a group
+type MembershipPeriod struct {
+	UserID       int    `json:"userId" db:"userId"`
+	UserDisplay  string `json:"userDisplay,omitempty" db:"userDisplay"`
+	GroupID      int    `json:"groupId" db:"groupId"`
+	GroupDisplay string `json:"groupDisplay,omitempty" db:"groupDisplay"`
+
+	// From is time when membership was added to mirror
+	From time.Time `json:"from" db:"validFrom"`
+
+	// To is time when membership was removed from mirror, empty for current memberships
+	To *time.Time `json:"to,omitempty" db:"validTo"`
+
+	// JobID is id of synchronization job which added the membership
+	JobID *uuid.UUID `json:"jobId,omitempty" db:"jobId"`
+}
+
+// MembershipPeriods is list of membership periods
+type MembershipPeriods = []MembershipPeriod
@@ -0,0 +1,78 @@
+package pam
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SyncJobStatus is synchronization job status
+type SyncJobStatus string
+
+// Synchronization job statuses
+const (
+	SyncJobRunning   SyncJobStatus = "running"
+	SyncJobSucceeded SyncJobStatus = "succeeded"
+	SyncJobFailed    SyncJobStatus = "failed"
+	SyncJobCancelled SyncJobStatus = "cancelled"
+)
+
+// SyncJob is on-demand or scheduled synchronization job.
+//
+// Embedded SyncResult reports job progress while job is running.
+type SyncJob struct {
+	ID uuid.UUID `json:"id" db:"id"`
+
+	// ResourceType is synchronized resource type, or "All" if all resource types are synchronized
+	ResourceType string `json:"resourceType" db:"resourceType"`
+
+	Status SyncJobStatus `json:"status" db:"status"`
+
+	// Actor is id of user which started the job, or "system" for scheduled jobs
+	Actor string `json:"actor" db:"actor"`
+
+	// CancelRequested reports whether job cancellation was requested
+	CancelRequested bool `json:"cancelRequested" db:"cancelRequested"`
+
+	// Error is error message of failed job
+	Error string `json:"error,omitempty" db:"error"`
+
+	SyncResult
+
+	// Created is job start time
+	Created time.Time `json:"created" db:"created"`
+
+	// Updated is time of the latest progress update
+	Updated time.Time `json:"updated" db:"updated"`
+
+	// Finished is job completion time
+	Finished *time.Time `json:"finished,omitempty" db:"finished"`
+}
+
+// SyncJobs is list of synchronization jobs
+type SyncJobs = []SyncJob
+
+// SyncJobRequest is synchronization job start request
+type SyncJobRequest struct {
+	// ResourceType is User, Group, Container or PrivilegedData, all resource types are synchronized if empty
+	ResourceType string `json:"resourceType"`
+
+	// Mode is synchronization mode, full synchronization is performed if empty
+	Mode SyncMode `json:"mode"`
+}
+
+type ctxSyncJobKey struct{}
+
+// ContextWithSyncJob returns context of synchronization job.
+//
+// Job ID is recorded in history of resources changed by the job.
+func ContextWithSyncJob(ctx context.Context, id uuid.UUID) context.Context {
+	return context.WithValue(ctx, ctxSyncJobKey{}, id)
+}
+
+// SyncJobFromContext returns synchronization job ID specified by ContextWithSyncJob
+func SyncJobFromContext(ctx context.Context) (uuid.UUID, bool) {
+	id, ok := ctx.Value(ctxSyncJobKey{}).(uuid.UUID)
+	return id, ok
+}
@@ -0,0 +1,53 @@
+package pam
+
+import "github.com/strick-j/scimfe/pkg/scim"
+
+// Reference is a reference to a related resource, used as group member or user group.
+type Reference struct {
+	Value   int    `db:"value"`
+	Display string `db:"display"`
+	Type    string `db:"type"`
+	Ref     string `db:"ref"`
+}
+
+func referencesToSCIM(refs []Reference) []scim.Reference {
+	if len(refs) == 0 {
+		return nil
+	}
+
+	out := make([]scim.Reference, 0, len(refs))
+	for _, r := range refs {
+		out = append(out, scim.Reference{
+			Value:   FormatID(r.Value),
+			Display: r.Display,
+			Type:    r.Type,
+			Ref:     r.Ref,
+		})
+	}
+	return out
+}
+
+// Membership is user membership in a group.
+//
+// Membership is stored on both sides of relationship: as group member and as user group.
+type Membership struct {
+	UserID  int
+	GroupID int
+
+	// UserDisplay and UserRef describe user in group members list
+	UserDisplay string
+	UserRef     string
+
+	// GroupDisplay and GroupRef describe group in user groups list
+	GroupDisplay string
+	GroupRef     string
+
+	// Type is membership type, "direct" or "indirect"
+	Type string
+}
+
+// MembershipScope defines set of users and groups whose memberships are replaced during synchronization.
+type MembershipScope struct {
+	UserIDs  []int
+	GroupIDs []int
+}
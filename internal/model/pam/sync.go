@@ -0,0 +1,63 @@
+package pam
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SyncMode is synchronization mode
+type SyncMode string
+
+// Synchronization modes
+const (
+	// SyncFull fetches all remote resources, stores them and removes local resources missing on remote
+	SyncFull SyncMode = "full"
+
+	// SyncDelta fetches and stores only resources modified since the previous synchronization,
+	// resources removed on remote are kept until the next full or reconcile pass.
+	SyncDelta SyncMode = "delta"
+
+	// SyncReconcile fetches only IDs of remote resources and removes local resources missing on remote
+	SyncReconcile SyncMode = "reconcile"
+)
+
+// ParseSyncMode parses synchronization mode, empty string is parsed as SyncFull.
+func ParseSyncMode(s string) (SyncMode, error) {
+	switch mode := SyncMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return SyncFull, nil
+	case SyncFull, SyncDelta, SyncReconcile:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported synchronization mode %q", s)
+	}
+}
+
+// SyncResult is synchronization job result
+type SyncResult struct {
+	// Mode is synchronization mode
+	Mode SyncMode `json:"mode" db:"mode"`
+
+	// Pages is number of fetched pages
+	Pages int `json:"pages" db:"pages"`
+
+	// Upserted is number of created or updated records
+	Upserted int `json:"upserted" db:"upserted"`
+
+	// Deleted is number of removed records
+	Deleted int `json:"deleted" db:"deleted"`
+
+	// Failed is number of records which were not synchronized
+	Failed int `json:"failed" db:"failed"`
+
+	// Memberships is number of stored group memberships
+	Memberships int `json:"memberships" db:"memberships"`
+
+	// Permissions is number of stored container permissions
+	Permissions int `json:"permissions" db:"permissions"`
+
+	// Unresolved is number of memberships, container permissions and accounts
+	// which reference missing users, groups or containers, including group members
+	// which can't be mirrored, like nested groups
+	Unresolved int `json:"unresolved" db:"unresolved"`
+}
@@ -0,0 +1,206 @@
+package pam
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// StringArray is TEXT[] column value
+type StringArray []string
+
+// Scan implements sql.Scanner
+func (a *StringArray) Scan(src interface{}) error {
+	var arr pgtype.TextArray
+	if err := arr.Scan(src); err != nil {
+		return err
+	}
+
+	*a = nil
+	return arr.AssignTo((*[]string)(a))
+}
+
+// Value implements driver.Valuer
+func (a StringArray) Value() (driver.Value, error) {
+	var arr pgtype.TextArray
+	if err := arr.Set([]string(a)); err != nil {
+		return nil, err
+	}
+	return arr.Value()
+}
+
+// ParseID parses PAM SCIM resource ID.
+//
+// PAM SCIM server uses numeric resource identifiers.
+func ParseID(str string) (int, error) {
+	id, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid resource id %q, numeric id expected", str)
+	}
+	return id, nil
+}
+
+// FormatID formats resource ID to SCIM representation
+func FormatID(id int) string {
+	return strconv.Itoa(id)
+}
+
+// Meta is resource metadata
+type Meta struct {
+	ResourceType string     `db:"resourceType"`
+	Created      *time.Time `db:"created"`
+	LastModified *time.Time `db:"lastModified"`
+	Location     string     `db:"location"`
+
+	// Version is resource version provided by remote server
+	Version string `db:"version"`
+}
+
+func metaFromSCIM(m *scim.Meta) *Meta {
+	if m == nil {
+		return nil
+	}
+
+	return &Meta{
+		ResourceType: m.ResourceType,
+		Created:      m.Created,
+		LastModified: m.LastModified,
+		Location:     m.Location,
+		Version:      m.Version,
+	}
+}
+
+// SCIM returns SCIM representation of metadata
+func (m *Meta) SCIM() *scim.Meta {
+	if m == nil {
+		return nil
+	}
+
+	return &scim.Meta{
+		ResourceType: m.ResourceType,
+		Created:      m.Created,
+		LastModified: m.LastModified,
+		Location:     m.Location,
+		Version:      m.Version,
+	}
+}
+
+// withVersion returns resource metadata with resource version.
+//
+// Version provided by remote server is kept, otherwise version is computed
+// from resource attributes, so it changes on each change of mirrored resource.
+func withVersion(m *scim.Meta, resourceType string, res interface{}) *scim.Meta {
+	if m != nil && m.Version != "" {
+		return m
+	}
+
+	version := scim.NewVersion(res)
+	if m == nil {
+		return &scim.Meta{ResourceType: resourceType, Version: version}
+	}
+
+	m.Version = version
+	return m
+}
+
+// MultiValue is multi-valued attribute value, like email or phone number.
+type MultiValue struct {
+	Type    string `db:"name"`
+	Primary bool   `db:"primary"`
+	Display string `db:"display"`
+	Value   string `db:"value"`
+	Ref     string `db:"ref"`
+}
+
+func multiValuesFromSCIM(vals []scim.MultiValue) []MultiValue {
+	if len(vals) == 0 {
+		return nil
+	}
+
+	out := make([]MultiValue, 0, len(vals))
+	for _, v := range vals {
+		out = append(out, MultiValue{
+			Type:    v.Type,
+			Primary: v.Primary,
+			Display: v.Display,
+			Value:   v.Value,
+			Ref:     v.Ref,
+		})
+	}
+	return out
+}
+
+func multiValuesToSCIM(vals []MultiValue) []scim.MultiValue {
+	if len(vals) == 0 {
+		return nil
+	}
+
+	out := make([]scim.MultiValue, 0, len(vals))
+	for _, v := range vals {
+		out = append(out, scim.MultiValue{
+			Type:    v.Type,
+			Primary: v.Primary,
+			Display: v.Display,
+			Value:   v.Value,
+			Ref:     v.Ref,
+		})
+	}
+	return out
+}
+
+func entitlementsFromSCIM(vals []scim.MultiValue) StringArray {
+	out := make(StringArray, 0, len(vals))
+	for _, v := range vals {
+		out = append(out, v.Value)
+	}
+	return out
+}
+
+func entitlementsToSCIM(vals StringArray) []scim.MultiValue {
+	if len(vals) == 0 {
+		return nil
+	}
+
+	out := make([]scim.MultiValue, 0, len(vals))
+	for _, v := range vals {
+		out = append(out, scim.MultiValue{Value: v})
+	}
+	return out
+}
+
+// Extensions is JSONB column value with schema extensions keyed by schema URI
+type Extensions map[string]json.RawMessage
+
+// Scan implements sql.Scanner
+func (e *Extensions) Scan(src interface{}) error {
+	*e = nil
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, e)
+	case string:
+		return json.Unmarshal([]byte(v), e)
+	}
+	return fmt.Errorf("cannot scan %T to extensions", src)
+}
+
+// Value implements driver.Valuer.
+//
+// Empty extensions are stored as NULL.
+func (e Extensions) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(map[string]json.RawMessage(e))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,233 @@
+package pam
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// Name is user name components
+type Name struct {
+	GivenName       string `db:"givenname"`
+	MiddleName      string `db:"middlename"`
+	FamilyName      string `db:"familyname"`
+	Formatted       string `db:"formatted"`
+	HonorificPrefix string `db:"honorificPrefix"`
+	HonorificSuffix string `db:"honorificSuffix"`
+}
+
+// Enterprise is enterprise user schema extension attributes
+type Enterprise struct {
+	EmployeeNumber string `db:"employeenumber"`
+	CostCenter     string `db:"costcenter"`
+	Organization   string `db:"organization"`
+	Division       string `db:"division"`
+	Department     string `db:"department"`
+
+	// ManagerValue is manager user ID
+	ManagerValue   string `db:"manager_value"`
+	ManagerRef     string `db:"manager_ref"`
+	ManagerDisplay string `db:"manager_display"`
+}
+
+// Users is list of users
+type Users = []User
+
+// User is PAM user mirrored from PAM SCIM server
+type User struct {
+	ID           int         `db:"id"`
+	UserName     string      `db:"username"`
+	DisplayName  string      `db:"displayname"`
+	NickName     string      `db:"nickname"`
+	ProfileURL   string      `db:"profileUrl"`
+	Title        string      `db:"title"`
+	UserType     string      `db:"userType"`
+	Locale       string      `db:"locale"`
+	Timezone     string      `db:"timezone"`
+	Active       bool        `db:"active"`
+	Entitlements StringArray `db:"entitlements"`
+	Schemas      StringArray `db:"schemas"`
+
+	// Extensions are schema extensions other than enterprise user extension,
+	// like vendor-specific extensions, stored as is.
+	Extensions Extensions `db:"extensions"`
+
+	Name         *Name
+	Enterprise   *Enterprise
+	Emails       []MultiValue
+	PhoneNumbers []MultiValue
+	Groups       []Reference
+	Meta         *Meta
+}
+
+// UserFromSCIM converts SCIM user resource to PAM user.
+//
+// User groups are not converted, see Membership.
+func UserFromSCIM(u scim.User) (*User, error) {
+	id, err := ParseID(u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("user %q: %w", u.UserName, err)
+	}
+
+	out := &User{
+		ID:           id,
+		UserName:     u.UserName,
+		DisplayName:  u.DisplayName,
+		NickName:     u.NickName,
+		ProfileURL:   u.ProfileURL,
+		Title:        u.Title,
+		UserType:     u.UserType,
+		Locale:       u.Locale,
+		Timezone:     u.Timezone,
+		Active:       u.Active == nil || *u.Active,
+		Entitlements: entitlementsFromSCIM(u.Entitlements),
+		Schemas:      StringArray(u.Schemas),
+		Emails:       multiValuesFromSCIM(u.Emails),
+		PhoneNumbers: multiValuesFromSCIM(u.PhoneNumbers),
+		Meta:         metaFromSCIM(u.Meta),
+		Enterprise:   enterpriseFromSCIM(u.Enterprise),
+	}
+
+	if len(u.Extensions) > 0 {
+		out.Extensions = Extensions(u.Extensions)
+	}
+
+	if u.Name != nil {
+		out.Name = &Name{
+			GivenName:       u.Name.GivenName,
+			MiddleName:      u.Name.MiddleName,
+			FamilyName:      u.Name.FamilyName,
+			Formatted:       u.Name.Formatted,
+			HonorificPrefix: u.Name.HonorificPrefix,
+			HonorificSuffix: u.Name.HonorificSuffix,
+		}
+	}
+
+	return out, nil
+}
+
+// SCIM returns SCIM representation of user
+func (u User) SCIM() scim.User {
+	active := u.Active
+	out := scim.User{
+		Schemas:      append([]string(nil), u.Schemas...),
+		ID:           FormatID(u.ID),
+		UserName:     u.UserName,
+		DisplayName:  u.DisplayName,
+		NickName:     u.NickName,
+		ProfileURL:   u.ProfileURL,
+		Title:        u.Title,
+		UserType:     u.UserType,
+		Locale:       u.Locale,
+		Timezone:     u.Timezone,
+		Active:       &active,
+		Emails:       multiValuesToSCIM(u.Emails),
+		PhoneNumbers: multiValuesToSCIM(u.PhoneNumbers),
+		Groups:       referencesToSCIM(u.Groups),
+		Entitlements: entitlementsToSCIM(u.Entitlements),
+		Meta:         u.Meta.SCIM(),
+		Enterprise:   u.Enterprise.SCIM(),
+	}
+
+	if len(u.Extensions) > 0 {
+		out.Extensions = scim.Extensions(u.Extensions)
+	}
+
+	// schemas list should contain URIs of all extensions the user has
+	out.Schemas = withoutSchema(out.Schemas, scim.SchemaEnterpriseUser)
+	if len(out.Schemas) == 0 {
+		out.Schemas = []string{scim.SchemaUser}
+	}
+
+	if out.Enterprise != nil {
+		out.Schemas = append(out.Schemas, scim.SchemaEnterpriseUser)
+	}
+
+	uris := make([]string, 0, len(out.Extensions))
+	for uri := range out.Extensions {
+		uris = append(uris, uri)
+	}
+
+	sort.Strings(uris)
+	for _, uri := range uris {
+		out.Schemas = withSchema(out.Schemas, uri)
+	}
+
+	if u.Name != nil {
+		out.Name = &scim.Name{
+			Formatted:       u.Name.Formatted,
+			FamilyName:      u.Name.FamilyName,
+			GivenName:       u.Name.GivenName,
+			MiddleName:      u.Name.MiddleName,
+			HonorificPrefix: u.Name.HonorificPrefix,
+			HonorificSuffix: u.Name.HonorificSuffix,
+		}
+	}
+
+	out.Meta = withVersion(out.Meta, scim.ResourceTypeUser, out)
+	return out
+}
+
+func enterpriseFromSCIM(e *scim.EnterpriseUser) *Enterprise {
+	if e == nil {
+		return nil
+	}
+
+	out := &Enterprise{
+		EmployeeNumber: e.EmployeeNumber,
+		CostCenter:     e.CostCenter,
+		Organization:   e.Organization,
+		Division:       e.Division,
+		Department:     e.Department,
+	}
+
+	if e.Manager != nil {
+		out.ManagerValue = e.Manager.Value
+		out.ManagerRef = e.Manager.Ref
+		out.ManagerDisplay = e.Manager.DisplayName
+	}
+	return out
+}
+
+// SCIM returns SCIM representation of enterprise user extension
+func (e *Enterprise) SCIM() *scim.EnterpriseUser {
+	if e == nil {
+		return nil
+	}
+
+	out := &scim.EnterpriseUser{
+		EmployeeNumber: e.EmployeeNumber,
+		CostCenter:     e.CostCenter,
+		Organization:   e.Organization,
+		Division:       e.Division,
+		Department:     e.Department,
+	}
+
+	if e.ManagerValue != "" || e.ManagerRef != "" {
+		out.Manager = &scim.Manager{Value: e.ManagerValue, Ref: e.ManagerRef, DisplayName: e.ManagerDisplay}
+	}
+	return out
+}
+
+// withoutSchema removes schema URI from schemas list
+func withoutSchema(schemas []string, uri string) []string {
+	out := schemas[:0]
+	for _, s := range schemas {
+		if !strings.EqualFold(s, uri) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// withSchema adds schema URI to schemas list if it's missing
+func withSchema(schemas []string, uri string) []string {
+	for _, s := range schemas {
+		if strings.EqualFold(s, uri) {
+			return schemas
+		}
+	}
+	return append(schemas, uri)
+}
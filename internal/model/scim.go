@@ -0,0 +1,112 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// Max attribute lengths, limited by PAM mirror schema
+const (
+	maxNameLength      = 64
+	maxTimezoneLength  = 16
+	maxEmailLength     = 254
+	maxPhoneLength     = 32
+	maxGroupNameLength = 100
+)
+
+// scimUserValidator validates SCIM user resource sent by client.
+func scimUserValidator(sl validator.StructLevel) {
+	u := sl.Current().Interface().(scim.User)
+	validateSchemas(sl, u.Schemas, scim.SchemaUser)
+
+	validateRequired(sl, u.UserName, "userName", "UserName")
+	validateMaxLength(sl, u.UserName, maxNameLength, "userName", "UserName")
+	validateMaxLength(sl, u.DisplayName, maxNameLength, "displayName", "DisplayName")
+	validateMaxLength(sl, u.NickName, maxNameLength, "nickName", "NickName")
+	validateMaxLength(sl, u.ProfileURL, maxNameLength, "profileUrl", "ProfileURL")
+	validateMaxLength(sl, u.Title, maxNameLength, "title", "Title")
+	validateMaxLength(sl, u.UserType, maxNameLength, "userType", "UserType")
+	validateMaxLength(sl, u.Locale, maxNameLength, "locale", "Locale")
+	validateMaxLength(sl, u.Timezone, maxTimezoneLength, "timezone", "Timezone")
+
+	for i, email := range u.Emails {
+		name := fmt.Sprintf("emails[%d].value", i)
+		validateRequired(sl, email.Value, name, "Value")
+		validateMaxLength(sl, email.Value, maxEmailLength, name, "Value")
+		if email.Value != "" && Validator.Var(email.Value, "email") != nil {
+			sl.ReportError(email.Value, name, "Value", "email", "")
+		}
+	}
+
+	for i, phone := range u.PhoneNumbers {
+		name := fmt.Sprintf("phoneNumbers[%d].value", i)
+		validateRequired(sl, phone.Value, name, "Value")
+		validateMaxLength(sl, phone.Value, maxPhoneLength, name, "Value")
+	}
+
+	validateSinglePrimary(sl, u.Emails, "emails", "Emails")
+	validateSinglePrimary(sl, u.PhoneNumbers, "phoneNumbers", "PhoneNumbers")
+}
+
+// scimGroupValidator validates SCIM group resource sent by client.
+func scimGroupValidator(sl validator.StructLevel) {
+	g := sl.Current().Interface().(scim.Group)
+	validateSchemas(sl, g.Schemas, scim.SchemaGroup)
+
+	validateRequired(sl, g.DisplayName, "displayName", "DisplayName")
+	validateMaxLength(sl, g.DisplayName, maxGroupNameLength, "displayName", "DisplayName")
+
+	for i, member := range g.Members {
+		name := fmt.Sprintf("members[%d]", i)
+		validateRequired(sl, member.Value, name+".value", "Value")
+		if member.Type != "" && member.Type != scim.ResourceTypeUser {
+			// nested groups are not supported by PAM mirror
+			sl.ReportError(member.Type, name+".type", "Type", "eq", scim.ResourceTypeUser)
+		}
+	}
+}
+
+// validateSchemas checks that resource schemas contain core schema.
+//
+// Empty schemas are allowed, client fills core schema before sending resource.
+func validateSchemas(sl validator.StructLevel, schemas []string, core string) {
+	if len(schemas) == 0 {
+		return
+	}
+
+	for _, s := range schemas {
+		if strings.EqualFold(s, core) {
+			return
+		}
+	}
+
+	sl.ReportError(schemas, "schemas", "Schemas", "contains", core)
+}
+
+func validateRequired(sl validator.StructLevel, val, name, field string) {
+	if strings.TrimSpace(val) == "" {
+		sl.ReportError(val, name, field, "required", "")
+	}
+}
+
+func validateMaxLength(sl validator.StructLevel, val string, max int, name, field string) {
+	if len([]rune(val)) > max {
+		sl.ReportError(val, name, field, "max", fmt.Sprint(max))
+	}
+}
+
+func validateSinglePrimary(sl validator.StructLevel, values []scim.MultiValue, name, field string) {
+	primary := 0
+	for _, v := range values {
+		if v.Primary {
+			primary++
+		}
+	}
+
+	if primary > 1 {
+		sl.ReportError(values, name, field, "single_primary", "")
+	}
+}
@@ -0,0 +1,48 @@
+package user
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// SCIM returns user as SCIM user resource.
+//
+// User email is used as SCIM userName and primary email.
+func (u User) SCIM() scim.User {
+	active := u.Active
+	return scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          IDToString(u.ID),
+		UserName:    u.Email,
+		DisplayName: u.Name,
+		Name:        &scim.Name{Formatted: u.Name},
+		Active:      &active,
+		Emails: []scim.MultiValue{
+			{Value: u.Email, Type: "work", Primary: true},
+		},
+		Meta: &scim.Meta{
+			ResourceType: scim.ResourceTypeUser,
+			Version:      scim.WeakETag(strconv.Itoa(u.Version)),
+		},
+	}
+}
+
+// SetSCIM updates user properties from SCIM user resource.
+//
+// User name is taken from displayName, formatted name or given and family names.
+// Missing active attribute means that user is active.
+// Password is not updated, see SetPassword.
+func (u *User) SetSCIM(su scim.User) {
+	u.Email = strings.ToLower(strings.TrimSpace(su.UserName))
+	u.Name = strings.TrimSpace(su.DisplayName)
+	if u.Name == "" && su.Name != nil {
+		u.Name = strings.TrimSpace(su.Name.Formatted)
+		if u.Name == "" {
+			u.Name = strings.TrimSpace(su.Name.GivenName + " " + su.Name.FamilyName)
+		}
+	}
+
+	u.Active = su.Active == nil || *su.Active
+}
@@ -10,6 +10,9 @@ import (
 
 type ID = pgtype.UUID
 
+// FirstVersion is version of a new user
+const FirstVersion = 1
+
 // IDToString converts user.ID to string
 func IDToString(uid ID) string {
 	var out string
@@ -38,8 +41,14 @@ type User struct {
 	// ID is unique user ID
 	ID pgtype.UUID `json:"id" db:"id"`
 
+	// Active is false for users deactivated by identity provider
+	Active bool `json:"active" db:"active"`
+
 	// PasswordHash contains encrypted password and salt in bcrypt format
 	PasswordHash string `json:"-" db:"password"`
+
+	// Version is incremented on each user update
+	Version int `json:"-" db:"version"`
 }
 
 // SetPassword encrypts and updates user password
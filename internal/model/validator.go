@@ -8,6 +8,7 @@ import (
 
 	"github.com/go-playground/validator/v10"
 	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
 )
 
 var (
@@ -28,6 +29,8 @@ func init() {
 	})
 
 	must(Validator.RegisterValidation("name", nameValidator))
+	Validator.RegisterStructValidation(scimUserValidator, scim.User{})
+	Validator.RegisterStructValidation(scimGroupValidator, scim.Group{})
 }
 
 type validatorErrors struct {
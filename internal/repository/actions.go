@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/audit"
+)
+
+const (
+	colAction     = "action"
+	colResourceID = `"resourceId"`
+	colActor      = "actor"
+	colSuccess    = "success"
+	colDigest     = "digest"
+	colError      = "error"
+	colDuration   = "duration"
+	colTime       = "time"
+
+	tableActions = "actions"
+)
+
+var actionSelectCols = append(
+	[]string{colID, colAction, colResourceType, colActor, colSuccess, colDuration, colTime},
+	coalesceStrings(colResourceID, colDigest, colError)...,
+)
+
+type ActionRepository struct {
+	db *sqlx.DB
+}
+
+// NewActionRepository is ActionRepository constructor
+func NewActionRepository(db *sqlx.DB) *ActionRepository {
+	return &ActionRepository{db: db}
+}
+
+// AddAction implements service.ActionStorage
+func (r ActionRepository) AddAction(ctx context.Context, a audit.Action) error {
+	return execBuilder(ctx, r.db, psql.Insert(tableActions).SetMap(map[string]interface{}{
+		colAction:       a.Action,
+		colResourceType: a.ResourceType,
+		colResourceID:   nullString(a.ResourceID),
+		colActor:        a.Actor,
+		colSuccess:      a.Success,
+		colDigest:       nullString(a.Digest),
+		colError:        nullString(a.Error),
+		colDuration:     a.Duration,
+		colTime:         utcTime(&a.Time),
+	}))
+}
+
+// DeleteActionsBefore implements service.ActionStorage
+func (r ActionRepository) DeleteActionsBefore(ctx context.Context, before time.Time) (int64, error) {
+	q, args, err := psql.Delete(tableActions).Where(squirrel.Lt{colTime: utcTime(&before)}).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove actions: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// ListActions implements service.ActionStorage
+func (r ActionRepository) ListActions(ctx context.Context, lq model.ListQuery) (audit.Actions, *model.Page, error) {
+	sel, count, err := actionFilter.listQuery(actionSelectCols, lq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page := new(model.Page)
+	if page.Total, err = countRows(ctx, r.db, count); err != nil || lq.Count == 0 {
+		return nil, page, err
+	}
+
+	q, args, err := sel.ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out audit.Actions
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, nil, err
+	}
+
+	out = out[:nextPage(lq, page, len(out), func(i int) string {
+		return out[i].ID.String()
+	})]
+
+	// column has no time zone, values are always stored in UTC.
+	for i := range out {
+		out[i].Time = out[i].Time.UTC()
+	}
+	return out, page, nil
+}
+
+// actionFilter maps audit log attributes to actions table
+var actionFilter = filterMapping{
+	table: tableActions,
+	attrs: map[string]attrColumn{
+		"id":           {column: qualify(tableActions, colID), kind: kindUUID},
+		"action":       {column: qualify(tableActions, colAction)},
+		"resourcetype": {column: qualify(tableActions, colResourceType)},
+		"resourceid":   {column: qualify(tableActions, colResourceID), caseExact: true},
+		"actor":        {column: qualify(tableActions, colActor), caseExact: true},
+		"success":      {column: qualify(tableActions, colSuccess), kind: kindBool},
+		"digest":       {column: qualify(tableActions, colDigest)},
+		"error":        {column: qualify(tableActions, colError)},
+		"duration":     {column: qualify(tableActions, colDuration), kind: kindInt},
+		"time":         {column: qualify(tableActions, colTime), kind: kindTime},
+	},
+	sortable: map[string]bool{
+		"id":           true,
+		"action":       true,
+		"resourcetype": true,
+		"success":      true,
+		"duration":     true,
+		"time":         true,
+	},
+}
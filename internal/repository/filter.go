@@ -0,0 +1,355 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+)
+
+// attrKind is attribute value type, used to validate and convert filter values
+type attrKind int
+
+const (
+	kindString attrKind = iota
+	kindInt
+	kindBool
+	kindTime
+	kindUUID
+	kindStringArray
+)
+
+// attrColumn maps SCIM attribute to a table column
+type attrColumn struct {
+	// table is a child table which contains attribute.
+	//
+	// Empty for attributes stored in resource table.
+	table string
+
+	// column is qualified column name
+	column string
+
+	kind attrKind
+
+	// caseExact enables case-sensitive string comparison
+	caseExact bool
+}
+
+// filterMapping describes how SCIM attributes of a resource are stored in database.
+//
+// Used to translate SCIM filters to SQL predicates.
+type filterMapping struct {
+	// table is resource table
+	table string
+
+	// schema is resource core schema URI.
+	//
+	// Attribute paths can be prefixed with schema URI.
+	schema string
+
+	// attrs is map of attribute paths (see filter.AttrPath.Key) to columns
+	attrs map[string]attrColumn
+
+	// multiValued is map of multi-valued attributes to child tables.
+	//
+	// Used to resolve value path filters like `emails[type eq "work"]`.
+	multiValued map[string]string
+
+	// sortable is set of attributes which can be used in sortBy parameter.
+	//
+	// Only attributes stored in resource table can be sortable.
+	sortable map[string]bool
+}
+
+// primaryValueAttr is multi-valued attribute sub-attribute used when no sub-attribute specified.
+const primaryValueAttr = "value"
+
+// compileFilter translates filter expression to SQL predicate.
+//
+// Returns nil predicate if expression is nil.
+func (m filterMapping) compileFilter(expr filter.Expression) (squirrel.Sqlizer, error) {
+	if expr == nil {
+		return nil, nil
+	}
+	return m.compile(expr, "")
+}
+
+// compile compiles expression. Parent is multi-valued attribute name if expression is a value path filter.
+func (m filterMapping) compile(expr filter.Expression, parent string) (squirrel.Sqlizer, error) {
+	switch e := expr.(type) {
+	case *filter.LogicalExpr:
+		left, err := m.compile(e.Left, parent)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := m.compile(e.Right, parent)
+		if err != nil {
+			return nil, err
+		}
+
+		if e.Operator == filter.OpAnd {
+			return squirrel.And{left, right}, nil
+		}
+		return squirrel.Or{left, right}, nil
+	case *filter.NotExpr:
+		pred, err := m.compile(e.Expr, parent)
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{pred}, nil
+	case *filter.ValuePathExpr:
+		if parent != "" {
+			return nil, filter.Error("nested value path filters are not allowed")
+		}
+
+		key := e.Path.Key()
+		table, ok := m.multiValued[key]
+		if !ok || !m.acceptsURI(e.Path.URI) {
+			return nil, filter.Error("attribute %q is not multi-valued or not filterable", e.Path)
+		}
+
+		pred, err := m.compile(e.Filter, key)
+		if err != nil {
+			return nil, err
+		}
+		return m.exists(table, pred), nil
+	case *filter.AttrExpr:
+		col, err := m.resolve(e.Path, parent)
+		if err != nil {
+			return nil, err
+		}
+
+		pred, err := comparePredicate(col, e)
+		if err != nil {
+			return nil, err
+		}
+
+		if parent == "" && col.table != "" {
+			return m.exists(col.table, pred), nil
+		}
+		return pred, nil
+	default:
+		return nil, filter.Error("unsupported filter expression %T", expr)
+	}
+}
+
+func (m filterMapping) acceptsURI(uri string) bool {
+	return uri == "" || strings.EqualFold(uri, m.schema)
+}
+
+// resolve returns column of attribute referenced in filter
+func (m filterMapping) resolve(path filter.AttrPath, parent string) (attrColumn, error) {
+	key := path.Key()
+	switch {
+	case parent != "":
+		if path.URI != "" || path.SubAttr != "" {
+			return attrColumn{}, filter.Error("invalid attribute %q in value path filter", path)
+		}
+
+		key = parent + "." + key
+		col, ok := m.attrs[key]
+		if !ok || col.table != m.multiValued[parent] {
+			return attrColumn{}, filter.Error("unknown attribute %q", key)
+		}
+		return col, nil
+	case !m.acceptsURI(path.URI):
+		// extension attributes are registered with schema URI prefix
+		key = strings.ToLower(path.URI) + ":" + key
+	case path.SubAttr == "":
+		if _, ok := m.multiValued[key]; ok {
+			key += "." + primaryValueAttr
+		}
+	}
+
+	col, ok := m.attrs[key]
+	if !ok {
+		return attrColumn{}, filter.Error("unknown or not filterable attribute %q", path)
+	}
+	return col, nil
+}
+
+// exists returns EXISTS predicate which matches resources with child table rows matching predicate.
+func (m filterMapping) exists(table string, pred squirrel.Sqlizer) squirrel.Sqlizer {
+	sql, args, err := pred.ToSql()
+	if err != nil {
+		return errExpr{err}
+	}
+
+	return squirrel.Expr(fmt.Sprintf(
+		"EXISTS (SELECT 1 FROM %[1]s WHERE %[1]s.%[2]s = %[3]s.%[2]s AND (%[4]s))",
+		table, colID, m.table, sql,
+	), args...)
+}
+
+func comparePredicate(col attrColumn, e *filter.AttrExpr) (squirrel.Sqlizer, error) {
+	if e.Operator == filter.OpPresent {
+		switch col.kind {
+		case kindString:
+			return squirrel.Expr(fmt.Sprintf("(%[1]s IS NOT NULL AND %[1]s <> '')", col.column)), nil
+		case kindStringArray:
+			return squirrel.Expr(fmt.Sprintf("COALESCE(cardinality(%s), 0) > 0", col.column)), nil
+		default:
+			return squirrel.Expr(col.column + " IS NOT NULL"), nil
+		}
+	}
+
+	if e.Value == nil {
+		switch e.Operator {
+		case filter.OpEqual:
+			return squirrel.Expr(col.column + " IS NULL"), nil
+		case filter.OpNotEqual:
+			return squirrel.Expr(col.column + " IS NOT NULL"), nil
+		default:
+			return nil, filter.Error("operator %q can't be used with null value", e.Operator)
+		}
+	}
+
+	val, err := convertFilterValue(col.kind, e)
+	if err != nil {
+		return nil, err
+	}
+
+	if col.kind == kindStringArray {
+		switch e.Operator {
+		case filter.OpEqual:
+			return squirrel.Expr("? = ANY("+col.column+")", val), nil
+		case filter.OpNotEqual:
+			return squirrel.Expr("NOT (? = ANY(COALESCE("+col.column+", '{}')))", val), nil
+		default:
+			return nil, filter.Error("operator %q is not supported for attribute %q", e.Operator, e.Path)
+		}
+	}
+
+	column, placeholder := col.column, "?"
+	if col.kind == kindString && !col.caseExact {
+		column, placeholder = "lower("+column+")", "lower(?)"
+	}
+
+	switch e.Operator {
+	case filter.OpEqual:
+		return squirrel.Expr(column+" = "+placeholder, val), nil
+	case filter.OpNotEqual:
+		return squirrel.Expr(column+" IS DISTINCT FROM "+placeholder, val), nil
+	case filter.OpGreater, filter.OpGreaterOrEqual, filter.OpLess, filter.OpLessOrEqual:
+		if col.kind == kindBool || col.kind == kindUUID {
+			return nil, filter.Error("operator %q is not supported for attribute %q", e.Operator, e.Path)
+		}
+		return squirrel.Expr(column+" "+comparisonOperators[e.Operator]+" "+placeholder, val), nil
+	case filter.OpContains, filter.OpStartsWith, filter.OpEndsWith:
+		if col.kind != kindString {
+			return nil, filter.Error("operator %q is supported only for string attributes", e.Operator)
+		}
+		return likePredicate(col, e.Operator, val.(string)), nil
+	default:
+		return nil, filter.Error("unsupported operator %q", e.Operator)
+	}
+}
+
+var comparisonOperators = map[filter.Operator]string{
+	filter.OpGreater:        ">",
+	filter.OpGreaterOrEqual: ">=",
+	filter.OpLess:           "<",
+	filter.OpLessOrEqual:    "<=",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func likePredicate(col attrColumn, op filter.Operator, val string) squirrel.Sqlizer {
+	pattern := likeEscaper.Replace(val)
+	switch op {
+	case filter.OpContains:
+		pattern = "%" + pattern + "%"
+	case filter.OpStartsWith:
+		pattern = pattern + "%"
+	case filter.OpEndsWith:
+		pattern = "%" + pattern
+	}
+
+	if col.caseExact {
+		return squirrel.Expr(col.column+" LIKE ?", pattern)
+	}
+	return squirrel.Expr(col.column+" ILIKE ?", pattern)
+}
+
+// convertFilterValue validates filter value and converts it to column type
+func convertFilterValue(kind attrKind, e *filter.AttrExpr) (interface{}, error) {
+	switch kind {
+	case kindString, kindStringArray:
+		if v, ok := e.Value.(string); ok {
+			return v, nil
+		}
+	case kindBool:
+		if v, ok := e.Value.(bool); ok {
+			return v, nil
+		}
+	case kindInt:
+		switch v := e.Value.(type) {
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) <= math.MaxInt32 {
+				return int(v), nil
+			}
+		case string:
+			if i, err := strconv.ParseInt(v, 10, 32); err == nil {
+				return int(i), nil
+			}
+		}
+	case kindTime:
+		if v, ok := e.Value.(string); ok {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return nil, filter.Error("invalid date-time value %q of attribute %q", v, e.Path)
+			}
+			return t.UTC(), nil
+		}
+	case kindUUID:
+		if v, ok := e.Value.(string); ok {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				return nil, filter.Error("invalid id value %q", v)
+			}
+			return id.String(), nil
+		}
+	}
+
+	return nil, filter.Error("invalid value %s for attribute %q", filter.FormatValue(e.Value), e.Path)
+}
+
+// notExpr is negated predicate
+type notExpr struct {
+	pred squirrel.Sqlizer
+}
+
+// ToSql implements squirrel.Sqlizer
+func (e notExpr) ToSql() (string, []interface{}, error) {
+	sql, args, err := e.pred.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+	return "NOT (" + sql + ")", args, nil
+}
+
+// errExpr is predicate which returns an error on build
+type errExpr struct {
+	err error
+}
+
+// ToSql implements squirrel.Sqlizer
+func (e errExpr) ToSql() (string, []interface{}, error) {
+	return "", nil, e.err
+}
+
+// qualify returns column name qualified with table name
+func qualify(table, col string) string {
+	return table + "." + col
+}
+
+// childColumn returns attribute column stored in child table
+func childColumn(table, col string, kind attrKind) attrColumn {
+	return attrColumn{table: table, column: qualify(table, col), kind: kind}
+}
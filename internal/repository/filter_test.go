@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+)
+
+// testFilter maps attributes of a resource stored in "res" table and "res_emails" child table
+var testFilter = filterMapping{
+	table:  "res",
+	schema: scim.SchemaUser,
+	attrs: map[string]attrColumn{
+		"id":           {column: "res.id", kind: kindUUID},
+		"username":     {column: "res.user_name"},
+		"externalid":   {column: "res.external_id", caseExact: true},
+		"age":          {column: "res.age", kind: kindInt},
+		"active":       {column: "res.active", kind: kindBool},
+		"created":      {column: "res.created", kind: kindTime},
+		"entitlements": {column: "res.entitlements", kind: kindStringArray},
+
+		"name.givenname": childColumn("res_name", "given_name", kindString),
+
+		"emails.value":   childColumn("res_emails", "value", kindString),
+		"emails.type":    childColumn("res_emails", "type", kindString),
+		"emails.primary": childColumn("res_emails", "is_primary", kindBool),
+
+		strings.ToLower(scim.SchemaEnterpriseUser) + ":employeenumber": childColumn("res_enterprise", "employee_number", kindString),
+	},
+	multiValued: map[string]string{
+		"emails": "res_emails",
+	},
+}
+
+func TestFilterMapping_CompileFilter(t *testing.T) {
+	cases := map[string]struct {
+		filter string
+		sql    string
+		args   []interface{}
+	}{
+		"case-insensitive string": {
+			filter: `userName eq "John"`,
+			sql:    "lower(res.user_name) = lower(?)",
+			args:   []interface{}{"John"},
+		},
+		"case-insensitive attribute name": {
+			filter: `USERNAME eq "john"`,
+			sql:    "lower(res.user_name) = lower(?)",
+			args:   []interface{}{"john"},
+		},
+		"case-exact string": {
+			filter: `externalId eq "John"`,
+			sql:    "res.external_id = ?",
+			args:   []interface{}{"John"},
+		},
+		"schema URI of resource": {
+			filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName ne "john"`,
+			sql:    "lower(res.user_name) IS DISTINCT FROM lower(?)",
+			args:   []interface{}{"john"},
+		},
+		"extension attribute": {
+			filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "42"`,
+			sql:    "EXISTS (SELECT 1 FROM res_enterprise WHERE res_enterprise.id = res.id AND (lower(res_enterprise.employee_number) = lower(?)))",
+			args:   []interface{}{"42"},
+		},
+		"present string": {
+			filter: `userName pr`,
+			sql:    "(res.user_name IS NOT NULL AND res.user_name <> '')",
+		},
+		"present array": {
+			filter: `entitlements pr`,
+			sql:    "COALESCE(cardinality(res.entitlements), 0) > 0",
+		},
+		"present number": {
+			filter: `age pr`,
+			sql:    "res.age IS NOT NULL",
+		},
+		"starts with": {
+			filter: `userName sw "jo"`,
+			sql:    "res.user_name ILIKE ?",
+			args:   []interface{}{"jo%"},
+		},
+		"ends with": {
+			filter: `userName ew "hn"`,
+			sql:    "res.user_name ILIKE ?",
+			args:   []interface{}{"%hn"},
+		},
+		"contains case-exact": {
+			filter: `externalId co "oh"`,
+			sql:    "res.external_id LIKE ?",
+			args:   []interface{}{"%oh%"},
+		},
+		"like wildcards are escaped": {
+			filter: `userName co "50%_off\\"`,
+			sql:    "res.user_name ILIKE ?",
+			args:   []interface{}{`%50\%\_off\\%`},
+		},
+		"null": {
+			filter: `userName eq null`,
+			sql:    "res.user_name IS NULL",
+		},
+		"not null": {
+			filter: `userName ne null`,
+			sql:    "res.user_name IS NOT NULL",
+		},
+		"number": {
+			filter: `age ge 18`,
+			sql:    "res.age >= ?",
+			args:   []interface{}{18},
+		},
+		"number as string": {
+			filter: `age lt "65"`,
+			sql:    "res.age < ?",
+			args:   []interface{}{65},
+		},
+		"bool": {
+			filter: `active eq true`,
+			sql:    "res.active = ?",
+			args:   []interface{}{true},
+		},
+		"time": {
+			filter: `created gt "2021-01-01T10:00:00+02:00"`,
+			sql:    "res.created > ?",
+			args:   []interface{}{time.Date(2021, 1, 1, 8, 0, 0, 0, time.UTC)},
+		},
+		"uuid": {
+			filter: `id eq "2819C223-7F76-453A-919D-413861904646"`,
+			sql:    "res.id = ?",
+			args:   []interface{}{"2819c223-7f76-453a-919d-413861904646"},
+		},
+		"array": {
+			filter: `entitlements eq "admin"`,
+			sql:    "? = ANY(res.entitlements)",
+			args:   []interface{}{"admin"},
+		},
+		"child table": {
+			filter: `name.givenName eq "John"`,
+			sql:    "EXISTS (SELECT 1 FROM res_name WHERE res_name.id = res.id AND (lower(res_name.given_name) = lower(?)))",
+			args:   []interface{}{"John"},
+		},
+		"multi-valued attribute value": {
+			filter: `emails co "@example.com"`,
+			sql:    "EXISTS (SELECT 1 FROM res_emails WHERE res_emails.id = res.id AND (res_emails.value ILIKE ?))",
+			args:   []interface{}{"%@example.com%"},
+		},
+		"value path": {
+			filter: `emails[type eq "work" and primary eq true]`,
+			sql:    "EXISTS (SELECT 1 FROM res_emails WHERE res_emails.id = res.id AND ((lower(res_emails.type) = lower(?) AND res_emails.is_primary = ?)))",
+			args:   []interface{}{"work", true},
+		},
+		"logical operators": {
+			filter: `userName eq "a" or not (active eq false) and age gt 1`,
+			sql:    "(lower(res.user_name) = lower(?) OR (NOT (res.active = ?) AND res.age > ?))",
+			args:   []interface{}{"a", false, 1},
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			expr, err := filter.Parse(c.filter)
+			require.NoError(t, err)
+
+			pred, err := testFilter.compileFilter(expr)
+			require.NoError(t, err)
+
+			sql, args, err := pred.ToSql()
+			require.NoError(t, err)
+			require.Equal(t, c.sql, sql)
+			require.Equal(t, c.args, args)
+		})
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		pred, err := testFilter.compileFilter(nil)
+		require.NoError(t, err)
+		require.Nil(t, pred)
+	})
+}
+
+func TestFilterMapping_CompileFilter_Invalid(t *testing.T) {
+	cases := map[string]struct {
+		filter string
+		msg    string
+	}{
+		"unknown attribute":         {filter: `nickName eq "x"`, msg: `unknown or not filterable attribute "nickName"`},
+		"unknown schema":            {filter: `urn:example:User:userName eq "x"`, msg: "unknown or not filterable attribute"},
+		"not multi-valued":          {filter: `userName[value eq "x"]`, msg: `attribute "userName" is not multi-valued`},
+		"unknown value path attr":   {filter: `emails[display eq "x"]`, msg: `unknown attribute "emails.display"`},
+		"sub-attr in value path":    {filter: `emails[value.x eq "x"]`, msg: "invalid attribute"},
+		"string value of bool":      {filter: `active eq "true"`, msg: `invalid value "true" for attribute "active"`},
+		"fractional number":         {filter: `age eq 1.5`, msg: "invalid value 1.5"},
+		"invalid time":              {filter: `created gt "yesterday"`, msg: `invalid date-time value "yesterday"`},
+		"invalid uuid":              {filter: `id eq "1"`, msg: `invalid id value "1"`},
+		"ordering bool":             {filter: `active gt true`, msg: `operator "gt" is not supported`},
+		"like non-string":           {filter: `age co 1`, msg: `operator "co" is supported only for string attributes`},
+		"ordering null":             {filter: `age gt null`, msg: `operator "gt" can't be used with null value`},
+		"ordering array":            {filter: `entitlements sw "a"`, msg: `operator "sw" is not supported`},
+		"value path of child table": {filter: `name[givenName eq "x"]`, msg: "is not multi-valued"},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			expr, err := filter.Parse(c.filter)
+			require.NoError(t, err)
+
+			_, err = testFilter.compileFilter(expr)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), c.msg)
+			require.True(t, errors.Is(err, scim.ErrInvalidFilter), "invalidFilter error is expected")
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+)
+
+// idAttr is resource id attribute key. Used for default ordering and keyset pagination.
+const idAttr = "id"
+
+// listQuery builds select and count queries for a list request.
+//
+// Results are always ordered by id to keep pages stable.
+// If cursor pagination is used, select query returns one extra row
+// to detect next page presence, see nextPage.
+func (m filterMapping) listQuery(cols []string, q model.ListQuery) (sel, count squirrel.SelectBuilder, err error) {
+	pred, err := m.compileFilter(q.Filter)
+	if err != nil {
+		return sel, count, err
+	}
+
+	sel = psql.Select(cols...).From(m.table)
+	count = psql.Select("COUNT(*)").From(m.table)
+	if pred != nil {
+		sel = sel.Where(pred)
+		count = count.Where(pred)
+	}
+
+	dir := " ASC"
+	if q.Descending() {
+		dir = " DESC"
+	}
+
+	idCol := m.attrs[idAttr].column
+	if q.Cursor != nil {
+		if q.SortBy != "" && !m.isIDPath(q.SortBy) {
+			return sel, count, scim.NewError(
+				http.StatusBadRequest, scim.ErrTypeInvalidValue,
+				"sorting by %q is not supported with cursor pagination", q.SortBy,
+			)
+		}
+
+		if *q.Cursor != "" {
+			after, err := m.decodeCursor(*q.Cursor)
+			if err != nil {
+				return sel, count, err
+			}
+
+			op := " > ?"
+			if q.Descending() {
+				op = " < ?"
+			}
+			sel = sel.Where(squirrel.Expr(idCol+op, after))
+		}
+
+		return sel.OrderBy(idCol + dir).Limit(uint64(q.Count) + 1), count, nil
+	}
+
+	if q.SortBy != "" {
+		col, err := m.sortColumn(q.SortBy)
+		if err != nil {
+			return sel, count, err
+		}
+
+		sel = sel.OrderBy(col + dir)
+	}
+
+	sel = sel.OrderBy(idCol + " ASC").Offset(uint64(q.Offset())).Limit(uint64(q.Count))
+	return sel, count, nil
+}
+
+// sortColumn returns sort expression for attribute.
+//
+// Only attributes listed in mapping sortable set are accepted.
+func (m filterMapping) sortColumn(attr string) (string, error) {
+	path, err := filter.ParseAttrPath(attr)
+	if err != nil || !m.acceptsURI(path.URI) || !m.sortable[path.Key()] {
+		return "", scim.NewError(
+			http.StatusBadRequest, scim.ErrTypeInvalidValue, "attribute %q is not sortable", attr,
+		)
+	}
+
+	col := m.attrs[path.Key()]
+	if col.kind == kindString && !col.caseExact {
+		return "lower(" + col.column + ")", nil
+	}
+	return col.column, nil
+}
+
+func (m filterMapping) isIDPath(attr string) bool {
+	path, err := filter.ParseAttrPath(attr)
+	return err == nil && m.acceptsURI(path.URI) && path.Key() == idAttr
+}
+
+// decodeCursor decodes cursor to id value of the last returned resource
+func (m filterMapping) decodeCursor(cursor string) (interface{}, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		switch m.attrs[idAttr].kind {
+		case kindString:
+			return string(raw), nil
+		case kindInt:
+			if id, err := strconv.ParseInt(string(raw), 10, 32); err == nil {
+				return int(id), nil
+			}
+		case kindUUID:
+			if id, err := uuid.ParseBytes(raw); err == nil {
+				return id.String(), nil
+			}
+		}
+	}
+
+	return nil, scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidCursor, "invalid cursor %q", cursor)
+}
+
+// encodeCursor returns cursor pointing to resource with passed id
+func encodeCursor(id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id))
+}
+
+// nextPage returns number of rows to keep from the fetched page and sets page cursor.
+//
+// lastID returns id of resource at passed index.
+func nextPage(q model.ListQuery, page *model.Page, rows int, lastID func(i int) string) int {
+	if q.Cursor == nil || rows <= q.Count {
+		return rows
+	}
+
+	page.NextCursor = encodeCursor(lastID(q.Count - 1))
+	return q.Count
+}
+
+// countRows executes count query
+func countRows(ctx context.Context, db sqlx.QueryerContext, qb squirrel.SelectBuilder) (int, error) {
+	q, args, err := qb.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	return count, sqlx.GetContext(ctx, db, &count, q, args...)
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/strick-j/scimfe/internal/service"
+)
+
+// Lock values are compared before release or refresh to
+// prevent removal of a lock which was taken by someone else after expiration.
+var (
+	releaseScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`)
+
+	refreshScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+type LockRepository struct {
+	redis redis.Cmdable
+}
+
+// NewLockRepository is LockRepository constructor
+func NewLockRepository(r redis.Cmdable) *LockRepository {
+	return &LockRepository{redis: r}
+}
+
+// Obtain implements service.Locker
+func (r LockRepository) Obtain(ctx context.Context, key string, ttl time.Duration) (service.Lock, error) {
+	l := &redisLock{
+		redis: r.redis,
+		key:   r.redisKey(key),
+		value: uuid.NewString(),
+	}
+
+	ok, err := r.redis.SetNX(ctx, l.key, l.value, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain lock %q: %w", key, err)
+	}
+
+	if !ok {
+		return nil, service.ErrLockNotObtained
+	}
+	return l, nil
+}
+
+func (_ LockRepository) redisKey(key string) string {
+	return "lock:" + key
+}
+
+type redisLock struct {
+	redis redis.Cmdable
+	key   string
+	value string
+}
+
+// Refresh implements service.Lock
+func (l redisLock) Refresh(ctx context.Context, ttl time.Duration) error {
+	n, err := refreshScript.Run(ctx, l.redis, []string{l.key}, l.value, ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to refresh lock %q: %w", l.key, err)
+	}
+
+	if n == 0 {
+		return service.ErrLockNotObtained
+	}
+	return nil
+}
+
+// Release implements service.Lock
+func (l redisLock) Release(ctx context.Context) error {
+	err := releaseScript.Run(ctx, l.redis, []string{l.key}, l.value).Err()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to release lock %q: %w", l.key, err)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+const (
+	colContainerID      = "container_id"
+	colContainerDisplay = "container_display"
+	colAddress          = "address"
+	colPlatform         = "platform"
+
+	tablePamAccount     = "pamaccount"
+	tablePamAccountMeta = "pamaccount_meta"
+)
+
+var (
+	pamAccountCols = []string{
+		colID, colName, colType, colContainerID, colContainerDisplay, colAddress, colPlatform, colUserName, colSchemas,
+	}
+
+	pamAccountSelectCols = append(
+		[]string{colID, colContainerID, colSchemas},
+		coalesceStrings(colName, colType, colContainerDisplay, colAddress, colPlatform, colUserName)...,
+	)
+)
+
+type PamAccountRepository struct {
+	db *sqlx.DB
+}
+
+// NewPamAccountRepository is PamAccountRepository constructor
+func NewPamAccountRepository(db *sqlx.DB) *PamAccountRepository {
+	return &PamAccountRepository{db: db}
+}
+
+// UpsertAccounts implements service.PamAccountSyncStore.
+//
+// Accounts are created or updated in a single transaction together with their metadata.
+// Accounts which reference containers missing in local mirror are skipped.
+// Returns number of skipped accounts.
+func (r PamAccountRepository) UpsertAccounts(ctx context.Context, accounts pam.Accounts) (int, error) {
+	if len(accounts) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	resolved, err := resolveAccounts(ctx, tx, uniqueAccounts(accounts))
+	if err != nil {
+		return 0, err
+	}
+
+	skipped := len(accounts) - len(resolved)
+	if len(resolved) == 0 {
+		return skipped, nil
+	}
+
+	ids := make([]string, 0, len(resolved))
+	insAccounts := psql.Insert(tablePamAccount).Columns(pamAccountCols...).
+		Suffix(upsertSuffix(colID, pamAccountCols[1:]...))
+	insMeta := psql.Insert(tablePamAccountMeta).Columns(metaCols...)
+
+	var hasMeta bool
+	for _, a := range resolved {
+		ids = append(ids, a.ID)
+		insAccounts = insAccounts.Values(
+			a.ID, nullString(a.Name), nullString(a.Type), a.ContainerID, nullString(a.ContainerDisplay),
+			nullString(a.Address), nullString(a.Platform), nullString(a.UserName), a.Schemas,
+		)
+
+		if m := a.Meta; m != nil {
+			hasMeta = true
+			insMeta = insMeta.Values(metaValues(a.ID, m)...)
+		}
+	}
+
+	if err = execBuilder(ctx, tx, insAccounts); err != nil {
+		return 0, fmt.Errorf("failed to upsert accounts: %w", err)
+	}
+
+	err = execBuilder(ctx, tx, psql.Delete(tablePamAccountMeta).Where(anyOfText(colID, ids)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear %s: %w", tablePamAccountMeta, err)
+	}
+
+	if hasMeta {
+		if err = execBuilder(ctx, tx, insMeta); err != nil {
+			return 0, fmt.Errorf("failed to insert %s: %w", tablePamAccountMeta, err)
+		}
+	}
+
+	return skipped, tx.Commit()
+}
+
+// DeleteAccountsExcept implements service.PamAccountSyncStore.
+//
+// Account metadata is removed by cascade.
+func (r PamAccountRepository) DeleteAccountsExcept(ctx context.Context, keep []string) (int64, error) {
+	q, args, err := psql.Delete(tablePamAccount).Where(noneOfText(colID, keep)).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove stale accounts: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// resolveAccounts returns accounts which reference existing containers
+func resolveAccounts(ctx context.Context, tx *sqlx.Tx, accounts pam.Accounts) (pam.Accounts, error) {
+	containerIDs := make([]string, 0, len(accounts))
+	for _, a := range accounts {
+		containerIDs = append(containerIDs, a.ContainerID)
+	}
+
+	q, args, err := psql.Select(colID).From(tablePamContainer).Where(anyOfText(colID, containerIDs)).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []string
+	if err = tx.SelectContext(ctx, &found, q, args...); err != nil {
+		return nil, fmt.Errorf("failed to resolve account containers: %w", err)
+	}
+
+	containers := make(map[string]bool, len(found))
+	for _, id := range found {
+		containers[id] = true
+	}
+
+	out := make(pam.Accounts, 0, len(accounts))
+	for _, a := range accounts {
+		if containers[a.ContainerID] {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// uniqueAccounts removes duplicate accounts from list, last occurrence wins.
+func uniqueAccounts(accounts pam.Accounts) pam.Accounts {
+	pos := make(map[string]int, len(accounts))
+	out := make(pam.Accounts, 0, len(accounts))
+	for _, a := range accounts {
+		if i, ok := pos[a.ID]; ok {
+			out[i] = a
+			continue
+		}
+
+		pos[a.ID] = len(out)
+		out = append(out, a)
+	}
+	return out
+}
+
+// ListAccounts implements service.PamAccountStorage
+func (r PamAccountRepository) ListAccounts(ctx context.Context, lq model.ListQuery) (pam.Accounts, *model.Page, error) {
+	sel, count, err := pamAccountFilter.listQuery(pamAccountSelectCols, lq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page := new(model.Page)
+	if page.Total, err = countRows(ctx, r.db, count); err != nil || lq.Count == 0 {
+		return nil, page, err
+	}
+
+	q, args, err := sel.ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out pam.Accounts
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, nil, err
+	}
+
+	out = out[:nextPage(lq, page, len(out), func(i int) string {
+		return out[i].ID
+	})]
+	return out, page, loadPamAccountMeta(ctx, r.db, out)
+}
+
+// AccountByID implements service.PamAccountStorage
+func (r PamAccountRepository) AccountByID(ctx context.Context, id string) (*pam.Account, error) {
+	q, args, err := psql.Select(pamAccountSelectCols...).From(tablePamAccount).Where(squirrel.Eq{
+		colID: id,
+	}).Limit(1).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var out pam.Accounts
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 {
+		return nil, web.NewErrNotFound("account not found")
+	}
+
+	if err = loadPamAccountMeta(ctx, r.db, out); err != nil {
+		return nil, err
+	}
+	return &out[0], nil
+}
+
+// loadPamAccountMeta loads metadata of passed accounts
+func loadPamAccountMeta(ctx context.Context, db sqlx.QueryerContext, accounts pam.Accounts) error {
+	if len(accounts) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(accounts))
+	for _, a := range accounts {
+		ids = append(ids, a.ID)
+	}
+
+	metas, err := selectTextMeta(ctx, db, tablePamAccountMeta, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range accounts {
+		accounts[i].Meta = metas[accounts[i].ID]
+	}
+	return nil
+}
+
+// pamAccountFilter maps SCIM privileged data attributes to pamaccount tables
+var pamAccountFilter = filterMapping{
+	table:  tablePamAccount,
+	schema: scim.SchemaPrivilegedData,
+	attrs: map[string]attrColumn{
+		"id":                {column: qualify(tablePamAccount, colID), caseExact: true},
+		"name":              {column: qualify(tablePamAccount, colName)},
+		"type":              {column: qualify(tablePamAccount, colType)},
+		"container.value":   {column: qualify(tablePamAccount, colContainerID), caseExact: true},
+		"container.display": {column: qualify(tablePamAccount, colContainerDisplay)},
+		"address":           {column: qualify(tablePamAccount, colAddress)},
+		"platform":          {column: qualify(tablePamAccount, colPlatform)},
+		"username":          {column: qualify(tablePamAccount, colUserName)},
+		"schemas":           {column: qualify(tablePamAccount, colSchemas), kind: kindStringArray},
+
+		"meta.resourcetype": childColumn(tablePamAccountMeta, colResourceType, kindString),
+		"meta.created":      childColumn(tablePamAccountMeta, colCreated, kindTime),
+		"meta.lastmodified": childColumn(tablePamAccountMeta, colLastModified, kindTime),
+		"meta.location":     childColumn(tablePamAccountMeta, colLocation, kindString),
+	},
+	sortable: map[string]bool{
+		"id":              true,
+		"name":            true,
+		"container.value": true,
+		"address":         true,
+		"platform":        true,
+		"username":        true,
+	},
+}
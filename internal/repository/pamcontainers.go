@@ -0,0 +1,422 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+const (
+	colDescription  = "description"
+	colOwnerValue   = "owner_value"
+	colOwnerDisplay = "owner_display"
+	colRemoteID     = "remote_id"
+	colUserID       = "user_id"
+	colGroupID      = "group_id"
+	colRights       = "rights"
+
+	tablePamContainer            = "pamcontainer"
+	tablePamContainerMeta        = "pamcontainer_meta"
+	tablePamContainerPermissions = "pamcontainer_permissions"
+)
+
+var (
+	pamContainerCols = []string{
+		colID, colName, colDisplayName, colDescription, colType, colOwnerValue, colOwnerDisplay, colSchemas,
+	}
+
+	pamPermissionCols = []string{colID, colRemoteID, colUserID, colGroupID, colDisplay, colRights}
+
+	pamContainerSelectCols = append(
+		[]string{colID, colSchemas},
+		coalesceStrings(colName, colDisplayName, colDescription, colType, colOwnerValue, colOwnerDisplay)...,
+	)
+
+	pamPermissionSelectCols = append([]string{
+		colID,
+		"CASE WHEN " + colUserID + " IS NOT NULL THEN 'User' ELSE 'Group' END AS principal_type",
+		"COALESCE(" + colUserID + ", " + colGroupID + ") AS principal_id",
+		colRights,
+	}, coalesceStrings(colRemoteID, colDisplay)...)
+)
+
+// containerGrantsQuery selects container rights granted to users directly and through groups
+const containerGrantsQuery = `
+SELECT g.user_id, COALESCE(u.username, '') AS username, COALESCE(u.displayname, '') AS displayname,
+       g.group_id, COALESCE(grp.displayname, '') AS group_display, g.rights
+FROM (
+    SELECT p.user_id, 0 AS group_id, p.rights
+    FROM pamcontainer_permissions p
+    WHERE p.id = $1 AND p.user_id IS NOT NULL
+    UNION ALL
+    SELECT m.value AS user_id, p.group_id, p.rights
+    FROM pamcontainer_permissions p
+    JOIN pamgroup_members m ON m.id = p.group_id
+    WHERE p.id = $1
+) g
+JOIN pamuser u ON u.id = g.user_id
+LEFT JOIN pamgroup grp ON grp.id = g.group_id
+ORDER BY g.user_id, g.group_id`
+
+type PamContainerRepository struct {
+	db *sqlx.DB
+}
+
+// NewPamContainerRepository is PamContainerRepository constructor
+func NewPamContainerRepository(db *sqlx.DB) *PamContainerRepository {
+	return &PamContainerRepository{db: db}
+}
+
+// UpsertContainers implements service.PamContainerSyncStore.
+//
+// Containers are created or updated in a single transaction together with their metadata.
+// Container permissions are not affected, see ReplacePermissions.
+func (r PamContainerRepository) UpsertContainers(ctx context.Context, containers pam.Containers) error {
+	if len(containers) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	containers = uniqueContainers(containers)
+	ids := make([]string, 0, len(containers))
+	insContainers := psql.Insert(tablePamContainer).Columns(pamContainerCols...).
+		Suffix(upsertSuffix(colID, pamContainerCols[1:]...))
+	insMeta := psql.Insert(tablePamContainerMeta).Columns(metaCols...)
+
+	var hasMeta bool
+	for _, c := range containers {
+		ids = append(ids, c.ID)
+		insContainers = insContainers.Values(
+			c.ID, nullString(c.Name), nullString(c.DisplayName), nullString(c.Description),
+			nullString(c.Type), nullString(c.OwnerValue), nullString(c.OwnerDisplay), c.Schemas,
+		)
+
+		if m := c.Meta; m != nil {
+			hasMeta = true
+			insMeta = insMeta.Values(metaValues(c.ID, m)...)
+		}
+	}
+
+	if err = execBuilder(ctx, tx, insContainers); err != nil {
+		return fmt.Errorf("failed to upsert containers: %w", err)
+	}
+
+	err = execBuilder(ctx, tx, psql.Delete(tablePamContainerMeta).Where(anyOfText(colID, ids)))
+	if err != nil {
+		return fmt.Errorf("failed to clear %s: %w", tablePamContainerMeta, err)
+	}
+
+	if hasMeta {
+		if err = execBuilder(ctx, tx, insMeta); err != nil {
+			return fmt.Errorf("failed to insert %s: %w", tablePamContainerMeta, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteContainersExcept implements service.PamContainerSyncStore.
+//
+// Container metadata and permissions are removed by cascade.
+func (r PamContainerRepository) DeleteContainersExcept(ctx context.Context, keep []string) (int64, error) {
+	q, args, err := psql.Delete(tablePamContainer).Where(noneOfText(colID, keep)).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove stale containers: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// ContainerIDs implements service.PamContainerSyncStore
+func (r PamContainerRepository) ContainerIDs(ctx context.Context) ([]string, error) {
+	q, args, err := psql.Select(colID).From(tablePamContainer).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, fmt.Errorf("failed to load container ids: %w", err)
+	}
+	return out, nil
+}
+
+// ReplacePermissions implements service.PamContainerSyncStore.
+//
+// Removes all permissions of containers in scope and stores passed permissions.
+// Permissions which reference not existing containers, users or groups are skipped.
+// Returns number of skipped permissions.
+func (r PamContainerRepository) ReplacePermissions(ctx context.Context, containerIDs []string, perms []pam.ContainerPermission) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	err = execBuilder(ctx, tx, psql.Delete(tablePamContainerPermissions).Where(anyOfText(colID, containerIDs)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear container permissions: %w", err)
+	}
+
+	resolved, err := resolvePermissions(ctx, tx, perms)
+	if err != nil {
+		return 0, err
+	}
+
+	for start := 0; start < len(resolved); start += membershipsBatchSize {
+		end := start + membershipsBatchSize
+		if end > len(resolved) {
+			end = len(resolved)
+		}
+
+		ins := psql.Insert(tablePamContainerPermissions).Columns(pamPermissionCols...)
+		for _, p := range resolved[start:end] {
+			var userID, groupID interface{}
+			if p.PrincipalType == scim.ResourceTypeGroup {
+				groupID = p.PrincipalID
+			} else {
+				userID = p.PrincipalID
+			}
+
+			ins = ins.Values(p.ContainerID, nullString(p.RemoteID), userID, groupID, nullString(p.Display), p.Rights)
+		}
+
+		if err = execBuilder(ctx, tx, ins); err != nil {
+			return 0, fmt.Errorf("failed to insert container permissions: %w", err)
+		}
+	}
+
+	return len(perms) - len(resolved), tx.Commit()
+}
+
+// resolvePermissions returns unique permissions which reference existing containers, users and groups
+func resolvePermissions(ctx context.Context, tx *sqlx.Tx, perms []pam.ContainerPermission) ([]pam.ContainerPermission, error) {
+	if len(perms) == 0 {
+		return nil, nil
+	}
+
+	containerIDs := make([]string, 0, len(perms))
+	var userIDs, groupIDs []int
+	for _, p := range perms {
+		containerIDs = append(containerIDs, p.ContainerID)
+		if p.PrincipalType == scim.ResourceTypeGroup {
+			groupIDs = append(groupIDs, p.PrincipalID)
+		} else {
+			userIDs = append(userIDs, p.PrincipalID)
+		}
+	}
+
+	q, args, err := psql.Select(colID).From(tablePamContainer).Where(anyOfText(colID, containerIDs)).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []string
+	if err = tx.SelectContext(ctx, &found, q, args...); err != nil {
+		return nil, fmt.Errorf("failed to resolve permission containers: %w", err)
+	}
+
+	containers := make(map[string]bool, len(found))
+	for _, id := range found {
+		containers[id] = true
+	}
+
+	users, err := existingIDs(ctx, tx, tablePamUser, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve permission users: %w", err)
+	}
+
+	groups, err := existingIDs(ctx, tx, tablePamGroup, groupIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve permission groups: %w", err)
+	}
+
+	type permissionKey struct {
+		container string
+		principal string
+		id        int
+	}
+
+	seen := make(map[permissionKey]bool, len(perms))
+	out := make([]pam.ContainerPermission, 0, len(perms))
+	for _, p := range perms {
+		key := permissionKey{container: p.ContainerID, principal: p.PrincipalType, id: p.PrincipalID}
+		exists := users[p.PrincipalID]
+		if p.PrincipalType == scim.ResourceTypeGroup {
+			exists = groups[p.PrincipalID]
+		}
+
+		if !containers[p.ContainerID] || !exists || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// uniqueContainers removes duplicate containers from list, last occurrence wins.
+func uniqueContainers(containers pam.Containers) pam.Containers {
+	pos := make(map[string]int, len(containers))
+	out := make(pam.Containers, 0, len(containers))
+	for _, c := range containers {
+		if i, ok := pos[c.ID]; ok {
+			out[i] = c
+			continue
+		}
+
+		pos[c.ID] = len(out)
+		out = append(out, c)
+	}
+	return out
+}
+
+// ListContainers implements service.PamContainerStorage
+func (r PamContainerRepository) ListContainers(ctx context.Context, lq model.ListQuery) (pam.Containers, *model.Page, error) {
+	sel, count, err := pamContainerFilter.listQuery(pamContainerSelectCols, lq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page := new(model.Page)
+	if page.Total, err = countRows(ctx, r.db, count); err != nil || lq.Count == 0 {
+		return nil, page, err
+	}
+
+	q, args, err := sel.ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out pam.Containers
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, nil, err
+	}
+
+	out = out[:nextPage(lq, page, len(out), func(i int) string {
+		return out[i].ID
+	})]
+	return out, page, loadPamContainerMeta(ctx, r.db, out)
+}
+
+// ContainerByID implements service.PamContainerStorage
+func (r PamContainerRepository) ContainerByID(ctx context.Context, id string) (*pam.Container, error) {
+	q, args, err := psql.Select(pamContainerSelectCols...).From(tablePamContainer).Where(squirrel.Eq{
+		colID: id,
+	}).Limit(1).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var out pam.Containers
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 {
+		return nil, web.NewErrNotFound("container not found")
+	}
+
+	if err = loadPamContainerMeta(ctx, r.db, out); err != nil {
+		return nil, err
+	}
+	return &out[0], nil
+}
+
+// ContainerPermissions implements service.PamContainerStorage.
+//
+// Group permissions are listed after user permissions.
+func (r PamContainerRepository) ContainerPermissions(ctx context.Context, id string) ([]pam.ContainerPermission, error) {
+	q, args, err := psql.Select(pamPermissionSelectCols...).From(tablePamContainerPermissions).
+		Where(squirrel.Eq{colID: id}).
+		OrderBy(colGroupID+" NULLS FIRST", colUserID).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []pam.ContainerPermission
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, fmt.Errorf("failed to load container permissions: %w", err)
+	}
+	return out, nil
+}
+
+// ContainerGrants implements service.PamContainerStorage.
+//
+// Group permissions are expanded to group members.
+func (r PamContainerRepository) ContainerGrants(ctx context.Context, id string) ([]pam.Grant, error) {
+	var out []pam.Grant
+	if err := r.db.SelectContext(ctx, &out, containerGrantsQuery, id); err != nil {
+		return nil, fmt.Errorf("failed to load container grants: %w", err)
+	}
+	return out, nil
+}
+
+// loadPamContainerMeta loads metadata of passed containers
+func loadPamContainerMeta(ctx context.Context, db sqlx.QueryerContext, containers pam.Containers) error {
+	if len(containers) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(containers))
+	for _, c := range containers {
+		ids = append(ids, c.ID)
+	}
+
+	metas, err := selectTextMeta(ctx, db, tablePamContainerMeta, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range containers {
+		containers[i].Meta = metas[containers[i].ID]
+	}
+	return nil
+}
+
+// pamContainerFilter maps SCIM container attributes to pamcontainer tables
+var pamContainerFilter = filterMapping{
+	table:  tablePamContainer,
+	schema: scim.SchemaContainer,
+	attrs: map[string]attrColumn{
+		"id":            {column: qualify(tablePamContainer, colID), caseExact: true},
+		"name":          {column: qualify(tablePamContainer, colName)},
+		"displayname":   {column: qualify(tablePamContainer, colDisplayName)},
+		"description":   {column: qualify(tablePamContainer, colDescription)},
+		"type":          {column: qualify(tablePamContainer, colType)},
+		"owner.value":   {column: qualify(tablePamContainer, colOwnerValue), caseExact: true},
+		"owner.display": {column: qualify(tablePamContainer, colOwnerDisplay)},
+		"schemas":       {column: qualify(tablePamContainer, colSchemas), kind: kindStringArray},
+
+		"meta.resourcetype": childColumn(tablePamContainerMeta, colResourceType, kindString),
+		"meta.created":      childColumn(tablePamContainerMeta, colCreated, kindTime),
+		"meta.lastmodified": childColumn(tablePamContainerMeta, colLastModified, kindTime),
+		"meta.location":     childColumn(tablePamContainerMeta, colLocation, kindString),
+	},
+	sortable: map[string]bool{
+		"id":          true,
+		"name":        true,
+		"displayname": true,
+		"type":        true,
+	},
+}
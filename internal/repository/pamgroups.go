@@ -0,0 +1,421 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+const (
+	colExternalID = "external_id"
+	colType       = "type"
+
+	tablePamGroup        = "pamgroup"
+	tablePamGroupMeta    = "pamgroup_meta"
+	tablePamGroupMembers = "pamgroup_members"
+	tablePamUserGroups   = "pamuser_groups"
+
+	// membershipsBatchSize limits number of rows in a single insert statement
+	membershipsBatchSize = 1000
+)
+
+var (
+	pamGroupCols = []string{colID, colDisplayName, colExternalID, colEntitlements, colSchemas}
+
+	pamGroupMemberCols = []string{colID, colValue, colDisplay, colRef}
+
+	pamUserGroupCols = []string{colID, colValue, colDisplay, colType, colRef}
+)
+
+type PamGroupRepository struct {
+	db *sqlx.DB
+}
+
+// NewPamGroupRepository is PamGroupRepository constructor
+func NewPamGroupRepository(db *sqlx.DB) *PamGroupRepository {
+	return &PamGroupRepository{db: db}
+}
+
+// UpsertGroups implements service.PamGroupSyncStore.
+//
+// Groups are created or updated in a single transaction together with their metadata,
+// changed groups are recorded in history. Group members are not affected, see ReplaceMemberships.
+func (r PamGroupRepository) UpsertGroups(ctx context.Context, groups pam.Groups) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	if err = upsertPamGroups(ctx, tx, groups); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteGroupsExcept implements service.PamGroupSyncStore.
+//
+// Group metadata and memberships are removed by cascade, removal is recorded in history.
+func (r PamGroupRepository) DeleteGroupsExcept(ctx context.Context, keep []int) (int64, error) {
+	deleted, err := r.deleteGroups(ctx, noneOf(colID, keep))
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove stale groups: %w", err)
+	}
+	return deleted, nil
+}
+
+// DeleteGroup implements service.PamGroupProvisionStore.
+//
+// Missing group is not an error, since mirror can be not synchronized yet.
+func (r PamGroupRepository) DeleteGroup(ctx context.Context, id int) error {
+	if _, err := r.deleteGroups(ctx, squirrel.Eq{colID: id}); err != nil {
+		return fmt.Errorf("failed to remove group: %w", err)
+	}
+	return nil
+}
+
+func (r PamGroupRepository) deleteGroups(ctx context.Context, pred squirrel.Sqlizer) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	deleted, err := deleteWithHistory(ctx, tx, scim.ResourceTypeGroup, pred)
+	if err != nil {
+		return 0, err
+	}
+	return deleted, tx.Commit()
+}
+
+// ReplaceMemberships implements service.PamGroupSyncStore.
+//
+// Removes all memberships of users and groups in scope and stores passed memberships
+// in both pamgroup_members and pamuser_groups tables.
+//
+// Memberships which reference not existing users or groups are skipped.
+// Returns number of skipped memberships.
+//
+// Added and removed memberships are recorded in membership history.
+func (r PamGroupRepository) ReplaceMemberships(ctx context.Context, scope pam.MembershipScope, ms []pam.Membership) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	err = execBuilder(ctx, tx, psql.Delete(tablePamGroupMembers).Where(squirrel.Or{
+		anyOf(colID, scope.GroupIDs), anyOf(colValue, scope.UserIDs),
+	}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear group members: %w", err)
+	}
+
+	err = execBuilder(ctx, tx, psql.Delete(tablePamUserGroups).Where(squirrel.Or{
+		anyOf(colID, scope.UserIDs), anyOf(colValue, scope.GroupIDs),
+	}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear user groups: %w", err)
+	}
+
+	resolved, err := resolveMemberships(ctx, tx, ms)
+	if err != nil {
+		return 0, err
+	}
+
+	for start := 0; start < len(resolved); start += membershipsBatchSize {
+		end := start + membershipsBatchSize
+		if end > len(resolved) {
+			end = len(resolved)
+		}
+
+		if err = insertMemberships(ctx, tx, resolved[start:end]); err != nil {
+			return 0, err
+		}
+	}
+
+	err = newHistoryWriter(ctx, tx).replaceMemberships(ctx, squirrel.Or{
+		anyOf(colMemberGroupID, scope.GroupIDs), anyOf(colMemberUserID, scope.UserIDs),
+	}, resolved)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(ms) - len(resolved), tx.Commit()
+}
+
+// resolveMemberships returns memberships which reference existing users and groups
+func resolveMemberships(ctx context.Context, tx *sqlx.Tx, ms []pam.Membership) ([]pam.Membership, error) {
+	if len(ms) == 0 {
+		return nil, nil
+	}
+
+	userIDs := make([]int, 0, len(ms))
+	groupIDs := make([]int, 0, len(ms))
+	for _, m := range ms {
+		userIDs = append(userIDs, m.UserID)
+		groupIDs = append(groupIDs, m.GroupID)
+	}
+
+	users, err := existingIDs(ctx, tx, tablePamUser, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve membership users: %w", err)
+	}
+
+	groups, err := existingIDs(ctx, tx, tablePamGroup, groupIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve membership groups: %w", err)
+	}
+
+	out := make([]pam.Membership, 0, len(ms))
+	for _, m := range ms {
+		if users[m.UserID] && groups[m.GroupID] {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func insertMemberships(ctx context.Context, tx *sqlx.Tx, ms []pam.Membership) error {
+	insMembers := psql.Insert(tablePamGroupMembers).Columns(pamGroupMemberCols...)
+	insUserGroups := psql.Insert(tablePamUserGroups).Columns(pamUserGroupCols...)
+	for _, m := range ms {
+		insMembers = insMembers.Values(m.GroupID, m.UserID, nullString(m.UserDisplay), nullString(m.UserRef))
+		insUserGroups = insUserGroups.Values(
+			m.UserID, m.GroupID, nullString(m.GroupDisplay), nullString(m.Type), nullString(m.GroupRef),
+		)
+	}
+
+	if err := execBuilder(ctx, tx, insMembers); err != nil {
+		return fmt.Errorf("failed to insert group members: %w", err)
+	}
+
+	if err := execBuilder(ctx, tx, insUserGroups); err != nil {
+		return fmt.Errorf("failed to insert user groups: %w", err)
+	}
+	return nil
+}
+
+func existingIDs(ctx context.Context, tx *sqlx.Tx, table string, ids []int) (map[int]bool, error) {
+	q, args, err := psql.Select(colID).From(table).Where(anyOf(colID, ids)).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []int
+	if err = tx.SelectContext(ctx, &found, q, args...); err != nil {
+		return nil, err
+	}
+
+	out := make(map[int]bool, len(found))
+	for _, id := range found {
+		out[id] = true
+	}
+	return out, nil
+}
+
+func upsertPamGroups(ctx context.Context, tx *sqlx.Tx, groups pam.Groups) error {
+	groups = uniqueGroups(groups)
+	ids := make([]int, 0, len(groups))
+	insGroups := psql.Insert(tablePamGroup).Columns(pamGroupCols...).
+		Suffix(upsertSuffix(colID, pamGroupCols[1:]...))
+	insMeta := psql.Insert(tablePamGroupMeta).Columns(metaCols...)
+
+	for _, g := range groups {
+		ids = append(ids, g.ID)
+	}
+
+	before, err := groupSnapshots(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+
+	after, err := groupsSnapshot(groups)
+	if err != nil {
+		return err
+	}
+
+	var hasMeta bool
+	for _, g := range groups {
+		insGroups = insGroups.Values(
+			g.ID, nullString(g.DisplayName), nullString(g.ExternalID), g.Entitlements, g.Schemas,
+		)
+
+		if m := g.Meta; m != nil {
+			hasMeta = true
+			insMeta = insMeta.Values(metaValues(g.ID, m)...)
+		}
+	}
+
+	if err := execBuilder(ctx, tx, insGroups); err != nil {
+		return fmt.Errorf("failed to upsert groups: %w", err)
+	}
+
+	if err := execBuilder(ctx, tx, psql.Delete(tablePamGroupMeta).Where(anyOf(colID, ids))); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", tablePamGroupMeta, err)
+	}
+
+	if hasMeta {
+		if err := execBuilder(ctx, tx, insMeta); err != nil {
+			return fmt.Errorf("failed to insert %s: %w", tablePamGroupMeta, err)
+		}
+	}
+
+	return newHistoryWriter(ctx, tx).recordChanges(ctx, scim.ResourceTypeGroup, before, after)
+}
+
+// uniqueGroups removes duplicate groups from list, last occurrence wins.
+func uniqueGroups(groups pam.Groups) pam.Groups {
+	pos := make(map[int]int, len(groups))
+	out := make(pam.Groups, 0, len(groups))
+	for _, g := range groups {
+		if i, ok := pos[g.ID]; ok {
+			out[i] = g
+			continue
+		}
+
+		pos[g.ID] = len(out)
+		out = append(out, g)
+	}
+	return out
+}
+
+var pamGroupSelectCols = append(
+	[]string{colID, colEntitlements, colSchemas},
+	coalesceStrings(colDisplayName, colExternalID)...,
+)
+
+// ListGroups implements service.PamGroupStorage
+func (r PamGroupRepository) ListGroups(ctx context.Context, lq model.ListQuery) (pam.Groups, *model.Page, error) {
+	sel, count, err := pamGroupFilter.listQuery(pamGroupSelectCols, lq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page := new(model.Page)
+	if page.Total, err = countRows(ctx, r.db, count); err != nil || lq.Count == 0 {
+		return nil, page, err
+	}
+
+	q, args, err := sel.ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out pam.Groups
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, nil, err
+	}
+
+	out = out[:nextPage(lq, page, len(out), func(i int) string {
+		return pam.FormatID(out[i].ID)
+	})]
+	return out, page, loadPamGroupAttributes(ctx, r.db, out)
+}
+
+// GroupByID implements service.PamGroupStorage
+func (r PamGroupRepository) GroupByID(ctx context.Context, id int) (*pam.Group, error) {
+	q, args, err := psql.Select(pamGroupSelectCols...).From(tablePamGroup).Where(squirrel.Eq{
+		colID: id,
+	}).Limit(1).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var out pam.Groups
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 {
+		return nil, web.NewErrNotFound("group not found")
+	}
+
+	if err = loadPamGroupAttributes(ctx, r.db, out); err != nil {
+		return nil, err
+	}
+	return &out[0], nil
+}
+
+// loadPamGroupAttributes loads members and metadata of passed groups.
+//
+// Each child table is queried once for all groups.
+func loadPamGroupAttributes(ctx context.Context, db sqlx.QueryerContext, groups pam.Groups) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(groups))
+	for _, g := range groups {
+		ids = append(ids, g.ID)
+	}
+	pos := indexByID(ids)
+
+	var members []referenceRow
+	err := selectByOwners(ctx, db, &members, tablePamGroupMembers, groupMemberSelectCols, ids, colValue)
+	if err != nil {
+		return err
+	}
+	for _, row := range members {
+		g := &groups[pos[row.OwnerID]]
+		g.Members = append(g.Members, row.Reference)
+	}
+
+	var metas []metaRow
+	if err = selectByOwners(ctx, db, &metas, tablePamGroupMeta, metaSelectCols, ids); err != nil {
+		return err
+	}
+	for _, row := range metas {
+		meta := row.Meta
+		groups[pos[row.OwnerID]].Meta = &meta
+	}
+
+	return nil
+}
+
+// pamGroupFilter maps SCIM group attributes to pamgroup tables
+var pamGroupFilter = filterMapping{
+	table:  tablePamGroup,
+	schema: scim.SchemaGroup,
+	attrs: map[string]attrColumn{
+		"id":                 {column: qualify(tablePamGroup, colID), kind: kindInt},
+		"displayname":        {column: qualify(tablePamGroup, colDisplayName)},
+		"externalid":         {column: qualify(tablePamGroup, colExternalID), caseExact: true},
+		"entitlements":       {column: qualify(tablePamGroup, colEntitlements), kind: kindStringArray},
+		"entitlements.value": {column: qualify(tablePamGroup, colEntitlements), kind: kindStringArray},
+		"schemas":            {column: qualify(tablePamGroup, colSchemas), kind: kindStringArray},
+
+		"members.value":   childColumn(tablePamGroupMembers, colValue, kindInt),
+		"members.display": childColumn(tablePamGroupMembers, colDisplay, kindString),
+
+		"meta.resourcetype": childColumn(tablePamGroupMeta, colResourceType, kindString),
+		"meta.created":      childColumn(tablePamGroupMeta, colCreated, kindTime),
+		"meta.lastmodified": childColumn(tablePamGroupMeta, colLastModified, kindTime),
+		"meta.location":     childColumn(tablePamGroupMeta, colLocation, kindString),
+	},
+	multiValued: map[string]string{
+		"members": tablePamGroupMembers,
+	},
+	sortable: map[string]bool{
+		"id":          true,
+		"displayname": true,
+		"externalid":  true,
+	},
+}
@@ -0,0 +1,461 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+const (
+	colOperation       = "operation"
+	colBefore          = "before"
+	colAfter           = "after"
+	colJobID           = `"jobId"`
+	colMemberUserID    = `"userId"`
+	colMemberGroupID   = `"groupId"`
+	colUserDisplay     = `"userDisplay"`
+	colGroupDisplay    = `"groupDisplay"`
+	colValidFrom       = `"validFrom"`
+	colValidTo         = `"validTo"`
+	tablePamHistory    = "pamhistory"
+	tablePamMemberHist = "pamhistory_memberships"
+)
+
+var (
+	pamHistoryCols = []string{colResourceType, colResourceID, colOperation, colBefore, colAfter, colJobID, colTime}
+
+	pamHistorySelectCols = []string{
+		colID, colResourceType, colResourceID, colOperation, colJobID, colTime,
+		"COALESCE(" + colBefore + "::text, '') AS " + colBefore,
+		"COALESCE(" + colAfter + "::text, '') AS " + colAfter,
+	}
+
+	pamMemberHistCols = []string{
+		colMemberUserID, colUserDisplay, colMemberGroupID, colGroupDisplay, colValidFrom, colJobID,
+	}
+
+	pamMemberHistSelectCols = append(
+		[]string{colMemberUserID, colMemberGroupID, colValidFrom, colValidTo, colJobID},
+		coalesceStrings(colUserDisplay, colGroupDisplay)...,
+	)
+
+	// historyIgnoredAttrs are attributes which are not stored in history.
+	//
+	// Metadata is maintained by PAM SCIM Server, memberships are stored separately.
+	historyIgnoredAttrs = []string{"meta", "groups", "members"}
+)
+
+type PamHistoryRepository struct {
+	db *sqlx.DB
+}
+
+// NewPamHistoryRepository is PamHistoryRepository constructor
+func NewPamHistoryRepository(db *sqlx.DB) *PamHistoryRepository {
+	return &PamHistoryRepository{db: db}
+}
+
+// changeRow is pamhistory row with resource states as text
+type changeRow struct {
+	pam.Change
+	Before string `db:"before"`
+	After  string `db:"after"`
+}
+
+// ListChanges implements service.PamHistoryStorage
+func (r PamHistoryRepository) ListChanges(ctx context.Context, lq model.ListQuery) (pam.Changes, *model.Page, error) {
+	sel, count, err := pamHistoryFilter.listQuery(pamHistorySelectCols, lq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page := new(model.Page)
+	if page.Total, err = countRows(ctx, r.db, count); err != nil || lq.Count == 0 {
+		return nil, page, err
+	}
+
+	q, args, err := sel.ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []changeRow
+	if err = r.db.SelectContext(ctx, &rows, q, args...); err != nil {
+		return nil, nil, err
+	}
+
+	rows = rows[:nextPage(lq, page, len(rows), func(i int) string {
+		return pam.FormatID(rows[i].ID)
+	})]
+
+	out := make(pam.Changes, 0, len(rows))
+	for _, row := range rows {
+		c := row.Change
+		if row.Before != "" {
+			c.Before = json.RawMessage(row.Before)
+		}
+		if row.After != "" {
+			c.After = json.RawMessage(row.After)
+		}
+
+		// column has no time zone, values are always stored in UTC.
+		c.Time = c.Time.UTC()
+		out = append(out, c)
+	}
+	return out, page, nil
+}
+
+// UserGroupsAt implements service.PamHistoryStorage
+func (r PamHistoryRepository) UserGroupsAt(ctx context.Context, userID int, asOf time.Time) (pam.MembershipPeriods, error) {
+	return r.membershipsAt(ctx, squirrel.Eq{colMemberUserID: userID}, asOf, colMemberGroupID)
+}
+
+// GroupMembersAt implements service.PamHistoryStorage
+func (r PamHistoryRepository) GroupMembersAt(ctx context.Context, groupID int, asOf time.Time) (pam.MembershipPeriods, error) {
+	return r.membershipsAt(ctx, squirrel.Eq{colMemberGroupID: groupID}, asOf, colMemberUserID)
+}
+
+// membershipsAt returns memberships which were valid at passed time
+func (r PamHistoryRepository) membershipsAt(ctx context.Context, pred squirrel.Sqlizer, asOf time.Time, orderBy string) (pam.MembershipPeriods, error) {
+	q, args, err := psql.Select(pamMemberHistSelectCols...).From(tablePamMemberHist).Where(squirrel.And{
+		pred,
+		squirrel.LtOrEq{colValidFrom: utcTime(&asOf)},
+		squirrel.Or{squirrel.Eq{colValidTo: nil}, squirrel.Gt{colValidTo: utcTime(&asOf)}},
+	}).OrderBy(orderBy).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var out pam.MembershipPeriods
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, fmt.Errorf("failed to load memberships: %w", err)
+	}
+
+	for i := range out {
+		out[i].From = out[i].From.UTC()
+		if out[i].To != nil {
+			to := out[i].To.UTC()
+			out[i].To = &to
+		}
+	}
+	return out, nil
+}
+
+// pamHistoryFilter maps change attributes to pamhistory table
+var pamHistoryFilter = filterMapping{
+	table: tablePamHistory,
+	attrs: map[string]attrColumn{
+		"id":           {column: qualify(tablePamHistory, colID), kind: kindInt},
+		"resourcetype": {column: qualify(tablePamHistory, colResourceType)},
+		"resourceid":   {column: qualify(tablePamHistory, colResourceID), kind: kindInt},
+		"operation":    {column: qualify(tablePamHistory, colOperation)},
+		"jobid":        {column: qualify(tablePamHistory, colJobID), kind: kindUUID},
+		"time":         {column: qualify(tablePamHistory, colTime), kind: kindTime},
+	},
+	sortable: map[string]bool{
+		"id":        true,
+		"operation": true,
+		"time":      true,
+	},
+}
+
+// historyWriter records changes of mirrored resources within mirror transaction
+type historyWriter struct {
+	tx    *sqlx.Tx
+	now   time.Time
+	jobID interface{}
+}
+
+func newHistoryWriter(ctx context.Context, tx *sqlx.Tx) historyWriter {
+	w := historyWriter{tx: tx, now: time.Now().UTC()}
+	if id, ok := pam.SyncJobFromContext(ctx); ok {
+		w.jobID = id.String()
+	}
+	return w
+}
+
+// recordChanges compares resource states before and after change and records changed resources.
+//
+// Resources missing in "after" are recorded as deleted, resources missing in "before" as created.
+func (w historyWriter) recordChanges(ctx context.Context, resourceType string, before, after map[int][]byte) error {
+	ids := make([]int, 0, len(after))
+	for id := range after {
+		ids = append(ids, id)
+	}
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	ins := psql.Insert(tablePamHistory).Columns(pamHistoryCols...)
+	rows := 0
+	for _, id := range ids {
+		b, inBefore := before[id]
+		a, inAfter := after[id]
+		op := pam.ChangeUpdated
+		switch {
+		case !inBefore:
+			op = pam.ChangeCreated
+		case !inAfter:
+			op = pam.ChangeDeleted
+		case bytes.Equal(b, a):
+			continue
+		}
+
+		ins = ins.Values(resourceType, id, op, jsonValue(b), jsonValue(a), w.jobID, w.now)
+		if rows++; rows == membershipsBatchSize {
+			if err := execBuilder(ctx, w.tx, ins); err != nil {
+				return fmt.Errorf("failed to record history: %w", err)
+			}
+			ins, rows = psql.Insert(tablePamHistory).Columns(pamHistoryCols...), 0
+		}
+	}
+
+	if rows == 0 {
+		return nil
+	}
+
+	if err := execBuilder(ctx, w.tx, ins); err != nil {
+		return fmt.Errorf("failed to record history: %w", err)
+	}
+	return nil
+}
+
+// replaceMemberships closes current memberships matching predicate which are not in passed list
+// and opens passed memberships which are not current yet.
+func (w historyWriter) replaceMemberships(ctx context.Context, pred squirrel.Sqlizer, ms []pam.Membership) error {
+	q, args, err := psql.Select(colMemberUserID, colMemberGroupID).From(tablePamMemberHist).
+		Where(squirrel.And{squirrel.Eq{colValidTo: nil}, pred}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	var current []struct {
+		UserID  int `db:"userId"`
+		GroupID int `db:"groupId"`
+	}
+	if err = w.tx.SelectContext(ctx, &current, q, args...); err != nil {
+		return fmt.Errorf("failed to load membership history: %w", err)
+	}
+
+	type key struct{ userID, groupID int }
+	keep := make(map[key]bool, len(ms))
+	for _, m := range ms {
+		keep[key{m.UserID, m.GroupID}] = true
+	}
+
+	var closeUsers, closeGroups []int
+	for _, c := range current {
+		k := key{c.UserID, c.GroupID}
+		if keep[k] {
+			delete(keep, k)
+			continue
+		}
+		closeUsers = append(closeUsers, c.UserID)
+		closeGroups = append(closeGroups, c.GroupID)
+	}
+
+	if len(closeUsers) > 0 {
+		err = w.closeMemberships(ctx, squirrel.Expr(
+			"("+colMemberUserID+", "+colMemberGroupID+") IN (SELECT * FROM unnest(?::int[], ?::int[]))",
+			intArray(closeUsers), intArray(closeGroups),
+		))
+		if err != nil {
+			return err
+		}
+	}
+
+	added := make([]pam.Membership, 0, len(keep))
+	for _, m := range ms {
+		if k := (key{m.UserID, m.GroupID}); keep[k] {
+			delete(keep, k)
+			added = append(added, m)
+		}
+	}
+
+	for start := 0; start < len(added); start += membershipsBatchSize {
+		end := start + membershipsBatchSize
+		if end > len(added) {
+			end = len(added)
+		}
+
+		ins := psql.Insert(tablePamMemberHist).Columns(pamMemberHistCols...)
+		for _, m := range added[start:end] {
+			ins = ins.Values(m.UserID, nullString(m.UserDisplay), m.GroupID, nullString(m.GroupDisplay), w.now, w.jobID)
+		}
+
+		if err = execBuilder(ctx, w.tx, ins); err != nil {
+			return fmt.Errorf("failed to record membership history: %w", err)
+		}
+	}
+	return nil
+}
+
+// closeMemberships ends current memberships matching predicate
+func (w historyWriter) closeMemberships(ctx context.Context, pred squirrel.Sqlizer) error {
+	err := execBuilder(ctx, w.tx, psql.Update(tablePamMemberHist).Set(colValidTo, w.now).
+		Where(squirrel.And{squirrel.Eq{colValidTo: nil}, pred}))
+	if err != nil {
+		return fmt.Errorf("failed to record membership history: %w", err)
+	}
+	return nil
+}
+
+// userSnapshots returns history states of stored users with passed IDs
+func userSnapshots(ctx context.Context, tx *sqlx.Tx, ids []int) (map[int][]byte, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	q, args, err := psql.Select(pamUserSelectCols...).From(tablePamUser).Where(anyOf(colID, ids)).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var users pam.Users
+	if err = tx.SelectContext(ctx, &users, q, args...); err != nil {
+		return nil, fmt.Errorf("failed to load users history: %w", err)
+	}
+
+	if err = loadPamUserAttributes(ctx, tx, users); err != nil {
+		return nil, fmt.Errorf("failed to load users history: %w", err)
+	}
+	return usersSnapshot(users)
+}
+
+// usersSnapshot returns history states of users
+func usersSnapshot(users pam.Users) (map[int][]byte, error) {
+	out := make(map[int][]byte, len(users))
+	for _, u := range users {
+		data, err := historyState(u.SCIM())
+		if err != nil {
+			return nil, fmt.Errorf("user %d: %w", u.ID, err)
+		}
+		out[u.ID] = data
+	}
+	return out, nil
+}
+
+// groupSnapshots returns history states of stored groups with passed IDs
+func groupSnapshots(ctx context.Context, tx *sqlx.Tx, ids []int) (map[int][]byte, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	q, args, err := psql.Select(pamGroupSelectCols...).From(tablePamGroup).Where(anyOf(colID, ids)).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var groups pam.Groups
+	if err = tx.SelectContext(ctx, &groups, q, args...); err != nil {
+		return nil, fmt.Errorf("failed to load groups history: %w", err)
+	}
+
+	if err = loadPamGroupAttributes(ctx, tx, groups); err != nil {
+		return nil, fmt.Errorf("failed to load groups history: %w", err)
+	}
+	return groupsSnapshot(groups)
+}
+
+// groupsSnapshot returns history states of groups
+func groupsSnapshot(groups pam.Groups) (map[int][]byte, error) {
+	out := make(map[int][]byte, len(groups))
+	for _, g := range groups {
+		data, err := historyState(g.SCIM())
+		if err != nil {
+			return nil, fmt.Errorf("group %d: %w", g.ID, err)
+		}
+		out[g.ID] = data
+	}
+	return out, nil
+}
+
+// historyState returns JSON representation of resource stored in history.
+//
+// Representation is normalized, so equal resources have equal representations.
+func historyState(res interface{}) ([]byte, error) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	var attrs map[string]interface{}
+	if err = json.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+
+	for _, k := range historyIgnoredAttrs {
+		delete(attrs, k)
+	}
+	return json.Marshal(attrs)
+}
+
+// jsonValue converts JSON document to JSONB column value
+func jsonValue(data []byte) interface{} {
+	if data == nil {
+		return nil
+	}
+	return string(data)
+}
+
+// deletedIDs returns IDs of rows which would be deleted by predicate
+func deletedIDs(ctx context.Context, tx *sqlx.Tx, table string, pred squirrel.Sqlizer) ([]int, error) {
+	q, args, err := psql.Select(colID).From(table).Where(pred).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	return ids, tx.SelectContext(ctx, &ids, q, args...)
+}
+
+// deleteWithHistory removes users or groups matching predicate and records their removal.
+//
+// Memberships of removed resources are closed, membership rows are removed by cascade.
+func deleteWithHistory(ctx context.Context, tx *sqlx.Tx, resourceType string, pred squirrel.Sqlizer) (int64, error) {
+	table, memberCol, snapshots := tablePamUser, colMemberUserID, userSnapshots
+	if resourceType == scim.ResourceTypeGroup {
+		table, memberCol, snapshots = tablePamGroup, colMemberGroupID, groupSnapshots
+	}
+
+	ids, err := deletedIDs(ctx, tx, table, pred)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	before, err := snapshots(ctx, tx, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	q, args, err := psql.Delete(table).Where(anyOf(colID, ids)).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, q, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	w := newHistoryWriter(ctx, tx)
+	if err = w.recordChanges(ctx, resourceType, before, nil); err != nil {
+		return 0, err
+	}
+
+	if err = w.closeMemberships(ctx, anyOf(memberCol, ids)); err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
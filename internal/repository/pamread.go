@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/strick-j/scimfe/internal/model/pam"
+)
+
+// Row types used to load resource attributes stored in child tables.
+// Each row contains owner resource ID to assign attribute to the owner.
+type (
+	nameRow struct {
+		OwnerID int `db:"id"`
+		pam.Name
+	}
+
+	multiValueRow struct {
+		OwnerID int `db:"id"`
+		pam.MultiValue
+	}
+
+	referenceRow struct {
+		OwnerID int `db:"id"`
+		pam.Reference
+	}
+
+	metaRow struct {
+		OwnerID int `db:"id"`
+		pam.Meta
+	}
+
+	enterpriseRow struct {
+		OwnerID int `db:"id"`
+		pam.Enterprise
+	}
+
+	// textMetaRow is metadata row of resource with text ID, like container
+	textMetaRow struct {
+		OwnerID string `db:"id"`
+		pam.Meta
+	}
+)
+
+var (
+	nameSelectCols = append([]string{colID}, coalesceStrings(pamUserNameCols[1:]...)...)
+
+	multiValueSelectCols = append([]string{colID, coalesce(colPrimary, "false")},
+		coalesceStrings(colName, colDisplay, colValue, colRef)...)
+
+	metaSelectCols = append([]string{colID, colCreated, colLastModified},
+		coalesceStrings(colResourceType, colLocation, colVersion)...)
+
+	enterpriseSelectCols = append([]string{colID}, coalesceStrings(pamUserEnterpriseCols[1:]...)...)
+
+	groupMemberSelectCols = append([]string{colID, colValue, "'User' AS " + colType},
+		coalesceStrings(colDisplay, colRef)...)
+
+	userGroupSelectCols = append([]string{colID, colValue},
+		coalesceStrings(colDisplay, colType, colRef)...)
+)
+
+// indexByID returns resource positions by resource ID
+func indexByID(ids []int) map[int]int {
+	out := make(map[int]int, len(ids))
+	for i, id := range ids {
+		out[id] = i
+	}
+	return out
+}
+
+// selectByOwners selects rows of child table which belong to resources with specified IDs.
+func selectByOwners(ctx context.Context, db sqlx.QueryerContext, dst interface{}, table string, cols []string, ids []int, orderBy ...string) error {
+	q, args, err := psql.Select(cols...).From(table).
+		Where(anyOf(colID, ids)).OrderBy(orderBy...).ToSql()
+	if err != nil {
+		return err
+	}
+
+	if err = sqlx.SelectContext(ctx, db, dst, q, args...); err != nil {
+		return fmt.Errorf("failed to load %s: %w", table, err)
+	}
+	return nil
+}
+
+// selectTextMeta returns metadata of resources with text IDs, like containers, by resource ID.
+func selectTextMeta(ctx context.Context, db sqlx.QueryerContext, table string, ids []string) (map[string]*pam.Meta, error) {
+	q, args, err := psql.Select(metaSelectCols...).From(table).Where(anyOfText(colID, ids)).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []textMetaRow
+	if err = sqlx.SelectContext(ctx, db, &rows, q, args...); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", table, err)
+	}
+
+	out := make(map[string]*pam.Meta, len(rows))
+	for i := range rows {
+		out[rows[i].OwnerID] = &rows[i].Meta
+	}
+	return out, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	colUpdated = "updated"
+
+	tablePamSyncState = "pamsync_state"
+)
+
+// PamSyncStateRepository stores synchronization high-water marks
+type PamSyncStateRepository struct {
+	db *sqlx.DB
+}
+
+// NewPamSyncStateRepository is PamSyncStateRepository constructor
+func NewPamSyncStateRepository(db *sqlx.DB) *PamSyncStateRepository {
+	return &PamSyncStateRepository{db: db}
+}
+
+// Watermark implements service.PamSyncStateStore
+func (r PamSyncStateRepository) Watermark(ctx context.Context, resourceType string) (*time.Time, error) {
+	q, args, err := psql.Select(colLastModified).From(tablePamSyncState).
+		Where(squirrel.Eq{colResourceType: resourceType}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(time.Time)
+	err = r.db.GetContext(ctx, out, q, args...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s watermark: %w", resourceType, err)
+	}
+	return out, nil
+}
+
+// SetWatermark implements service.PamSyncStateStore.
+//
+// Watermark is never moved back, so concurrent synchronizations can't cause refetch of the same changes.
+func (r PamSyncStateRepository) SetWatermark(ctx context.Context, resourceType string, lastModified time.Time) error {
+	q, args, err := psql.Insert(tablePamSyncState).
+		Columns(colResourceType, colLastModified, colUpdated).
+		Values(resourceType, lastModified.UTC(), time.Now().UTC()).
+		Suffix("ON CONFLICT (" + colResourceType + ") DO UPDATE SET " +
+			colLastModified + " = GREATEST(" + tablePamSyncState + "." + colLastModified + ", EXCLUDED." + colLastModified + "), " +
+			colUpdated + " = EXCLUDED." + colUpdated).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = r.db.ExecContext(ctx, q, args...); err != nil {
+		return fmt.Errorf("failed to store %s watermark: %w", resourceType, err)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/service"
+	"github.com/strick-j/scimfe/internal/web"
+)
+
+const (
+	colMode            = "mode"
+	colStatus          = "status"
+	colCancelRequested = `"cancelRequested"`
+	colPages           = "pages"
+	colUpserted        = "upserted"
+	colDeleted         = "deleted"
+	colFailed          = "failed"
+	colMemberships     = "memberships"
+	colPermissions     = "permissions"
+	colUnresolved      = "unresolved"
+	colFinished        = "finished"
+
+	tablePamSyncJobs = "pamsync_jobs"
+)
+
+// errJobInterrupted is error message of jobs which were running on a stopped replica
+const errJobInterrupted = "job was interrupted"
+
+var syncJobSelectCols = append(
+	[]string{
+		colID, colResourceType, colMode, colStatus, colActor, colCancelRequested,
+		colPages, colUpserted, colDeleted, colFailed, colMemberships, colPermissions, colUnresolved,
+		colCreated, colUpdated, colFinished,
+	},
+	coalesceStrings(colError)...,
+)
+
+// PamSyncJobRepository stores synchronization jobs
+type PamSyncJobRepository struct {
+	db *sqlx.DB
+}
+
+// NewPamSyncJobRepository is PamSyncJobRepository constructor
+func NewPamSyncJobRepository(db *sqlx.DB) *PamSyncJobRepository {
+	return &PamSyncJobRepository{db: db}
+}
+
+// AddJob implements service.PamSyncJobStore.
+//
+// Returns service.ErrSyncJobRunning if another job is running.
+func (r PamSyncJobRepository) AddJob(ctx context.Context, job pam.SyncJob) error {
+	q, args, err := psql.Insert(tablePamSyncJobs).SetMap(map[string]interface{}{
+		colID:           job.ID,
+		colResourceType: job.ResourceType,
+		colMode:         job.Mode,
+		colStatus:       job.Status,
+		colActor:        job.Actor,
+		colCreated:      utcTime(&job.Created),
+		colUpdated:      utcTime(&job.Updated),
+	}).Suffix("ON CONFLICT (" + colStatus + ") WHERE " + colStatus + " = '" + string(pam.SyncJobRunning) + "' DO NOTHING").
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("failed to store synchronization job: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return service.ErrSyncJobRunning
+	}
+	return nil
+}
+
+// UpdateJobProgress implements service.PamSyncJobStore
+func (r PamSyncJobRepository) UpdateJobProgress(ctx context.Context, id uuid.UUID, progress pam.SyncResult) (bool, error) {
+	q, args, err := psql.Update(tablePamSyncJobs).
+		SetMap(progressValues(progress)).
+		Set(colUpdated, time.Now().UTC()).
+		Where(squirrel.Eq{colID: id}).
+		Suffix(returningSuffix(colCancelRequested)).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	var cancelRequested bool
+	if err = r.db.GetContext(ctx, &cancelRequested, q, args...); err != nil {
+		return false, fmt.Errorf("failed to update synchronization job progress: %w", err)
+	}
+	return cancelRequested, nil
+}
+
+// FinishJob implements service.PamSyncJobStore
+func (r PamSyncJobRepository) FinishJob(ctx context.Context, job pam.SyncJob) error {
+	err := execBuilder(ctx, r.db, psql.Update(tablePamSyncJobs).
+		SetMap(progressValues(job.SyncResult)).
+		SetMap(map[string]interface{}{
+			colStatus:   job.Status,
+			colError:    nullString(job.Error),
+			colUpdated:  utcTime(&job.Updated),
+			colFinished: utcTime(job.Finished),
+		}).
+		Where(squirrel.Eq{colID: job.ID}))
+	if err != nil {
+		return fmt.Errorf("failed to store synchronization job result: %w", err)
+	}
+	return nil
+}
+
+// RequestJobCancel implements service.PamSyncJobStore
+func (r PamSyncJobRepository) RequestJobCancel(ctx context.Context, id uuid.UUID) (bool, error) {
+	q, args, err := psql.Update(tablePamSyncJobs).
+		Set(colCancelRequested, true).
+		Where(squirrel.Eq{colID: id, colStatus: pam.SyncJobRunning}).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel synchronization job: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FailStaleJobs implements service.PamSyncJobStore
+func (r PamSyncJobRepository) FailStaleJobs(ctx context.Context, updatedBefore time.Time) (int64, error) {
+	now := time.Now().UTC()
+	q, args, err := psql.Update(tablePamSyncJobs).
+		SetMap(map[string]interface{}{
+			colStatus:   pam.SyncJobFailed,
+			colError:    errJobInterrupted,
+			colFinished: now,
+		}).
+		Where(squirrel.Eq{colStatus: pam.SyncJobRunning}).
+		Where(squirrel.Lt{colUpdated: updatedBefore.UTC()}).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail stale synchronization jobs: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// JobByID implements service.PamSyncJobStore
+func (r PamSyncJobRepository) JobByID(ctx context.Context, id uuid.UUID) (*pam.SyncJob, error) {
+	q, args, err := psql.Select(syncJobSelectCols...).From(tablePamSyncJobs).
+		Where(squirrel.Eq{colID: id}).Limit(1).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var out pam.SyncJobs
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 {
+		return nil, web.NewErrNotFound("synchronization job not found")
+	}
+
+	utcJobTimes(out)
+	return &out[0], nil
+}
+
+// ListJobs implements service.PamSyncJobStore
+func (r PamSyncJobRepository) ListJobs(ctx context.Context, lq model.ListQuery) (pam.SyncJobs, *model.Page, error) {
+	sel, count, err := syncJobFilter.listQuery(syncJobSelectCols, lq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page := new(model.Page)
+	if page.Total, err = countRows(ctx, r.db, count); err != nil || lq.Count == 0 {
+		return nil, page, err
+	}
+
+	q, args, err := sel.ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out pam.SyncJobs
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, nil, err
+	}
+
+	out = out[:nextPage(lq, page, len(out), func(i int) string {
+		return out[i].ID.String()
+	})]
+
+	utcJobTimes(out)
+	return out, page, nil
+}
+
+func progressValues(p pam.SyncResult) map[string]interface{} {
+	return map[string]interface{}{
+		colPages:       p.Pages,
+		colUpserted:    p.Upserted,
+		colDeleted:     p.Deleted,
+		colFailed:      p.Failed,
+		colMemberships: p.Memberships,
+		colPermissions: p.Permissions,
+		colUnresolved:  p.Unresolved,
+	}
+}
+
+// utcJobTimes sets UTC location of job times, since columns have no time zone
+func utcJobTimes(jobs pam.SyncJobs) {
+	for i := range jobs {
+		jobs[i].Created = jobs[i].Created.UTC()
+		jobs[i].Updated = jobs[i].Updated.UTC()
+		if jobs[i].Finished != nil {
+			t := jobs[i].Finished.UTC()
+			jobs[i].Finished = &t
+		}
+	}
+}
+
+// syncJobFilter maps synchronization job attributes to jobs table
+var syncJobFilter = filterMapping{
+	table: tablePamSyncJobs,
+	attrs: map[string]attrColumn{
+		"id":              {column: qualify(tablePamSyncJobs, colID), kind: kindUUID},
+		"resourcetype":    {column: qualify(tablePamSyncJobs, colResourceType)},
+		"mode":            {column: qualify(tablePamSyncJobs, colMode)},
+		"status":          {column: qualify(tablePamSyncJobs, colStatus)},
+		"actor":           {column: qualify(tablePamSyncJobs, colActor), caseExact: true},
+		"cancelrequested": {column: qualify(tablePamSyncJobs, colCancelRequested), kind: kindBool},
+		"error":           {column: qualify(tablePamSyncJobs, colError)},
+		"created":         {column: qualify(tablePamSyncJobs, colCreated), kind: kindTime},
+		"updated":         {column: qualify(tablePamSyncJobs, colUpdated), kind: kindTime},
+		"finished":        {column: qualify(tablePamSyncJobs, colFinished), kind: kindTime},
+	},
+	sortable: map[string]bool{
+		"id":           true,
+		"resourcetype": true,
+		"status":       true,
+		"created":      true,
+		"finished":     true,
+	},
+}
@@ -0,0 +1,504 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+const (
+	colUserName     = "username"
+	colDisplayName  = "displayname"
+	colNickName     = "nickname"
+	colProfileURL   = `"profileUrl"`
+	colTitle        = "title"
+	colUserType     = `"userType"`
+	colLocale       = "locale"
+	colTimezone     = "timezone"
+	colActive       = "active"
+	colEntitlements = "entitlements"
+	colSchemas      = "schemas"
+	colExtensions   = "extensions"
+
+	colGivenName       = "givenname"
+	colMiddleName      = "middlename"
+	colFamilyName      = "familyname"
+	colFormatted       = "formatted"
+	colHonorificPrefix = `"honorificPrefix"`
+	colHonorificSuffix = `"honorificSuffix"`
+
+	// colName is used as value type in multi-valued attribute tables
+	colPrimary = `"primary"`
+	colDisplay = "display"
+	colValue   = "value"
+	colRef     = "ref"
+
+	colEmployeeNumber = "employeenumber"
+	colCostCenter     = "costcenter"
+	colOrganization   = "organization"
+	colDivision       = "division"
+	colDepartment     = "department"
+	colManagerValue   = "manager_value"
+	colManagerRef     = "manager_ref"
+	colManagerDisplay = "manager_display"
+
+	colResourceType = `"resourceType"`
+	colCreated      = "created"
+	colLastModified = `"lastModified"`
+	colLocation     = "location"
+	colVersion      = "version"
+
+	tablePamUser             = "pamuser"
+	tablePamUserName         = "pamuser_name"
+	tablePamUserEmails       = "pamuser_emails"
+	tablePamUserPhoneNumbers = "pamuser_phonenumbers"
+	tablePamUserMeta         = "pamuser_meta"
+	tablePamUserEnterprise   = "pamuser_enterprise"
+)
+
+var (
+	pamUserCols = []string{
+		colID, colUserName, colDisplayName, colNickName, colProfileURL, colTitle,
+		colUserType, colLocale, colTimezone, colActive, colEntitlements, colSchemas, colExtensions,
+	}
+
+	pamUserNameCols = []string{
+		colID, colGivenName, colMiddleName, colFamilyName, colFormatted,
+		colHonorificPrefix, colHonorificSuffix,
+	}
+
+	pamUserEnterpriseCols = []string{
+		colID, colEmployeeNumber, colCostCenter, colOrganization, colDivision, colDepartment,
+		colManagerValue, colManagerRef, colManagerDisplay,
+	}
+
+	multiValueCols = []string{colID, colName, colPrimary, colDisplay, colValue, colRef}
+
+	metaCols = []string{colID, colResourceType, colCreated, colLastModified, colLocation, colVersion}
+
+	// pamUserChildTables contain user attributes and are replaced on each user update.
+	pamUserChildTables = []string{
+		tablePamUserName, tablePamUserEmails, tablePamUserPhoneNumbers, tablePamUserMeta, tablePamUserEnterprise,
+	}
+)
+
+type PamUserRepository struct {
+	db *sqlx.DB
+}
+
+// NewPamUserRepository is PamUserRepository constructor
+func NewPamUserRepository(db *sqlx.DB) *PamUserRepository {
+	return &PamUserRepository{db: db}
+}
+
+// UpsertUsers implements service.PamUserSyncStore.
+//
+// Users are created or updated in a single transaction together with their attributes,
+// changed users are recorded in history.
+func (r PamUserRepository) UpsertUsers(ctx context.Context, users pam.Users) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	if err = upsertPamUsers(ctx, tx, users); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteUsersExcept implements service.PamUserSyncStore.
+//
+// User attributes and memberships are removed by cascade, removal is recorded in history.
+func (r PamUserRepository) DeleteUsersExcept(ctx context.Context, keep []int) (int64, error) {
+	deleted, err := r.deleteUsers(ctx, noneOf(colID, keep))
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove stale users: %w", err)
+	}
+	return deleted, nil
+}
+
+// DeleteUser implements service.PamUserProvisionStore.
+//
+// Missing user is not an error, since mirror can be not synchronized yet.
+func (r PamUserRepository) DeleteUser(ctx context.Context, id int) error {
+	if _, err := r.deleteUsers(ctx, squirrel.Eq{colID: id}); err != nil {
+		return fmt.Errorf("failed to remove user: %w", err)
+	}
+	return nil
+}
+
+func (r PamUserRepository) deleteUsers(ctx context.Context, pred squirrel.Sqlizer) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	deleted, err := deleteWithHistory(ctx, tx, scim.ResourceTypeUser, pred)
+	if err != nil {
+		return 0, err
+	}
+	return deleted, tx.Commit()
+}
+
+func upsertPamUsers(ctx context.Context, tx *sqlx.Tx, users pam.Users) error {
+	users = uniqueUsers(users)
+	ids := make([]int, 0, len(users))
+	insUsers := psql.Insert(tablePamUser).Columns(pamUserCols...).
+		Suffix(upsertSuffix(colID, pamUserCols[1:]...))
+	insNames := psql.Insert(tablePamUserName).Columns(pamUserNameCols...)
+	insEmails := psql.Insert(tablePamUserEmails).Columns(multiValueCols...)
+	insPhones := psql.Insert(tablePamUserPhoneNumbers).Columns(multiValueCols...)
+	insMeta := psql.Insert(tablePamUserMeta).Columns(metaCols...)
+	insEnterprise := psql.Insert(tablePamUserEnterprise).Columns(pamUserEnterpriseCols...)
+
+	var hasNames, hasEmails, hasPhones, hasMeta, hasEnterprise bool
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+
+	before, err := userSnapshots(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+
+	after, err := usersSnapshot(users)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		insUsers = insUsers.Values(
+			u.ID, nullString(u.UserName), nullString(u.DisplayName), nullString(u.NickName),
+			nullString(u.ProfileURL), nullString(u.Title), nullString(u.UserType),
+			nullString(u.Locale), nullString(u.Timezone), u.Active, u.Entitlements, u.Schemas, u.Extensions,
+		)
+
+		if n := u.Name; n != nil {
+			hasNames = true
+			insNames = insNames.Values(
+				u.ID, nullString(n.GivenName), nullString(n.MiddleName), nullString(n.FamilyName),
+				nullString(n.Formatted), nullString(n.HonorificPrefix), nullString(n.HonorificSuffix),
+			)
+		}
+
+		for _, v := range u.Emails {
+			hasEmails = true
+			insEmails = insEmails.Values(multiValueValues(u.ID, v)...)
+		}
+
+		for _, v := range u.PhoneNumbers {
+			hasPhones = true
+			insPhones = insPhones.Values(multiValueValues(u.ID, v)...)
+		}
+
+		if m := u.Meta; m != nil {
+			hasMeta = true
+			insMeta = insMeta.Values(metaValues(u.ID, m)...)
+		}
+
+		if e := u.Enterprise; e != nil {
+			hasEnterprise = true
+			insEnterprise = insEnterprise.Values(
+				u.ID, nullString(e.EmployeeNumber), nullString(e.CostCenter), nullString(e.Organization),
+				nullString(e.Division), nullString(e.Department), nullString(e.ManagerValue),
+				nullString(e.ManagerRef), nullString(e.ManagerDisplay),
+			)
+		}
+	}
+
+	if err := execBuilder(ctx, tx, insUsers); err != nil {
+		return fmt.Errorf("failed to upsert users: %w", err)
+	}
+
+	for _, table := range pamUserChildTables {
+		if err := execBuilder(ctx, tx, psql.Delete(table).Where(anyOf(colID, ids))); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", table, err)
+		}
+	}
+
+	inserts := []struct {
+		ok      bool
+		table   string
+		builder squirrel.Sqlizer
+	}{
+		{hasNames, tablePamUserName, insNames},
+		{hasEmails, tablePamUserEmails, insEmails},
+		{hasPhones, tablePamUserPhoneNumbers, insPhones},
+		{hasMeta, tablePamUserMeta, insMeta},
+		{hasEnterprise, tablePamUserEnterprise, insEnterprise},
+	}
+
+	for _, ins := range inserts {
+		if !ins.ok {
+			continue
+		}
+
+		if err := execBuilder(ctx, tx, ins.builder); err != nil {
+			return fmt.Errorf("failed to insert %s: %w", ins.table, err)
+		}
+	}
+
+	return newHistoryWriter(ctx, tx).recordChanges(ctx, scim.ResourceTypeUser, before, after)
+}
+
+// uniqueUsers removes duplicate users from list, last occurrence wins.
+//
+// Duplicates are not allowed in a single upsert statement.
+func uniqueUsers(users pam.Users) pam.Users {
+	pos := make(map[int]int, len(users))
+	out := make(pam.Users, 0, len(users))
+	for _, u := range users {
+		if i, ok := pos[u.ID]; ok {
+			out[i] = u
+			continue
+		}
+
+		pos[u.ID] = len(out)
+		out = append(out, u)
+	}
+	return out
+}
+
+func multiValueValues(id int, v pam.MultiValue) []interface{} {
+	return []interface{}{
+		id, nullString(v.Type), v.Primary, nullString(v.Display), nullString(v.Value), nullString(v.Ref),
+	}
+}
+
+func metaValues(id interface{}, m *pam.Meta) []interface{} {
+	return []interface{}{
+		id, nullString(m.ResourceType), utcTime(m.Created), utcTime(m.LastModified), nullString(m.Location),
+		nullString(m.Version),
+	}
+}
+
+var pamUserSelectCols = append(
+	[]string{colID, coalesce(colActive, "true"), colEntitlements, colSchemas, colExtensions},
+	coalesceStrings(
+		colUserName, colDisplayName, colNickName, colProfileURL,
+		colTitle, colUserType, colLocale, colTimezone,
+	)...,
+)
+
+// ListUsers implements service.PamUserStorage
+func (r PamUserRepository) ListUsers(ctx context.Context, lq model.ListQuery) (pam.Users, *model.Page, error) {
+	sel, count, err := pamUserFilter.listQuery(pamUserSelectCols, lq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page := new(model.Page)
+	if page.Total, err = countRows(ctx, r.db, count); err != nil || lq.Count == 0 {
+		return nil, page, err
+	}
+
+	q, args, err := sel.ToSql()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out pam.Users
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, nil, err
+	}
+
+	out = out[:nextPage(lq, page, len(out), func(i int) string {
+		return pam.FormatID(out[i].ID)
+	})]
+	return out, page, loadPamUserAttributes(ctx, r.db, out)
+}
+
+// UserByID implements service.PamUserStorage
+func (r PamUserRepository) UserByID(ctx context.Context, id int) (*pam.User, error) {
+	q, args, err := psql.Select(pamUserSelectCols...).From(tablePamUser).Where(squirrel.Eq{
+		colID: id,
+	}).Limit(1).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var out pam.Users
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 {
+		return nil, web.NewErrNotFound("user not found")
+	}
+
+	if err = loadPamUserAttributes(ctx, r.db, out); err != nil {
+		return nil, err
+	}
+	return &out[0], nil
+}
+
+// loadPamUserAttributes loads attributes of passed users from child tables.
+//
+// Each child table is queried once for all users.
+func loadPamUserAttributes(ctx context.Context, db sqlx.QueryerContext, users pam.Users) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	pos := indexByID(ids)
+
+	var names []nameRow
+	if err := selectByOwners(ctx, db, &names, tablePamUserName, nameSelectCols, ids); err != nil {
+		return err
+	}
+	for _, row := range names {
+		name := row.Name
+		users[pos[row.OwnerID]].Name = &name
+	}
+
+	var emails []multiValueRow
+	err := selectByOwners(ctx, db, &emails, tablePamUserEmails, multiValueSelectCols, ids, colPrimary+" DESC", colValue)
+	if err != nil {
+		return err
+	}
+	for _, row := range emails {
+		u := &users[pos[row.OwnerID]]
+		u.Emails = append(u.Emails, row.MultiValue)
+	}
+
+	var phones []multiValueRow
+	err = selectByOwners(ctx, db, &phones, tablePamUserPhoneNumbers, multiValueSelectCols, ids, colPrimary+" DESC", colValue)
+	if err != nil {
+		return err
+	}
+	for _, row := range phones {
+		u := &users[pos[row.OwnerID]]
+		u.PhoneNumbers = append(u.PhoneNumbers, row.MultiValue)
+	}
+
+	var groups []referenceRow
+	err = selectByOwners(ctx, db, &groups, tablePamUserGroups, userGroupSelectCols, ids, colValue)
+	if err != nil {
+		return err
+	}
+	for _, row := range groups {
+		u := &users[pos[row.OwnerID]]
+		u.Groups = append(u.Groups, row.Reference)
+	}
+
+	var metas []metaRow
+	if err = selectByOwners(ctx, db, &metas, tablePamUserMeta, metaSelectCols, ids); err != nil {
+		return err
+	}
+	for _, row := range metas {
+		meta := row.Meta
+		users[pos[row.OwnerID]].Meta = &meta
+	}
+
+	var enterprise []enterpriseRow
+	if err = selectByOwners(ctx, db, &enterprise, tablePamUserEnterprise, enterpriseSelectCols, ids); err != nil {
+		return err
+	}
+	for _, row := range enterprise {
+		e := row.Enterprise
+		users[pos[row.OwnerID]].Enterprise = &e
+	}
+
+	return nil
+}
+
+// pamUserFilter maps SCIM user attributes to pamuser tables
+var pamUserFilter = filterMapping{
+	table:  tablePamUser,
+	schema: scim.SchemaUser,
+	attrs: map[string]attrColumn{
+		"id":                 {column: qualify(tablePamUser, colID), kind: kindInt},
+		"username":           {column: qualify(tablePamUser, colUserName)},
+		"displayname":        {column: qualify(tablePamUser, colDisplayName)},
+		"nickname":           {column: qualify(tablePamUser, colNickName)},
+		"profileurl":         {column: qualify(tablePamUser, colProfileURL)},
+		"title":              {column: qualify(tablePamUser, colTitle)},
+		"usertype":           {column: qualify(tablePamUser, colUserType)},
+		"locale":             {column: qualify(tablePamUser, colLocale)},
+		"timezone":           {column: qualify(tablePamUser, colTimezone)},
+		"active":             {column: qualify(tablePamUser, colActive), kind: kindBool},
+		"entitlements":       {column: qualify(tablePamUser, colEntitlements), kind: kindStringArray},
+		"entitlements.value": {column: qualify(tablePamUser, colEntitlements), kind: kindStringArray},
+		"schemas":            {column: qualify(tablePamUser, colSchemas), kind: kindStringArray},
+
+		"name.givenname":       childColumn(tablePamUserName, colGivenName, kindString),
+		"name.middlename":      childColumn(tablePamUserName, colMiddleName, kindString),
+		"name.familyname":      childColumn(tablePamUserName, colFamilyName, kindString),
+		"name.formatted":       childColumn(tablePamUserName, colFormatted, kindString),
+		"name.honorificprefix": childColumn(tablePamUserName, colHonorificPrefix, kindString),
+		"name.honorificsuffix": childColumn(tablePamUserName, colHonorificSuffix, kindString),
+
+		"emails.value":   childColumn(tablePamUserEmails, colValue, kindString),
+		"emails.type":    childColumn(tablePamUserEmails, colName, kindString),
+		"emails.display": childColumn(tablePamUserEmails, colDisplay, kindString),
+		"emails.primary": childColumn(tablePamUserEmails, colPrimary, kindBool),
+
+		"phonenumbers.value":   childColumn(tablePamUserPhoneNumbers, colValue, kindString),
+		"phonenumbers.type":    childColumn(tablePamUserPhoneNumbers, colName, kindString),
+		"phonenumbers.display": childColumn(tablePamUserPhoneNumbers, colDisplay, kindString),
+		"phonenumbers.primary": childColumn(tablePamUserPhoneNumbers, colPrimary, kindBool),
+
+		"groups.value":   childColumn(tablePamUserGroups, colValue, kindInt),
+		"groups.display": childColumn(tablePamUserGroups, colDisplay, kindString),
+		"groups.type":    childColumn(tablePamUserGroups, colType, kindString),
+
+		"meta.resourcetype": childColumn(tablePamUserMeta, colResourceType, kindString),
+		"meta.created":      childColumn(tablePamUserMeta, colCreated, kindTime),
+		"meta.lastmodified": childColumn(tablePamUserMeta, colLastModified, kindTime),
+		"meta.location":     childColumn(tablePamUserMeta, colLocation, kindString),
+
+		enterpriseAttr("employeenumber"): childColumn(tablePamUserEnterprise, colEmployeeNumber, kindString),
+		enterpriseAttr("costcenter"):     childColumn(tablePamUserEnterprise, colCostCenter, kindString),
+		enterpriseAttr("organization"):   childColumn(tablePamUserEnterprise, colOrganization, kindString),
+		enterpriseAttr("division"):       childColumn(tablePamUserEnterprise, colDivision, kindString),
+		enterpriseAttr("department"):     childColumn(tablePamUserEnterprise, colDepartment, kindString),
+		enterpriseAttr("manager.value"): {
+			table: tablePamUserEnterprise, column: qualify(tablePamUserEnterprise, colManagerValue), caseExact: true,
+		},
+		enterpriseAttr("manager.displayname"): childColumn(tablePamUserEnterprise, colManagerDisplay, kindString),
+	},
+	multiValued: map[string]string{
+		"emails":       tablePamUserEmails,
+		"phonenumbers": tablePamUserPhoneNumbers,
+		"groups":       tablePamUserGroups,
+	},
+	sortable: map[string]bool{
+		"id":          true,
+		"username":    true,
+		"displayname": true,
+		"nickname":    true,
+		"title":       true,
+		"usertype":    true,
+		"locale":      true,
+		"timezone":    true,
+		"active":      true,
+	},
+}
+
+// enterpriseAttr returns filter mapping key of enterprise user extension attribute
+func enterpriseAttr(key string) string {
+	return strings.ToLower(scim.SchemaEnterpriseUser) + ":" + key
+}
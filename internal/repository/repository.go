@@ -1,10 +1,15 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgtype"
+	"github.com/jmoiron/sqlx"
 	"github.com/strick-j/scimfe/internal/web"
 )
 
@@ -31,3 +36,99 @@ func checkAffectedRows(r sql.Result) error {
 
 	return nil
 }
+
+// anyOf returns "col = ANY(ids)" predicate
+func anyOf(col string, ids []int) squirrel.Sqlizer {
+	return squirrel.Expr(col+" = ANY(?)", intArray(ids))
+}
+
+// noneOf returns "col <> ALL(ids)" predicate
+func noneOf(col string, ids []int) squirrel.Sqlizer {
+	return squirrel.Expr(col+" <> ALL(?)", intArray(ids))
+}
+
+// anyOfText returns "col = ANY(ids)" predicate for text identifiers
+func anyOfText(col string, ids []string) squirrel.Sqlizer {
+	return squirrel.Expr(col+" = ANY(?)", textArray(ids))
+}
+
+// noneOfText returns "col <> ALL(ids)" predicate for text identifiers
+func noneOfText(col string, ids []string) squirrel.Sqlizer {
+	return squirrel.Expr(col+" <> ALL(?)", textArray(ids))
+}
+
+func textArray(ids []string) pgtype.TextArray {
+	if ids == nil {
+		ids = []string{}
+	}
+
+	arr := pgtype.TextArray{}
+	_ = arr.Set(ids)
+	return arr
+}
+
+func intArray(ids []int) pgtype.Int4Array {
+	if ids == nil {
+		// nil slice is encoded as NULL instead of empty array
+		ids = []int{}
+	}
+
+	arr := pgtype.Int4Array{}
+
+	// error occurs only on unsupported type
+	_ = arr.Set(ids)
+	return arr
+}
+
+// upsertSuffix returns "ON CONFLICT DO UPDATE" clause which overwrites passed columns.
+func upsertSuffix(conflictCol string, cols ...string) string {
+	sets := make([]string, 0, len(cols))
+	for _, col := range cols {
+		sets = append(sets, col+" = EXCLUDED."+col)
+	}
+
+	return "ON CONFLICT (" + conflictCol + ") DO UPDATE SET " + strings.Join(sets, ", ")
+}
+
+// nullString converts empty string to NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// execBuilder builds and executes a query
+func execBuilder(ctx context.Context, db sqlx.ExecerContext, b squirrel.Sqlizer) error {
+	q, args, err := b.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, q, args...)
+	return err
+}
+
+// utcTime returns time in UTC, since timestamp columns have no time zone.
+func utcTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// coalesce returns column expression which replaces NULL with default value.
+//
+// Column alias is kept the same, so result can be scanned into a struct.
+func coalesce(col, def string) string {
+	return "COALESCE(" + col + ", " + def + ") AS " + col
+}
+
+// coalesceStrings returns column expressions which replace NULL strings with empty string.
+func coalesceStrings(cols ...string) []string {
+	out := make([]string, 0, len(cols))
+	for _, col := range cols {
+		out = append(out, coalesce(col, "''"))
+	}
+	return out
+}
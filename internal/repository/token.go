@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/strick-j/scimfe/internal/model/auth"
+)
+
+const (
+	colAccessToken = "access_token"
+	colTokenType   = "token_type"
+	colExpiry      = "expiry"
+
+	tableAuth = "auth"
+)
+
+var tokenCols = []string{colID, colAccessToken, colTokenType, colExpiry}
+
+type TokenRepository struct {
+	db *sqlx.DB
+}
+
+// NewTokenRepository is TokenRepository constructor
+func NewTokenRepository(db *sqlx.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+// LatestToken implements service.TokenStore
+func (r TokenRepository) LatestToken(ctx context.Context) (*auth.OAuthToken, error) {
+	q, args, err := psql.Select(tokenCols...).From(tableAuth).
+		OrderBy(colExpiry + " DESC").Limit(1).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	t := new(auth.OAuthToken)
+	err = r.db.GetContext(ctx, t, q, args...)
+	if err != nil {
+		return nil, wrapRecordError(err)
+	}
+
+	// column has no time zone, values are always stored in UTC.
+	t.Expiry = t.Expiry.UTC()
+	return t, nil
+}
+
+// SaveToken implements service.TokenStore.
+//
+// Previously issued tokens are removed, since only the latest token is used.
+func (r TokenRepository) SaveToken(ctx context.Context, t auth.OAuthToken) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// nolint: errcheck
+	defer tx.Rollback()
+
+	q, args, err := psql.Delete(tableAuth).ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, q, args...); err != nil {
+		return fmt.Errorf("failed to remove old tokens: %w", err)
+	}
+
+	q, args, err = psql.Insert(tableAuth).SetMap(map[string]interface{}{
+		colAccessToken: t.AccessToken,
+		colTokenType:   t.TokenType,
+		colExpiry:      t.Expiry.UTC(),
+	}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, q, args...); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -3,12 +3,15 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/strick-j/scimfe/internal/model"
 	"github.com/strick-j/scimfe/internal/model/user"
 	"github.com/strick-j/scimfe/internal/service"
 	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
 )
 
 const (
@@ -20,7 +23,7 @@ const (
 	tableUsers = "users"
 )
 
-var userCols = []string{colID, colEmail, colName, colPassword}
+var userCols = []string{colID, colEmail, colName, colPassword, colActive, colVersion}
 
 type UserRepository struct {
 	db *sqlx.DB
@@ -31,18 +34,31 @@ func NewUserRepository(db *sqlx.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r UserRepository) AllUsers(ctx context.Context) (user.Users, error) {
-	q, args, err := psql.Select(userCols...).From(tableUsers).ToSql()
+func (r UserRepository) ListUsers(ctx context.Context, lq model.ListQuery) (user.Users, *model.Page, error) {
+	sel, count, err := userFilter.listQuery(userCols, lq)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	page := new(model.Page)
+	if page.Total, err = countRows(ctx, r.db, count); err != nil || lq.Count == 0 {
+		return nil, page, err
+	}
+
+	q, args, err := sel.ToSql()
+	if err != nil {
+		return nil, nil, err
 	}
 
 	var out user.Users
-	err = r.db.SelectContext(ctx, &out, q, args...)
-	if err == sql.ErrNoRows {
-		return nil, nil
+	if err = r.db.SelectContext(ctx, &out, q, args...); err != nil {
+		return nil, nil, err
 	}
-	return out, err
+
+	out = out[:nextPage(lq, page, len(out), func(i int) string {
+		return user.IDToString(out[i].ID)
+	})]
+	return out, page, nil
 }
 
 func (r UserRepository) AddUser(ctx context.Context, u user.User) (*user.ID, error) {
@@ -50,6 +66,8 @@ func (r UserRepository) AddUser(ctx context.Context, u user.User) (*user.ID, err
 		colEmail:    u.Email,
 		colName:     u.Name,
 		colPassword: u.PasswordHash,
+		colActive:   u.Active,
+		colVersion:  u.Version,
 	}).Suffix("RETURNING " + colID).ToSql()
 	if err != nil {
 		return nil, err
@@ -87,6 +105,65 @@ func (r UserRepository) UserByID(ctx context.Context, uid user.ID) (*user.User,
 	return u, err
 }
 
+// UpdateUser implements service.UserStorage.
+//
+// User is updated only if it has the same version, so concurrent changes are not lost.
+func (r UserRepository) UpdateUser(ctx context.Context, u user.User) error {
+	q, args, err := psql.Update(tableUsers).SetMap(map[string]interface{}{
+		colEmail:    u.Email,
+		colName:     u.Name,
+		colPassword: u.PasswordHash,
+		colActive:   u.Active,
+		colVersion:  squirrel.Expr(colVersion + " + 1"),
+	}).Where(squirrel.Eq{colID: u.ID, colVersion: u.Version}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil || n > 0 {
+		return err
+	}
+
+	// user is either removed or updated by another request
+	if _, err = r.UserByID(ctx, u.ID); err != nil {
+		return err
+	}
+	return service.ErrModified
+}
+
+// DeleteUser implements service.UserStorage.
+//
+// User is removed only if it has the same version, see UpdateUser.
+func (r UserRepository) DeleteUser(ctx context.Context, u user.User) error {
+	q, args, err := psql.Delete(tableUsers).
+		Where(squirrel.Eq{colID: u.ID, colVersion: u.Version}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("failed to remove user: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil || n > 0 {
+		return err
+	}
+
+	// user is either removed or updated by another request
+	if _, err = r.UserByID(ctx, u.ID); err != nil {
+		return err
+	}
+	return service.ErrModified
+}
+
 func (r UserRepository) Exists(email string) (bool, error) {
 	q, args, err := psql.Select("COUNT(*)").From(tableUsers).Where(squirrel.Eq{
 		colEmail: email,
@@ -109,3 +186,30 @@ func wrapRecordError(err error) error {
 		return err
 	}
 }
+
+// userFilter maps SCIM user attributes to local users table.
+//
+// Local attribute names ("email", "name") are accepted as well.
+var userFilter = filterMapping{
+	table:  tableUsers,
+	schema: scim.SchemaUser,
+	attrs: map[string]attrColumn{
+		"id":             {column: qualify(tableUsers, colID), kind: kindUUID},
+		"username":       {column: qualify(tableUsers, colEmail)},
+		"emails":         {column: qualify(tableUsers, colEmail)},
+		"emails.value":   {column: qualify(tableUsers, colEmail)},
+		"email":          {column: qualify(tableUsers, colEmail)},
+		"displayname":    {column: qualify(tableUsers, colName)},
+		"name.formatted": {column: qualify(tableUsers, colName)},
+		"name":           {column: qualify(tableUsers, colName)},
+		"active":         {column: qualify(tableUsers, colActive), kind: kindBool},
+	},
+	sortable: map[string]bool{
+		"id":             true,
+		"username":       true,
+		"email":          true,
+		"displayname":    true,
+		"name.formatted": true,
+		"name":           true,
+	},
+}
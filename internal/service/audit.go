@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/audit"
+	"github.com/strick-j/scimfe/internal/model/auth"
+	"github.com/strick-j/scimfe/internal/model/user"
+	"go.uber.org/zap"
+)
+
+// ActionStorage is audit log storage
+type ActionStorage interface {
+	// AddAction appends action to audit log
+	AddAction(ctx context.Context, a audit.Action) error
+
+	// ListActions returns a page of actions matching list query
+	ListActions(ctx context.Context, q model.ListQuery) (audit.Actions, *model.Page, error)
+
+	// DeleteActionsBefore removes actions started before specified time
+	DeleteActionsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// ActionRecorder records synchronization and provisioning actions to audit log.
+//
+// Failure to write audit record doesn't fail recorded action, error is only logged.
+type ActionRecorder struct {
+	log   *zap.Logger
+	store ActionStorage
+}
+
+// NewActionRecorder is ActionRecorder constructor
+func NewActionRecorder(log *zap.Logger, store ActionStorage) *ActionRecorder {
+	return &ActionRecorder{
+		log:   log.Named("service.audit"),
+		store: store,
+	}
+}
+
+// Start starts action recording.
+//
+// Action is written to audit log when ActionRecord.Finish is called.
+func (r *ActionRecorder) Start(ctx context.Context, action, resourceType string) *ActionRecord {
+	return &ActionRecord{
+		ctx:      ctx,
+		recorder: r,
+		startAt:  time.Now(),
+		action: audit.Action{
+			Action:       action,
+			ResourceType: resourceType,
+			Actor:        actorFromContext(ctx),
+		},
+	}
+}
+
+// List returns a page of recorded actions
+func (r *ActionRecorder) List(ctx context.Context, q model.ListQuery) (audit.Actions, *model.Page, error) {
+	return r.store.ListActions(ctx, q)
+}
+
+// Purge removes actions older than retention period
+func (r *ActionRecorder) Purge(ctx context.Context, retention time.Duration) error {
+	n, err := r.store.DeleteActionsBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+
+	r.log.Info("purged audit log", zap.Int64("count", n), zap.Duration("retention", retention))
+	return nil
+}
+
+func (r *ActionRecorder) add(ctx context.Context, a audit.Action) {
+	if ctx.Err() != nil {
+		// record action even if request was cancelled
+		ctx = context.Background()
+	}
+
+	if err := r.store.AddAction(ctx, a); err != nil {
+		r.log.Error("failed to write audit record",
+			zap.String("action", a.Action),
+			zap.String("resourceType", a.ResourceType),
+			zap.String("resourceId", a.ResourceID),
+			zap.Error(err))
+	}
+}
+
+// ActionRecord is action which is being recorded
+type ActionRecord struct {
+	ctx      context.Context
+	recorder *ActionRecorder
+	startAt  time.Time
+	action   audit.Action
+}
+
+// SetResourceID sets id of action target resource
+func (r *ActionRecord) SetResourceID(id string) *ActionRecord {
+	r.action.ResourceID = id
+	return r
+}
+
+// SetPayload sets digest of action request payload
+func (r *ActionRecord) SetPayload(payload interface{}) *ActionRecord {
+	r.action.Digest = audit.PayloadDigest(payload)
+	return r
+}
+
+// Finish writes action to audit log.
+//
+// Action is recorded as failed if error is not nil.
+func (r *ActionRecord) Finish(err error) {
+	r.action.Time = r.startAt
+	r.action.Duration = time.Since(r.startAt).Milliseconds()
+	r.action.Success = err == nil
+	if err != nil {
+		r.action.Error = err.Error()
+	}
+
+	r.recorder.add(r.ctx, r.action)
+}
+
+// actorFromContext returns actor of action.
+//
+// Actor is either explicitly specified actor, logged in user id or audit.ActorSystem.
+func actorFromContext(ctx context.Context) string {
+	if actor := audit.ActorFromContext(ctx); actor != "" {
+		return actor
+	}
+
+	if sess := auth.SessionFromContext(ctx); sess != nil {
+		return user.IDToString(sess.UserID)
+	}
+
+	return audit.ActorSystem
+}
@@ -71,7 +71,7 @@ func (s AuthService) Authenticate(ctx context.Context, creds auth.Credentials) (
 		return nil, fmt.Errorf("cannot check password: %w", err)
 	}
 
-	if !passEqual {
+	if !passEqual || !usr.Active {
 		return nil, ErrInvalidCredentials
 	}
 
@@ -105,7 +105,8 @@ func (s AuthService) CreateSession(ctx context.Context, uid user.ID, remember bo
 
 // GetSession retrieves session using provided token.
 //
-// Returns ErrAuthRequired if session is invalid.
+// Returns ErrAuthRequired if session is invalid or session user
+// was deleted or deactivated, such session is removed.
 func (s AuthService) GetSession(ctx context.Context, ssid uuid.UUID) (*auth.Session, error) {
 	sess, err := s.store.GetSession(ctx, ssid)
 	if err != nil {
@@ -120,6 +121,16 @@ func (s AuthService) GetSession(ctx context.Context, ssid uuid.UUID) (*auth.Sess
 		}
 	}
 
+	usr, err := s.users.UserByID(ctx, sess.UserID)
+	deleted := err == ErrNotExists || isNotFound(err)
+	if err != nil && !deleted {
+		return nil, err
+	}
+	if deleted || !usr.Active {
+		s.dropInactiveSession(ctx, sess)
+		return nil, ErrAuthRequired
+	}
+
 	return sess, nil
 }
 
@@ -144,3 +155,15 @@ func (s AuthService) dropCorruptedSession(ctx context.Context, ssid uuid.UUID) {
 
 	s.log.Warn("removed corrupted session", zap.String("ssid", ssid.String()))
 }
+
+func (s AuthService) dropInactiveSession(ctx context.Context, sess *auth.Session) {
+	if err := s.store.RemoveSession(ctx, sess.ID); err != nil && err != ErrSessionNotExists {
+		s.log.Error("failed to remove session of inactive user",
+			zap.String("ssid", sess.ID.String()),
+			zap.Error(err))
+		return
+	}
+
+	s.log.Info("removed session of inactive user",
+		zap.String("ssid", sess.ID.String()))
+}
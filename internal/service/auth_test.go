@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/internal/model/auth"
+	"github.com/strick-j/scimfe/internal/model/user"
+	"github.com/strick-j/scimfe/internal/web"
+	"go.uber.org/zap"
+)
+
+func TestAuthService_GetSession(t *testing.T) {
+	uid := testUserID(1)
+	errStore := errors.New("store is unavailable")
+	cases := map[string]struct {
+		users   map[user.ID]user.User
+		userErr error
+		wantErr error
+		removed bool
+	}{
+		"active user": {
+			users: map[user.ID]user.User{uid: {ID: uid, Active: true}},
+		},
+		"deactivated user": {
+			users:   map[user.ID]user.User{uid: {ID: uid}},
+			wantErr: ErrAuthRequired,
+			removed: true,
+		},
+		"deleted user": {
+			users:   map[user.ID]user.User{},
+			wantErr: ErrAuthRequired,
+			removed: true,
+		},
+		"deleted user record": {
+			userErr: ErrNotExists,
+			wantErr: ErrAuthRequired,
+			removed: true,
+		},
+		"user store error": {
+			userErr: errStore,
+			wantErr: errStore,
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			sessions := newFakeSessionStore()
+			sess, err := sessions.CreateSession(context.Background(), uid, time.Hour)
+			require.NoError(t, err)
+
+			users := &fakeUserStore{users: c.users, err: c.userErr}
+			svc := NewAuthService(zap.NewNop(), NewUsersService(zap.NewNop(), users), sessions)
+			got, err := svc.GetSession(context.Background(), sess.ID)
+			if c.wantErr != nil {
+				require.Equal(t, c.wantErr, err)
+				require.Nil(t, got)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, sess, got)
+			}
+
+			_, err = sessions.GetSession(context.Background(), sess.ID)
+			if c.removed {
+				require.Equal(t, ErrSessionNotExists, err, "session should be removed")
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("session not exists", func(t *testing.T) {
+		svc := NewAuthService(zap.NewNop(), NewUsersService(zap.NewNop(), &fakeUserStore{}), newFakeSessionStore())
+		_, err := svc.GetSession(context.Background(), uuid.New())
+		require.Equal(t, ErrAuthRequired, err)
+	})
+
+	t.Run("corrupted session", func(t *testing.T) {
+		sessions := newFakeSessionStore()
+		ssid := uuid.New()
+		sessions.sessions[ssid] = nil
+
+		svc := NewAuthService(zap.NewNop(), NewUsersService(zap.NewNop(), &fakeUserStore{}), sessions)
+		_, err := svc.GetSession(context.Background(), ssid)
+		require.Equal(t, ErrAuthRequired, err)
+		require.NotContains(t, sessions.sessions, ssid, "session should be removed")
+	})
+}
+
+func testUserID(n byte) user.ID {
+	return pgtype.UUID{Bytes: [16]byte{15: n}, Status: pgtype.Present}
+}
+
+// fakeSessionStore keeps sessions in memory, nil session is treated as corrupted
+type fakeSessionStore struct {
+	sessions map[uuid.UUID]*auth.Session
+}
+
+func newFakeSessionStore() *fakeSessionStore {
+	return &fakeSessionStore{sessions: make(map[uuid.UUID]*auth.Session)}
+}
+
+func (s *fakeSessionStore) CreateSession(_ context.Context, uid user.ID, ttl time.Duration) (*auth.Session, error) {
+	sess := &auth.Session{ID: uuid.New(), UserID: uid, LoggedAt: time.Now(), TTL: ttl}
+	s.sessions[sess.ID] = sess
+	return sess, nil
+}
+
+func (s *fakeSessionStore) GetSession(_ context.Context, ssid uuid.UUID) (*auth.Session, error) {
+	sess, ok := s.sessions[ssid]
+	switch {
+	case !ok:
+		return nil, ErrSessionNotExists
+	case sess == nil:
+		return nil, ErrCorruptedSession
+	}
+	return sess, nil
+}
+
+func (s *fakeSessionStore) RemoveSession(_ context.Context, ssid uuid.UUID) error {
+	if _, ok := s.sessions[ssid]; !ok {
+		return ErrSessionNotExists
+	}
+	delete(s.sessions, ssid)
+	return nil
+}
+
+// fakeUserStore implements UserByID of UserStorage, other methods are not used.
+//
+// Missing users are reported with "404 Not Found" error like UserRepository does.
+type fakeUserStore struct {
+	UserStorage
+
+	users map[user.ID]user.User
+	err   error
+}
+
+func (s *fakeUserStore) UserByID(_ context.Context, uid user.ID) (*user.User, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	u, ok := s.users[uid]
+	if !ok {
+		return nil, web.NewErrNotFound("user not found")
+	}
+	return &u, nil
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrLockNotObtained = errors.New("lock is held by another process")
+
+// Lock is distributed lock obtained by Locker
+type Lock interface {
+	// Refresh extends lock TTL.
+	//
+	// Returns ErrLockNotObtained if lock was expired and taken by someone else.
+	Refresh(ctx context.Context, ttl time.Duration) error
+
+	// Release releases the lock
+	Release(ctx context.Context) error
+}
+
+// Locker provides distributed locks shared between service replicas
+type Locker interface {
+	// Obtain tries to obtain a lock with specified key.
+	//
+	// Returns ErrLockNotObtained if lock is already held.
+	Obtain(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
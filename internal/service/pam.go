@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"go.uber.org/zap"
+)
+
+// PamUserStorage provides access to PAM users mirror
+type PamUserStorage interface {
+	// ListUsers returns a page of users matching list query with their attributes
+	ListUsers(ctx context.Context, q model.ListQuery) (pam.Users, *model.Page, error)
+
+	// UserByID returns user by ID
+	UserByID(ctx context.Context, id int) (*pam.User, error)
+}
+
+// PamGroupStorage provides access to PAM groups mirror
+type PamGroupStorage interface {
+	// ListGroups returns a page of groups matching list query with their members
+	ListGroups(ctx context.Context, q model.ListQuery) (pam.Groups, *model.Page, error)
+
+	// GroupByID returns group by ID
+	GroupByID(ctx context.Context, id int) (*pam.Group, error)
+}
+
+// PamContainerStorage provides access to PAM containers mirror
+type PamContainerStorage interface {
+	// ListContainers returns a page of containers matching list query
+	ListContainers(ctx context.Context, q model.ListQuery) (pam.Containers, *model.Page, error)
+
+	// ContainerByID returns container by ID
+	ContainerByID(ctx context.Context, id string) (*pam.Container, error)
+
+	// ContainerPermissions returns permissions of container
+	ContainerPermissions(ctx context.Context, id string) ([]pam.ContainerPermission, error)
+
+	// ContainerGrants returns container rights granted to users directly and through groups
+	ContainerGrants(ctx context.Context, id string) ([]pam.Grant, error)
+}
+
+// PamAccountStorage provides access to PAM accounts mirror
+type PamAccountStorage interface {
+	// ListAccounts returns a page of accounts matching list query
+	ListAccounts(ctx context.Context, q model.ListQuery) (pam.Accounts, *model.Page, error)
+
+	// AccountByID returns account by ID
+	AccountByID(ctx context.Context, id string) (*pam.Account, error)
+}
+
+// PamService provides access to PAM users, groups, containers and accounts synchronized from PAM SCIM server
+type PamService struct {
+	log        *zap.Logger
+	users      PamUserStorage
+	groups     PamGroupStorage
+	containers PamContainerStorage
+	accounts   PamAccountStorage
+}
+
+// NewPamService is PamService constructor
+func NewPamService(log *zap.Logger, users PamUserStorage, groups PamGroupStorage, containers PamContainerStorage, accounts PamAccountStorage) *PamService {
+	return &PamService{
+		log:        log.Named("service.pam"),
+		users:      users,
+		groups:     groups,
+		containers: containers,
+		accounts:   accounts,
+	}
+}
+
+// ListUsers returns a page of PAM users
+func (s PamService) ListUsers(ctx context.Context, q model.ListQuery) (pam.Users, *model.Page, error) {
+	return s.users.ListUsers(ctx, q)
+}
+
+// UserByID returns PAM user by id
+func (s PamService) UserByID(ctx context.Context, id int) (*pam.User, error) {
+	return s.users.UserByID(ctx, id)
+}
+
+// ListGroups returns a page of PAM groups
+func (s PamService) ListGroups(ctx context.Context, q model.ListQuery) (pam.Groups, *model.Page, error) {
+	return s.groups.ListGroups(ctx, q)
+}
+
+// GroupByID returns PAM group by id
+func (s PamService) GroupByID(ctx context.Context, id int) (*pam.Group, error) {
+	return s.groups.GroupByID(ctx, id)
+}
+
+// ListContainers returns a page of PAM containers
+func (s PamService) ListContainers(ctx context.Context, q model.ListQuery) (pam.Containers, *model.Page, error) {
+	return s.containers.ListContainers(ctx, q)
+}
+
+// ContainerByID returns PAM container by id
+func (s PamService) ContainerByID(ctx context.Context, id string) (*pam.Container, error) {
+	return s.containers.ContainerByID(ctx, id)
+}
+
+// ContainerPermissions returns permissions of PAM container
+func (s PamService) ContainerPermissions(ctx context.Context, id string) ([]pam.ContainerPermission, error) {
+	if _, err := s.containers.ContainerByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.containers.ContainerPermissions(ctx, id)
+}
+
+// ContainerAccess returns users who can access PAM container
+// with their effective rights granted directly and through groups.
+func (s PamService) ContainerAccess(ctx context.Context, id string) ([]pam.Access, error) {
+	if _, err := s.containers.ContainerByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	grants, err := s.containers.ContainerGrants(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return pam.EffectiveAccess(grants), nil
+}
+
+// ListAccounts returns a page of PAM accounts
+func (s PamService) ListAccounts(ctx context.Context, q model.ListQuery) (pam.Accounts, *model.Page, error) {
+	return s.accounts.ListAccounts(ctx, q)
+}
+
+// AccountByID returns PAM account by id
+func (s PamService) AccountByID(ctx context.Context, id string) (*pam.Account, error) {
+	return s.accounts.AccountByID(ctx, id)
+}
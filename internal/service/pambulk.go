@@ -0,0 +1,520 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"go.uber.org/zap"
+)
+
+// Bulk request limits, see RFC 7644 section 3.7.4.
+const (
+	// BulkMaxOperations is max number of operations in a single bulk request
+	BulkMaxOperations = 1000
+
+	// BulkMaxPayloadSize is max bulk request payload size in bytes
+	BulkMaxPayloadSize = 1 << 20
+)
+
+// PamBulkService processes SCIM bulk requests for PAM users and groups.
+//
+// Each bulk operation is executed by PamProvisioningService,
+// so operations are sent to remote server and audited one by one.
+type PamBulkService struct {
+	log  *zap.Logger
+	prov *PamProvisioningService
+}
+
+// NewPamBulkService is PamBulkService constructor
+func NewPamBulkService(log *zap.Logger, prov *PamProvisioningService) *PamBulkService {
+	return &PamBulkService{
+		log:  log.Named("service.pambulk"),
+		prov: prov,
+	}
+}
+
+// Process executes bulk request operations and returns per-operation results.
+//
+// Operations are executed in request order, except operations which reference
+// resources created later in the same request by "bulkId:<id>" value.
+// Such operations are postponed until referenced resources are created.
+// Operations which reference failed or unknown bulkId or have circular
+// references fail with "409 Conflict" status.
+//
+// If request FailOnErrors is set, processing stops after given number of errors
+// and the rest of operations are omitted from response.
+func (s PamBulkService) Process(ctx context.Context, req scim.BulkRequest) (*scim.BulkResponse, error) {
+	if err := validateBulkRequest(req); err != nil {
+		return nil, err
+	}
+
+	b := bulkState{
+		ctx:      ctx,
+		svc:      s,
+		req:      req,
+		results:  make([]*scim.BulkOperationResult, len(req.Operations)),
+		declared: make(map[string]bool, len(req.Operations)),
+		created:  make(map[string]string, len(req.Operations)),
+		failed:   make(map[string]bool),
+	}
+
+	pending := make([]int, 0, len(req.Operations))
+	for i, op := range req.Operations {
+		if op.BulkID != "" {
+			b.declared[op.BulkID] = true
+		}
+		pending = append(pending, i)
+	}
+
+	for len(pending) > 0 && !b.stopped() {
+		pending = b.run(pending)
+	}
+
+	if b.stopped() {
+		s.log.Info("bulk request processing stopped after errors",
+			zap.Int("errors", b.errCount), zap.Int("operations", len(req.Operations)))
+	}
+
+	rsp := &scim.BulkResponse{
+		Schemas:    []string{scim.SchemaBulkResponse},
+		Operations: make([]scim.BulkOperationResult, 0, len(b.results)),
+	}
+	for _, r := range b.results {
+		if r != nil {
+			rsp.Operations = append(rsp.Operations, *r)
+		}
+	}
+	return rsp, nil
+}
+
+// bulkState is state of a single bulk request processing
+type bulkState struct {
+	ctx context.Context
+	svc PamBulkService
+	req scim.BulkRequest
+
+	// results are operation results in request order, nil for skipped operations
+	results []*scim.BulkOperationResult
+
+	// declared contains all bulkIds of request operations
+	declared map[string]bool
+
+	// created contains resource IDs created by operations with bulkId
+	created map[string]string
+
+	// failed contains bulkIds of failed operations
+	failed map[string]bool
+
+	errCount int
+}
+
+// run executes operations whose references are resolved and returns postponed operations.
+//
+// If no operation can be executed, postponed operations fail.
+func (b *bulkState) run(pending []int) []int {
+	postponed := make([]int, 0, len(pending))
+	progress := false
+	for _, i := range pending {
+		if b.stopped() {
+			return nil
+		}
+
+		op := b.req.Operations[i]
+		refs, err := bulkReferences(op)
+		if err != nil {
+			b.fail(i, err)
+			progress = true
+			continue
+		}
+
+		if ref, ok := b.brokenReference(refs); ok {
+			b.fail(i, scim.NewError(http.StatusConflict, scim.ErrTypeInvalidValue,
+				"operation references failed or unknown bulkId %q", ref))
+			progress = true
+			continue
+		}
+
+		if !b.resolved(refs) {
+			postponed = append(postponed, i)
+			continue
+		}
+
+		progress = true
+		op, err = resolveBulkReferences(op, b.created)
+		if err != nil {
+			b.fail(i, err)
+			continue
+		}
+
+		res, id, err := b.svc.execute(b.ctx, op)
+		if err != nil {
+			b.fail(i, err)
+			continue
+		}
+
+		if op.BulkID != "" {
+			b.created[op.BulkID] = id
+		}
+		b.results[i] = res
+	}
+
+	if progress {
+		return postponed
+	}
+
+	for _, i := range postponed {
+		b.fail(i, scim.NewError(http.StatusConflict, scim.ErrTypeInvalidValue,
+			"operation has circular bulkId reference"))
+	}
+	return nil
+}
+
+func (b *bulkState) fail(i int, err error) {
+	op := b.req.Operations[i]
+	if op.BulkID != "" {
+		b.failed[op.BulkID] = true
+	}
+
+	apiErr := web.ToAPIError(err)
+	b.errCount++
+	b.results[i] = &scim.BulkOperationResult{
+		Method:  strings.ToUpper(op.Method),
+		BulkID:  op.BulkID,
+		Version: op.Version,
+		Status:  strconv.Itoa(apiErr.Status),
+		Response: &scim.Error{
+			StatusCode: apiErr.Status,
+			ScimType:   apiErr.ScimType,
+			Detail:     apiErr.Message,
+		},
+	}
+}
+
+func (b bulkState) stopped() bool {
+	return b.req.FailOnErrors > 0 && b.errCount >= b.req.FailOnErrors
+}
+
+func (b bulkState) brokenReference(refs []string) (string, bool) {
+	for _, ref := range refs {
+		if b.failed[ref] || !b.declared[ref] {
+			return ref, true
+		}
+	}
+	return "", false
+}
+
+func (b bulkState) resolved(refs []string) bool {
+	for _, ref := range refs {
+		if _, ok := b.created[ref]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// execute executes a single bulk operation with resolved references.
+//
+// Returns operation result and ID of target resource.
+func (s PamBulkService) execute(ctx context.Context, op scim.BulkOperation) (*scim.BulkOperationResult, string, error) {
+	method := strings.ToUpper(op.Method)
+	endpoint, rawID, err := splitBulkPath(op.Path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var id int
+	switch method {
+	case http.MethodPost:
+		if op.BulkID == "" {
+			return nil, "", scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "bulkId is required for POST operation")
+		}
+		if rawID != "" {
+			return nil, "", scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidPath, "POST operation path should not contain resource id")
+		}
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		if id, err = pam.ParseID(rawID); err != nil {
+			return nil, "", scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidPath, "%s", err)
+		}
+
+		// operation version is used as If-Match precondition, see RFC 7644 section 3.7.
+		if op.Version != "" {
+			ctx = scim.WithPrecondition(ctx, scim.Precondition{IfMatch: op.Version})
+		}
+	default:
+		return nil, "", scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "unsupported operation method %q", op.Method)
+	}
+
+	res := &scim.BulkOperationResult{
+		Method:  method,
+		BulkID:  op.BulkID,
+		Version: op.Version,
+		Status:  strconv.Itoa(http.StatusOK),
+	}
+
+	var meta *scim.Meta
+	var resID string
+	if endpoint == scim.EndpointUsers {
+		meta, resID, err = s.executeUser(ctx, method, id, op.Data)
+		res.Location = "/pam/users/" + resID
+	} else {
+		meta, resID, err = s.executeGroup(ctx, method, id, op.Data)
+		res.Location = "/pam/groups/" + resID
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch method {
+	case http.MethodPost:
+		res.Status = strconv.Itoa(http.StatusCreated)
+	case http.MethodDelete:
+		res.Status = strconv.Itoa(http.StatusNoContent)
+	}
+
+	if meta != nil && meta.Version != "" {
+		res.Version = meta.Version
+	}
+	return res, resID, nil
+}
+
+func (s PamBulkService) executeUser(ctx context.Context, method string, id int, data json.RawMessage) (*scim.Meta, string, error) {
+	if method == http.MethodDelete {
+		return nil, pam.FormatID(id), s.prov.DeactivateUser(ctx, id)
+	}
+
+	if method == http.MethodPatch {
+		var req scim.PatchRequest
+		if err := decodeBulkData(data, &req); err != nil {
+			return nil, "", err
+		}
+
+		out, err := s.prov.PatchUser(ctx, id, req)
+		if err != nil {
+			return nil, "", err
+		}
+		return out.Meta, out.ID, nil
+	}
+
+	var u scim.User
+	if err := decodeBulkResource(scim.ResourceTypeUser, data, &u); err != nil {
+		return nil, "", err
+	}
+
+	if err := model.Validate(&u); err != nil {
+		return nil, "", err
+	}
+
+	var out *scim.User
+	var err error
+	if method == http.MethodPost {
+		out, err = s.prov.CreateUser(ctx, u)
+	} else {
+		out, err = s.prov.ReplaceUser(ctx, id, u)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return out.Meta, out.ID, nil
+}
+
+func (s PamBulkService) executeGroup(ctx context.Context, method string, id int, data json.RawMessage) (*scim.Meta, string, error) {
+	if method == http.MethodDelete {
+		return nil, pam.FormatID(id), s.prov.DeleteGroup(ctx, id)
+	}
+
+	if method == http.MethodPatch {
+		var req scim.PatchRequest
+		if err := decodeBulkData(data, &req); err != nil {
+			return nil, "", err
+		}
+
+		out, err := s.prov.PatchGroup(ctx, id, req)
+		if err != nil {
+			return nil, "", err
+		}
+		return out.Meta, out.ID, nil
+	}
+
+	var g scim.Group
+	if err := decodeBulkResource(scim.ResourceTypeGroup, data, &g); err != nil {
+		return nil, "", err
+	}
+
+	if err := model.Validate(&g); err != nil {
+		return nil, "", err
+	}
+
+	var out *scim.Group
+	var err error
+	if method == http.MethodPost {
+		out, err = s.prov.CreateGroup(ctx, g)
+	} else {
+		out, err = s.prov.ReplaceGroup(ctx, id, g)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return out.Meta, out.ID, nil
+}
+
+func validateBulkRequest(req scim.BulkRequest) error {
+	if !containsSchema(req.Schemas, scim.SchemaBulkRequest) {
+		return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidSyntax,
+			"request schemas should contain %q", scim.SchemaBulkRequest)
+	}
+
+	if req.FailOnErrors < 0 {
+		return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidValue, "failOnErrors should not be negative")
+	}
+
+	if len(req.Operations) > BulkMaxOperations {
+		return scim.NewError(http.StatusRequestEntityTooLarge, "",
+			"the number of operations exceeds the maxOperations (%d)", BulkMaxOperations)
+	}
+
+	bulkIDs := make(map[string]bool, len(req.Operations))
+	for _, op := range req.Operations {
+		if op.BulkID == "" {
+			continue
+		}
+
+		if bulkIDs[op.BulkID] {
+			return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "duplicate bulkId %q", op.BulkID)
+		}
+		bulkIDs[op.BulkID] = true
+	}
+	return nil
+}
+
+// splitBulkPath returns resource endpoint and optional resource ID from operation path
+func splitBulkPath(path string) (endpoint, id string, err error) {
+	path = strings.TrimPrefix(path, "/")
+	name := path
+	if i := strings.IndexByte(path, '/'); i != -1 {
+		name, id = path[:i], path[i+1:]
+	}
+
+	switch {
+	case strings.EqualFold("/"+name, scim.EndpointUsers):
+		endpoint = scim.EndpointUsers
+	case strings.EqualFold("/"+name, scim.EndpointGroups):
+		endpoint = scim.EndpointGroups
+	default:
+		return "", "", scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidPath,
+			"invalid operation path %q, %s or %s resource expected", "/"+path, scim.EndpointUsers, scim.EndpointGroups)
+	}
+
+	if strings.Contains(id, "/") {
+		return "", "", scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidPath, "invalid operation path %q", "/"+path)
+	}
+	return endpoint, id, nil
+}
+
+// bulkReferences returns bulkIds referenced by operation path and data
+func bulkReferences(op scim.BulkOperation) ([]string, error) {
+	var refs []string
+	collect := func(s string) string {
+		if ref, ok := scim.ParseBulkID(s); ok {
+			refs = append(refs, ref)
+		}
+		return s
+	}
+
+	collect(op.Path[strings.LastIndexByte(op.Path, '/')+1:])
+	if len(op.Data) == 0 {
+		return refs, nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(op.Data, &data); err != nil {
+		return nil, invalidBulkData(err)
+	}
+
+	mapStrings(data, collect)
+	return refs, nil
+}
+
+// resolveBulkReferences replaces "bulkId:<id>" values in operation path and data with created resource IDs
+func resolveBulkReferences(op scim.BulkOperation, created map[string]string) (scim.BulkOperation, error) {
+	resolve := func(s string) string {
+		if ref, ok := scim.ParseBulkID(s); ok {
+			return created[ref]
+		}
+		return s
+	}
+
+	i := strings.LastIndexByte(op.Path, '/') + 1
+	op.Path = op.Path[:i] + resolve(op.Path[i:])
+	if len(op.Data) == 0 {
+		return op, nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(op.Data, &data); err != nil {
+		return op, invalidBulkData(err)
+	}
+
+	out, err := json.Marshal(mapStrings(data, resolve))
+	if err != nil {
+		return op, err
+	}
+
+	op.Data = out
+	return op, nil
+}
+
+// mapStrings replaces all string values of decoded JSON value using fn
+func mapStrings(v interface{}, fn func(string) string) interface{} {
+	switch t := v.(type) {
+	case string:
+		return fn(t)
+	case []interface{}:
+		for i, item := range t {
+			t[i] = mapStrings(item, fn)
+		}
+	case map[string]interface{}:
+		for k, item := range t {
+			t[k] = mapStrings(item, fn)
+		}
+	}
+	return v
+}
+
+func decodeBulkData(data json.RawMessage, dst interface{}) error {
+	if len(data) == 0 {
+		return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "operation data is required")
+	}
+
+	if err := json.Unmarshal(data, dst); err != nil {
+		return invalidBulkData(err)
+	}
+	return nil
+}
+
+// decodeBulkResource decodes operation resource according to PamSchemas
+func decodeBulkResource(resourceType string, data json.RawMessage, dst interface{}) error {
+	if len(data) == 0 {
+		return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "operation data is required")
+	}
+	return PamSchemas.Decode(resourceType, data, dst)
+}
+
+func invalidBulkData(err error) error {
+	return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "invalid operation data: %s", err)
+}
+
+func containsSchema(schemas []string, schema string) bool {
+	for _, s := range schemas {
+		if strings.EqualFold(s, schema) {
+			return true
+		}
+	}
+	return false
+}
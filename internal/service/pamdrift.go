@@ -0,0 +1,420 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/audit"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"go.uber.org/zap"
+)
+
+// driftIgnoredAttrs are attributes which are not compared.
+//
+// Metadata is maintained by servers, memberships are compared separately.
+var driftIgnoredAttrs = map[string]bool{
+	"meta":    true,
+	"groups":  true,
+	"members": true,
+}
+
+// PamDriftService compares PAM mirror with PAM SCIM server.
+//
+// Comparison is read-only, neither mirror nor remote server is modified.
+type PamDriftService struct {
+	log      *zap.Logger
+	remote   PamDirectory
+	users    PamUserStorage
+	groups   PamGroupStorage
+	audit    *ActionRecorder
+	pageSize int
+}
+
+// NewPamDriftService is PamDriftService constructor
+func NewPamDriftService(log *zap.Logger, remote PamDirectory, users PamUserStorage, groups PamGroupStorage, recorder *ActionRecorder, pageSize int) *PamDriftService {
+	if pageSize <= 0 {
+		pageSize = defaultSyncPageSize
+	}
+
+	return &PamDriftService{
+		log:      log.Named("service.drift"),
+		remote:   remote,
+		users:    users,
+		groups:   groups,
+		audit:    recorder,
+		pageSize: pageSize,
+	}
+}
+
+// Report fetches users and groups from remote server and compares them
+// attribute by attribute with the mirror, including group memberships.
+//
+// Remote resources are converted the same way as synchronization does,
+// so report contains only differences which would be changed by full synchronization.
+func (s PamDriftService) Report(ctx context.Context) (report *pam.DriftReport, err error) {
+	rec := s.audit.Start(ctx, audit.ActionDiff, audit.ResourceTypeAll)
+	defer func() { rec.Finish(err) }()
+
+	report = &pam.DriftReport{
+		Generated:   time.Now().UTC(),
+		Resources:   []pam.ResourceDrift{},
+		Memberships: []pam.MembershipDrift{},
+	}
+
+	remoteMembers := newMembershipSet()
+	remoteUsers, err := s.remoteUsers(ctx, remoteMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteGroups, err := s.remoteGroups(ctx, remoteMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	localUsers, err := s.localUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	localGroups, err := s.localGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.compareUsers(report, localUsers, remoteUsers); err != nil {
+		return nil, err
+	}
+	if err = s.compareGroups(report, localGroups, remoteGroups); err != nil {
+		return nil, err
+	}
+	compareMemberships(report, localGroups, remoteMembers)
+
+	s.log.Info("drift report generated",
+		zap.Int("added", report.Summary.Added),
+		zap.Int("removed", report.Summary.Removed),
+		zap.Int("changed", report.Summary.Changed),
+		zap.Int("membershipsAdded", report.Summary.MembershipsAdded),
+		zap.Int("membershipsRemoved", report.Summary.MembershipsRemoved))
+	return report, nil
+}
+
+func (s PamDriftService) remoteUsers(ctx context.Context, ms *membershipSet) (map[int]pam.User, error) {
+	out := make(map[int]pam.User)
+	for startIndex := 1; ; {
+		page, err := s.remote.ListUsers(ctx, scim.ListParams{StartIndex: startIndex, Count: s.pageSize})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch users page at %d: %w", startIndex, err)
+		}
+
+		for _, res := range page.Resources {
+			u, err := pam.UserFromSCIM(res)
+			if err != nil {
+				s.log.Warn("skipped invalid user", zap.Error(err))
+				continue
+			}
+
+			out[u.ID] = *u
+			ms.addUserGroups(s.log, *u, res.Groups)
+		}
+
+		if !page.HasMore(len(page.Resources)) {
+			return out, nil
+		}
+		startIndex += len(page.Resources)
+	}
+}
+
+func (s PamDriftService) remoteGroups(ctx context.Context, ms *membershipSet) (map[int]pam.Group, error) {
+	out := make(map[int]pam.Group)
+	for startIndex := 1; ; {
+		page, err := s.remote.ListGroups(ctx, scim.ListParams{StartIndex: startIndex, Count: s.pageSize})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch groups page at %d: %w", startIndex, err)
+		}
+
+		for _, res := range page.Resources {
+			g, err := pam.GroupFromSCIM(res)
+			if err != nil {
+				s.log.Warn("skipped invalid group", zap.Error(err))
+				continue
+			}
+
+			out[g.ID] = *g
+			ms.addGroupMembers(s.log, *g, res.Members)
+		}
+
+		if !page.HasMore(len(page.Resources)) {
+			return out, nil
+		}
+		startIndex += len(page.Resources)
+	}
+}
+
+func (s PamDriftService) localUsers(ctx context.Context) (map[int]pam.User, error) {
+	out := make(map[int]pam.User)
+	cursor := ""
+	for {
+		q := model.ListQuery{Count: model.MaxPageSize, Cursor: &cursor}
+		users, page, err := s.users.ListUsers(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load mirror users: %w", err)
+		}
+
+		for _, u := range users {
+			out[u.ID] = u
+		}
+
+		if page.NextCursor == "" {
+			return out, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func (s PamDriftService) localGroups(ctx context.Context) (map[int]pam.Group, error) {
+	out := make(map[int]pam.Group)
+	cursor := ""
+	for {
+		q := model.ListQuery{Count: model.MaxPageSize, Cursor: &cursor}
+		groups, page, err := s.groups.ListGroups(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load mirror groups: %w", err)
+		}
+
+		for _, g := range groups {
+			out[g.ID] = g
+		}
+
+		if page.NextCursor == "" {
+			return out, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+func (s PamDriftService) compareUsers(report *pam.DriftReport, local, remote map[int]pam.User) error {
+	ids := make(map[int]struct{}, len(local)+len(remote))
+	for id := range local {
+		ids[id] = struct{}{}
+	}
+	for id := range remote {
+		ids[id] = struct{}{}
+	}
+
+	for _, id := range sortedIDs(ids) {
+		l, inLocal := local[id]
+		r, inRemote := remote[id]
+		d := pam.ResourceDrift{ResourceType: scim.ResourceTypeUser, ID: pam.FormatID(id)}
+		switch {
+		case !inLocal:
+			d.Kind, d.Name = pam.DriftAdded, r.UserName
+		case !inRemote:
+			d.Kind, d.Name = pam.DriftRemoved, l.UserName
+		default:
+			l.Groups, r.Groups = nil, nil
+			attrs, err := diffAttributes(l.SCIM(), r.SCIM())
+			if err != nil {
+				return fmt.Errorf("failed to compare user %d: %w", id, err)
+			}
+			if len(attrs) == 0 {
+				continue
+			}
+			d.Kind, d.Name, d.Attributes = pam.DriftChanged, r.UserName, attrs
+		}
+		report.AddResource(d)
+	}
+	return nil
+}
+
+func (s PamDriftService) compareGroups(report *pam.DriftReport, local, remote map[int]pam.Group) error {
+	ids := make(map[int]struct{}, len(local)+len(remote))
+	for id := range local {
+		ids[id] = struct{}{}
+	}
+	for id := range remote {
+		ids[id] = struct{}{}
+	}
+
+	for _, id := range sortedIDs(ids) {
+		l, inLocal := local[id]
+		r, inRemote := remote[id]
+		d := pam.ResourceDrift{ResourceType: scim.ResourceTypeGroup, ID: pam.FormatID(id)}
+		switch {
+		case !inLocal:
+			d.Kind, d.Name = pam.DriftAdded, r.DisplayName
+		case !inRemote:
+			d.Kind, d.Name = pam.DriftRemoved, l.DisplayName
+		default:
+			l.Members, r.Members = nil, nil
+			attrs, err := diffAttributes(l.SCIM(), r.SCIM())
+			if err != nil {
+				return fmt.Errorf("failed to compare group %d: %w", id, err)
+			}
+			if len(attrs) == 0 {
+				continue
+			}
+			d.Kind, d.Name, d.Attributes = pam.DriftChanged, r.DisplayName, attrs
+		}
+		report.AddResource(d)
+	}
+	return nil
+}
+
+// compareMemberships compares mirror group members with memberships collected from remote users and groups
+func compareMemberships(report *pam.DriftReport, localGroups map[int]pam.Group, remote *membershipSet) {
+	local := newMembershipSet()
+	for _, g := range localGroups {
+		for _, ref := range g.Members {
+			m := local.get(ref.Value, g.ID)
+			m.UserDisplay, m.GroupDisplay = ref.Display, g.DisplayName
+		}
+	}
+
+	var drift []pam.MembershipDrift
+	for key, m := range remote.items {
+		if _, ok := local.items[key]; !ok {
+			drift = append(drift, membershipDrift(pam.DriftAdded, *m))
+		}
+	}
+	for key, m := range local.items {
+		if _, ok := remote.items[key]; !ok {
+			drift = append(drift, membershipDrift(pam.DriftRemoved, *m))
+		}
+	}
+
+	sort.Slice(drift, func(i, j int) bool {
+		if drift[i].GroupID != drift[j].GroupID {
+			return drift[i].GroupID < drift[j].GroupID
+		}
+		return drift[i].UserID < drift[j].UserID
+	})
+	for _, d := range drift {
+		report.AddMembership(d)
+	}
+}
+
+func membershipDrift(kind pam.DriftKind, m pam.Membership) pam.MembershipDrift {
+	return pam.MembershipDrift{
+		Kind:         kind,
+		GroupID:      m.GroupID,
+		GroupDisplay: m.GroupDisplay,
+		UserID:       m.UserID,
+		UserDisplay:  m.UserDisplay,
+	}
+}
+
+// sortedIDs returns sorted set items
+func sortedIDs(set map[int]struct{}) []int {
+	out := make([]int, 0, len(set))
+	for id := range set {
+		out = append(out, id)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// diffAttributes compares JSON representations of mirror and remote resources.
+//
+// Complex attributes are compared by sub-attributes, multi-valued attributes
+// are compared as a whole regardless of values order.
+func diffAttributes(local, remote interface{}) ([]pam.AttributeDrift, error) {
+	l, err := flattenAttributes(local)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := flattenAttributes(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(l)+len(r))
+	for p := range l {
+		paths = append(paths, p)
+	}
+	for p := range r {
+		if _, ok := l[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var out []pam.AttributeDrift
+	for _, p := range paths {
+		if !reflect.DeepEqual(l[p], r[p]) {
+			out = append(out, pam.AttributeDrift{Path: p, Mirror: l[p], Remote: r[p]})
+		}
+	}
+	return out, nil
+}
+
+// flattenAttributes returns map of attribute paths to values of resource JSON representation
+func flattenAttributes(res interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	var attrs map[string]interface{}
+	if err = json.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]interface{})
+	for k, v := range attrs {
+		if !driftIgnoredAttrs[k] {
+			flattenValue(out, k, v)
+		}
+	}
+	return out, nil
+}
+
+func flattenValue(dst map[string]interface{}, path string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, sub := range v {
+			flattenValue(dst, path+"."+k, sub)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return
+		}
+		dst[path] = sortedValues(v)
+	case nil:
+	case string:
+		// unset and empty attributes are equivalent
+		if v != "" {
+			dst[path] = v
+		}
+	default:
+		dst[path] = v
+	}
+}
+
+// sortedValues sorts multi-valued attribute values by their JSON representation
+func sortedValues(values []interface{}) []interface{} {
+	keys := make([]string, len(values))
+	for i, v := range values {
+		data, _ := json.Marshal(v)
+		keys[i] = string(data)
+	}
+
+	idx := make([]int, len(values))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool { return keys[idx[i]] < keys[idx[j]] })
+
+	out := make([]interface{}, len(values))
+	for i, k := range idx {
+		out[i] = values[k]
+	}
+	return out
+}
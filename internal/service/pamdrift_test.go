@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"go.uber.org/zap"
+)
+
+func TestPamDriftService_Report(t *testing.T) {
+	remote := newFakePamDirectory(time.Now)
+	remote.users = []scim.User{
+		{ID: "1", UserName: "alice"},
+		{ID: "2", UserName: "bob", DisplayName: "Bob"},
+		{ID: "3", UserName: "carol"},
+	}
+	remote.groups = []scim.Group{
+		{ID: "10", DisplayName: "admins", Members: []scim.Reference{{Value: "1"}, {Value: "3"}}},
+		{ID: "11", DisplayName: "auditors"},
+	}
+
+	// mirror is synchronized with remote, then drifts apart
+	mirror := newFakePamMirror(t, remote)
+	delete(mirror.users, 3)
+	mirror.users[9] = pam.User{ID: 9, UserName: "dave", Active: true}
+	bob := mirror.users[2]
+	bob.DisplayName = "Robert"
+	mirror.users[2] = bob
+	admins := mirror.groups[10]
+	admins.Members = []pam.Reference{{Value: 1}, {Value: 9}}
+	mirror.groups[10] = admins
+	delete(mirror.groups, 11)
+
+	log := zap.NewNop()
+	svc := NewPamDriftService(log, remote, mirror, mirror, NewActionRecorder(log, nopActionStore{}), 2)
+	got, err := svc.Report(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, pam.DriftSummary{
+		Added:              2,
+		Removed:            1,
+		Changed:            1,
+		MembershipsAdded:   1,
+		MembershipsRemoved: 1,
+	}, got.Summary)
+	require.Equal(t, []pam.ResourceDrift{
+		{
+			Kind: pam.DriftChanged, ResourceType: scim.ResourceTypeUser, ID: "2", Name: "bob",
+			Attributes: []pam.AttributeDrift{{Path: "displayName", Mirror: "Robert", Remote: "Bob"}},
+		},
+		{Kind: pam.DriftAdded, ResourceType: scim.ResourceTypeUser, ID: "3", Name: "carol"},
+		{Kind: pam.DriftRemoved, ResourceType: scim.ResourceTypeUser, ID: "9", Name: "dave"},
+		{Kind: pam.DriftAdded, ResourceType: scim.ResourceTypeGroup, ID: "11", Name: "auditors"},
+	}, got.Resources)
+
+	require.Len(t, got.Memberships, 2)
+	require.Equal(t, pam.DriftAdded, got.Memberships[0].Kind)
+	require.Equal(t, [2]int{10, 3}, [2]int{got.Memberships[0].GroupID, got.Memberships[0].UserID})
+	require.Equal(t, pam.DriftRemoved, got.Memberships[1].Kind)
+	require.Equal(t, [2]int{10, 9}, [2]int{got.Memberships[1].GroupID, got.Memberships[1].UserID})
+}
+
+// fakePamMirror is PAM mirror storage of users and groups
+type fakePamMirror struct {
+	users  map[int]pam.User
+	groups map[int]pam.Group
+}
+
+// newFakePamMirror returns mirror with users and groups of remote directory, like after full synchronization
+func newFakePamMirror(t *testing.T, remote *fakePamDirectory) *fakePamMirror {
+	t.Helper()
+	m := &fakePamMirror{users: make(map[int]pam.User), groups: make(map[int]pam.Group)}
+	for _, res := range remote.users {
+		u, err := pam.UserFromSCIM(res)
+		require.NoError(t, err)
+		m.users[u.ID] = *u
+	}
+
+	for _, res := range remote.groups {
+		g, err := pam.GroupFromSCIM(res)
+		require.NoError(t, err)
+		for _, ref := range res.Members {
+			id, err := pam.ParseID(ref.Value)
+			require.NoError(t, err)
+			g.Members = append(g.Members, pam.Reference{Value: id})
+		}
+		m.groups[g.ID] = *g
+	}
+	return m
+}
+
+func (m *fakePamMirror) ListUsers(context.Context, model.ListQuery) (pam.Users, *model.Page, error) {
+	out := make(pam.Users, 0, len(m.users))
+	for _, u := range m.users {
+		out = append(out, u)
+	}
+	return out, &model.Page{Total: len(out)}, nil
+}
+
+func (m *fakePamMirror) UserByID(_ context.Context, id int) (*pam.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, ErrNotExists
+	}
+	return &u, nil
+}
+
+func (m *fakePamMirror) ListGroups(context.Context, model.ListQuery) (pam.Groups, *model.Page, error) {
+	out := make(pam.Groups, 0, len(m.groups))
+	for _, g := range m.groups {
+		out = append(out, g)
+	}
+	return out, &model.Page{Total: len(out)}, nil
+}
+
+func (m *fakePamMirror) GroupByID(_ context.Context, id int) (*pam.Group, error) {
+	g, ok := m.groups[id]
+	if !ok {
+		return nil, ErrNotExists
+	}
+	return &g, nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"go.uber.org/zap"
+)
+
+// PamHistoryStorage provides access to change history of PAM users and groups mirror
+type PamHistoryStorage interface {
+	// ListChanges returns a page of recorded changes matching list query
+	ListChanges(ctx context.Context, q model.ListQuery) (pam.Changes, *model.Page, error)
+
+	// UserGroupsAt returns group memberships of user which were valid at passed time
+	UserGroupsAt(ctx context.Context, userID int, asOf time.Time) (pam.MembershipPeriods, error)
+
+	// GroupMembersAt returns members of group which were valid at passed time
+	GroupMembersAt(ctx context.Context, groupID int, asOf time.Time) (pam.MembershipPeriods, error)
+}
+
+// PamHistoryService provides access to change history of PAM users and groups.
+//
+// History is recorded by mirror storage on each synchronization and provisioning change.
+type PamHistoryService struct {
+	log   *zap.Logger
+	store PamHistoryStorage
+}
+
+// NewPamHistoryService is PamHistoryService constructor
+func NewPamHistoryService(log *zap.Logger, store PamHistoryStorage) *PamHistoryService {
+	return &PamHistoryService{
+		log:   log.Named("service.history"),
+		store: store,
+	}
+}
+
+// ListChanges returns a page of changes of mirrored users and groups
+func (s PamHistoryService) ListChanges(ctx context.Context, q model.ListQuery) (pam.Changes, *model.Page, error) {
+	return s.store.ListChanges(ctx, q)
+}
+
+// UserGroups returns group memberships of user at passed time, or current memberships if time is nil.
+//
+// Memberships of removed users are available as well.
+func (s PamHistoryService) UserGroups(ctx context.Context, userID int, asOf *time.Time) (pam.MembershipPeriods, error) {
+	return s.store.UserGroupsAt(ctx, userID, pointInTime(asOf))
+}
+
+// GroupMembers returns members of group at passed time, or current members if time is nil.
+//
+// Members of removed groups are available as well.
+func (s PamHistoryService) GroupMembers(ctx context.Context, groupID int, asOf *time.Time) (pam.MembershipPeriods, error) {
+	return s.store.GroupMembersAt(ctx, groupID, pointInTime(asOf))
+}
+
+func pointInTime(asOf *time.Time) time.Time {
+	if asOf == nil {
+		return time.Now().UTC()
+	}
+	return asOf.UTC()
+}
@@ -0,0 +1,597 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/audit"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/patch"
+	"go.uber.org/zap"
+)
+
+// PamProvisioner is remote PAM SCIM server which accepts provisioning requests
+type PamProvisioner interface {
+	// CreateUser creates a new user and returns created resource
+	CreateUser(ctx context.Context, u scim.User) (*scim.User, error)
+
+	// ReplaceUser replaces user and returns updated resource
+	ReplaceUser(ctx context.Context, id string, u scim.User) (*scim.User, error)
+
+	// PatchUser applies PATCH operations to user.
+	//
+	// Returns nil resource if server returned no content.
+	PatchUser(ctx context.Context, id string, ops ...scim.PatchOperation) (*scim.User, error)
+
+	// CreateGroup creates a new group and returns created resource
+	CreateGroup(ctx context.Context, g scim.Group) (*scim.Group, error)
+
+	// ReplaceGroup replaces group and returns updated resource
+	ReplaceGroup(ctx context.Context, id string, g scim.Group) (*scim.Group, error)
+
+	// PatchGroup applies PATCH operations to group.
+	//
+	// Returns nil resource if server returned no content.
+	PatchGroup(ctx context.Context, id string, ops ...scim.PatchOperation) (*scim.Group, error)
+
+	// DeleteGroup deletes group
+	DeleteGroup(ctx context.Context, id string) error
+
+	// ServiceProviderConfig returns remote server features
+	ServiceProviderConfig(ctx context.Context) (*scim.ServiceProviderConfig, error)
+}
+
+// PamUserProvisionStore is PAM users mirror storage used by provisioning
+type PamUserProvisionStore interface {
+	// UserByID returns user by ID
+	UserByID(ctx context.Context, id int) (*pam.User, error)
+
+	// UpsertUsers creates or updates users with all their attributes
+	UpsertUsers(ctx context.Context, users pam.Users) error
+
+	// DeleteUser removes user from mirror
+	DeleteUser(ctx context.Context, id int) error
+}
+
+// PamGroupProvisionStore is PAM groups mirror storage used by provisioning
+type PamGroupProvisionStore interface {
+	// GroupByID returns group by ID
+	GroupByID(ctx context.Context, id int) (*pam.Group, error)
+
+	// UpsertGroups creates or updates groups with their metadata
+	UpsertGroups(ctx context.Context, groups pam.Groups) error
+
+	// ReplaceMemberships replaces memberships of users and groups in scope.
+	ReplaceMemberships(ctx context.Context, scope pam.MembershipScope, ms []pam.Membership) (int, error)
+
+	// DeleteGroup removes group from mirror
+	DeleteGroup(ctx context.Context, id int) error
+}
+
+// PamProvisioningService manages PAM users and groups on remote PAM SCIM server.
+//
+// Each change is sent to remote server first, local mirror is updated only if remote call succeeded.
+// Remote server is a source of truth, so mirror update failure doesn't fail the request,
+// mirror is fixed by the next synchronization.
+//
+// Request precondition from context (see scim.WithPrecondition) is checked against resource version
+// in mirror and is passed to remote server if it supports ETags.
+type PamProvisioningService struct {
+	log    *zap.Logger
+	remote PamProvisioner
+	users  PamUserProvisionStore
+	groups PamGroupProvisionStore
+	audit  *ActionRecorder
+	etag   *remoteETag
+}
+
+// NewPamProvisioningService is PamProvisioningService constructor
+func NewPamProvisioningService(log *zap.Logger, remote PamProvisioner, users PamUserProvisionStore, groups PamGroupProvisionStore, recorder *ActionRecorder) *PamProvisioningService {
+	return &PamProvisioningService{
+		log:    log.Named("service.pamprovision"),
+		remote: remote,
+		users:  users,
+		groups: groups,
+		audit:  recorder,
+		etag:   new(remoteETag),
+	}
+}
+
+// CreateUser creates a new PAM user
+func (s PamProvisioningService) CreateUser(ctx context.Context, u scim.User) (out *scim.User, err error) {
+	rec := s.audit.Start(ctx, audit.ActionCreate, scim.ResourceTypeUser).SetPayload(userPayload(u))
+	defer func() {
+		if out != nil {
+			rec.SetResourceID(out.ID)
+		}
+		rec.Finish(err)
+	}()
+
+	// id is assigned by server
+	u.ID = ""
+	out, err = s.remote.CreateUser(ctx, u)
+	if err != nil {
+		return nil, remoteError(err)
+	}
+
+	s.storeUser(ctx, *out)
+	return out, nil
+}
+
+// ReplaceUser replaces PAM user
+func (s PamProvisioningService) ReplaceUser(ctx context.Context, id int, u scim.User) (out *scim.User, err error) {
+	rec := s.audit.Start(ctx, audit.ActionReplace, scim.ResourceTypeUser).
+		SetResourceID(pam.FormatID(id)).
+		SetPayload(userPayload(u))
+	defer func() { rec.Finish(err) }()
+
+	if err = checkResourceID(u.ID, id); err != nil {
+		return nil, err
+	}
+
+	if err = s.checkUserVersion(ctx, id); err != nil {
+		return nil, err
+	}
+
+	if err = s.checkUserImmutable(ctx, id, u); err != nil {
+		return nil, err
+	}
+
+	u.ID = pam.FormatID(id)
+	out, err = s.remote.ReplaceUser(s.remoteContext(ctx), u.ID, u)
+	if err != nil {
+		return nil, remoteError(err)
+	}
+
+	s.storeUser(ctx, *out)
+	return out, nil
+}
+
+// PatchUser applies PATCH operations to PAM user.
+//
+// Operations are applied to user from mirror and the result is validated
+// before operations are sent to remote server.
+// Mirror is updated with resource returned by remote server
+// or with locally patched resource if server returned no content.
+func (s PamProvisioningService) PatchUser(ctx context.Context, id int, req scim.PatchRequest) (out *scim.User, err error) {
+	rec := s.audit.Start(ctx, audit.ActionPatch, scim.ResourceTypeUser).
+		SetResourceID(pam.FormatID(id)).
+		SetPayload(patchPayload(req))
+	defer func() { rec.Finish(err) }()
+
+	if err = patch.ValidateRequest(req); err != nil {
+		return nil, err
+	}
+
+	current, err := s.users.UserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := current.SCIM()
+	if err = scim.PreconditionFromContext(ctx).Check(res.Meta.Version); err != nil {
+		return nil, err
+	}
+
+	if err = PamSchemas.Patcher(scim.ResourceTypeUser).ApplyTo(&res, req.Operations); err != nil {
+		return nil, err
+	}
+
+	if err = PamSchemas.Validate(scim.ResourceTypeUser, res); err != nil {
+		return nil, err
+	}
+
+	if err = model.Validate(&res); err != nil {
+		return nil, err
+	}
+
+	out, err = s.remote.PatchUser(s.remoteContext(ctx), pam.FormatID(id), req.Operations...)
+	if err != nil {
+		return nil, remoteError(err)
+	}
+
+	if out == nil {
+		// version of patched resource is unknown
+		res.Password = ""
+		res.Meta.Version = ""
+		out = &res
+	}
+
+	s.storeUser(ctx, *out)
+	return out, nil
+}
+
+// DeactivateUser deactivates PAM user.
+//
+// Users are never deleted on remote server, since deletion can't be undone there:
+// user is deactivated with PATCH request and is kept in mirror as inactive.
+// User which doesn't exist on remote server anymore is removed from mirror.
+func (s PamProvisioningService) DeactivateUser(ctx context.Context, id int) (err error) {
+	rec := s.audit.Start(ctx, audit.ActionDeactivate, scim.ResourceTypeUser).SetResourceID(pam.FormatID(id))
+	defer func() { rec.Finish(err) }()
+
+	if err = s.checkUserVersion(ctx, id); err != nil {
+		return err
+	}
+
+	out, err := s.remote.PatchUser(s.remoteContext(ctx), pam.FormatID(id),
+		scim.PatchOperation{Op: scim.PatchReplace, Path: "active", Value: false})
+	if errors.Is(err, scim.ErrNotFound) {
+		if mirrorErr := s.users.DeleteUser(ctx, id); mirrorErr != nil && !isNotFound(mirrorErr) {
+			s.log.Error("failed to remove user from mirror", zap.Int("id", id), zap.Error(mirrorErr))
+		}
+		return nil
+	}
+	if err != nil {
+		return remoteError(err)
+	}
+
+	if out == nil {
+		current, mirrorErr := s.users.UserByID(ctx, id)
+		if mirrorErr != nil {
+			s.log.Error("failed to deactivate user in mirror", zap.Int("id", id), zap.Error(mirrorErr))
+			return nil
+		}
+
+		// version of patched resource is unknown
+		res := current.SCIM()
+		res.Active = new(bool)
+		res.Meta.Version = ""
+		out = &res
+	}
+
+	s.storeUser(ctx, *out)
+	return nil
+}
+
+// CreateGroup creates a new PAM group
+func (s PamProvisioningService) CreateGroup(ctx context.Context, g scim.Group) (out *scim.Group, err error) {
+	rec := s.audit.Start(ctx, audit.ActionCreate, scim.ResourceTypeGroup).SetPayload(g)
+	defer func() {
+		if out != nil {
+			rec.SetResourceID(out.ID)
+		}
+		rec.Finish(err)
+	}()
+
+	g.ID = ""
+	out, err = s.remote.CreateGroup(ctx, g)
+	if err != nil {
+		return nil, remoteError(err)
+	}
+
+	s.storeGroup(ctx, *out)
+	return out, nil
+}
+
+// ReplaceGroup replaces PAM group including its members
+func (s PamProvisioningService) ReplaceGroup(ctx context.Context, id int, g scim.Group) (out *scim.Group, err error) {
+	rec := s.audit.Start(ctx, audit.ActionReplace, scim.ResourceTypeGroup).
+		SetResourceID(pam.FormatID(id)).
+		SetPayload(g)
+	defer func() { rec.Finish(err) }()
+
+	if err = checkResourceID(g.ID, id); err != nil {
+		return nil, err
+	}
+
+	if err = s.checkGroupVersion(ctx, id); err != nil {
+		return nil, err
+	}
+
+	if err = s.checkGroupImmutable(ctx, id, g); err != nil {
+		return nil, err
+	}
+
+	g.ID = pam.FormatID(id)
+	out, err = s.remote.ReplaceGroup(s.remoteContext(ctx), g.ID, g)
+	if err != nil {
+		return nil, remoteError(err)
+	}
+
+	s.storeGroup(ctx, *out)
+	return out, nil
+}
+
+// PatchGroup applies PATCH operations to PAM group.
+//
+// Members can be added or removed without sending the whole members list,
+// like `{"op": "remove", "path": "members[value eq \"101\"]"}`.
+//
+// See PatchUser for details.
+func (s PamProvisioningService) PatchGroup(ctx context.Context, id int, req scim.PatchRequest) (out *scim.Group, err error) {
+	rec := s.audit.Start(ctx, audit.ActionPatch, scim.ResourceTypeGroup).
+		SetResourceID(pam.FormatID(id)).
+		SetPayload(req)
+	defer func() { rec.Finish(err) }()
+
+	if err = patch.ValidateRequest(req); err != nil {
+		return nil, err
+	}
+
+	current, err := s.groups.GroupByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := current.SCIM()
+	if err = scim.PreconditionFromContext(ctx).Check(res.Meta.Version); err != nil {
+		return nil, err
+	}
+
+	if err = PamSchemas.Patcher(scim.ResourceTypeGroup).ApplyTo(&res, req.Operations); err != nil {
+		return nil, err
+	}
+
+	if err = PamSchemas.Validate(scim.ResourceTypeGroup, res); err != nil {
+		return nil, err
+	}
+
+	if err = model.Validate(&res); err != nil {
+		return nil, err
+	}
+
+	out, err = s.remote.PatchGroup(s.remoteContext(ctx), pam.FormatID(id), req.Operations...)
+	if err != nil {
+		return nil, remoteError(err)
+	}
+
+	if out == nil {
+		res.Meta.Version = ""
+		out = &res
+	}
+
+	s.storeGroup(ctx, *out)
+	return out, nil
+}
+
+// DeleteGroup deletes PAM group.
+//
+// Group is removed from mirror also if it doesn't exist on remote server anymore.
+func (s PamProvisioningService) DeleteGroup(ctx context.Context, id int) (err error) {
+	rec := s.audit.Start(ctx, audit.ActionDelete, scim.ResourceTypeGroup).SetResourceID(pam.FormatID(id))
+	defer func() { rec.Finish(err) }()
+
+	if err = s.checkGroupVersion(ctx, id); err != nil {
+		return err
+	}
+
+	err = s.remote.DeleteGroup(s.remoteContext(ctx), pam.FormatID(id))
+	if err != nil && !errors.Is(err, scim.ErrNotFound) {
+		return remoteError(err)
+	}
+
+	// group which doesn't exist on remote server is already deleted
+	if mirrorErr := s.groups.DeleteGroup(ctx, id); mirrorErr != nil && !isNotFound(mirrorErr) {
+		s.log.Error("failed to remove group from mirror", zap.Int("id", id), zap.Error(mirrorErr))
+	}
+	return nil
+}
+
+// storeUser updates user in mirror. Errors are only logged.
+func (s PamProvisioningService) storeUser(ctx context.Context, res scim.User) {
+	u, err := pam.UserFromSCIM(res)
+	if err == nil {
+		err = s.users.UpsertUsers(ctx, pam.Users{*u})
+	}
+
+	if err != nil {
+		s.log.Error("failed to update user in mirror", zap.String("id", res.ID), zap.Error(err))
+	}
+}
+
+// storeGroup updates group and its members in mirror. Errors are only logged.
+func (s PamProvisioningService) storeGroup(ctx context.Context, res scim.Group) {
+	g, err := pam.GroupFromSCIM(res)
+	if err == nil {
+		err = s.groups.UpsertGroups(ctx, pam.Groups{*g})
+	}
+
+	if err == nil {
+		ms := newMembershipSet()
+		ms.addGroupMembers(s.log, *g, res.Members)
+		_, err = s.groups.ReplaceMemberships(ctx, pam.MembershipScope{GroupIDs: []int{g.ID}}, ms.list())
+	}
+
+	if err != nil {
+		s.log.Error("failed to update group in mirror", zap.String("id", res.ID), zap.Error(err))
+	}
+}
+
+// checkUserVersion checks request precondition against version of user in mirror
+func (s PamProvisioningService) checkUserVersion(ctx context.Context, id int) error {
+	pre := scim.PreconditionFromContext(ctx)
+	if pre.IsZero() {
+		return nil
+	}
+
+	var version string
+	u, err := s.users.UserByID(ctx, id)
+	switch {
+	case err == nil:
+		version = u.SCIM().Meta.Version
+	case !isNotFound(err):
+		return err
+	}
+	return pre.Check(version)
+}
+
+// checkGroupVersion checks request precondition against version of group in mirror
+func (s PamProvisioningService) checkGroupVersion(ctx context.Context, id int) error {
+	pre := scim.PreconditionFromContext(ctx)
+	if pre.IsZero() {
+		return nil
+	}
+
+	var version string
+	g, err := s.groups.GroupByID(ctx, id)
+	switch {
+	case err == nil:
+		version = g.SCIM().Meta.Version
+	case !isNotFound(err):
+		return err
+	}
+	return pre.Check(version)
+}
+
+// checkUserImmutable checks that replacement doesn't change immutable attributes of user in mirror
+func (s PamProvisioningService) checkUserImmutable(ctx context.Context, id int, u scim.User) error {
+	current, err := s.users.UserByID(ctx, id)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return PamSchemas.CheckImmutable(scim.ResourceTypeUser, current.SCIM(), u)
+}
+
+// checkGroupImmutable checks that replacement doesn't change immutable attributes of group in mirror,
+// like type of existing members.
+func (s PamProvisioningService) checkGroupImmutable(ctx context.Context, id int, g scim.Group) error {
+	current, err := s.groups.GroupByID(ctx, id)
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return PamSchemas.CheckImmutable(scim.ResourceTypeGroup, current.SCIM(), g)
+}
+
+// remoteContext returns context of remote write request.
+//
+// Request precondition is removed from context if remote server doesn't support ETags,
+// in this case precondition is checked only against mirror.
+func (s PamProvisioningService) remoteContext(ctx context.Context) context.Context {
+	if scim.PreconditionFromContext(ctx).IsZero() || s.etag.supported(ctx, s.log, s.remote) {
+		return ctx
+	}
+	return scim.WithPrecondition(ctx, scim.Precondition{})
+}
+
+// etagCheckBackoff is delay before failed check of remote server ETag support is retried
+const etagCheckBackoff = time.Minute
+
+// remoteETag caches ETag support flag of remote server
+type remoteETag struct {
+	mu       sync.Mutex
+	checked  bool
+	checking bool
+	value    bool
+	retryAt  time.Time
+}
+
+// supported reports whether remote server supports ETags.
+//
+// Remote server config is requested once without holding the lock, failed request
+// is retried after etagCheckBackoff. ETags are considered unsupported until check succeeds.
+func (e *remoteETag) supported(ctx context.Context, log *zap.Logger, remote PamProvisioner) bool {
+	e.mu.Lock()
+	if e.checked || e.checking || time.Now().Before(e.retryAt) {
+		defer e.mu.Unlock()
+		return e.value
+	}
+	e.checking = true
+	e.mu.Unlock()
+
+	cfg, err := remote.ServiceProviderConfig(ctx)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.checking = false
+	if err != nil {
+		e.retryAt = time.Now().Add(etagCheckBackoff)
+		log.Warn("failed to check PAM SCIM server ETag support",
+			zap.Duration("retryIn", etagCheckBackoff), zap.Error(err))
+		return false
+	}
+
+	e.checked, e.value = true, cfg.ETag.Supported
+	return e.value
+}
+
+// checkResourceID checks that resource id in payload matches id in request path
+func checkResourceID(payloadID string, id int) error {
+	if payloadID == "" || payloadID == pam.FormatID(id) {
+		return nil
+	}
+
+	return scim.NewError(http.StatusBadRequest, scim.ErrTypeMutability,
+		"resource id %q doesn't match requested id %d", payloadID, id)
+}
+
+// userPayload returns user payload for audit digest without password
+func userPayload(u scim.User) scim.User {
+	u.Password = ""
+	return u
+}
+
+// patchPayload returns PATCH request for audit digest without password values
+func patchPayload(req scim.PatchRequest) scim.PatchRequest {
+	ops := make([]scim.PatchOperation, 0, len(req.Operations))
+	for _, op := range req.Operations {
+		if isPasswordAttr(op.Path) {
+			op.Value = nil
+		} else if v, ok := op.Value.(map[string]interface{}); ok {
+			clean := make(map[string]interface{}, len(v))
+			for k, val := range v {
+				if !isPasswordAttr(k) {
+					clean[k] = val
+				}
+			}
+			op.Value = clean
+		}
+		ops = append(ops, op)
+	}
+
+	req.Operations = ops
+	return req
+}
+
+// isPasswordAttr reports whether attribute name or path refers to user password.
+//
+// Attribute names are case-insensitive and can be prefixed with core schema URI.
+func isPasswordAttr(name string) bool {
+	return strings.EqualFold(name, "password") ||
+		strings.EqualFold(name, scim.SchemaUser+":password")
+}
+
+// isNotFound reports whether error is "404 Not Found" error
+func isNotFound(err error) bool {
+	var apiErr *web.APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
+
+// remoteError converts remote PAM SCIM server error.
+//
+// Authentication errors are caused by scimfe credentials and not by client request,
+// so they are reported as bad gateway as well as transport errors.
+// Requests rejected by open circuit breaker are reported as service unavailable.
+// Other SCIM errors are returned as is.
+func remoteError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, scim.ErrCircuitOpen) {
+		return web.NewAPIError(http.StatusServiceUnavailable, "PAM SCIM server is unavailable: %s", err)
+	}
+
+	var scimErr *scim.Error
+	if !errors.As(err, &scimErr) {
+		return web.NewAPIError(http.StatusBadGateway, "PAM SCIM server request failed: %s", err)
+	}
+
+	if errors.Is(err, scim.ErrUnauthorized) || errors.Is(err, scim.ErrForbidden) {
+		return web.NewAPIError(http.StatusBadGateway, "PAM SCIM server rejected request: %s", err)
+	}
+	return err
+}
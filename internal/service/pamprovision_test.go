@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+func TestPatchPayload(t *testing.T) {
+	cases := map[string]struct {
+		op   scim.PatchOperation
+		want scim.PatchOperation
+	}{
+		"password path": {
+			op:   scim.PatchOperation{Op: "replace", Path: "password", Value: "secret"},
+			want: scim.PatchOperation{Op: "replace", Path: "password"},
+		},
+		"password path in other case": {
+			op:   scim.PatchOperation{Op: "replace", Path: "PassWord", Value: "secret"},
+			want: scim.PatchOperation{Op: "replace", Path: "PassWord"},
+		},
+		"password path with schema URI": {
+			op:   scim.PatchOperation{Op: "add", Path: "urn:ietf:params:scim:schemas:core:2.0:User:password", Value: "secret"},
+			want: scim.PatchOperation{Op: "add", Path: "urn:ietf:params:scim:schemas:core:2.0:User:password"},
+		},
+		"password key": {
+			op: scim.PatchOperation{Op: "replace", Value: map[string]interface{}{
+				"password": "secret", "displayName": "John",
+			}},
+			want: scim.PatchOperation{Op: "replace", Value: map[string]interface{}{"displayName": "John"}},
+		},
+		"password key in other case": {
+			op: scim.PatchOperation{Op: "replace", Value: map[string]interface{}{
+				"Password": "secret", "displayName": "John",
+			}},
+			want: scim.PatchOperation{Op: "replace", Value: map[string]interface{}{"displayName": "John"}},
+		},
+		"password key with schema URI": {
+			op: scim.PatchOperation{Op: "replace", Value: map[string]interface{}{
+				"urn:ietf:params:scim:schemas:core:2.0:User:PASSWORD": "secret", "displayName": "John",
+			}},
+			want: scim.PatchOperation{Op: "replace", Value: map[string]interface{}{"displayName": "John"}},
+		},
+		"other attribute": {
+			op:   scim.PatchOperation{Op: "replace", Path: "displayName", Value: "John"},
+			want: scim.PatchOperation{Op: "replace", Path: "displayName", Value: "John"},
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			req := scim.PatchRequest{Operations: []scim.PatchOperation{c.op}}
+			got := patchPayload(req)
+			require.Equal(t, []scim.PatchOperation{c.want}, got.Operations)
+		})
+	}
+
+	t.Run("request isn't modified", func(t *testing.T) {
+		value := map[string]interface{}{"password": "secret"}
+		patchPayload(scim.PatchRequest{Operations: []scim.PatchOperation{{Op: "replace", Value: value}}})
+		require.Equal(t, map[string]interface{}{"password": "secret"}, value)
+	})
+}
@@ -0,0 +1,979 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/strick-j/scimfe/internal/model/audit"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"go.uber.org/zap"
+)
+
+const defaultSyncPageSize = 100
+
+// PamDirectory is remote PAM SCIM server directory
+type PamDirectory interface {
+	// ListUsers returns a page of users
+	ListUsers(ctx context.Context, p scim.ListParams) (*scim.UserList, error)
+
+	// ListGroups returns a page of groups
+	ListGroups(ctx context.Context, p scim.ListParams) (*scim.GroupList, error)
+
+	// ListContainers returns a page of containers
+	ListContainers(ctx context.Context, p scim.ListParams) (*scim.ContainerList, error)
+
+	// ListContainerPermissions returns a page of container permissions
+	ListContainerPermissions(ctx context.Context, p scim.ListParams) (*scim.ContainerPermissionList, error)
+
+	// ListPrivilegedData returns a page of accounts
+	ListPrivilegedData(ctx context.Context, p scim.ListParams) (*scim.PrivilegedDataList, error)
+}
+
+// PamUserSyncStore is PAM users mirror storage used by synchronization
+type PamUserSyncStore interface {
+	// UpsertUsers creates or updates users with all their attributes
+	UpsertUsers(ctx context.Context, users pam.Users) error
+
+	// DeleteUsersExcept removes all users except users with specified IDs.
+	//
+	// Returns number of removed users.
+	DeleteUsersExcept(ctx context.Context, keep []int) (int64, error)
+}
+
+// PamGroupSyncStore is PAM groups mirror storage used by synchronization
+type PamGroupSyncStore interface {
+	// UpsertGroups creates or updates groups with their metadata
+	UpsertGroups(ctx context.Context, groups pam.Groups) error
+
+	// DeleteGroupsExcept removes all groups except groups with specified IDs.
+	//
+	// Returns number of removed groups.
+	DeleteGroupsExcept(ctx context.Context, keep []int) (int64, error)
+
+	// ReplaceMemberships replaces memberships of users and groups in scope.
+	//
+	// Memberships referencing missing users or groups are skipped,
+	// returns number of skipped memberships.
+	ReplaceMemberships(ctx context.Context, scope pam.MembershipScope, ms []pam.Membership) (int, error)
+}
+
+// PamContainerSyncStore is PAM containers mirror storage used by synchronization
+type PamContainerSyncStore interface {
+	// UpsertContainers creates or updates containers with their metadata
+	UpsertContainers(ctx context.Context, containers pam.Containers) error
+
+	// ContainerIDs returns IDs of all containers
+	ContainerIDs(ctx context.Context) ([]string, error)
+
+	// DeleteContainersExcept removes all containers except containers with specified IDs.
+	//
+	// Returns number of removed containers.
+	DeleteContainersExcept(ctx context.Context, keep []string) (int64, error)
+
+	// ReplacePermissions replaces permissions of containers in scope.
+	//
+	// Permissions referencing missing containers, users or groups are skipped,
+	// returns number of skipped permissions.
+	ReplacePermissions(ctx context.Context, containerIDs []string, perms []pam.ContainerPermission) (int, error)
+}
+
+// PamAccountSyncStore is PAM accounts mirror storage used by synchronization
+type PamAccountSyncStore interface {
+	// UpsertAccounts creates or updates accounts with their metadata.
+	//
+	// Accounts referencing missing containers are skipped,
+	// returns number of skipped accounts.
+	UpsertAccounts(ctx context.Context, accounts pam.Accounts) (int, error)
+
+	// DeleteAccountsExcept removes all accounts except accounts with specified IDs.
+	//
+	// Returns number of removed accounts.
+	DeleteAccountsExcept(ctx context.Context, keep []string) (int64, error)
+}
+
+// PamSyncStateStore stores synchronization high-water marks
+type PamSyncStateStore interface {
+	// Watermark returns the latest modification time of synchronized resources of resource type.
+	//
+	// Returns nil if resource type was never synchronized.
+	Watermark(ctx context.Context, resourceType string) (*time.Time, error)
+
+	// SetWatermark updates the latest modification time of synchronized resources of resource type
+	SetWatermark(ctx context.Context, resourceType string, lastModified time.Time) error
+}
+
+// PamSyncService synchronizes local PAM mirror with remote PAM SCIM server.
+//
+// Synchronization is performed in one of modes:
+//
+//   - full: all remote resources are fetched and stored, local resources missing on remote are removed.
+//   - delta: only resources modified since the high-water mark of resource type are fetched and stored.
+//   - reconcile: only IDs of remote resources are fetched, local resources missing on remote are removed.
+//
+// Delta synchronization doesn't detect removed resources, so it should be combined
+// with periodic reconcile or full synchronization.
+//
+// Local users and groups are not removed if some remote users or groups are invalid,
+// since invalid remote resources can't be matched with local ones.
+type PamSyncService struct {
+	log        *zap.Logger
+	remote     PamDirectory
+	users      PamUserSyncStore
+	groups     PamGroupSyncStore
+	containers PamContainerSyncStore
+	accounts   PamAccountSyncStore
+	state      PamSyncStateStore
+	audit      *ActionRecorder
+	pageSize   int
+}
+
+// NewPamSyncService is PamSyncService constructor
+func NewPamSyncService(log *zap.Logger, remote PamDirectory, users PamUserSyncStore, groups PamGroupSyncStore, containers PamContainerSyncStore, accounts PamAccountSyncStore, state PamSyncStateStore, recorder *ActionRecorder, pageSize int) *PamSyncService {
+	if pageSize <= 0 {
+		pageSize = defaultSyncPageSize
+	}
+
+	return &PamSyncService{
+		log:        log.Named("service.pamsync"),
+		remote:     remote,
+		users:      users,
+		groups:     groups,
+		containers: containers,
+		accounts:   accounts,
+		state:      state,
+		audit:      recorder,
+		pageSize:   pageSize,
+	}
+}
+
+// SyncAll synchronizes users, groups, memberships, containers and accounts.
+//
+// Memberships are collected from both users and groups and stored
+// after both resource types were synchronized.
+// Containers are synchronized after users and groups, since container permissions
+// reference users and groups, accounts are synchronized last.
+func (s PamSyncService) SyncAll(ctx context.Context, mode pam.SyncMode) (result *pam.SyncResult, err error) {
+	rec := s.audit.Start(ctx, audit.ActionSync, audit.ResourceTypeAll)
+	defer func() { rec.Finish(err) }()
+
+	if result, err = newSyncResult(mode); err != nil {
+		return nil, err
+	}
+
+	if result.Mode == pam.SyncReconcile {
+		for _, resourceType := range syncResourceTypes {
+			if err = s.reconcile(ctx, result, resourceType); err != nil {
+				return result, err
+			}
+		}
+
+		s.logResult("reconciliation finished", result)
+		return result, nil
+	}
+
+	ms := newMembershipSet()
+	usersScope, err := s.syncUsers(ctx, result, ms)
+	if err != nil {
+		return result, err
+	}
+
+	groupsScope, err := s.syncGroups(ctx, result, ms)
+	if err != nil {
+		return result, err
+	}
+
+	scope := pam.MembershipScope{UserIDs: usersScope, GroupIDs: groupsScope}
+	if err = s.storeMemberships(ctx, result, scope, ms); err != nil {
+		return result, err
+	}
+
+	if err = s.syncContainers(ctx, result); err != nil {
+		return result, err
+	}
+
+	if err = s.syncAccounts(ctx, result); err != nil {
+		return result, err
+	}
+
+	s.logResult("synchronization finished", result)
+	return result, nil
+}
+
+// SyncUsers synchronizes users.
+//
+// Remote users are fetched page by page and each page is stored in a single transaction.
+// In full mode users which don't exist on remote anymore are removed after all pages were fetched,
+// unless some remote users are invalid.
+//
+// Memberships are not changed, except memberships of removed users.
+func (s PamSyncService) SyncUsers(ctx context.Context, mode pam.SyncMode) (result *pam.SyncResult, err error) {
+	rec := s.audit.Start(ctx, audit.ActionSync, scim.ResourceTypeUser)
+	defer func() { rec.Finish(err) }()
+
+	if result, err = newSyncResult(mode); err != nil {
+		return nil, err
+	}
+
+	if result.Mode == pam.SyncReconcile {
+		err = s.reconcile(ctx, result, scim.ResourceTypeUser)
+	} else {
+		_, err = s.syncUsers(ctx, result, nil)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	s.logResult("users synchronization finished", result)
+	return result, nil
+}
+
+// SyncGroups synchronizes groups including group members.
+//
+// Group members are stored after all groups were fetched.
+// Members which reference users missing in local mirror are skipped.
+func (s PamSyncService) SyncGroups(ctx context.Context, mode pam.SyncMode) (result *pam.SyncResult, err error) {
+	rec := s.audit.Start(ctx, audit.ActionSync, scim.ResourceTypeGroup)
+	defer func() { rec.Finish(err) }()
+
+	if result, err = newSyncResult(mode); err != nil {
+		return nil, err
+	}
+
+	if result.Mode == pam.SyncReconcile {
+		if err = s.reconcile(ctx, result, scim.ResourceTypeGroup); err != nil {
+			return result, err
+		}
+
+		s.logResult("groups synchronization finished", result)
+		return result, nil
+	}
+
+	ms := newMembershipSet()
+	seen, err := s.syncGroups(ctx, result, ms)
+	if err != nil {
+		return result, err
+	}
+
+	if err = s.storeMemberships(ctx, result, pam.MembershipScope{GroupIDs: seen}, ms); err != nil {
+		return result, err
+	}
+
+	s.logResult("groups synchronization finished", result)
+	return result, nil
+}
+
+// SyncContainers synchronizes containers including container permissions.
+//
+// Permissions are fetched after all containers were fetched and replace
+// permissions of all synchronized containers.
+// Permissions which reference users or groups missing in local mirror are skipped.
+func (s PamSyncService) SyncContainers(ctx context.Context, mode pam.SyncMode) (result *pam.SyncResult, err error) {
+	rec := s.audit.Start(ctx, audit.ActionSync, scim.ResourceTypeContainer)
+	defer func() { rec.Finish(err) }()
+
+	if result, err = newSyncResult(mode); err != nil {
+		return nil, err
+	}
+
+	if result.Mode == pam.SyncReconcile {
+		err = s.reconcile(ctx, result, scim.ResourceTypeContainer)
+	} else {
+		err = s.syncContainers(ctx, result)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	s.logResult("containers synchronization finished", result)
+	return result, nil
+}
+
+// SyncAccounts synchronizes accounts.
+//
+// Accounts which reference containers missing in local mirror are skipped.
+// Account secrets are never requested nor stored.
+func (s PamSyncService) SyncAccounts(ctx context.Context, mode pam.SyncMode) (result *pam.SyncResult, err error) {
+	rec := s.audit.Start(ctx, audit.ActionSync, scim.ResourceTypePrivilegedData)
+	defer func() { rec.Finish(err) }()
+
+	if result, err = newSyncResult(mode); err != nil {
+		return nil, err
+	}
+
+	if result.Mode == pam.SyncReconcile {
+		err = s.reconcile(ctx, result, scim.ResourceTypePrivilegedData)
+	} else {
+		err = s.syncAccounts(ctx, result)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	s.logResult("accounts synchronization finished", result)
+	return result, nil
+}
+
+// Sync synchronizes resources of resource type, audit.ResourceTypeAll synchronizes all resource types.
+func (s PamSyncService) Sync(ctx context.Context, resourceType string, mode pam.SyncMode) (*pam.SyncResult, error) {
+	switch resourceType {
+	case audit.ResourceTypeAll:
+		return s.SyncAll(ctx, mode)
+	case scim.ResourceTypeUser:
+		return s.SyncUsers(ctx, mode)
+	case scim.ResourceTypeGroup:
+		return s.SyncGroups(ctx, mode)
+	case scim.ResourceTypeContainer:
+		return s.SyncContainers(ctx, mode)
+	case scim.ResourceTypePrivilegedData:
+		return s.SyncAccounts(ctx, mode)
+	default:
+		return nil, web.NewErrBadRequest("unsupported resource type %q", resourceType)
+	}
+}
+
+// ParseSyncResourceType validates synchronized resource type.
+//
+// Empty resource type is parsed as audit.ResourceTypeAll.
+func ParseSyncResourceType(resourceType string) (string, error) {
+	if resourceType == "" || resourceType == audit.ResourceTypeAll {
+		return audit.ResourceTypeAll, nil
+	}
+
+	for _, rt := range syncResourceTypes {
+		if rt == resourceType {
+			return rt, nil
+		}
+	}
+	return "", web.NewErrBadRequest("unsupported resource type %q", resourceType)
+}
+
+func (s PamSyncService) syncUsers(ctx context.Context, result *pam.SyncResult, ms *membershipSet) ([]int, error) {
+	filter, err := s.deltaFilter(ctx, result.Mode, scim.ResourceTypeUser)
+	if err != nil {
+		return nil, err
+	}
+
+	var seen []int
+	var invalid int
+	var hw highWater
+	for startIndex := 1; ; {
+		page, err := s.remote.ListUsers(ctx, scim.ListParams{
+			Filter:     filter,
+			StartIndex: startIndex,
+			Count:      s.pageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch users page at %d: %w", startIndex, err)
+		}
+
+		result.Pages++
+		users := make(pam.Users, 0, len(page.Resources))
+		for _, res := range page.Resources {
+			u, err := pam.UserFromSCIM(res)
+			if err != nil {
+				result.Failed++
+				invalid++
+				s.log.Warn("skipped invalid user", zap.Error(err))
+				continue
+			}
+
+			users = append(users, *u)
+			seen = append(seen, u.ID)
+			hw.observe(u.Meta)
+			if ms != nil {
+				ms.addUserGroups(s.log, *u, res.Groups)
+			}
+		}
+
+		if err = s.users.UpsertUsers(ctx, users); err != nil {
+			return nil, fmt.Errorf("failed to store users page at %d: %w", startIndex, err)
+		}
+
+		result.Upserted += len(users)
+		reportSyncProgress(ctx, result)
+		if !page.HasMore(len(page.Resources)) {
+			break
+		}
+		startIndex += len(page.Resources)
+	}
+
+	if result.Mode == pam.SyncFull && invalid > 0 {
+		s.logSkippedRemoval(scim.ResourceTypeUser, invalid)
+	} else if result.Mode == pam.SyncFull {
+		deleted, err := s.users.DeleteUsersExcept(ctx, seen)
+		if err != nil {
+			return nil, err
+		}
+		result.Deleted += int(deleted)
+	}
+
+	return seen, s.storeWatermark(ctx, scim.ResourceTypeUser, hw)
+}
+
+func (s PamSyncService) syncGroups(ctx context.Context, result *pam.SyncResult, ms *membershipSet) ([]int, error) {
+	filter, err := s.deltaFilter(ctx, result.Mode, scim.ResourceTypeGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	var seen []int
+	var invalid int
+	var hw highWater
+	for startIndex := 1; ; {
+		page, err := s.remote.ListGroups(ctx, scim.ListParams{
+			Filter:     filter,
+			StartIndex: startIndex,
+			Count:      s.pageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch groups page at %d: %w", startIndex, err)
+		}
+
+		result.Pages++
+		groups := make(pam.Groups, 0, len(page.Resources))
+		for _, res := range page.Resources {
+			g, err := pam.GroupFromSCIM(res)
+			if err != nil {
+				result.Failed++
+				invalid++
+				s.log.Warn("skipped invalid group", zap.Error(err))
+				continue
+			}
+
+			groups = append(groups, *g)
+			seen = append(seen, g.ID)
+			hw.observe(g.Meta)
+			ms.addGroupMembers(s.log, *g, res.Members)
+		}
+
+		if err = s.groups.UpsertGroups(ctx, groups); err != nil {
+			return nil, fmt.Errorf("failed to store groups page at %d: %w", startIndex, err)
+		}
+
+		result.Upserted += len(groups)
+		reportSyncProgress(ctx, result)
+		if !page.HasMore(len(page.Resources)) {
+			break
+		}
+		startIndex += len(page.Resources)
+	}
+
+	if result.Mode == pam.SyncFull && invalid > 0 {
+		s.logSkippedRemoval(scim.ResourceTypeGroup, invalid)
+	} else if result.Mode == pam.SyncFull {
+		deleted, err := s.groups.DeleteGroupsExcept(ctx, seen)
+		if err != nil {
+			return nil, err
+		}
+		result.Deleted += int(deleted)
+	}
+
+	return seen, s.storeWatermark(ctx, scim.ResourceTypeGroup, hw)
+}
+
+func (s PamSyncService) syncContainers(ctx context.Context, result *pam.SyncResult) error {
+	filter, err := s.deltaFilter(ctx, result.Mode, scim.ResourceTypeContainer)
+	if err != nil {
+		return err
+	}
+
+	var seen []string
+	var hw highWater
+	for startIndex := 1; ; {
+		page, err := s.remote.ListContainers(ctx, scim.ListParams{
+			Filter:     filter,
+			StartIndex: startIndex,
+			Count:      s.pageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to fetch containers page at %d: %w", startIndex, err)
+		}
+
+		result.Pages++
+		containers := make(pam.Containers, 0, len(page.Resources))
+		for _, res := range page.Resources {
+			c, err := pam.ContainerFromSCIM(res)
+			if err != nil {
+				result.Failed++
+				s.log.Warn("skipped invalid container", zap.Error(err))
+				continue
+			}
+
+			containers = append(containers, *c)
+			seen = append(seen, c.ID)
+			hw.observe(c.Meta)
+		}
+
+		if err = s.containers.UpsertContainers(ctx, containers); err != nil {
+			return fmt.Errorf("failed to store containers page at %d: %w", startIndex, err)
+		}
+
+		result.Upserted += len(containers)
+		reportSyncProgress(ctx, result)
+		if !page.HasMore(len(page.Resources)) {
+			break
+		}
+		startIndex += len(page.Resources)
+	}
+
+	switch result.Mode {
+	case pam.SyncFull:
+		deleted, err := s.containers.DeleteContainersExcept(ctx, seen)
+		if err != nil {
+			return err
+		}
+		result.Deleted += int(deleted)
+	case pam.SyncDelta:
+		// permission changes don't modify containers, so permissions of all containers are replaced
+		if seen, err = s.containers.ContainerIDs(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err = s.storeWatermark(ctx, scim.ResourceTypeContainer, hw); err != nil {
+		return err
+	}
+	return s.syncPermissions(ctx, result, seen)
+}
+
+func (s PamSyncService) syncAccounts(ctx context.Context, result *pam.SyncResult) error {
+	filter, err := s.deltaFilter(ctx, result.Mode, scim.ResourceTypePrivilegedData)
+	if err != nil {
+		return err
+	}
+
+	var seen []string
+	var hw highWater
+	for startIndex := 1; ; {
+		page, err := s.remote.ListPrivilegedData(ctx, scim.ListParams{
+			Filter:     filter,
+			StartIndex: startIndex,
+			Count:      s.pageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to fetch accounts page at %d: %w", startIndex, err)
+		}
+
+		result.Pages++
+		accounts := make(pam.Accounts, 0, len(page.Resources))
+		for _, res := range page.Resources {
+			a, err := pam.AccountFromSCIM(res)
+			if err != nil {
+				result.Failed++
+				s.log.Warn("skipped invalid account", zap.Error(err))
+				continue
+			}
+
+			accounts = append(accounts, *a)
+			seen = append(seen, a.ID)
+			hw.observe(a.Meta)
+		}
+
+		skipped, err := s.accounts.UpsertAccounts(ctx, accounts)
+		if err != nil {
+			return fmt.Errorf("failed to store accounts page at %d: %w", startIndex, err)
+		}
+
+		result.Upserted += len(accounts) - skipped
+		result.Unresolved += skipped
+		if skipped > 0 {
+			s.log.Warn("some accounts reference missing containers and were skipped",
+				zap.Int("count", skipped))
+		}
+
+		reportSyncProgress(ctx, result)
+		if !page.HasMore(len(page.Resources)) {
+			break
+		}
+		startIndex += len(page.Resources)
+	}
+
+	if result.Mode == pam.SyncFull {
+		deleted, err := s.accounts.DeleteAccountsExcept(ctx, seen)
+		if err != nil {
+			return err
+		}
+		result.Deleted += int(deleted)
+	}
+
+	return s.storeWatermark(ctx, scim.ResourceTypePrivilegedData, hw)
+}
+
+// reconcile removes local resources of resource type which don't exist on remote server anymore.
+//
+// Only IDs of remote resources are requested, so reconciliation is much cheaper than full synchronization.
+func (s PamSyncService) reconcile(ctx context.Context, result *pam.SyncResult, resourceType string) error {
+	ids, err := s.remoteIDs(ctx, result, resourceType)
+	if err != nil {
+		return err
+	}
+
+	var deleted int64
+	switch resourceType {
+	case scim.ResourceTypeUser, scim.ResourceTypeGroup:
+		keep, invalid := s.parseIDs(result, resourceType, ids)
+		if invalid > 0 {
+			s.logSkippedRemoval(resourceType, invalid)
+			return nil
+		}
+
+		if resourceType == scim.ResourceTypeUser {
+			deleted, err = s.users.DeleteUsersExcept(ctx, keep)
+		} else {
+			deleted, err = s.groups.DeleteGroupsExcept(ctx, keep)
+		}
+	case scim.ResourceTypeContainer:
+		deleted, err = s.containers.DeleteContainersExcept(ctx, ids)
+	case scim.ResourceTypePrivilegedData:
+		deleted, err = s.accounts.DeleteAccountsExcept(ctx, ids)
+	}
+	if err != nil {
+		return err
+	}
+
+	result.Deleted += int(deleted)
+	return nil
+}
+
+// remoteIDs fetches IDs of all remote resources of resource type
+func (s PamSyncService) remoteIDs(ctx context.Context, result *pam.SyncResult, resourceType string) ([]string, error) {
+	var ids []string
+	for startIndex := 1; ; {
+		params := scim.ListParams{
+			StartIndex: startIndex,
+			Count:      s.pageSize,
+			Attributes: []string{"id"},
+		}
+
+		var page scim.ListResponse
+		var n int
+		var err error
+		switch resourceType {
+		case scim.ResourceTypeUser:
+			var rsp *scim.UserList
+			if rsp, err = s.remote.ListUsers(ctx, params); err == nil {
+				page, n = rsp.ListResponse, len(rsp.Resources)
+				for _, res := range rsp.Resources {
+					ids = append(ids, res.ID)
+				}
+			}
+		case scim.ResourceTypeGroup:
+			var rsp *scim.GroupList
+			if rsp, err = s.remote.ListGroups(ctx, params); err == nil {
+				page, n = rsp.ListResponse, len(rsp.Resources)
+				for _, res := range rsp.Resources {
+					ids = append(ids, res.ID)
+				}
+			}
+		case scim.ResourceTypeContainer:
+			var rsp *scim.ContainerList
+			if rsp, err = s.remote.ListContainers(ctx, params); err == nil {
+				page, n = rsp.ListResponse, len(rsp.Resources)
+				for _, res := range rsp.Resources {
+					ids = append(ids, res.ID)
+				}
+			}
+		case scim.ResourceTypePrivilegedData:
+			var rsp *scim.PrivilegedDataList
+			if rsp, err = s.remote.ListPrivilegedData(ctx, params); err == nil {
+				page, n = rsp.ListResponse, len(rsp.Resources)
+				for _, res := range rsp.Resources {
+					ids = append(ids, res.ID)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported resource type %q", resourceType)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s ids page at %d: %w", resourceType, startIndex, err)
+		}
+
+		result.Pages++
+		reportSyncProgress(ctx, result)
+		if !page.HasMore(n) {
+			break
+		}
+		startIndex += n
+	}
+	return ids, nil
+}
+
+// parseIDs parses numeric IDs of users or groups, invalid IDs are skipped.
+//
+// Returns parsed IDs and number of invalid IDs.
+func (s PamSyncService) parseIDs(result *pam.SyncResult, resourceType string, ids []string) ([]int, int) {
+	out := make([]int, 0, len(ids))
+	var invalid int
+	for _, v := range ids {
+		id, err := pam.ParseID(v)
+		if err != nil {
+			result.Failed++
+			invalid++
+			s.log.Warn("skipped invalid resource id", zap.String("resourceType", resourceType), zap.Error(err))
+			continue
+		}
+		out = append(out, id)
+	}
+	return out, invalid
+}
+
+// logSkippedRemoval logs that local resources missing on remote were not removed,
+// since some remote resources are invalid and can't be matched with local resources
+func (s PamSyncService) logSkippedRemoval(resourceType string, invalid int) {
+	s.log.Warn("local resources missing on remote are not removed because of invalid remote resources",
+		zap.String("resourceType", resourceType), zap.Int("invalid", invalid))
+}
+
+// deltaFilter returns filter of resources modified since the high-water mark of resource type.
+//
+// Resources modified at the high-water mark are fetched again, since other resources
+// may be modified within the same second after previous synchronization and remote
+// timestamps are often truncated to seconds. Refetched resources are upserted idempotently.
+//
+// Returns empty filter if mode is not delta or resource type was never synchronized,
+// so all resources are fetched.
+func (s PamSyncService) deltaFilter(ctx context.Context, mode pam.SyncMode, resourceType string) (string, error) {
+	if mode != pam.SyncDelta {
+		return "", nil
+	}
+
+	since, err := s.state.Watermark(ctx, resourceType)
+	if err != nil || since == nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("meta.lastModified ge %q", since.UTC().Format(time.RFC3339Nano)), nil
+}
+
+// storeWatermark stores the latest modification time of fetched resources of resource type
+func (s PamSyncService) storeWatermark(ctx context.Context, resourceType string, hw highWater) error {
+	if hw.lastModified == nil {
+		return nil
+	}
+	return s.state.SetWatermark(ctx, resourceType, *hw.lastModified)
+}
+
+// syncPermissions fetches all container permissions and stores them
+// once all containers are synchronized.
+func (s PamSyncService) syncPermissions(ctx context.Context, result *pam.SyncResult, containerIDs []string) error {
+	var perms []pam.ContainerPermission
+	for startIndex := 1; ; {
+		page, err := s.remote.ListContainerPermissions(ctx, scim.ListParams{
+			StartIndex: startIndex,
+			Count:      s.pageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to fetch container permissions page at %d: %w", startIndex, err)
+		}
+
+		result.Pages++
+		for _, res := range page.Resources {
+			p, err := pam.PermissionFromSCIM(res)
+			if err != nil {
+				result.Failed++
+				s.log.Warn("skipped invalid container permission", zap.Error(err))
+				continue
+			}
+			perms = append(perms, *p)
+		}
+
+		reportSyncProgress(ctx, result)
+		if !page.HasMore(len(page.Resources)) {
+			break
+		}
+		startIndex += len(page.Resources)
+	}
+
+	unresolved, err := s.containers.ReplacePermissions(ctx, containerIDs, perms)
+	if err != nil {
+		return fmt.Errorf("failed to store container permissions: %w", err)
+	}
+
+	result.Permissions = len(perms) - unresolved
+	result.Unresolved += unresolved
+	if unresolved > 0 {
+		s.log.Warn("some container permissions reference missing containers, users or groups and were skipped",
+			zap.Int("count", unresolved))
+	}
+	return nil
+}
+
+// storeMemberships stores deferred memberships once users and groups are synchronized.
+//
+// Skipped group members and user groups are counted as unresolved memberships.
+func (s PamSyncService) storeMemberships(ctx context.Context, result *pam.SyncResult, scope pam.MembershipScope, ms *membershipSet) error {
+	list := ms.list()
+	unresolved, err := s.groups.ReplaceMemberships(ctx, scope, list)
+	if err != nil {
+		return fmt.Errorf("failed to store memberships: %w", err)
+	}
+
+	result.Memberships = len(list) - unresolved
+	result.Unresolved += unresolved + ms.skipped
+	if unresolved > 0 {
+		s.log.Warn("some memberships reference missing users or groups and were skipped",
+			zap.Int("count", unresolved))
+	}
+	if ms.skipped > 0 {
+		s.log.Warn("some group members are not users or have invalid IDs and were skipped",
+			zap.Int("count", ms.skipped))
+	}
+	return nil
+}
+
+func (s PamSyncService) logResult(msg string, result *pam.SyncResult) {
+	s.log.Info(msg,
+		zap.String("mode", string(result.Mode)),
+		zap.Int("pages", result.Pages),
+		zap.Int("upserted", result.Upserted),
+		zap.Int("deleted", result.Deleted),
+		zap.Int("failed", result.Failed),
+		zap.Int("memberships", result.Memberships),
+		zap.Int("permissions", result.Permissions),
+		zap.Int("unresolved", result.Unresolved))
+}
+
+// SyncProgressFunc receives synchronization progress after each fetched page
+type SyncProgressFunc func(progress pam.SyncResult)
+
+type syncProgressKey struct{}
+
+// WithSyncProgress returns context with synchronization progress receiver
+func WithSyncProgress(ctx context.Context, fn SyncProgressFunc) context.Context {
+	return context.WithValue(ctx, syncProgressKey{}, fn)
+}
+
+func reportSyncProgress(ctx context.Context, result *pam.SyncResult) {
+	if fn, ok := ctx.Value(syncProgressKey{}).(SyncProgressFunc); ok {
+		fn(*result)
+	}
+}
+
+// syncResourceTypes are synchronized resource types in synchronization order
+var syncResourceTypes = []string{
+	scim.ResourceTypeUser, scim.ResourceTypeGroup, scim.ResourceTypeContainer, scim.ResourceTypePrivilegedData,
+}
+
+func newSyncResult(mode pam.SyncMode) (*pam.SyncResult, error) {
+	mode, err := pam.ParseSyncMode(string(mode))
+	if err != nil {
+		return nil, web.NewErrBadRequest(err.Error())
+	}
+	return &pam.SyncResult{Mode: mode}, nil
+}
+
+// highWater tracks the latest modification time of fetched resources
+type highWater struct {
+	lastModified *time.Time
+}
+
+func (hw *highWater) observe(m *pam.Meta) {
+	if m == nil || m.LastModified == nil {
+		return
+	}
+
+	if hw.lastModified == nil || m.LastModified.After(*hw.lastModified) {
+		hw.lastModified = m.LastModified
+	}
+}
+
+type membershipKey struct {
+	userID  int
+	groupID int
+}
+
+// membershipSet collects memberships from both users and groups.
+//
+// Memberships can't be stored while resources are synchronized, since related
+// user or group may not be synchronized yet.
+type membershipSet struct {
+	items map[membershipKey]*pam.Membership
+	order []membershipKey
+
+	// skipped is number of group member and user group references which can't be mirrored:
+	// members which are not users, like nested groups, and references with invalid IDs
+	skipped int
+}
+
+func newMembershipSet() *membershipSet {
+	return &membershipSet{items: make(map[membershipKey]*pam.Membership)}
+}
+
+func (ms *membershipSet) get(userID, groupID int) *pam.Membership {
+	key := membershipKey{userID: userID, groupID: groupID}
+	if m, ok := ms.items[key]; ok {
+		return m
+	}
+
+	m := &pam.Membership{UserID: userID, GroupID: groupID}
+	ms.items[key] = m
+	ms.order = append(ms.order, key)
+	return m
+}
+
+func (ms *membershipSet) addUserGroups(log *zap.Logger, u pam.User, refs []scim.Reference) {
+	display := u.DisplayName
+	if display == "" {
+		display = u.UserName
+	}
+
+	for _, ref := range refs {
+		gid, err := pam.ParseID(ref.Value)
+		if err != nil {
+			ms.skipped++
+			log.Warn("skipped invalid user group", zap.Int("user", u.ID), zap.Error(err))
+			continue
+		}
+
+		m := ms.get(u.ID, gid)
+		m.GroupDisplay = ref.Display
+		m.GroupRef = ref.Ref
+		m.Type = ref.Type
+		if m.UserDisplay == "" {
+			m.UserDisplay = display
+		}
+		if m.UserRef == "" && u.Meta != nil {
+			m.UserRef = u.Meta.Location
+		}
+	}
+}
+
+func (ms *membershipSet) addGroupMembers(log *zap.Logger, g pam.Group, refs []scim.Reference) {
+	for _, ref := range refs {
+		if ref.Type != "" && ref.Type != scim.ResourceTypeUser {
+			// nested groups are not supported by mirror schema
+			ms.skipped++
+			log.Warn("skipped group member which is not a user",
+				zap.Int("group", g.ID), zap.String("type", ref.Type), zap.String("value", ref.Value))
+			continue
+		}
+
+		uid, err := pam.ParseID(ref.Value)
+		if err != nil {
+			ms.skipped++
+			log.Warn("skipped invalid group member", zap.Int("group", g.ID), zap.Error(err))
+			continue
+		}
+
+		m := ms.get(uid, g.ID)
+		m.UserDisplay = ref.Display
+		m.UserRef = ref.Ref
+		if m.GroupDisplay == "" {
+			m.GroupDisplay = g.DisplayName
+		}
+		if m.GroupRef == "" && g.Meta != nil {
+			m.GroupRef = g.Meta.Location
+		}
+	}
+}
+
+func (ms *membershipSet) list() []pam.Membership {
+	out := make([]pam.Membership, 0, len(ms.order))
+	for _, key := range ms.order {
+		out = append(out, *ms.items[key])
+	}
+	return out
+}
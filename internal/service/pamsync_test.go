@@ -0,0 +1,423 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/audit"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+	"go.uber.org/zap"
+)
+
+func TestPamSyncService_SyncUsers(t *testing.T) {
+	t.Run("delta", func(t *testing.T) {
+		// remote timestamps have second precision
+		clock := newTestClock(time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC))
+		remote := newFakePamDirectory(clock.Now)
+		users := newFakePamUserStore()
+		state := newFakeSyncState()
+		svc := newTestSyncService(remote, users, state, 0)
+		ctx := context.Background()
+
+		remote.addUser("alice")
+		got, err := svc.SyncUsers(ctx, pam.SyncDelta)
+		require.NoError(t, err)
+		require.Equal(t, 1, got.Upserted, "first delta synchronization fetches all users")
+		require.Equal(t, []string{"alice"}, users.names())
+		require.Equal(t, clock.Now(), state.marks[scim.ResourceTypeUser])
+
+		// modified within the same second as the high-water mark
+		remote.addUser("bob")
+		got, err = svc.SyncUsers(ctx, pam.SyncDelta)
+		require.NoError(t, err)
+		require.Equal(t, 2, got.Upserted, "users modified at the high-water mark are fetched again")
+		require.Equal(t, []string{"alice", "bob"}, users.names())
+
+		clock.Add(time.Second)
+		remote.addUser("carol")
+		got, err = svc.SyncUsers(ctx, pam.SyncDelta)
+		require.NoError(t, err)
+		require.Equal(t, 3, got.Upserted)
+		require.Equal(t, []string{"alice", "bob", "carol"}, users.names())
+		require.Equal(t, clock.Now(), state.marks[scim.ResourceTypeUser])
+
+		got, err = svc.SyncUsers(ctx, pam.SyncDelta)
+		require.NoError(t, err)
+		require.Equal(t, 1, got.Upserted, "only users modified since the high-water mark are fetched")
+		require.Zero(t, got.Deleted, "delta synchronization doesn't remove users")
+		require.Contains(t, remote.lastFilter(), "meta.lastModified ge")
+	})
+
+	t.Run("full", func(t *testing.T) {
+		remote := newFakePamDirectory(time.Now)
+		users := newFakePamUserStore()
+		svc := newTestSyncService(remote, users, newFakeSyncState(), 2)
+		ctx := context.Background()
+
+		remote.addUser("alice")
+		bob := remote.addUser("bob")
+		remote.addUser("carol")
+
+		got, err := svc.SyncUsers(ctx, pam.SyncFull)
+		require.NoError(t, err)
+		require.Equal(t, 3, got.Upserted)
+		require.Equal(t, 2, got.Pages)
+
+		remote.removeUser(bob.ID)
+		got, err = svc.SyncUsers(ctx, pam.SyncFull)
+		require.NoError(t, err)
+		require.Equal(t, 2, got.Upserted)
+		require.Equal(t, 1, got.Deleted)
+		require.Equal(t, []string{"alice", "carol"}, users.names())
+	})
+}
+
+func TestPamSyncService_SyncGroups(t *testing.T) {
+	remote := newFakePamDirectory(time.Now)
+	groups := newFakePamGroupStore()
+	svc := NewPamSyncService(zap.NewNop(), remote, newFakePamUserStore(), groups, nil, nil, newFakeSyncState(),
+		NewActionRecorder(zap.NewNop(), nopActionStore{}), 0)
+
+	alice := remote.addUser("alice")
+	auditors := remote.addGroup(scim.Group{DisplayName: "auditors"})
+	remote.addGroup(scim.Group{DisplayName: "admins", Members: []scim.Reference{
+		{Value: alice.ID, Type: scim.ResourceTypeUser},
+		{Value: auditors.ID, Type: scim.ResourceTypeGroup},
+		{Value: "bob"},
+	}})
+
+	got, err := svc.SyncGroups(context.Background(), pam.SyncFull)
+	require.NoError(t, err)
+	require.Equal(t, 2, got.Upserted)
+	require.Equal(t, 1, got.Memberships)
+	require.Equal(t, 2, got.Unresolved, "nested group and invalid member are counted as unresolved")
+	require.Len(t, groups.memberships, 1)
+	require.Equal(t, 1, groups.memberships[0].UserID)
+}
+
+func TestPamSyncService_InvalidRemoteResources(t *testing.T) {
+	for _, mode := range []pam.SyncMode{pam.SyncFull, pam.SyncReconcile} {
+		t.Run(string(mode)+" keeps local users", func(t *testing.T) {
+			remote := newFakePamDirectory(time.Now)
+			users := newFakePamUserStore()
+			svc := newTestSyncService(remote, users, newFakeSyncState(), 0)
+			ctx := context.Background()
+
+			remote.addUser("alice")
+			remote.addUser("bob")
+			_, err := svc.SyncUsers(ctx, pam.SyncFull)
+			require.NoError(t, err)
+
+			remote.users[0].ID = "1x"
+			remote.removeUser("2")
+			got, err := svc.SyncUsers(ctx, mode)
+			require.NoError(t, err)
+			require.Equal(t, 1, got.Failed)
+			require.Zero(t, got.Deleted, "local users aren't removed if remote user is invalid")
+			require.Equal(t, []string{"alice", "bob"}, users.names())
+		})
+
+		t.Run(string(mode)+" keeps local groups", func(t *testing.T) {
+			remote := newFakePamDirectory(time.Now)
+			groups := newFakePamGroupStore()
+			svc := NewPamSyncService(zap.NewNop(), remote, newFakePamUserStore(), groups, nil, nil, newFakeSyncState(),
+				NewActionRecorder(zap.NewNop(), nopActionStore{}), 0)
+			ctx := context.Background()
+
+			remote.addGroup(scim.Group{DisplayName: "admins"})
+			remote.addGroup(scim.Group{DisplayName: "auditors"})
+			_, err := svc.SyncGroups(ctx, pam.SyncFull)
+			require.NoError(t, err)
+
+			remote.groups[0].ID = "admins"
+			remote.groups = remote.groups[:1]
+			got, err := svc.SyncGroups(ctx, mode)
+			require.NoError(t, err)
+			require.Equal(t, 1, got.Failed)
+			require.Zero(t, got.Deleted, "local groups aren't removed if remote group is invalid")
+			require.Len(t, groups.groups, 2)
+		})
+	}
+}
+
+func newTestSyncService(remote PamDirectory, users PamUserSyncStore, state PamSyncStateStore, pageSize int) *PamSyncService {
+	log := zap.NewNop()
+	return NewPamSyncService(log, remote, users, nil, nil, nil, state,
+		NewActionRecorder(log, nopActionStore{}), pageSize)
+}
+
+// fakePamDirectory is in-memory PAM directory, list filters are evaluated like PAM SCIM server does
+type fakePamDirectory struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	lastID  int
+	users   []scim.User
+	groups  []scim.Group
+	filters []string
+}
+
+func newFakePamDirectory(now func() time.Time) *fakePamDirectory {
+	return &fakePamDirectory{now: now}
+}
+
+func (d *fakePamDirectory) addUser(userName string) scim.User {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lastID++
+	u := scim.User{ID: strconv.Itoa(d.lastID), UserName: userName, Meta: d.meta()}
+	d.users = append(d.users, u)
+	return u
+}
+
+func (d *fakePamDirectory) addGroup(g scim.Group) scim.Group {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if g.ID == "" {
+		d.lastID++
+		g.ID = strconv.Itoa(d.lastID)
+	}
+	g.Meta = d.meta()
+	d.groups = append(d.groups, g)
+	return g
+}
+
+func (d *fakePamDirectory) removeUser(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, u := range d.users {
+		if u.ID == id {
+			d.users = append(d.users[:i], d.users[i+1:]...)
+			return
+		}
+	}
+}
+
+// lastFilter returns filter of the last list request
+func (d *fakePamDirectory) lastFilter() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.filters) == 0 {
+		return ""
+	}
+	return d.filters[len(d.filters)-1]
+}
+
+func (d *fakePamDirectory) meta() *scim.Meta {
+	now := d.now().UTC()
+	return &scim.Meta{Created: &now, LastModified: &now}
+}
+
+func (d *fakePamDirectory) ListUsers(_ context.Context, p scim.ListParams) (*scim.UserList, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := new(scim.UserList)
+	return out, d.list(p, d.users, &out.ListResponse, &out.Resources)
+}
+
+func (d *fakePamDirectory) ListGroups(_ context.Context, p scim.ListParams) (*scim.GroupList, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := new(scim.GroupList)
+	return out, d.list(p, d.groups, &out.ListResponse, &out.Resources)
+}
+
+func (d *fakePamDirectory) ListContainers(context.Context, scim.ListParams) (*scim.ContainerList, error) {
+	return new(scim.ContainerList), nil
+}
+
+func (d *fakePamDirectory) ListContainerPermissions(context.Context, scim.ListParams) (*scim.ContainerPermissionList, error) {
+	return new(scim.ContainerPermissionList), nil
+}
+
+func (d *fakePamDirectory) ListPrivilegedData(context.Context, scim.ListParams) (*scim.PrivilegedDataList, error) {
+	return new(scim.PrivilegedDataList), nil
+}
+
+// list writes a page of resources matching list params to out, lock should be held
+func (d *fakePamDirectory) list(p scim.ListParams, resources interface{}, rsp *scim.ListResponse, out interface{}) error {
+	d.filters = append(d.filters, p.Filter)
+	expr, err := filter.Parse(p.Filter)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(resources)
+	if err != nil {
+		return err
+	}
+
+	var all, matched []map[string]interface{}
+	if err = json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for _, res := range all {
+		if filter.Matches(expr, res) {
+			matched = append(matched, res)
+		}
+	}
+
+	startIndex := p.StartIndex
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	from, to := startIndex-1, len(matched)
+	if from > to {
+		from = to
+	}
+	if p.Count > 0 && from+p.Count < to {
+		to = from + p.Count
+	}
+
+	*rsp = *scim.NewListResponse(nil, len(matched), startIndex, to-from)
+	if data, err = json.Marshal(matched[from:to]); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// testClock is adjustable clock with second precision
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newTestClock(now time.Time) *testClock {
+	return &testClock{now: now}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type fakePamUserStore struct {
+	users map[int]pam.User
+}
+
+func newFakePamUserStore() *fakePamUserStore {
+	return &fakePamUserStore{users: make(map[int]pam.User)}
+}
+
+func (s *fakePamUserStore) UpsertUsers(_ context.Context, users pam.Users) error {
+	for _, u := range users {
+		s.users[u.ID] = u
+	}
+	return nil
+}
+
+func (s *fakePamUserStore) DeleteUsersExcept(_ context.Context, keep []int) (int64, error) {
+	kept := make(map[int]bool, len(keep))
+	for _, id := range keep {
+		kept[id] = true
+	}
+
+	var n int64
+	for id := range s.users {
+		if !kept[id] {
+			delete(s.users, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *fakePamUserStore) names() []string {
+	out := make([]string, 0, len(s.users))
+	for _, u := range s.users {
+		out = append(out, u.UserName)
+	}
+	sort.Strings(out)
+	return out
+}
+
+type fakePamGroupStore struct {
+	groups      map[int]pam.Group
+	memberships []pam.Membership
+}
+
+func newFakePamGroupStore() *fakePamGroupStore {
+	return &fakePamGroupStore{groups: make(map[int]pam.Group)}
+}
+
+func (s *fakePamGroupStore) UpsertGroups(_ context.Context, groups pam.Groups) error {
+	for _, g := range groups {
+		s.groups[g.ID] = g
+	}
+	return nil
+}
+
+func (s *fakePamGroupStore) DeleteGroupsExcept(_ context.Context, keep []int) (int64, error) {
+	kept := make(map[int]bool, len(keep))
+	for _, id := range keep {
+		kept[id] = true
+	}
+
+	var n int64
+	for id := range s.groups {
+		if !kept[id] {
+			delete(s.groups, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *fakePamGroupStore) ReplaceMemberships(_ context.Context, _ pam.MembershipScope, ms []pam.Membership) (int, error) {
+	s.memberships = ms
+	return 0, nil
+}
+
+// fakeSyncState never moves high-water mark back, like repository.PamSyncStateRepository
+type fakeSyncState struct {
+	marks map[string]time.Time
+}
+
+func newFakeSyncState() *fakeSyncState {
+	return &fakeSyncState{marks: make(map[string]time.Time)}
+}
+
+func (s *fakeSyncState) Watermark(_ context.Context, resourceType string) (*time.Time, error) {
+	t, ok := s.marks[resourceType]
+	if !ok {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+func (s *fakeSyncState) SetWatermark(_ context.Context, resourceType string, lastModified time.Time) error {
+	if cur, ok := s.marks[resourceType]; !ok || lastModified.After(cur) {
+		s.marks[resourceType] = lastModified.UTC()
+	}
+	return nil
+}
+
+type nopActionStore struct{}
+
+func (nopActionStore) AddAction(context.Context, audit.Action) error {
+	return nil
+}
+
+func (nopActionStore) ListActions(context.Context, model.ListQuery) (audit.Actions, *model.Page, error) {
+	return nil, nil, nil
+}
+
+func (nopActionStore) DeleteActionsBefore(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
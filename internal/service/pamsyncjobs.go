@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/audit"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/web"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrSyncJobRunning  = web.NewAPIError(http.StatusConflict, "another synchronization is already running")
+	ErrSyncJobFinished = web.NewAPIError(http.StatusConflict, "synchronization job is already finished")
+)
+
+const (
+	// syncJobHeartbeat is interval of job progress updates
+	syncJobHeartbeat = 5 * time.Second
+
+	// syncJobStaleAfter is time after the latest progress update
+	// when running job is considered interrupted
+	syncJobStaleAfter = time.Minute
+
+	syncJobFinishTimeout = 5 * time.Second
+)
+
+// PamSyncJobStore stores synchronization jobs
+type PamSyncJobStore interface {
+	// AddJob stores a new running job.
+	//
+	// Returns ErrSyncJobRunning if another job is running.
+	AddJob(ctx context.Context, job pam.SyncJob) error
+
+	// UpdateJobProgress stores job progress and reports whether job cancellation was requested
+	UpdateJobProgress(ctx context.Context, id uuid.UUID, progress pam.SyncResult) (bool, error)
+
+	// FinishJob stores job status and result
+	FinishJob(ctx context.Context, job pam.SyncJob) error
+
+	// RequestJobCancel requests cancellation of a running job.
+	//
+	// Returns false if job doesn't exist or is not running.
+	RequestJobCancel(ctx context.Context, id uuid.UUID) (bool, error)
+
+	// FailStaleJobs marks running jobs which were not updated since specified time as failed
+	FailStaleJobs(ctx context.Context, updatedBefore time.Time) (int64, error)
+
+	// JobByID returns job by ID
+	JobByID(ctx context.Context, id uuid.UUID) (*pam.SyncJob, error)
+
+	// ListJobs returns a page of jobs matching list query
+	ListJobs(ctx context.Context, q model.ListQuery) (pam.SyncJobs, *model.Page, error)
+}
+
+// PamSyncJobService runs synchronization jobs and tracks their progress.
+//
+// Job state is stored in PamSyncJobStore, so jobs can be inspected and cancelled
+// through any service replica. Job progress is stored periodically, running jobs
+// which were not updated for a while are considered interrupted and marked as failed.
+type PamSyncJobService struct {
+	log     *zap.Logger
+	sync    *PamSyncService
+	store   PamSyncJobStore
+	baseCtx context.Context
+
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running map[uuid.UUID]*runningJob
+}
+
+// NewPamSyncJobService is PamSyncJobService constructor.
+//
+// Background jobs are cancelled when base context is cancelled.
+func NewPamSyncJobService(baseCtx context.Context, log *zap.Logger, syncSvc *PamSyncService, store PamSyncJobStore) *PamSyncJobService {
+	return &PamSyncJobService{
+		log:     log.Named("service.syncjobs"),
+		sync:    syncSvc,
+		store:   store,
+		baseCtx: baseCtx,
+		running: make(map[uuid.UUID]*runningJob),
+	}
+}
+
+// Start starts synchronization job in background and returns started job
+func (s *PamSyncJobService) Start(ctx context.Context, req pam.SyncJobRequest) (*pam.SyncJob, error) {
+	job, err := s.newJob(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Job outlives the request, so only the actor is kept from request context.
+	jobCtx := audit.ContextWithActor(s.baseCtx, job.Actor)
+	started := *job
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		_ = s.run(jobCtx, job)
+	}()
+	return &started, nil
+}
+
+// Run runs synchronization job and waits for job completion.
+//
+// Returns finished job and job error.
+func (s *PamSyncJobService) Run(ctx context.Context, req pam.SyncJobRequest) (*pam.SyncJob, error) {
+	job, err := s.newJob(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.run(ctx, job)
+	return job, err
+}
+
+// Cancel requests cancellation of a running job.
+//
+// Job running on this replica is cancelled immediately, jobs running on other replicas
+// are cancelled on their next progress update.
+func (s *PamSyncJobService) Cancel(ctx context.Context, id uuid.UUID) (*pam.SyncJob, error) {
+	ok, err := s.store.RequestJobCancel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.store.JobByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, ErrSyncJobFinished
+	}
+
+	s.cancel(id)
+	return job, nil
+}
+
+// JobByID returns synchronization job by ID
+func (s *PamSyncJobService) JobByID(ctx context.Context, id uuid.UUID) (*pam.SyncJob, error) {
+	return s.store.JobByID(ctx, id)
+}
+
+// List returns a page of synchronization jobs
+func (s *PamSyncJobService) List(ctx context.Context, q model.ListQuery) (pam.SyncJobs, *model.Page, error) {
+	s.failStaleJobs(ctx)
+	return s.store.ListJobs(ctx, q)
+}
+
+// Wait waits until all background jobs are finished
+func (s *PamSyncJobService) Wait() {
+	s.wg.Wait()
+}
+
+func (s *PamSyncJobService) newJob(ctx context.Context, req pam.SyncJobRequest) (*pam.SyncJob, error) {
+	resourceType, err := ParseSyncResourceType(req.ResourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, err := pam.ParseSyncMode(string(req.Mode))
+	if err != nil {
+		return nil, web.NewErrBadRequest(err.Error())
+	}
+
+	s.failStaleJobs(ctx)
+	now := time.Now().UTC()
+	job := &pam.SyncJob{
+		ID:           uuid.New(),
+		ResourceType: resourceType,
+		Status:       pam.SyncJobRunning,
+		Actor:        actorFromContext(ctx),
+		SyncResult:   pam.SyncResult{Mode: mode},
+		Created:      now,
+		Updated:      now,
+	}
+
+	if err = s.store.AddJob(ctx, *job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// run performs synchronization and stores job result
+func (s *PamSyncJobService) run(ctx context.Context, job *pam.SyncJob) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rj := &runningJob{cancelFunc: cancel, progress: job.SyncResult}
+	s.mu.Lock()
+	s.running[job.ID] = rj
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, job.ID)
+		s.mu.Unlock()
+	}()
+
+	log := s.log.With(zap.Stringer("job", job.ID), zap.String("resourceType", job.ResourceType),
+		zap.String("mode", string(job.Mode)))
+	log.Info("synchronization job started")
+
+	heartbeat := make(chan struct{})
+	go func() {
+		defer close(heartbeat)
+		s.heartbeat(ctx, job.ID, rj, log)
+	}()
+
+	syncCtx := WithSyncProgress(pam.ContextWithSyncJob(ctx, job.ID), rj.setProgress)
+	result, err := s.sync.Sync(syncCtx, job.ResourceType, job.Mode)
+	cancel()
+	<-heartbeat
+
+	if result != nil {
+		job.SyncResult = *result
+	}
+
+	switch {
+	case err == nil:
+		job.Status = pam.SyncJobSucceeded
+	case rj.isCancelled():
+		job.Status = pam.SyncJobCancelled
+		job.CancelRequested = true
+	default:
+		job.Status = pam.SyncJobFailed
+		job.Error = err.Error()
+	}
+
+	now := time.Now().UTC()
+	job.Updated = now
+	job.Finished = &now
+
+	// Result is stored even if job was cancelled by application shutdown.
+	finishCtx, cancelFinish := context.WithTimeout(context.Background(), syncJobFinishTimeout)
+	defer cancelFinish()
+	if ferr := s.store.FinishJob(finishCtx, *job); ferr != nil {
+		log.Error("failed to store synchronization job result", zap.Error(ferr))
+	}
+
+	log.Info("synchronization job finished", zap.String("status", string(job.Status)))
+	return err
+}
+
+// heartbeat stores job progress periodically until context is done
+// and cancels job if cancellation was requested through another replica.
+func (s *PamSyncJobService) heartbeat(ctx context.Context, id uuid.UUID, rj *runningJob, log *zap.Logger) {
+	ticker := time.NewTicker(syncJobHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cancelRequested, err := s.store.UpdateJobProgress(ctx, id, rj.getProgress())
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Warn("failed to update synchronization job progress", zap.Error(err))
+				}
+				continue
+			}
+
+			if cancelRequested {
+				log.Info("synchronization job cancellation requested")
+				rj.cancel()
+				return
+			}
+		}
+	}
+}
+
+func (s *PamSyncJobService) cancel(id uuid.UUID) {
+	s.mu.Lock()
+	rj, ok := s.running[id]
+	s.mu.Unlock()
+	if ok {
+		rj.cancel()
+	}
+}
+
+func (s *PamSyncJobService) failStaleJobs(ctx context.Context) {
+	n, err := s.store.FailStaleJobs(ctx, time.Now().Add(-syncJobStaleAfter))
+	if err != nil {
+		s.log.Warn("failed to check interrupted synchronization jobs", zap.Error(err))
+		return
+	}
+
+	if n > 0 {
+		s.log.Warn("marked interrupted synchronization jobs as failed", zap.Int64("count", n))
+	}
+}
+
+// runningJob is synchronization job running on this replica
+type runningJob struct {
+	cancelFunc context.CancelFunc
+
+	mu        sync.Mutex
+	cancelled bool
+	progress  pam.SyncResult
+}
+
+func (rj *runningJob) cancel() {
+	rj.mu.Lock()
+	rj.cancelled = true
+	rj.mu.Unlock()
+	rj.cancelFunc()
+}
+
+func (rj *runningJob) isCancelled() bool {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+	return rj.cancelled
+}
+
+func (rj *runningJob) setProgress(p pam.SyncResult) {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+	rj.progress = p
+}
+
+func (rj *runningJob) getProgress() pam.SyncResult {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+	return rj.progress
+}
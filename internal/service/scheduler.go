@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/strick-j/scimfe/pkg/cron"
+	"go.uber.org/zap"
+)
+
+const (
+	schedulerLeaderKey = "scheduler:leader"
+	schedulerJobKey    = "scheduler:job:"
+	schedulerTick      = time.Second
+	releaseTimeout     = 5 * time.Second
+
+	defaultLeaderTTL = 30 * time.Second
+)
+
+// ScheduledJob is a background job which runs on schedule
+type ScheduledJob struct {
+	// Name is unique job name
+	Name string
+
+	// Schedule is job schedule
+	Schedule *cron.Schedule
+
+	// Run performs the job.
+	//
+	// Context is cancelled when the application is stopped or the job lock is lost.
+	Run func(ctx context.Context) error
+}
+
+// Scheduler runs background jobs on schedule.
+//
+// Scheduler replicas elect a leader using a distributed lock, only the leader starts jobs.
+// Each running job additionally holds a job lock, so the same job never runs concurrently,
+// even if leadership changes while the job is running.
+//
+// Schedules are evaluated in UTC.
+type Scheduler struct {
+	log       *zap.Logger
+	locker    Locker
+	leaderTTL time.Duration
+	jobs      []ScheduledJob
+
+	// now and newTicker are time sources, replaced in tests
+	now       func() time.Time
+	newTicker func(d time.Duration) (<-chan time.Time, func())
+}
+
+// NewScheduler is Scheduler constructor
+func NewScheduler(log *zap.Logger, locker Locker, leaderTTL time.Duration, jobs ...ScheduledJob) *Scheduler {
+	if leaderTTL <= 0 {
+		leaderTTL = defaultLeaderTTL
+	}
+
+	return &Scheduler{
+		log:       log.Named("service.scheduler"),
+		locker:    locker,
+		leaderTTL: leaderTTL,
+		jobs:      jobs,
+		now:       time.Now,
+		newTicker: newTicker,
+	}
+}
+
+// Run runs scheduled jobs until context is cancelled.
+//
+// Running jobs are cancelled with context, Run returns after all of them were stopped.
+func (s *Scheduler) Run(ctx context.Context) {
+	if len(s.jobs) == 0 {
+		s.log.Info("no scheduled jobs configured")
+		return
+	}
+
+	var leader Lock
+	defer func() { s.release(leader, schedulerLeaderKey) }()
+
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+
+	now := s.now().UTC()
+	next := make([]time.Time, len(s.jobs))
+	for i, j := range s.jobs {
+		next[i] = j.Schedule.Next(now)
+		s.log.Info("job scheduled", zap.String("job", j.Name),
+			zap.Stringer("schedule", j.Schedule), zap.Time("next", next[i]))
+	}
+
+	ticks, stopTicker := s.newTicker(schedulerTick)
+	defer stopTicker()
+
+	var electAt time.Time
+	for {
+		if !now.Before(electAt) {
+			leader = s.elect(ctx, leader)
+			electAt = now.Add(s.leaderTTL / 3)
+		}
+
+		for i, j := range s.jobs {
+			if next[i].IsZero() || now.Before(next[i]) {
+				continue
+			}
+
+			next[i] = j.Schedule.Next(now)
+			if leader == nil {
+				continue
+			}
+
+			wg.Add(1)
+			go func(j ScheduledJob) {
+				defer wg.Done()
+				s.runJob(ctx, j)
+			}(j)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.log.Info("stopping scheduler")
+			return
+		case t := <-ticks:
+			now = t.UTC()
+		}
+	}
+}
+
+// elect refreshes current leadership or tries to become a leader.
+//
+// Returns leader lock or nil if another replica is the leader.
+func (s *Scheduler) elect(ctx context.Context, leader Lock) Lock {
+	if leader != nil {
+		err := leader.Refresh(ctx, s.leaderTTL)
+		if err == nil {
+			return leader
+		}
+
+		s.log.Warn("lost scheduler leadership", zap.Error(err))
+	}
+
+	lock, err := s.locker.Obtain(ctx, schedulerLeaderKey, s.leaderTTL)
+	if err == ErrLockNotObtained {
+		return nil
+	}
+	if err != nil {
+		s.log.Warn("failed to obtain scheduler leadership", zap.Error(err))
+		return nil
+	}
+
+	s.log.Info("became scheduler leader")
+	return lock
+}
+
+func (s *Scheduler) runJob(ctx context.Context, j ScheduledJob) {
+	log := s.log.With(zap.String("job", j.Name))
+	key := schedulerJobKey + j.Name
+	lock, err := s.locker.Obtain(ctx, key, s.leaderTTL)
+	if err == ErrLockNotObtained {
+		log.Info("skipped job, previous run is still in progress")
+		return
+	}
+	if err != nil {
+		log.Error("failed to obtain job lock", zap.Error(err))
+		return
+	}
+
+	// lock could be released by previous run cancelled on scheduler stop
+	if ctx.Err() != nil {
+		s.release(lock, key)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	held := make(chan struct{})
+	go func() {
+		defer close(held)
+		s.holdLock(ctx, lock, cancel, log)
+	}()
+
+	defer func() {
+		cancel()
+		<-held
+		s.release(lock, key)
+	}()
+
+	log.Info("job started")
+	startAt := time.Now()
+	err = runSafe(ctx, j.Run)
+	if err != nil && ctx.Err() != nil {
+		log.Warn("job cancelled", zap.Error(err), zap.Duration("duration", time.Since(startAt)))
+		return
+	}
+	if err != nil {
+		log.Error("job failed", zap.Error(err), zap.Duration("duration", time.Since(startAt)))
+		return
+	}
+	log.Info("job finished", zap.Duration("duration", time.Since(startAt)))
+}
+
+// holdLock refreshes job lock until context is done, job is cancelled if lock is lost
+func (s *Scheduler) holdLock(ctx context.Context, lock Lock, cancel context.CancelFunc, log *zap.Logger) {
+	ticks, stopTicker := s.newTicker(s.leaderTTL / 3)
+	defer stopTicker()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			if err := lock.Refresh(ctx, s.leaderTTL); err != nil && ctx.Err() == nil {
+				log.Error("lost job lock, cancelling job", zap.Error(err))
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// release releases a lock, lock is released even if application context is cancelled
+func (s *Scheduler) release(lock Lock, key string) {
+	if lock == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+	if err := lock.Release(ctx); err != nil {
+		s.log.Warn("failed to release lock", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// newTicker returns channel of ticks with interval d and function which stops ticks
+func newTicker(d time.Duration) (<-chan time.Time, func()) {
+	t := time.NewTicker(d)
+	return t.C, t.Stop
+}
+
+// runSafe runs job and converts job panic to error
+func runSafe(ctx context.Context, run func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return run(ctx)
+}
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/cron"
+	"go.uber.org/zap"
+)
+
+func TestScheduler_Run(t *testing.T) {
+	t.Run("leader runs jobs", func(t *testing.T) {
+		locker := newFakeLocker()
+		runs := make(chan struct{}, 10)
+		s := startScheduler(t, locker, 30*time.Second, ScheduledJob{
+			Name:     "sync",
+			Schedule: cron.MustParse("@every 1m"),
+			Run: func(ctx context.Context) error {
+				runs <- struct{}{}
+				return nil
+			},
+		})
+
+		s.tick(t, 30*time.Second)
+		s.tick(t, 30*time.Second)
+		waitSignal(t, runs, "job was not started")
+		require.True(t, locker.isHeld(schedulerLeaderKey))
+		require.Eventually(t, func() bool {
+			return !locker.isHeld(schedulerJobKey + "sync")
+		}, time.Second, time.Millisecond, "job lock is released after run")
+
+		s.tick(t, time.Minute)
+		waitSignal(t, runs, "job was not started again")
+
+		s.stop(t)
+		require.Empty(t, runs, "job runs only on schedule")
+		require.False(t, locker.isHeld(schedulerLeaderKey), "leader lock is released on stop")
+		require.False(t, locker.isHeld(schedulerJobKey+"sync"), "job lock is released")
+	})
+
+	t.Run("cron schedule", func(t *testing.T) {
+		locker := newFakeLocker()
+		runs := make(chan struct{}, 10)
+		s := startScheduler(t, locker, 30*time.Second, ScheduledJob{
+			Name:     "sync",
+			Schedule: cron.MustParse("*/5 * * * *"),
+			Run: func(ctx context.Context) error {
+				runs <- struct{}{}
+				return nil
+			},
+		})
+
+		// scheduler is started at 10:00, job runs at 10:05 and 10:10
+		for i := 0; i < 5; i++ {
+			s.tick(t, time.Minute)
+		}
+		waitSignal(t, runs, "job was not started")
+		require.Eventually(t, func() bool {
+			return !locker.isHeld(schedulerJobKey + "sync")
+		}, time.Second, time.Millisecond, "job lock is released after run")
+
+		for i := 0; i < 5; i++ {
+			s.tick(t, time.Minute)
+		}
+		waitSignal(t, runs, "job was not started again")
+
+		s.tick(t, time.Minute)
+		s.tick(t, time.Minute)
+		s.stop(t)
+		require.Empty(t, runs, "job runs only on schedule")
+	})
+
+	t.Run("follower doesn't run jobs", func(t *testing.T) {
+		locker := newFakeLocker()
+		locker.held[schedulerLeaderKey] = true
+		var runs int32
+		s := startScheduler(t, locker, 30*time.Second, ScheduledJob{
+			Name:     "sync",
+			Schedule: cron.MustParse("@every 1m"),
+			Run: func(ctx context.Context) error {
+				atomic.AddInt32(&runs, 1)
+				return nil
+			},
+		})
+
+		s.tick(t, time.Minute)
+		s.tick(t, time.Minute)
+		s.stop(t)
+		require.Zero(t, atomic.LoadInt32(&runs))
+	})
+
+	t.Run("follower becomes leader", func(t *testing.T) {
+		locker := newFakeLocker()
+		locker.held[schedulerLeaderKey] = true
+		runs := make(chan struct{}, 10)
+		s := startScheduler(t, locker, 30*time.Second, ScheduledJob{
+			Name:     "sync",
+			Schedule: cron.MustParse("@every 1m"),
+			Run: func(ctx context.Context) error {
+				runs <- struct{}{}
+				return nil
+			},
+		})
+
+		s.tick(t, time.Minute)
+		locker.release(schedulerLeaderKey)
+
+		// leadership is checked every third of leader TTL
+		s.tick(t, 10*time.Second)
+		s.tick(t, 50*time.Second)
+		require.True(t, locker.isHeld(schedulerLeaderKey))
+		waitSignal(t, runs, "job was not started by new leader")
+		s.stop(t)
+	})
+
+	t.Run("job runs don't overlap", func(t *testing.T) {
+		locker := newFakeLocker()
+		var runs int32
+		started := make(chan struct{}, 10)
+		s := startScheduler(t, locker, 30*time.Second, ScheduledJob{
+			Name:     "sync",
+			Schedule: cron.MustParse("@every 1m"),
+			Run: func(ctx context.Context) error {
+				atomic.AddInt32(&runs, 1)
+				started <- struct{}{}
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		})
+
+		s.tick(t, time.Minute)
+		waitSignal(t, started, "job was not started")
+		s.tick(t, time.Minute)
+		s.tick(t, time.Minute)
+		require.True(t, locker.isHeld(schedulerJobKey+"sync"))
+
+		s.stop(t)
+		require.Equal(t, int32(1), atomic.LoadInt32(&runs))
+		require.False(t, locker.isHeld(schedulerJobKey+"sync"))
+	})
+
+	t.Run("lost job lock cancels job", func(t *testing.T) {
+		locker := newFakeLocker()
+		started, cancelled := make(chan struct{}, 1), make(chan struct{}, 1)
+		s := startScheduler(t, locker, 30*time.Second, ScheduledJob{
+			Name:     "sync",
+			Schedule: cron.MustParse("@every 1m"),
+			Run: func(ctx context.Context) error {
+				started <- struct{}{}
+				<-ctx.Done()
+				cancelled <- struct{}{}
+				return ctx.Err()
+			},
+		})
+		defer s.stop(t)
+
+		s.tick(t, time.Minute)
+		waitSignal(t, started, "job was not started")
+
+		locker.lose(schedulerJobKey + "sync")
+		s.refreshLocks(t)
+		waitSignal(t, cancelled, "job was not cancelled")
+	})
+
+	t.Run("lost leadership stops jobs scheduling", func(t *testing.T) {
+		locker := newFakeLocker()
+		locker.lose(schedulerLeaderKey)
+		var runs int32
+		s := startScheduler(t, locker, 30*time.Second, ScheduledJob{
+			Name:     "sync",
+			Schedule: cron.MustParse("@every 1m"),
+			Run: func(ctx context.Context) error {
+				atomic.AddInt32(&runs, 1)
+				return nil
+			},
+		})
+
+		// leadership is obtained on start and lost on the first refresh,
+		// before the first job activation
+		s.tick(t, 10*time.Second)
+		s.tick(t, 50*time.Second)
+		s.tick(t, time.Minute)
+		s.stop(t)
+		require.Zero(t, atomic.LoadInt32(&runs))
+	})
+}
+
+func TestRunSafe(t *testing.T) {
+	err := runSafe(context.Background(), func(ctx context.Context) error {
+		panic("boom")
+	})
+	require.EqualError(t, err, "job panicked: boom")
+
+	errFailed := errors.New("failed")
+	err = runSafe(context.Background(), func(ctx context.Context) error {
+		return errFailed
+	})
+	require.Equal(t, errFailed, err)
+}
+
+// testScheduler is scheduler running in background with time driven by test
+type testScheduler struct {
+	now  time.Time
+	done chan struct{}
+
+	// ticks are scheduler ticks, holds are ticks of job lock refresh.
+	// Channels are unbuffered, so a tick is accepted only after the previous one is processed.
+	ticks chan time.Time
+	holds chan time.Time
+
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+// startScheduler runs scheduler in background, scheduler is started at 2021-01-01 10:00 UTC
+func startScheduler(t *testing.T, locker Locker, leaderTTL time.Duration, jobs ...ScheduledJob) *testScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := &testScheduler{
+		now:    time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC),
+		done:   make(chan struct{}),
+		ticks:  make(chan time.Time),
+		holds:  make(chan time.Time),
+		cancel: cancel,
+	}
+
+	start := ts.now
+	s := NewScheduler(zap.NewNop(), locker, leaderTTL, jobs...)
+	s.now = func() time.Time { return start }
+	s.newTicker = func(d time.Duration) (<-chan time.Time, func()) {
+		if d == schedulerTick {
+			return ts.ticks, func() {}
+		}
+		return ts.holds, func() {}
+	}
+
+	go func() {
+		defer close(ts.done)
+		s.Run(ctx)
+	}()
+	return ts
+}
+
+// tick advances scheduler time by d.
+//
+// Tick is processed asynchronously, it's complete when the next tick is accepted or scheduler is stopped.
+func (ts *testScheduler) tick(t *testing.T, d time.Duration) {
+	t.Helper()
+	ts.now = ts.now.Add(d)
+	select {
+	case ts.ticks <- ts.now:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler doesn't accept ticks")
+	}
+}
+
+// refreshLocks triggers refresh of running job lock
+func (ts *testScheduler) refreshLocks(t *testing.T) {
+	t.Helper()
+	select {
+	case ts.holds <- ts.now:
+	case <-time.After(time.Second):
+		t.Fatal("no running job locks")
+	}
+}
+
+// stop stops scheduler and waits until it's stopped
+func (ts *testScheduler) stop(t *testing.T) {
+	t.Helper()
+	ts.once.Do(func() {
+		ts.cancel()
+		select {
+		case <-ts.done:
+		case <-time.After(releaseTimeout):
+			t.Fatal("scheduler was not stopped")
+		}
+	})
+}
+
+func waitSignal(t *testing.T, ch <-chan struct{}, msg string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal(msg)
+	}
+}
+
+// fakeLocker is in-memory Locker, lock TTLs are ignored
+type fakeLocker struct {
+	mu   sync.Mutex
+	held map[string]bool
+
+	// lost are keys which locks can't be refreshed, like if they were expired
+	lost map[string]bool
+}
+
+func newFakeLocker() *fakeLocker {
+	return &fakeLocker{held: make(map[string]bool), lost: make(map[string]bool)}
+}
+
+func (l *fakeLocker) Obtain(_ context.Context, key string, _ time.Duration) (Lock, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[key] {
+		return nil, ErrLockNotObtained
+	}
+
+	l.held[key] = true
+	return &fakeLock{locker: l, key: key}, nil
+}
+
+func (l *fakeLocker) isHeld(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.held[key]
+}
+
+// release releases lock of key held by another process
+func (l *fakeLocker) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.held, key)
+}
+
+// lose makes lock of key expired and taken by another process
+func (l *fakeLocker) lose(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lost[key] = true
+}
+
+type fakeLock struct {
+	locker *fakeLocker
+	key    string
+}
+
+func (l *fakeLock) Refresh(context.Context, time.Duration) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+	if l.locker.lost[l.key] {
+		return ErrLockNotObtained
+	}
+	return nil
+}
+
+func (l *fakeLock) Release(context.Context) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+	if !l.locker.lost[l.key] {
+		delete(l.locker.held, l.key)
+	}
+	return nil
+}
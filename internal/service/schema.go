@@ -0,0 +1,75 @@
+package service
+
+import (
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/schema"
+)
+
+// PamSchemas is schema registry of PAM users, groups, containers and accounts endpoints
+var PamSchemas = schema.NewRegistry("/pam", scim.ServiceProviderConfig{
+	Patch:          scim.Supported{Supported: true},
+	Bulk:           scim.BulkSupport{Supported: true, MaxOperations: BulkMaxOperations, MaxPayloadSize: BulkMaxPayloadSize},
+	Filter:         scim.FilterSupport{Supported: true, MaxResults: model.MaxPageSize},
+	ChangePassword: scim.Supported{Supported: true},
+	Sort:           scim.Supported{Supported: true},
+	ETag:           scim.Supported{Supported: true},
+	AuthenticationSchemes: []scim.AuthenticationScheme{
+		{
+			Type:        "sessiontoken",
+			Name:        "Session token",
+			Description: "Session token obtained from /auth, passed in X-Auth-Token header",
+			Primary:     true,
+		},
+	},
+}).
+	Register(scim.ResourceType{
+		Name:        scim.ResourceTypeUser,
+		Endpoint:    "/users",
+		Description: "PAM user account",
+	}, schema.User, schema.EnterpriseUser).
+	AllowExtensions(scim.ResourceTypeUser).
+	Register(scim.ResourceType{
+		Name:        scim.ResourceTypeGroup,
+		Endpoint:    "/groups",
+		Description: "PAM group",
+	}, schema.Group).
+	Register(scim.ResourceType{
+		Name:        scim.ResourceTypeContainer,
+		Endpoint:    "/containers",
+		Description: "PAM safe",
+	}, schema.Container).
+	Register(scim.ResourceType{
+		Name:        scim.ResourceTypePrivilegedData,
+		Endpoint:    "/accounts",
+		Description: "PAM account stored in a safe",
+	}, schema.PrivilegedData)
+
+// ScimSchemas is schema registry of SCIM service provider which exposes scimfe users.
+//
+// Only attributes stored by scimfe are published, user email is used as userName,
+// so emails are read-only.
+var ScimSchemas = schema.NewRegistry("/scim/v2", scim.ServiceProviderConfig{
+	Patch:          scim.Supported{Supported: true},
+	Filter:         scim.FilterSupport{Supported: true, MaxResults: model.MaxPageSize},
+	ChangePassword: scim.Supported{Supported: true},
+	Sort:           scim.Supported{Supported: true},
+	ETag:           scim.Supported{Supported: true},
+	AuthenticationSchemes: []scim.AuthenticationScheme{
+		{
+			Type:        "oauthbearertoken",
+			Name:        "Bearer token",
+			Description: "Static bearer token configured in SCIMFE_SCIM_TOKENS",
+			SpecURI:     "https://www.rfc-editor.org/info/rfc6750",
+			Primary:     true,
+		},
+	},
+}).
+	Register(scim.ResourceType{
+		Name:        scim.ResourceTypeUser,
+		Endpoint:    scim.EndpointUsers,
+		Description: "scimfe operator account",
+	}, schema.Modify(
+		schema.Select(schema.User, "userName", "name", "displayName", "active", "password", "emails"),
+		"emails", schema.Mutability(schema.MutabilityReadOnly),
+	))
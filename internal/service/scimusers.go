@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/strick-j/scimfe/internal/model"
+	"github.com/strick-j/scimfe/internal/model/audit"
+	"github.com/strick-j/scimfe/internal/model/user"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/patch"
+	"go.uber.org/zap"
+)
+
+// ScimUsersEndpoint is path of SCIM users endpoint, used in resource location
+const ScimUsersEndpoint = "/scim/v2/Users"
+
+// ScimUsersService exposes scimfe users as SCIM user resources,
+// so identity provider can provision scimfe operators.
+//
+// User email is used as SCIM userName.
+// Users created without password can't log in until password is set.
+type ScimUsersService struct {
+	log   *zap.Logger
+	users *UsersService
+	audit *ActionRecorder
+}
+
+// NewScimUsersService is ScimUsersService constructor
+func NewScimUsersService(log *zap.Logger, users *UsersService, recorder *ActionRecorder) *ScimUsersService {
+	return &ScimUsersService{
+		log:   log.Named("service.scimusers"),
+		users: users,
+		audit: recorder,
+	}
+}
+
+// List returns a page of users
+func (s ScimUsersService) List(ctx context.Context, q model.ListQuery) ([]scim.User, *model.Page, error) {
+	list, page, err := s.users.List(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make([]scim.User, 0, len(list))
+	for _, u := range list {
+		out = append(out, scimUser(u))
+	}
+	return out, page, nil
+}
+
+// UserByID returns user by ID
+func (s ScimUsersService) UserByID(ctx context.Context, id string) (*scim.User, error) {
+	usr, err := s.userByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	out := scimUser(*usr)
+	return &out, nil
+}
+
+// CreateUser creates a new user
+func (s ScimUsersService) CreateUser(ctx context.Context, su scim.User) (out *scim.User, err error) {
+	rec := s.audit.Start(ctx, audit.ActionCreate, audit.ResourceTypeOperator).SetPayload(userPayload(su))
+	defer func() {
+		if out != nil {
+			rec.SetResourceID(out.ID)
+		}
+		rec.Finish(err)
+	}()
+
+	var usr user.User
+	usr.SetSCIM(su)
+	if err = s.setPassword(&usr, su.Password); err != nil {
+		return nil, err
+	}
+
+	created, err := s.users.CreateUser(ctx, usr)
+	if err != nil {
+		return nil, uniquenessError(err)
+	}
+
+	res := scimUser(*created)
+	return &res, nil
+}
+
+// ReplaceUser replaces user.
+//
+// Password is updated only if it's present in resource.
+// Request precondition from context is checked against current user version, see scim.WithPrecondition.
+func (s ScimUsersService) ReplaceUser(ctx context.Context, id string, su scim.User) (out *scim.User, err error) {
+	rec := s.audit.Start(ctx, audit.ActionReplace, audit.ResourceTypeOperator).
+		SetResourceID(id).
+		SetPayload(userPayload(su))
+	defer func() { rec.Finish(err) }()
+
+	if su.ID != "" && su.ID != id {
+		return nil, scim.NewError(http.StatusBadRequest, scim.ErrTypeMutability,
+			"resource id %q doesn't match requested id %q", su.ID, id)
+	}
+
+	usr, err := s.currentUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = ScimSchemas.CheckImmutable(scim.ResourceTypeUser, scimUser(*usr), su); err != nil {
+		return nil, err
+	}
+
+	return s.update(ctx, *usr, su)
+}
+
+// PatchUser applies PATCH operations to user.
+//
+// See ReplaceUser for precondition check details.
+func (s ScimUsersService) PatchUser(ctx context.Context, id string, req scim.PatchRequest) (out *scim.User, err error) {
+	rec := s.audit.Start(ctx, audit.ActionPatch, audit.ResourceTypeOperator).
+		SetResourceID(id).
+		SetPayload(patchPayload(req))
+	defer func() { rec.Finish(err) }()
+
+	if err = patch.ValidateRequest(req); err != nil {
+		return nil, err
+	}
+
+	usr, err := s.currentUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := usr.SCIM()
+	if err = ScimSchemas.Patcher(scim.ResourceTypeUser).ApplyTo(&res, req.Operations); err != nil {
+		return nil, err
+	}
+
+	if err = ScimSchemas.Validate(scim.ResourceTypeUser, res); err != nil {
+		return nil, err
+	}
+
+	return s.update(ctx, *usr, res)
+}
+
+// DeleteUser removes user.
+//
+// See ReplaceUser for precondition check details.
+func (s ScimUsersService) DeleteUser(ctx context.Context, id string) (err error) {
+	rec := s.audit.Start(ctx, audit.ActionDelete, audit.ResourceTypeOperator).SetResourceID(id)
+	defer func() { rec.Finish(err) }()
+
+	usr, err := s.currentUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.users.DeleteUser(ctx, *usr)
+}
+
+func (s ScimUsersService) update(ctx context.Context, usr user.User, su scim.User) (*scim.User, error) {
+	usr.SetSCIM(su)
+	if su.Password != "" {
+		if err := s.setPassword(&usr, su.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.users.UpdateUser(ctx, usr); err != nil {
+		return nil, uniquenessError(err)
+	}
+
+	usr.Version++
+	out := scimUser(usr)
+	return &out, nil
+}
+
+func (s ScimUsersService) userByID(ctx context.Context, id string) (*user.User, error) {
+	uid, err := decodeUserID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.users.UserByID(ctx, *uid)
+}
+
+// currentUser returns user and checks request precondition against user version
+func (s ScimUsersService) currentUser(ctx context.Context, id string) (*user.User, error) {
+	usr, err := s.userByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = scim.PreconditionFromContext(ctx).Check(scimUser(*usr).Meta.Version); err != nil {
+		return nil, err
+	}
+	return usr, nil
+}
+
+// setPassword validates user and sets password.
+//
+// Random password is set if password is empty.
+func (s ScimUsersService) setPassword(usr *user.User, password string) error {
+	if password == "" {
+		var err error
+		if password, err = randomPassword(); err != nil {
+			return err
+		}
+	}
+
+	if err := model.Validate(user.Registration{Props: usr.Props, Password: password}); err != nil {
+		return err
+	}
+	return usr.SetPassword(password)
+}
+
+// scimUser returns SCIM representation of user with resource location
+func scimUser(u user.User) scim.User {
+	out := u.SCIM()
+	out.Meta.Location = ScimUsersEndpoint + "/" + out.ID
+	return out
+}
+
+// decodeUserID decodes user ID, invalid ID is reported as missing user
+func decodeUserID(id string) (*user.ID, error) {
+	uid := new(user.ID)
+	if err := uid.DecodeText(nil, []byte(id)); err != nil || id == "" {
+		return nil, scim.NewError(http.StatusNotFound, "", "user %q not found", id)
+	}
+	return uid, nil
+}
+
+func uniquenessError(err error) error {
+	if err == ErrExists {
+		return scim.NewError(http.StatusConflict, scim.ErrTypeUniqueness, "user with the same userName already exists")
+	}
+	return err
+}
+
+func randomPassword() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strick-j/scimfe/internal/model/auth"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"go.uber.org/zap"
+)
+
+const (
+	tokenLockKey = "pam-oauth-token"
+
+	// tokenLockTTL should be long enough to complete token request.
+	tokenLockTTL       = 30 * time.Second
+	tokenPollInterval  = 250 * time.Millisecond
+	defaultTokenMargin = time.Minute
+
+	// defaultTokenTTL is used when token endpoint omits "expires_in" field.
+	defaultTokenTTL = time.Hour
+)
+
+var ErrTokenNotConfigured = errors.New("PAM token endpoint is not configured")
+
+// TokenStore is OAuth2 token store
+type TokenStore interface {
+	// LatestToken returns the most recent token.
+	//
+	// Returns ErrNotExists if there are no stored tokens.
+	LatestToken(ctx context.Context) (*auth.OAuthToken, error)
+
+	// SaveToken stores a new token
+	SaveToken(ctx context.Context, t auth.OAuthToken) error
+}
+
+// TokenParams is OAuth2 client credentials grant params
+type TokenParams struct {
+	// TokenURL is token endpoint URL
+	TokenURL string
+
+	// ClientID is OAuth2 client ID
+	ClientID string
+
+	// ClientSecret is OAuth2 client secret
+	ClientSecret string
+
+	// Scope is optional space-delimited list of requested scopes
+	Scope string
+
+	// RefreshMargin is time before token expiration when token should be refreshed
+	RefreshMargin time.Duration
+}
+
+// TokenService issues OAuth2 access tokens for PAM SCIM server using client credentials grant.
+//
+// Tokens are shared between service replicas using TokenStore.
+// Token refresh is guarded by a distributed lock, so only one replica requests a new token.
+type TokenService struct {
+	log    *zap.Logger
+	store  TokenStore
+	locker Locker
+	http   *http.Client
+	params TokenParams
+
+	mu      sync.Mutex
+	cached  *auth.OAuthToken
+	loading *tokenLoad
+}
+
+// tokenLoad is in-flight token load shared by concurrent callers
+type tokenLoad struct {
+	done  chan struct{}
+	token *auth.OAuthToken
+	err   error
+}
+
+// NewTokenService is TokenService constructor
+func NewTokenService(log *zap.Logger, store TokenStore, locker Locker, h *http.Client, p TokenParams) *TokenService {
+	if p.RefreshMargin <= 0 {
+		p.RefreshMargin = defaultTokenMargin
+	}
+
+	return &TokenService{
+		log:    log.Named("service.token"),
+		store:  store,
+		locker: locker,
+		http:   h,
+		params: p,
+	}
+}
+
+// Token implements scim.TokenSource
+func (s *TokenService) Token(ctx context.Context) (*scim.Token, error) {
+	t, err := s.validToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &scim.Token{
+		AccessToken: t.AccessToken,
+		TokenType:   t.TokenType,
+	}, nil
+}
+
+// validToken returns cached token if it's still valid, otherwise loads a token.
+//
+// Only one token load is performed at a time, concurrent callers wait for its result.
+// Lock is not held while token is loaded, so callers aren't blocked by token requests
+// beyond their own context deadline.
+func (s *TokenService) validToken(ctx context.Context) (*auth.OAuthToken, error) {
+	s.mu.Lock()
+	if s.cached != nil && s.cached.ValidFor(s.params.RefreshMargin) {
+		t := s.cached
+		s.mu.Unlock()
+		return t, nil
+	}
+
+	if load := s.loading; load != nil {
+		s.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-load.done:
+			return load.token, load.err
+		}
+	}
+
+	load := &tokenLoad{done: make(chan struct{})}
+	s.loading = load
+	s.mu.Unlock()
+
+	load.token, load.err = s.loadToken(ctx)
+
+	s.mu.Lock()
+	if load.err == nil {
+		s.cached = load.token
+	}
+	s.loading = nil
+	s.mu.Unlock()
+
+	close(load.done)
+	return load.token, load.err
+}
+
+// loadToken returns a valid stored token or obtains a new one
+func (s *TokenService) loadToken(ctx context.Context) (*auth.OAuthToken, error) {
+	t, err := s.storedToken(ctx)
+	if err != nil || t != nil {
+		return t, err
+	}
+
+	return s.refreshToken(ctx)
+}
+
+// storedToken returns a stored token if it's still valid
+func (s *TokenService) storedToken(ctx context.Context) (*auth.OAuthToken, error) {
+	t, err := s.store.LatestToken(ctx)
+	if err == ErrNotExists {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored token: %w", err)
+	}
+
+	if !t.ValidFor(s.params.RefreshMargin) {
+		return nil, nil
+	}
+	return t, nil
+}
+
+func (s *TokenService) refreshToken(ctx context.Context) (*auth.OAuthToken, error) {
+	lock, err := s.locker.Obtain(ctx, tokenLockKey, tokenLockTTL)
+	if err == ErrLockNotObtained {
+		// Another replica is refreshing the token right now.
+		return s.waitForToken(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := lock.Release(ctx); err != nil {
+			s.log.Warn("failed to release token lock", zap.Error(err))
+		}
+	}()
+
+	// Token might be refreshed by another replica while lock was not held.
+	t, err := s.storedToken(ctx)
+	if err != nil || t != nil {
+		return t, err
+	}
+
+	t, err = s.requestToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.store.SaveToken(ctx, *t); err != nil {
+		return nil, fmt.Errorf("failed to store access token: %w", err)
+	}
+
+	s.log.Info("obtained a new access token", zap.Time("expiry", t.Expiry))
+	return t, nil
+}
+
+func (s *TokenService) waitForToken(ctx context.Context) (*auth.OAuthToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, tokenLockTTL)
+	defer cancel()
+
+	ticker := time.NewTicker(tokenPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timeout waiting for access token refresh: %w", ctx.Err())
+		case <-ticker.C:
+			t, err := s.storedToken(ctx)
+			if err != nil || t != nil {
+				return t, err
+			}
+		}
+	}
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (s *TokenService) requestToken(ctx context.Context) (*auth.OAuthToken, error) {
+	if s.params.TokenURL == "" {
+		return nil, ErrTokenNotConfigured
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if s.params.Scope != "" {
+		form.Set("scope", s.params.Scope)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.params.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("can't prepare token request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.params.ClientID), url.QueryEscape(s.params.ClientSecret))
+
+	issuedAt := time.Now()
+	rsp, err := s.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send token request: %w", err)
+	}
+
+	defer rsp.Body.Close()
+	content, err := ioutil.ReadAll(rsp.Body)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var tr tokenResponse
+	if err = json.Unmarshal(content, &tr); err != nil {
+		return nil, fmt.Errorf("failed to decode token response (%s): %w", rsp.Status, err)
+	}
+
+	if rsp.StatusCode != http.StatusOK || tr.Error != "" {
+		return nil, fmt.Errorf("token request failed (%s): %s %s", rsp.Status, tr.Error, tr.ErrorDescription)
+	}
+
+	if tr.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned empty access token")
+	}
+
+	ttl := defaultTokenTTL
+	if tr.ExpiresIn > 0 {
+		ttl = time.Duration(tr.ExpiresIn) * time.Second
+	}
+
+	return &auth.OAuthToken{
+		AccessToken: tr.AccessToken,
+		TokenType:   tr.TokenType,
+		Expiry:      issuedAt.Add(ttl).UTC(),
+	}, nil
+}
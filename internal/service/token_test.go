@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/internal/model/auth"
+	"go.uber.org/zap"
+)
+
+func TestTokenService_Token(t *testing.T) {
+	t.Run("requests and stores a new token", func(t *testing.T) {
+		srv := newTokenServer(`{"access_token":"new","token_type":"Bearer","expires_in":3600}`)
+		defer srv.Close()
+
+		store := &fakeTokenStore{}
+		svc := newTestTokenService(srv, store, newFakeLocker())
+		got, err := svc.Token(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "new", got.AccessToken)
+		require.Equal(t, "Bearer", got.TokenType)
+		require.Equal(t, int32(1), srv.requests())
+
+		saved, err := store.LatestToken(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "new", saved.AccessToken)
+		require.WithinDuration(t, time.Now().Add(time.Hour), saved.Expiry, time.Minute)
+	})
+
+	t.Run("uses cached token", func(t *testing.T) {
+		srv := newTokenServer(`{"access_token":"new","token_type":"Bearer"}`)
+		defer srv.Close()
+
+		store := &fakeTokenStore{}
+		svc := newTestTokenService(srv, store, newFakeLocker())
+		for i := 0; i < 3; i++ {
+			got, err := svc.Token(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, "new", got.AccessToken)
+		}
+		require.Equal(t, int32(1), srv.requests())
+		require.Equal(t, 2, store.reads(), "store is read only before and after obtaining refresh lock")
+	})
+
+	t.Run("uses token stored by another replica", func(t *testing.T) {
+		srv := newTokenServer(`{"access_token":"new","token_type":"Bearer"}`)
+		defer srv.Close()
+
+		store := &fakeTokenStore{}
+		require.NoError(t, store.SaveToken(context.Background(), auth.OAuthToken{
+			AccessToken: "stored", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour),
+		}))
+
+		svc := newTestTokenService(srv, store, newFakeLocker())
+		got, err := svc.Token(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "stored", got.AccessToken)
+		require.Zero(t, srv.requests())
+	})
+
+	t.Run("refreshes token expiring within margin", func(t *testing.T) {
+		srv := newTokenServer(`{"access_token":"new","token_type":"Bearer"}`)
+		defer srv.Close()
+
+		store := &fakeTokenStore{}
+		require.NoError(t, store.SaveToken(context.Background(), auth.OAuthToken{
+			AccessToken: "expiring", TokenType: "Bearer", Expiry: time.Now().Add(30 * time.Second),
+		}))
+
+		svc := newTestTokenService(srv, store, newFakeLocker())
+		got, err := svc.Token(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "new", got.AccessToken)
+		require.Equal(t, int32(1), srv.requests())
+	})
+
+	t.Run("refreshes expired cached token", func(t *testing.T) {
+		srv := newTokenServer(`{"access_token":"new","token_type":"Bearer"}`)
+		defer srv.Close()
+
+		svc := newTestTokenService(srv, &fakeTokenStore{}, newFakeLocker())
+		svc.cached = &auth.OAuthToken{AccessToken: "expired", TokenType: "Bearer", Expiry: time.Now().Add(-time.Minute)}
+		got, err := svc.Token(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "new", got.AccessToken)
+	})
+
+	t.Run("concurrent callers share token request", func(t *testing.T) {
+		srv := newTokenServer(`{"access_token":"new","token_type":"Bearer"}`)
+		srv.delay = 100 * time.Millisecond
+		defer srv.Close()
+
+		svc := newTestTokenService(srv, &fakeTokenStore{}, newFakeLocker())
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got, err := svc.Token(context.Background())
+				require.NoError(t, err)
+				require.Equal(t, "new", got.AccessToken)
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, int32(1), srv.requests())
+	})
+
+	t.Run("waiting caller isn't blocked beyond its deadline", func(t *testing.T) {
+		srv := newTokenServer(`{"access_token":"new","token_type":"Bearer"}`)
+		srv.delay = time.Second
+		defer srv.Close()
+
+		svc := newTestTokenService(srv, &fakeTokenStore{}, newFakeLocker())
+		go func() { _, _ = svc.Token(context.Background()) }()
+		require.Eventually(t, func() bool { return srv.requests() == 1 }, time.Second, 5*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := svc.Token(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+	})
+
+	t.Run("waits for token refreshed by another replica", func(t *testing.T) {
+		srv := newTokenServer(`{"access_token":"new","token_type":"Bearer"}`)
+		defer srv.Close()
+
+		store := &fakeTokenStore{}
+		locker := newFakeLocker()
+		locker.held[tokenLockKey] = true
+		time.AfterFunc(300*time.Millisecond, func() {
+			_ = store.SaveToken(context.Background(), auth.OAuthToken{
+				AccessToken: "replica", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour),
+			})
+		})
+
+		got, err := newTestTokenService(srv, store, locker).Token(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, "replica", got.AccessToken)
+		require.Zero(t, srv.requests())
+	})
+
+	t.Run("token endpoint error", func(t *testing.T) {
+		srv := newTokenServer(`{"error":"invalid_client","error_description":"bad secret"}`)
+		srv.status = http.StatusUnauthorized
+		defer srv.Close()
+
+		store := &fakeTokenStore{}
+		svc := newTestTokenService(srv, store, newFakeLocker())
+		_, err := svc.Token(context.Background())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid_client bad secret")
+		require.Nil(t, svc.cached)
+
+		_, err = store.LatestToken(context.Background())
+		require.Equal(t, ErrNotExists, err)
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		svc := NewTokenService(zap.NewNop(), &fakeTokenStore{}, newFakeLocker(), http.DefaultClient, TokenParams{})
+		_, err := svc.Token(context.Background())
+		require.Equal(t, ErrTokenNotConfigured, err)
+	})
+}
+
+func newTestTokenService(srv *tokenServer, store TokenStore, locker Locker) *TokenService {
+	return NewTokenService(zap.NewNop(), store, locker, srv.Client(), TokenParams{
+		TokenURL:     srv.URL,
+		ClientID:     "scimfe",
+		ClientSecret: "secret",
+	})
+}
+
+// tokenServer is token endpoint which responds with the same body to every request
+type tokenServer struct {
+	*httptest.Server
+	status int
+	delay  time.Duration
+	count  int32
+}
+
+func newTokenServer(body string) *tokenServer {
+	s := &tokenServer{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&s.count, 1)
+		time.Sleep(s.delay)
+		if id, secret, _ := r.BasicAuth(); id != "scimfe" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(s.status)
+		_, _ = w.Write([]byte(body))
+	}))
+	return s
+}
+
+func (s *tokenServer) requests() int32 {
+	return atomic.LoadInt32(&s.count)
+}
+
+type fakeTokenStore struct {
+	mu      sync.Mutex
+	tokens  []auth.OAuthToken
+	readNum int
+}
+
+func (s *fakeTokenStore) LatestToken(context.Context) (*auth.OAuthToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readNum++
+	if len(s.tokens) == 0 {
+		return nil, ErrNotExists
+	}
+
+	t := s.tokens[len(s.tokens)-1]
+	return &t, nil
+}
+
+func (s *fakeTokenStore) SaveToken(_ context.Context, t auth.OAuthToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = append(s.tokens, t)
+	return nil
+}
+
+func (s *fakeTokenStore) reads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readNum
+}
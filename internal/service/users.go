@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/strick-j/scimfe/internal/model"
@@ -14,6 +15,7 @@ import (
 var (
 	ErrNotExists = web.NewErrBadRequest("record not found")
 	ErrExists    = web.NewErrBadRequest("record already exists")
+	ErrModified  = web.NewAPIError(http.StatusPreconditionFailed, "record was modified by another request")
 )
 
 // UserStorage provides user storage
@@ -29,8 +31,18 @@ type UserStorage interface {
 	// UserByID returns user by ID
 	UserByID(ctx context.Context, uid user.ID) (*user.User, error)
 
-	// AllUsers returns all users
-	AllUsers(ctx context.Context) (user.Users, error)
+	// ListUsers returns a page of users matching list query
+	ListUsers(ctx context.Context, q model.ListQuery) (user.Users, *model.Page, error)
+
+	// UpdateUser updates user properties, password and status.
+	//
+	// User version should match stored version, otherwise ErrModified is returned.
+	UpdateUser(ctx context.Context, u user.User) error
+
+	// DeleteUser removes user.
+	//
+	// User version should match stored version, otherwise ErrModified is returned.
+	DeleteUser(ctx context.Context, u user.User) error
 
 	// Exists checks if user with specified email exists
 	Exists(email string) (bool, error)
@@ -48,9 +60,9 @@ func NewUsersService(log *zap.Logger, store UserStorage) *UsersService {
 	}
 }
 
-// GetAll returns all users
-func (s UsersService) GetAll(ctx context.Context) (user.Users, error) {
-	return s.store.AllUsers(ctx)
+// List returns a page of users
+func (s UsersService) List(ctx context.Context, q model.ListQuery) (user.Users, *model.Page, error) {
+	return s.store.ListUsers(ctx, q)
 }
 
 // UserByID returns user by id
@@ -77,8 +89,20 @@ func (s UsersService) AddUser(ctx context.Context, usrReg user.Registration) (*u
 		return nil, err
 	}
 
-	usrReg.Email = strings.ToLower(usrReg.Email)
-	exists, err := s.store.Exists(usrReg.Email)
+	usr := user.User{Props: usrReg.Props, Active: true}
+	if err := usr.SetPassword(usrReg.Password); err != nil {
+		return nil, err
+	}
+
+	return s.CreateUser(ctx, usr)
+}
+
+// CreateUser stores a new user with already set password.
+//
+// Returns ErrExists if user with the same email already exists.
+func (s UsersService) CreateUser(ctx context.Context, usr user.User) (*user.User, error) {
+	usr.Email = strings.ToLower(usr.Email)
+	exists, err := s.store.Exists(usr.Email)
 	if err != nil {
 		return nil, fmt.Errorf("can't check if user exists: %w", err)
 	}
@@ -87,16 +111,41 @@ func (s UsersService) AddUser(ctx context.Context, usrReg user.Registration) (*u
 		return nil, ErrExists
 	}
 
-	usr := user.User{Props: usrReg.Props}
-	if err = usr.SetPassword(usrReg.Password); err != nil {
-		return nil, err
-	}
-
+	usr.Version = user.FirstVersion
 	uid, err := s.store.AddUser(ctx, usr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create new user %q: %w", usrReg.Email, err)
+		return nil, fmt.Errorf("failed to create new user %q: %w", usr.Email, err)
 	}
 
 	usr.ID = *uid
 	return &usr, nil
 }
+
+// UpdateUser updates user, stored user version is incremented.
+//
+// Returns ErrExists if email is already used by another user
+// and ErrModified if user was updated since it was read.
+func (s UsersService) UpdateUser(ctx context.Context, usr user.User) error {
+	if err := model.Validate(usr.Props); err != nil {
+		return err
+	}
+
+	usr.Email = strings.ToLower(usr.Email)
+	other, err := s.store.UserByEmail(ctx, usr.Email)
+	switch {
+	case err == ErrNotExists:
+	case err != nil:
+		return fmt.Errorf("can't check if user exists: %w", err)
+	case other.ID != usr.ID:
+		return ErrExists
+	}
+
+	return s.store.UpdateUser(ctx, usr)
+}
+
+// DeleteUser removes user.
+//
+// Returns ErrModified if user was updated since it was read.
+func (s UsersService) DeleteUser(ctx context.Context, usr user.User) error {
+	return s.store.DeleteUser(ctx, usr)
+}
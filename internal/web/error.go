@@ -1,8 +1,11 @@
 package web
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+
+	"github.com/strick-j/scimfe/pkg/scim"
 )
 
 var ErrNotImplemented = NewAPIError(http.StatusNotImplemented, "method not implemented")
@@ -15,6 +18,9 @@ type APIError struct {
 	// Message is error message
 	Message string `json:"message"`
 
+	// ScimType is optional SCIM error type, see scim.Error
+	ScimType string `json:"scimType,omitempty"`
+
 	// Data is optional error data
 	Data interface{} `json:"data,omitempty"`
 }
@@ -67,13 +73,23 @@ func NewErrForbidden(msg string, args ...interface{}) *APIError {
 // ToAPIError constructs APIError from passed error.
 //
 // If error implements APIErrorer interface, APIError() method will be called.
+// SCIM errors are converted to APIError with the same status and SCIM error type.
 func ToAPIError(err error) *APIError {
+	var scimErr *scim.Error
 	switch t := err.(type) {
 	case APIErrorer:
 		return t.APIError()
 	case *APIError:
 		return t
 	default:
+		if errors.As(err, &scimErr) {
+			return &APIError{
+				Status:   scimErr.StatusCode,
+				Message:  scimErr.Detail,
+				ScimType: scimErr.ScimType,
+			}
+		}
+
 		return &APIError{
 			Status:  http.StatusInternalServerError,
 			Message: err.Error(),
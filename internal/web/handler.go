@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"github.com/strick-j/scimfe/pkg/scim"
 	"go.uber.org/zap"
 )
 
@@ -28,6 +29,9 @@ type MiddlewareFunc = func(rw http.ResponseWriter, req *http.Request) (*http.Req
 // Wrapper is http handler wrapper and composer.
 type Wrapper struct {
 	log *zap.Logger
+
+	// scim enables SCIM media type and error format
+	scim bool
 }
 
 // NewWrapper is Wrapper constructor
@@ -35,6 +39,13 @@ func NewWrapper(log *zap.Logger) *Wrapper {
 	return &Wrapper{log: log}
 }
 
+// NewSCIMWrapper constructs Wrapper for SCIM service provider endpoints.
+//
+// Responses use SCIM media type and errors are served in SCIM format, see RFC 7644 section 3.12.
+func NewSCIMWrapper(log *zap.Logger) *Wrapper {
+	return &Wrapper{log: log, scim: true}
+}
+
 // WrapHandler wraps web's HandlerFunc onto http.HandlerFunc.
 //
 // Accepts optional list of middleware functions to be called before handler.
@@ -75,23 +86,46 @@ func (w Wrapper) WrapHandler(handler HandlerFunc, wrappers ...MiddlewareFunc) ht
 
 // WrapResourceHandler wraps resource handler onto http.HandlerFunc.
 // Use *web.APIError or implement web.APIErrorer to return custom error.
+// Return *web.Response to set custom response status code or headers.
+//
+// If response has resource version (see NewResourceResponse), GET request with
+// matching If-None-Match header gets "304 Not Modified" response without body.
 //
 // Accepts optional list of middleware functions to be called before handler.
 //
 // See: WrapHandler
 func (w Wrapper) WrapResourceHandler(h ResourceHandlerFunc, mw ...MiddlewareFunc) http.HandlerFunc {
 	return w.WrapHandler(func(rw http.ResponseWriter, req *http.Request) error {
-		rw.Header().Set("Content-Type", "application/json")
+		rw.Header().Set("Content-Type", w.contentType())
 		obj, err := h(req)
 		if err != nil {
 			return err
 		}
 
+		status := http.StatusOK
+		if rsp, ok := obj.(*Response); ok {
+			for k, v := range rsp.Header {
+				rw.Header()[k] = v
+			}
+			status, obj = rsp.Status, rsp.Body
+		}
+
+		if status == http.StatusOK && notModified(req, rw.Header()) {
+			rw.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+
+		if obj == nil {
+			rw.WriteHeader(status)
+			return nil
+		}
+
 		data, err := json.Marshal(obj)
 		if err != nil {
 			return fmt.Errorf("failed to encode response: %w", err)
 		}
 
+		rw.WriteHeader(status)
 		if _, err = rw.Write(data); err != nil {
 			// request connection is corrupted, just log error and exit
 			w.log.Error("failed to serve response", zap.Error(err))
@@ -122,15 +156,40 @@ func (w Wrapper) serveResponseError(rw http.ResponseWriter, err error) {
 	}
 
 	apiErr := ToAPIError(err)
-	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Content-Type", w.contentType())
 	rw.WriteHeader(apiErr.Status)
 	if apiErr.Status >= http.StatusInternalServerError {
 		// Log critical response errors
 		w.log.Error(err.Error(), zap.Int("status", apiErr.Status))
 	}
 
-	resp := ErrorResponse{Error: apiErr}
+	var resp interface{} = ErrorResponse{Error: apiErr}
+	if w.scim {
+		resp = scim.Error{
+			StatusCode: apiErr.Status,
+			ScimType:   apiErr.ScimType,
+			Detail:     apiErr.Message,
+		}
+	}
+
 	if err := json.NewEncoder(rw).Encode(resp); err != nil {
 		w.log.Error("failed to encode error response", zap.Error(err))
 	}
 }
+
+// notModified reports whether requested resource version matches If-None-Match header of GET request
+func notModified(req *http.Request, h http.Header) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	ifNoneMatch := req.Header.Get(scim.HeaderIfNoneMatch)
+	return ifNoneMatch != "" && scim.MatchETag(ifNoneMatch, h.Get(scim.HeaderETag))
+}
+
+func (w Wrapper) contentType() string {
+	if w.scim {
+		return scim.ContentType
+	}
+	return "application/json"
+}
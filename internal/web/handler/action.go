@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/strick-j/scimfe/internal/model/audit"
+	"github.com/strick-j/scimfe/internal/service"
+	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+)
+
+type ActionHandler struct {
+	recorder *service.ActionRecorder
+}
+
+// NewActionHandler is ActionHandler constructor
+func NewActionHandler(recorder *service.ActionRecorder) *ActionHandler {
+	return &ActionHandler{recorder: recorder}
+}
+
+// GetActionsList returns audit log records.
+//
+// Besides list query parameters, records can be filtered by
+// "resourceType", "success" and time range ("from" inclusive, "to" exclusive).
+// Records are sorted by time in descending order by default.
+func (h ActionHandler) GetActionsList(r *http.Request) (interface{}, error) {
+	q, err := ListQueryFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	params := r.URL.Query()
+	exprs := []filter.Expression{q.Filter}
+	if v := params.Get("resourceType"); v != "" {
+		exprs = append(exprs, actionAttrExpr("resourceType", filter.OpEqual, v))
+	}
+
+	if v := params.Get("success"); v != "" {
+		success, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, web.NewErrBadRequest("invalid success value %q", v)
+		}
+		exprs = append(exprs, actionAttrExpr("success", filter.OpEqual, success))
+	}
+
+	for _, bound := range timeRangeParams {
+		v := params.Get(bound.name)
+		if v == "" {
+			continue
+		}
+
+		if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+			return nil, web.NewErrBadRequest("invalid %s value %q, RFC 3339 time expected", bound.name, v)
+		}
+		exprs = append(exprs, actionAttrExpr("time", bound.op, v))
+	}
+	q.Filter = filter.And(exprs...)
+
+	if q.SortBy == "" && q.Cursor == nil {
+		q.SortBy = "time"
+		q.SortOrder = scim.SortDescending
+	}
+
+	actions, page, err := h.recorder.List(r.Context(), *q)
+	if err != nil {
+		return nil, err
+	}
+
+	if actions == nil {
+		actions = audit.Actions{}
+	}
+	return NewListResponse(actions, len(actions), q, page), nil
+}
+
+var timeRangeParams = []struct {
+	name string
+	op   filter.Operator
+}{
+	{name: "from", op: filter.OpGreaterOrEqual},
+	{name: "to", op: filter.OpLess},
+}
+
+func actionAttrExpr(name string, op filter.Operator, value interface{}) filter.Expression {
+	return &filter.AttrExpr{
+		Path:     filter.AttrPath{Name: name},
+		Operator: op,
+		Value:    value,
+	}
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/schema"
+)
+
+// DiscoveryHandler serves SCIM discovery endpoints generated from schema registry
+type DiscoveryHandler struct {
+	reg *schema.Registry
+}
+
+// NewDiscoveryHandler is DiscoveryHandler constructor
+func NewDiscoveryHandler(reg *schema.Registry) *DiscoveryHandler {
+	return &DiscoveryHandler{reg: reg}
+}
+
+func (h DiscoveryHandler) GetServiceProviderConfig(_ *http.Request) (interface{}, error) {
+	return h.reg.ServiceProviderConfig(), nil
+}
+
+func (h DiscoveryHandler) GetResourceTypes(_ *http.Request) (interface{}, error) {
+	out := h.reg.ResourceTypes()
+	return scim.NewListResponse(out, len(out), 1, len(out)), nil
+}
+
+func (h DiscoveryHandler) GetResourceType(r *http.Request) (interface{}, error) {
+	return h.reg.ResourceType(mux.Vars(r)["id"])
+}
+
+func (h DiscoveryHandler) GetSchemas(_ *http.Request) (interface{}, error) {
+	out := h.reg.Schemas()
+	return scim.NewListResponse(out, len(out), 1, len(out)), nil
+}
+
+func (h DiscoveryHandler) GetSchema(r *http.Request) (interface{}, error) {
+	return h.reg.Schema(mux.Vars(r)["id"])
+}
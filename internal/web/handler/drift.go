@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/strick-j/scimfe/internal/service"
+)
+
+type DriftHandler struct {
+	driftSvc *service.PamDriftService
+}
+
+// NewDriftHandler is DriftHandler constructor
+func NewDriftHandler(driftSvc *service.PamDriftService) *DriftHandler {
+	return &DriftHandler{driftSvc: driftSvc}
+}
+
+// GetDriftReport compares PAM mirror with PAM SCIM server and returns differences.
+//
+// Report is read-only, nothing is written to the mirror.
+func (h DriftHandler) GetDriftReport(r *http.Request) (interface{}, error) {
+	return h.driftSvc.Report(r.Context())
+}
+
+// GetDriftReportCSV returns drift report as downloadable CSV file
+func (h DriftHandler) GetDriftReportCSV(w http.ResponseWriter, r *http.Request) error {
+	report, err := h.driftSvc.Report(r.Context())
+	if err != nil {
+		return err
+	}
+
+	// report is buffered to serve JSON error if it can't be written
+	buf := &bytes.Buffer{}
+	if err := report.WriteCSV(buf); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("drift-%s.csv", report.Generated.UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -1,10 +1,17 @@
 package handler
 
 import (
+	"context"
 	"io"
+	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/strick-j/scimfe/internal/model"
 	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+	"github.com/strick-j/scimfe/pkg/scim/schema"
 )
 
 // UnmarshalAndValidate unmarshals request from JSON in HTTP request and runs validation.
@@ -15,3 +22,149 @@ func UnmarshalAndValidate(r io.ReadCloser, dst interface{}) error {
 
 	return model.Validate(dst)
 }
+
+// DecodeResource decodes SCIM resource in HTTP request according to schema registry and runs validation.
+//
+// Read-only and unknown attributes are ignored, see schema.Registry.Decode.
+func DecodeResource(r io.ReadCloser, reg *schema.Registry, resourceType string, dst interface{}) error {
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return web.NewErrBadRequest("cannot read request: %s", err)
+	}
+
+	if err := reg.Decode(resourceType, data, dst); err != nil {
+		return err
+	}
+
+	return model.Validate(dst)
+}
+
+// Projection selects returned resource attributes according to
+// "attributes" and "excludedAttributes" request parameters.
+type Projection struct {
+	reg          *schema.Registry
+	resourceType string
+	attributes   []string
+	excluded     []string
+}
+
+// ProjectionFromRequest returns resource projection for request
+func ProjectionFromRequest(r *http.Request, reg *schema.Registry, resourceType string) Projection {
+	params := r.URL.Query()
+	return Projection{
+		reg:          reg,
+		resourceType: resourceType,
+		attributes:   splitAttributes(params.Get("attributes")),
+		excluded:     splitAttributes(params.Get("excludedAttributes")),
+	}
+}
+
+// Resource returns resource with selected attributes
+func (p Projection) Resource(v interface{}) (interface{}, error) {
+	return p.reg.Project(p.resourceType, v, p.attributes, p.excluded)
+}
+
+// VersionedResource returns resource with selected attributes and resource version in ETag header.
+//
+// Resource version is taken from resource metadata.
+func (p Projection) VersionedResource(v interface{}, meta *scim.Meta) (interface{}, error) {
+	res, err := p.Resource(v)
+	if err != nil {
+		return nil, err
+	}
+	return web.NewResourceResponse(http.StatusOK, metaVersion(meta), res), nil
+}
+
+// List returns list of resources with selected attributes
+func (p Projection) List(count int, item func(i int) interface{}) ([]interface{}, error) {
+	out := make([]interface{}, 0, count)
+	for i := 0; i < count; i++ {
+		res, err := p.Resource(item(i))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+func metaVersion(m *scim.Meta) string {
+	if m == nil {
+		return ""
+	}
+	return m.Version
+}
+
+// preconditionContext returns request context with If-Match and If-None-Match request precondition
+func preconditionContext(r *http.Request) context.Context {
+	return scim.WithPrecondition(r.Context(), scim.PreconditionFromHeader(r.Header))
+}
+
+func splitAttributes(v string) []string {
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+// ListQueryFromRequest parses SCIM list query parameters from request URL.
+//
+// Negative count is interpreted as zero, count is limited by model.MaxPageSize.
+func ListQueryFromRequest(r *http.Request) (*model.ListQuery, error) {
+	params := r.URL.Query()
+	f, err := filter.Parse(params.Get("filter"))
+	if err != nil {
+		return nil, err
+	}
+
+	q := model.NewListQuery()
+	q.Filter = f
+	q.SortBy = params.Get("sortBy")
+	q.SortOrder = params.Get("sortOrder")
+	if q.SortOrder != "" && q.SortOrder != scim.SortAscending && q.SortOrder != scim.SortDescending {
+		return nil, invalidListParam("sortOrder", q.SortOrder)
+	}
+
+	if v := params.Get("startIndex"); v != "" {
+		if q.StartIndex, err = strconv.Atoi(v); err != nil {
+			return nil, invalidListParam("startIndex", v)
+		}
+		if q.StartIndex < 1 {
+			q.StartIndex = 1
+		}
+	}
+
+	if v := params.Get("count"); v != "" {
+		if q.Count, err = strconv.Atoi(v); err != nil {
+			return nil, invalidListParam("count", v)
+		}
+		if q.Count < 0 {
+			q.Count = 0
+		}
+		if q.Count > model.MaxPageSize {
+			q.Count = model.MaxPageSize
+		}
+	}
+
+	if _, ok := params["cursor"]; ok {
+		cursor := params.Get("cursor")
+		q.Cursor = &cursor
+	}
+	return &q, nil
+}
+
+func invalidListParam(name, value string) error {
+	return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidValue, "invalid %s value %q", name, value)
+}
+
+// NewListResponse returns SCIM list response for a page of resources
+func NewListResponse(resources interface{}, count int, q *model.ListQuery, page *model.Page) *scim.ListResponse {
+	if q.Cursor != nil {
+		rsp := scim.NewListResponse(resources, page.Total, 0, count)
+		rsp.NextCursor = page.NextCursor
+		return rsp
+	}
+
+	return scim.NewListResponse(resources, page.Total, q.StartIndex, count)
+}
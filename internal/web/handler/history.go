@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/service"
+	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+)
+
+type HistoryHandler struct {
+	historySvc *service.PamHistoryService
+}
+
+// NewHistoryHandler is HistoryHandler constructor
+func NewHistoryHandler(historySvc *service.PamHistoryService) *HistoryHandler {
+	return &HistoryHandler{historySvc: historySvc}
+}
+
+// GetUserHistory returns recorded changes of PAM user, see changesList
+func (h HistoryHandler) GetUserHistory(r *http.Request) (interface{}, error) {
+	return h.changesList(r, scim.ResourceTypeUser, "userId")
+}
+
+// GetGroupHistory returns recorded changes of PAM group, see changesList
+func (h HistoryHandler) GetGroupHistory(r *http.Request) (interface{}, error) {
+	return h.changesList(r, scim.ResourceTypeGroup, "groupId")
+}
+
+// GetUserGroups returns group memberships of PAM user.
+//
+// Memberships at a point in time are returned if "asOf" parameter is set.
+func (h HistoryHandler) GetUserGroups(r *http.Request) (interface{}, error) {
+	id, err := pamIDFromRequest(r, "userId")
+	if err != nil {
+		return nil, err
+	}
+
+	asOf, err := asOfFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ms, err := h.historySvc.UserGroups(r.Context(), id, asOf)
+	if err != nil {
+		return nil, err
+	}
+	return membershipsResponse(ms), nil
+}
+
+// GetGroupMembers returns members of PAM group.
+//
+// Members at a point in time are returned if "asOf" parameter is set.
+func (h HistoryHandler) GetGroupMembers(r *http.Request) (interface{}, error) {
+	id, err := pamIDFromRequest(r, "groupId")
+	if err != nil {
+		return nil, err
+	}
+
+	asOf, err := asOfFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ms, err := h.historySvc.GroupMembers(r.Context(), id, asOf)
+	if err != nil {
+		return nil, err
+	}
+	return membershipsResponse(ms), nil
+}
+
+// changesList returns changes of resource identified by path variable.
+//
+// Besides list query parameters, changes can be filtered by "operation", "jobId"
+// and time range ("from" inclusive, "to" exclusive).
+// Changes are sorted by time in descending order by default.
+func (h HistoryHandler) changesList(r *http.Request, resourceType, varName string) (interface{}, error) {
+	id, err := pamIDFromRequest(r, varName)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := ListQueryFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	params := r.URL.Query()
+	exprs := []filter.Expression{
+		q.Filter,
+		actionAttrExpr("resourceType", filter.OpEqual, resourceType),
+		actionAttrExpr("resourceId", filter.OpEqual, float64(id)),
+	}
+	for _, name := range []string{"operation", "jobId"} {
+		if v := params.Get(name); v != "" {
+			exprs = append(exprs, actionAttrExpr(name, filter.OpEqual, v))
+		}
+	}
+
+	for _, bound := range timeRangeParams {
+		v := params.Get(bound.name)
+		if v == "" {
+			continue
+		}
+
+		if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+			return nil, web.NewErrBadRequest("invalid %s value %q, RFC 3339 time expected", bound.name, v)
+		}
+		exprs = append(exprs, actionAttrExpr("time", bound.op, v))
+	}
+	q.Filter = filter.And(exprs...)
+
+	if q.SortBy == "" && q.Cursor == nil {
+		q.SortBy = "time"
+		q.SortOrder = scim.SortDescending
+	}
+
+	changes, page, err := h.historySvc.ListChanges(r.Context(), *q)
+	if err != nil {
+		return nil, err
+	}
+
+	if changes == nil {
+		changes = pam.Changes{}
+	}
+	return NewListResponse(changes, len(changes), q, page), nil
+}
+
+// asOfFromRequest returns point in time from "asOf" query parameter, nil if parameter is not set
+func asOfFromRequest(r *http.Request) (*time.Time, error) {
+	v := r.URL.Query().Get("asOf")
+	if v == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil, web.NewErrBadRequest("invalid asOf value %q, RFC 3339 time expected", v)
+	}
+	return &t, nil
+}
+
+func membershipsResponse(ms pam.MembershipPeriods) *scim.ListResponse {
+	if ms == nil {
+		ms = pam.MembershipPeriods{}
+	}
+	return scim.NewListResponse(ms, len(ms), 1, len(ms))
+}
@@ -1,11 +1,63 @@
 package handler
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// Health statuses
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
 
 type MessageResponse struct {
 	Message string `json:"message"`
 }
 
+// HealthResponse is service health report
+type HealthResponse struct {
+	// Status is "degraded" if any of dependencies is unavailable
+	Status string    `json:"status"`
+	PAM    PAMHealth `json:"pam"`
+}
+
+// PAMHealth is PAM SCIM server health
+type PAMHealth struct {
+	Status  string             `json:"status"`
+	Breaker scim.BreakerStatus `json:"breaker"`
+}
+
 func Ping(_ *http.Request) (interface{}, error) {
 	return MessageResponse{Message: "pong"}, nil
 }
+
+type HealthHandler struct {
+	pamBreaker *scim.CircuitBreaker
+}
+
+// NewHealthHandler is HealthHandler constructor
+func NewHealthHandler(pamBreaker *scim.CircuitBreaker) *HealthHandler {
+	return &HealthHandler{pamBreaker: pamBreaker}
+}
+
+// GetHealth returns health of service dependencies.
+//
+// PAM SCIM server is reported as degraded while its circuit breaker is not closed.
+// Degraded service still responds with "200 OK", since it keeps serving the mirror.
+func (h HealthHandler) GetHealth(_ *http.Request) (interface{}, error) {
+	rsp := HealthResponse{
+		Status: HealthOK,
+		PAM: PAMHealth{
+			Status:  HealthOK,
+			Breaker: h.pamBreaker.Status(),
+		},
+	}
+
+	if rsp.PAM.Breaker.State != scim.BreakerClosed {
+		rsp.PAM.Status = HealthDegraded
+		rsp.Status = HealthDegraded
+	}
+	return rsp, nil
+}
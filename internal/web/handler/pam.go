@@ -0,0 +1,361 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/service"
+	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+type PamHandler struct {
+	pamSvc  *service.PamService
+	provSvc *service.PamProvisioningService
+	bulkSvc *service.PamBulkService
+}
+
+// NewPamHandler is PamHandler constructor
+func NewPamHandler(pamSvc *service.PamService, provSvc *service.PamProvisioningService, bulkSvc *service.PamBulkService) *PamHandler {
+	return &PamHandler{pamSvc: pamSvc, provSvc: provSvc, bulkSvc: bulkSvc}
+}
+
+func (h PamHandler) GetUsersList(r *http.Request) (interface{}, error) {
+	q, err := ListQueryFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	proj := ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypeUser)
+	users, page, err := h.pamSvc.ListUsers(r.Context(), *q)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := proj.List(len(users), func(i int) interface{} { return users[i].SCIM() })
+	if err != nil {
+		return nil, err
+	}
+
+	return NewListResponse(out, len(out), q, page), nil
+}
+
+func (h PamHandler) GetUserByID(r *http.Request) (interface{}, error) {
+	id, err := pamIDFromRequest(r, "userId")
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := h.pamSvc.UserByID(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := u.SCIM()
+	return ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypeUser).VersionedResource(res, res.Meta)
+}
+
+func (h PamHandler) GetGroupsList(r *http.Request) (interface{}, error) {
+	q, err := ListQueryFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	proj := ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypeGroup)
+	groups, page, err := h.pamSvc.ListGroups(r.Context(), *q)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := proj.List(len(groups), func(i int) interface{} { return groups[i].SCIM() })
+	if err != nil {
+		return nil, err
+	}
+
+	return NewListResponse(out, len(out), q, page), nil
+}
+
+func (h PamHandler) GetGroupByID(r *http.Request) (interface{}, error) {
+	id, err := pamIDFromRequest(r, "groupId")
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := h.pamSvc.GroupByID(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := g.SCIM()
+	return ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypeGroup).VersionedResource(res, res.Meta)
+}
+
+func (h PamHandler) GetContainersList(r *http.Request) (interface{}, error) {
+	q, err := ListQueryFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	proj := ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypeContainer)
+	containers, page, err := h.pamSvc.ListContainers(r.Context(), *q)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := proj.List(len(containers), func(i int) interface{} { return containers[i].SCIM() })
+	if err != nil {
+		return nil, err
+	}
+
+	return NewListResponse(out, len(out), q, page), nil
+}
+
+func (h PamHandler) GetContainerByID(r *http.Request) (interface{}, error) {
+	c, err := h.pamSvc.ContainerByID(r.Context(), mux.Vars(r)["containerId"])
+	if err != nil {
+		return nil, err
+	}
+
+	res := c.SCIM()
+	return ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypeContainer).VersionedResource(res, res.Meta)
+}
+
+// GetContainerPermissions returns all permissions of container as SCIM list response
+func (h PamHandler) GetContainerPermissions(r *http.Request) (interface{}, error) {
+	perms, err := h.pamSvc.ContainerPermissions(r.Context(), mux.Vars(r)["containerId"])
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]scim.ContainerPermission, 0, len(perms))
+	for _, p := range perms {
+		out = append(out, p.SCIM())
+	}
+
+	return scim.NewListResponse(out, len(out), 1, len(out)), nil
+}
+
+// GetContainerAccess returns effective access of users to container as SCIM list response
+func (h PamHandler) GetContainerAccess(r *http.Request) (interface{}, error) {
+	access, err := h.pamSvc.ContainerAccess(r.Context(), mux.Vars(r)["containerId"])
+	if err != nil {
+		return nil, err
+	}
+
+	return scim.NewListResponse(access, len(access), 1, len(access)), nil
+}
+
+func (h PamHandler) GetAccountsList(r *http.Request) (interface{}, error) {
+	q, err := ListQueryFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	proj := ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypePrivilegedData)
+	accounts, page, err := h.pamSvc.ListAccounts(r.Context(), *q)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := proj.List(len(accounts), func(i int) interface{} { return accounts[i].SCIM() })
+	if err != nil {
+		return nil, err
+	}
+
+	return NewListResponse(out, len(out), q, page), nil
+}
+
+func (h PamHandler) GetAccountByID(r *http.Request) (interface{}, error) {
+	a, err := h.pamSvc.AccountByID(r.Context(), mux.Vars(r)["accountId"])
+	if err != nil {
+		return nil, err
+	}
+
+	res := a.SCIM()
+	return ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypePrivilegedData).VersionedResource(res, res.Meta)
+}
+
+func (h PamHandler) CreateUser(r *http.Request) (interface{}, error) {
+	var u scim.User
+	if err := DecodeResource(r.Body, service.PamSchemas, scim.ResourceTypeUser, &u); err != nil {
+		return nil, err
+	}
+
+	out, err := h.provSvc.CreateUser(r.Context(), u)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypeUser).Resource(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return createdResponse("/pam/users/"+out.ID, out.Meta, res), nil
+}
+
+func (h PamHandler) ReplaceUser(r *http.Request) (interface{}, error) {
+	id, err := pamIDFromRequest(r, "userId")
+	if err != nil {
+		return nil, err
+	}
+
+	var u scim.User
+	if err := DecodeResource(r.Body, service.PamSchemas, scim.ResourceTypeUser, &u); err != nil {
+		return nil, err
+	}
+
+	out, err := h.provSvc.ReplaceUser(preconditionContext(r), id, u)
+	if err != nil {
+		return nil, err
+	}
+
+	return ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypeUser).VersionedResource(out, out.Meta)
+}
+
+func (h PamHandler) PatchUser(r *http.Request) (interface{}, error) {
+	id, err := pamIDFromRequest(r, "userId")
+	if err != nil {
+		return nil, err
+	}
+
+	var req scim.PatchRequest
+	if err := web.UnmarshalJSON(r.Body, &req); err != nil {
+		return nil, err
+	}
+
+	out, err := h.provSvc.PatchUser(preconditionContext(r), id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypeUser).VersionedResource(out, out.Meta)
+}
+
+// DeactivateUser deactivates PAM user, users are never deleted on remote server
+func (h PamHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) error {
+	id, err := pamIDFromRequest(r, "userId")
+	if err != nil {
+		return err
+	}
+
+	if err := h.provSvc.DeactivateUser(preconditionContext(r), id); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (h PamHandler) CreateGroup(r *http.Request) (interface{}, error) {
+	var g scim.Group
+	if err := DecodeResource(r.Body, service.PamSchemas, scim.ResourceTypeGroup, &g); err != nil {
+		return nil, err
+	}
+
+	out, err := h.provSvc.CreateGroup(r.Context(), g)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypeGroup).Resource(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return createdResponse("/pam/groups/"+out.ID, out.Meta, res), nil
+}
+
+func (h PamHandler) ReplaceGroup(r *http.Request) (interface{}, error) {
+	id, err := pamIDFromRequest(r, "groupId")
+	if err != nil {
+		return nil, err
+	}
+
+	var g scim.Group
+	if err := DecodeResource(r.Body, service.PamSchemas, scim.ResourceTypeGroup, &g); err != nil {
+		return nil, err
+	}
+
+	out, err := h.provSvc.ReplaceGroup(preconditionContext(r), id, g)
+	if err != nil {
+		return nil, err
+	}
+
+	return ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypeGroup).VersionedResource(out, out.Meta)
+}
+
+func (h PamHandler) PatchGroup(r *http.Request) (interface{}, error) {
+	id, err := pamIDFromRequest(r, "groupId")
+	if err != nil {
+		return nil, err
+	}
+
+	var req scim.PatchRequest
+	if err := web.UnmarshalJSON(r.Body, &req); err != nil {
+		return nil, err
+	}
+
+	out, err := h.provSvc.PatchGroup(preconditionContext(r), id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return ProjectionFromRequest(r, service.PamSchemas, scim.ResourceTypeGroup).VersionedResource(out, out.Meta)
+}
+
+func (h PamHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) error {
+	id, err := pamIDFromRequest(r, "groupId")
+	if err != nil {
+		return err
+	}
+
+	if err := h.provSvc.DeleteGroup(preconditionContext(r), id); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// Bulk processes SCIM bulk request.
+//
+// Request payload size is limited by service.BulkMaxPayloadSize.
+func (h PamHandler) Bulk(r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(r.Body, service.BulkMaxPayloadSize+1))
+	if err != nil {
+		return nil, web.NewErrBadRequest("cannot read request: %s", err)
+	}
+
+	if len(body) > service.BulkMaxPayloadSize {
+		return nil, scim.NewError(http.StatusRequestEntityTooLarge, "",
+			"the size of the bulk operation exceeds the maxPayloadSize (%d)", service.BulkMaxPayloadSize)
+	}
+
+	var req scim.BulkRequest
+	if err := web.UnmarshalJSON(io.NopCloser(bytes.NewReader(body)), &req); err != nil {
+		return nil, err
+	}
+
+	return h.bulkSvc.Process(r.Context(), req)
+}
+
+// createdResponse returns "201 Created" response with created resource location and version
+func createdResponse(location string, meta *scim.Meta, body interface{}) *web.Response {
+	rsp := web.NewResourceResponse(http.StatusCreated, metaVersion(meta), body)
+	rsp.Header.Set("Location", location)
+	return rsp
+}
+
+// pamIDFromRequest returns PAM resource ID from request path variable
+func pamIDFromRequest(r *http.Request, varName string) (int, error) {
+	id, err := pam.ParseID(mux.Vars(r)[varName])
+	if err != nil {
+		return 0, web.NewErrBadRequest(err.Error())
+	}
+	return id, nil
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/strick-j/scimfe/internal/service"
+	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// ScimUserHandler serves scimfe users as SCIM service provider
+type ScimUserHandler struct {
+	svc *service.ScimUsersService
+}
+
+// NewScimUserHandler is ScimUserHandler constructor
+func NewScimUserHandler(svc *service.ScimUsersService) *ScimUserHandler {
+	return &ScimUserHandler{svc: svc}
+}
+
+func (h ScimUserHandler) GetUsersList(r *http.Request) (interface{}, error) {
+	q, err := ListQueryFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	proj := ProjectionFromRequest(r, service.ScimSchemas, scim.ResourceTypeUser)
+	users, page, err := h.svc.List(r.Context(), *q)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := proj.List(len(users), func(i int) interface{} { return users[i] })
+	if err != nil {
+		return nil, err
+	}
+
+	return NewListResponse(out, len(out), q, page), nil
+}
+
+func (h ScimUserHandler) GetUserByID(r *http.Request) (interface{}, error) {
+	out, err := h.svc.UserByID(r.Context(), mux.Vars(r)["userId"])
+	if err != nil {
+		return nil, err
+	}
+
+	return ProjectionFromRequest(r, service.ScimSchemas, scim.ResourceTypeUser).VersionedResource(out, out.Meta)
+}
+
+func (h ScimUserHandler) CreateUser(r *http.Request) (interface{}, error) {
+	var u scim.User
+	if err := DecodeResource(r.Body, service.ScimSchemas, scim.ResourceTypeUser, &u); err != nil {
+		return nil, err
+	}
+
+	out, err := h.svc.CreateUser(r.Context(), u)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := ProjectionFromRequest(r, service.ScimSchemas, scim.ResourceTypeUser).Resource(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return createdResponse(out.Meta.Location, out.Meta, res), nil
+}
+
+func (h ScimUserHandler) ReplaceUser(r *http.Request) (interface{}, error) {
+	var u scim.User
+	if err := DecodeResource(r.Body, service.ScimSchemas, scim.ResourceTypeUser, &u); err != nil {
+		return nil, err
+	}
+
+	out, err := h.svc.ReplaceUser(preconditionContext(r), mux.Vars(r)["userId"], u)
+	if err != nil {
+		return nil, err
+	}
+
+	return ProjectionFromRequest(r, service.ScimSchemas, scim.ResourceTypeUser).VersionedResource(out, out.Meta)
+}
+
+func (h ScimUserHandler) PatchUser(r *http.Request) (interface{}, error) {
+	var req scim.PatchRequest
+	if err := web.UnmarshalJSON(r.Body, &req); err != nil {
+		return nil, err
+	}
+
+	out, err := h.svc.PatchUser(preconditionContext(r), mux.Vars(r)["userId"], req)
+	if err != nil {
+		return nil, err
+	}
+
+	return ProjectionFromRequest(r, service.ScimSchemas, scim.ResourceTypeUser).VersionedResource(out, out.Meta)
+}
+
+func (h ScimUserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) error {
+	if err := h.svc.DeleteUser(preconditionContext(r), mux.Vars(r)["userId"]); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/strick-j/scimfe/internal/model/pam"
+	"github.com/strick-j/scimfe/internal/service"
+	"github.com/strick-j/scimfe/internal/web"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+)
+
+type SyncJobHandler struct {
+	jobsSvc *service.PamSyncJobService
+}
+
+// NewSyncJobHandler is SyncJobHandler constructor
+func NewSyncJobHandler(jobsSvc *service.PamSyncJobService) *SyncJobHandler {
+	return &SyncJobHandler{jobsSvc: jobsSvc}
+}
+
+// StartJob starts synchronization job in background.
+//
+// Returns "202 Accepted" response with started job, job progress is available at job location.
+func (h SyncJobHandler) StartJob(r *http.Request) (interface{}, error) {
+	var req pam.SyncJobRequest
+	if err := UnmarshalAndValidate(r.Body, &req); err != nil {
+		return nil, err
+	}
+
+	job, err := h.jobsSvc.Start(r.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := web.NewResponse(http.StatusAccepted, job)
+	rsp.Header.Set("Location", "/sync/jobs/"+job.ID.String())
+	return rsp, nil
+}
+
+// GetJobsList returns running and finished synchronization jobs.
+//
+// Besides list query parameters, jobs can be filtered by "resourceType" and "status".
+// Jobs are sorted by start time in descending order by default.
+func (h SyncJobHandler) GetJobsList(r *http.Request) (interface{}, error) {
+	q, err := ListQueryFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	params := r.URL.Query()
+	exprs := []filter.Expression{q.Filter}
+	for _, name := range []string{"resourceType", "status"} {
+		if v := params.Get(name); v != "" {
+			exprs = append(exprs, actionAttrExpr(name, filter.OpEqual, v))
+		}
+	}
+	q.Filter = filter.And(exprs...)
+
+	if q.SortBy == "" && q.Cursor == nil {
+		q.SortBy = "created"
+		q.SortOrder = scim.SortDescending
+	}
+
+	jobs, page, err := h.jobsSvc.List(r.Context(), *q)
+	if err != nil {
+		return nil, err
+	}
+
+	if jobs == nil {
+		jobs = pam.SyncJobs{}
+	}
+	return NewListResponse(jobs, len(jobs), q, page), nil
+}
+
+func (h SyncJobHandler) GetJobByID(r *http.Request) (interface{}, error) {
+	id, err := syncJobIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.jobsSvc.JobByID(r.Context(), id)
+}
+
+// CancelJob requests cancellation of a running job.
+//
+// Returns job with cancellation flag set, job status is changed once job is stopped.
+func (h SyncJobHandler) CancelJob(r *http.Request) (interface{}, error) {
+	id, err := syncJobIDFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.jobsSvc.Cancel(r.Context(), id)
+}
+
+// syncJobIDFromRequest returns synchronization job ID from request path
+func syncJobIDFromRequest(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(mux.Vars(r)["jobId"])
+	if err != nil {
+		return id, web.NewErrBadRequest("invalid job id: %s", err)
+	}
+	return id, nil
+}
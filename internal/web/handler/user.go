@@ -6,7 +6,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/strick-j/scimfe/internal/model"
 	"github.com/strick-j/scimfe/internal/model/auth"
-	"github.com/strick-j/scimfe/internal/model/request"
+	"github.com/strick-j/scimfe/internal/model/user"
 	"github.com/strick-j/scimfe/internal/service"
 )
 
@@ -20,12 +20,20 @@ func NewUserHandler(usersSvc *service.UsersService) *UserHandler {
 }
 
 func (h UserHandler) GetUsersList(r *http.Request) (interface{}, error) {
-	list, err := h.usersSvc.GetAll(r.Context())
+	q, err := ListQueryFromRequest(r)
 	if err != nil {
 		return nil, err
 	}
 
-	return request.UsersList{Users: list}, nil
+	list, page, err := h.usersSvc.List(r.Context(), *q)
+	if err != nil {
+		return nil, err
+	}
+
+	if list == nil {
+		list = user.Users{}
+	}
+	return NewListResponse(list, len(list), q, page), nil
 }
 
 func (h UserHandler) GetByID(r *http.Request) (interface{}, error) {
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/strick-j/scimfe/internal/model/audit"
+	"github.com/strick-j/scimfe/internal/service"
+	"github.com/strick-j/scimfe/internal/web"
+)
+
+// ActorSCIM is audit actor of requests authorized by SCIM bearer token
+const ActorSCIM = "scim"
+
+// NewBearerAuthMiddleware returns a new middleware which checks bearer token in Authorization header.
+//
+// Used by SCIM service provider endpoints, which are called by identity provider
+// with a static token instead of user session.
+// All requests are rejected if there are no configured tokens.
+func NewBearerAuthMiddleware(tokens []string) web.MiddlewareFunc {
+	return func(rw http.ResponseWriter, req *http.Request) (*http.Request, error) {
+		token := bearerToken(req)
+		if token == "" || !validToken(tokens, token) {
+			rw.Header().Set("WWW-Authenticate", `Bearer realm="scimfe"`)
+			return req, service.ErrAuthRequired
+		}
+
+		ctx := audit.ContextWithActor(req.Context(), ActorSCIM)
+		return req.WithContext(ctx), nil
+	}
+}
+
+func bearerToken(req *http.Request) string {
+	parts := strings.SplitN(req.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
+
+func validToken(tokens []string, token string) bool {
+	valid := false
+	for _, t := range tokens {
+		if t != "" && subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
@@ -0,0 +1,41 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// Response is resource handler result with custom status code and headers.
+//
+// Body is encoded to JSON, response has no body if Body is nil.
+type Response struct {
+	// Status is HTTP status code
+	Status int
+
+	// Header contains additional response headers
+	Header http.Header
+
+	// Body is response payload
+	Body interface{}
+}
+
+// NewResponse constructs a new response
+func NewResponse(status int, body interface{}) *Response {
+	return &Response{
+		Status: status,
+		Header: http.Header{},
+		Body:   body,
+	}
+}
+
+// NewResourceResponse constructs a new response with resource version in ETag header.
+//
+// ETag header is omitted if version is empty.
+func NewResourceResponse(status int, version string, body interface{}) *Response {
+	rsp := NewResponse(status, body)
+	if version != "" {
+		rsp.Header.Set(scim.HeaderETag, version)
+	}
+	return rsp
+}
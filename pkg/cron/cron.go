@@ -0,0 +1,260 @@
+// Package cron parses cron schedule expressions and computes schedule activation times.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchYears is a number of years to search for the next activation time
+const searchYears = 5
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+
+	// day of week 7 is Sunday as well as 0
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// maxDays is max number of days in month, February has 29 days in leap years
+var maxDays = [...]int{1: 31, 2: 29, 3: 31, 4: 30, 5: 31, 6: 30, 7: 31, 8: 31, 9: 30, 10: 31, 11: 30, 12: 31}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Schedule is parsed cron schedule
+type Schedule struct {
+	spec string
+
+	// bit sets of allowed field values
+	minute, hour, dom, month, dow uint64
+
+	// restricted reports whether both day of month and day of week are restricted,
+	// in this case a day matches if it matches either of fields.
+	restricted bool
+
+	// every is a fixed interval of "@every" schedule
+	every time.Duration
+}
+
+// Parse parses cron schedule.
+//
+// Schedule is either a standard 5-field expression "minute hour day-of-month month day-of-week",
+// one of descriptors @yearly (@annually), @monthly, @weekly, @daily (@midnight), @hourly,
+// or a fixed interval "@every <duration>", like "@every 15m".
+//
+// Fields support lists ("1,15"), ranges ("1-5"), steps ("*/10", "0-30/5")
+// and English names of months and days of week ("JAN", "MON-FRI").
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	s := &Schedule{spec: spec}
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("invalid schedule %q: interval should be at least 1s", spec)
+		}
+
+		s.every = d
+		return s, nil
+	}
+
+	expr := spec
+	if v, ok := descriptors[strings.ToLower(spec)]; ok {
+		expr = v
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	var err error
+	dst := []*uint64{&s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+	for i, f := range []field{minuteField, hourField, domField, monthField, dowField} {
+		if *dst[i], err = f.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	s.restricted = !isWildcard(fields[2]) && !isWildcard(fields[4])
+	if !s.satisfiable() {
+		return nil, fmt.Errorf("invalid schedule %q: day of month doesn't exist in any of months", spec)
+	}
+	return s, nil
+}
+
+// MustParse is like Parse but panics if schedule is invalid
+func MustParse(spec string) *Schedule {
+	s, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// String returns schedule expression
+func (s Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the first activation time after t in location of t.
+//
+// Returns zero time if schedule doesn't activate within next few years,
+// like "0 0 29 2 */7" which activates on February 29 only if it's Sunday.
+func (s Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Truncate(time.Second).Add(s.every)
+	}
+
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+	limit := t.Year() + searchYears
+	for t.Year() <= limit {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !has(s.hour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !has(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// satisfiable reports whether some day of allowed months matches day of month.
+//
+// If both day of month and day of week are restricted, any day of week matches as well.
+func (s Schedule) satisfiable() bool {
+	if s.restricted {
+		return true
+	}
+
+	for m := 1; m <= 12; m++ {
+		if !has(s.month, m) {
+			continue
+		}
+		for d := 1; d <= maxDays[m]; d++ {
+			if has(s.dom, d) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+	if s.restricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func (f field) parse(expr string) (uint64, error) {
+	var out uint64
+	for _, item := range strings.Split(expr, ",") {
+		bits, err := f.parseItem(item)
+		if err != nil {
+			return 0, err
+		}
+		out |= bits
+	}
+	return out, nil
+}
+
+// parseItem parses a single list item: "*", "N", "N-M", optionally followed by "/step"
+func (f field) parseItem(item string) (uint64, error) {
+	rng, step := item, 1
+	if i := strings.IndexByte(item, '/'); i >= 0 {
+		var err error
+		rng = item[:i]
+		if step, err = strconv.Atoi(item[i+1:]); err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid %s step %q", f.name, item[i+1:])
+		}
+	}
+
+	lo, hi := f.min, f.max
+	if !isWildcard(rng) {
+		var err error
+		bounds := strings.SplitN(rng, "-", 2)
+		if lo, err = f.value(bounds[0]); err != nil {
+			return 0, err
+		}
+
+		hi = lo
+		if len(bounds) == 2 {
+			if hi, err = f.value(bounds[1]); err != nil {
+				return 0, err
+			}
+		} else if step > 1 {
+			// "N/step" means from N to the end of range
+			hi = f.max
+		}
+
+		if lo > hi {
+			return 0, fmt.Errorf("invalid %s range %q", f.name, rng)
+		}
+	}
+
+	var out uint64
+	for v := lo; v <= hi; v += step {
+		out |= 1 << uint(v)
+	}
+	return out, nil
+}
+
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s value %q, expected number from %d to %d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+func isWildcard(s string) bool {
+	return s == "*" || s == "?" || strings.HasPrefix(s, "*/")
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
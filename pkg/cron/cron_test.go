@@ -0,0 +1,119 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"*/15 9-17 * * MON-FRI",
+		"0 0 1,15 * *",
+		"0 0 29 2 *",
+		"0 0 31 1-2 *",
+		"0 0 31 2 1",
+		"5/10 * * jan,Dec sun",
+		"@daily",
+		"@Hourly",
+		"@every 90s",
+	}
+	for _, spec := range valid {
+		s, err := Parse(spec)
+		require.NoError(t, err, spec)
+		require.Equal(t, spec, s.String())
+	}
+
+	invalid := map[string]string{
+		"":                  "expected 5 fields",
+		"* * * *":           "expected 5 fields",
+		"60 * * * *":        "invalid minute value",
+		"* 24 * * *":        "invalid hour value",
+		"* * 0 * *":         "invalid day of month value",
+		"* * * 13 *":        "invalid month value",
+		"* * * * 8":         "invalid day of week value",
+		"* * * foo *":       "invalid month value",
+		"10-5 * * * *":      "invalid minute range",
+		"*/0 * * * *":       "invalid minute step",
+		"0 0 31 2 *":        "day of month doesn't exist",
+		"0 0 30,31 feb *":   "day of month doesn't exist",
+		"0 0 31 4,6,9,11 *": "day of month doesn't exist",
+		"0 0 31 2 */2":      "day of month doesn't exist",
+		"@every 10ms":       "at least 1s",
+		"@every soon":       "invalid duration",
+		"@weekly 1":         "expected 5 fields",
+	}
+	for spec, msg := range invalid {
+		_, err := Parse(spec)
+		require.Error(t, err, spec)
+		require.Contains(t, err.Error(), msg, spec)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// Friday
+	from := time.Date(2021, 1, 1, 10, 30, 15, 0, time.UTC)
+	cases := map[string]struct {
+		spec string
+		want time.Time
+	}{
+		"every minute": {
+			spec: "* * * * *",
+			want: time.Date(2021, 1, 1, 10, 31, 0, 0, time.UTC),
+		},
+		"minute step": {
+			spec: "*/20 * * * *",
+			want: time.Date(2021, 1, 1, 10, 40, 0, 0, time.UTC),
+		},
+		"daily": {
+			spec: "@daily",
+			want: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		"next month": {
+			spec: "0 6 1 * *",
+			want: time.Date(2021, 2, 1, 6, 0, 0, 0, time.UTC),
+		},
+		"day of week": {
+			spec: "0 9 * * MON-FRI",
+			want: time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC),
+		},
+		"day of week 7 is sunday": {
+			spec: "0 0 * * 7",
+			want: time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC),
+		},
+		"day of month or day of week": {
+			spec: "0 0 15 * sun",
+			want: time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC),
+		},
+		"short month is skipped": {
+			spec: "0 0 31 * *",
+			want: time.Date(2021, 1, 31, 0, 0, 0, 0, time.UTC),
+		},
+		"leap day": {
+			spec: "0 0 29 2 *",
+			want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		"interval": {
+			spec: "@every 1h",
+			want: time.Date(2021, 1, 1, 11, 30, 15, 0, time.UTC),
+		},
+		"never within search range": {
+			spec: "0 0 29 2 */7",
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			got := MustParse(c.spec).Next(from)
+			require.Equal(t, c.want, got)
+		})
+	}
+
+	t.Run("location", func(t *testing.T) {
+		loc := time.FixedZone("UTC+3", 3*60*60)
+		got := MustParse("0 0 * * *").Next(from.In(loc))
+		require.Equal(t, time.Date(2021, 1, 2, 0, 0, 0, 0, loc), got)
+	})
+}
@@ -0,0 +1,78 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// BulkIDPrefix is prefix of bulkId reference to resource created in the same bulk request
+const BulkIDPrefix = "bulkId:"
+
+// BulkOperation is a single operation of bulk request, see RFC 7644 section 3.7.
+type BulkOperation struct {
+	// Method is HTTP method of operation: POST, PUT, PATCH or DELETE
+	Method string `json:"method"`
+
+	// BulkID is client-defined operation ID, required for POST operations.
+	//
+	// Other operations can reference created resource as "bulkId:<BulkID>".
+	BulkID string `json:"bulkId,omitempty"`
+
+	// Version is optional resource version for conditional operations
+	Version string `json:"version,omitempty"`
+
+	// Path is resource endpoint relative path, like "/Users" or "/Groups/123"
+	Path string `json:"path"`
+
+	// Data is operation payload: resource for POST and PUT, PatchOp message for PATCH
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// BulkRequest is SCIM bulk request message
+type BulkRequest struct {
+	Schemas []string `json:"schemas"`
+
+	// FailOnErrors is number of errors after which the rest of operations are skipped.
+	//
+	// Zero means all operations are processed.
+	FailOnErrors int `json:"failOnErrors,omitempty"`
+
+	Operations []BulkOperation `json:"Operations"`
+}
+
+// NewBulkRequest constructs a new bulk request from operations list
+func NewBulkRequest(failOnErrors int, ops ...BulkOperation) *BulkRequest {
+	return &BulkRequest{
+		Schemas:      []string{SchemaBulkRequest},
+		FailOnErrors: failOnErrors,
+		Operations:   ops,
+	}
+}
+
+// BulkOperationResult is result of a single bulk operation
+type BulkOperationResult struct {
+	Method   string `json:"method"`
+	BulkID   string `json:"bulkId,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Location string `json:"location,omitempty"`
+
+	// Status is HTTP status code of operation, encoded as string
+	Status string `json:"status"`
+
+	// Response is error returned by failed operation
+	Response *Error `json:"response,omitempty"`
+}
+
+// BulkResponse is SCIM bulk response message
+type BulkResponse struct {
+	Schemas    []string              `json:"schemas"`
+	Operations []BulkOperationResult `json:"Operations"`
+}
+
+// ParseBulkID returns referenced bulkId if value is bulkId reference
+func ParseBulkID(val string) (string, bool) {
+	if !strings.HasPrefix(val, BulkIDPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(val, BulkIDPrefix), true
+}
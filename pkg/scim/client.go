@@ -0,0 +1,193 @@
+package scim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ContentType is SCIM media type
+const ContentType = "application/scim+json"
+
+// Resource endpoints
+const (
+	EndpointUsers                 = "/Users"
+	EndpointGroups                = "/Groups"
+	EndpointServiceProviderConfig = "/ServiceProviderConfig"
+	EndpointSchemas               = "/Schemas"
+	EndpointResourceTypes         = "/ResourceTypes"
+
+	// CyberArk PAM endpoints
+	EndpointContainers           = "/Containers"
+	EndpointContainerPermissions = "/ContainerPermissions"
+	EndpointPrivilegedData       = "/PrivilegedData"
+)
+
+// Token is access token used to authorize requests
+type Token struct {
+	AccessToken string
+	TokenType   string
+}
+
+// SetAuthHeader sets Authorization header to the request
+func (t Token) SetAuthHeader(r *http.Request) {
+	tokenType := t.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+
+	r.Header.Set("Authorization", tokenType+" "+t.AccessToken)
+}
+
+// TokenSource provides access tokens for requests
+type TokenSource interface {
+	// Token returns a valid access token
+	Token(ctx context.Context) (*Token, error)
+}
+
+// StaticToken is TokenSource which always returns the same bearer token
+type StaticToken string
+
+// Token implements TokenSource
+func (t StaticToken) Token(_ context.Context) (*Token, error) {
+	return &Token{AccessToken: string(t), TokenType: "Bearer"}, nil
+}
+
+// Client is SCIM 2.0 API client
+type Client struct {
+	http    *http.Client
+	baseUrl string
+	tokens  TokenSource
+}
+
+// NewClient is Client constructor.
+//
+// Base URL should point to SCIM service root, e.g. "https://pam.example.com/scim/v2".
+// Token source is optional and can be nil.
+func NewClient(h *http.Client, baseUrl string, tokens TokenSource) *Client {
+	if h == nil {
+		h = http.DefaultClient
+	}
+
+	return &Client{
+		http:    h,
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		tokens:  tokens,
+	}
+}
+
+func resourcePath(endpoint, id string) string {
+	return endpoint + "/" + url.PathEscape(id)
+}
+
+func (c Client) newRequest(ctx context.Context, method, reqPath string, query url.Values, data interface{}) (*http.Request, error) {
+	var body io.Reader
+	if data != nil {
+		data, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare request: %w", err)
+		}
+
+		body = bytes.NewReader(data)
+	}
+
+	reqUrl := c.baseUrl + reqPath
+	if len(query) > 0 {
+		reqUrl += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqUrl, body)
+	if err != nil {
+		return nil, fmt.Errorf("can't prepare request: %w", err)
+	}
+
+	req.Header.Set("Accept", ContentType)
+	if body != nil {
+		req.Header.Set("Content-Type", ContentType)
+	}
+
+	// conditional request, see WithPrecondition
+	PreconditionFromContext(ctx).SetHeader(req.Header)
+
+	if c.tokens != nil {
+		tkn, err := c.tokens.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain access token: %w", err)
+		}
+		tkn.SetAuthHeader(req)
+	}
+
+	return req, nil
+}
+
+func (c Client) do(req *http.Request, out interface{}) error {
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer rsp.Body.Close()
+	content, err := ioutil.ReadAll(rsp.Body)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if rsp.StatusCode >= http.StatusOK && rsp.StatusCode < http.StatusMultipleChoices {
+		if out == nil || rsp.StatusCode == http.StatusNoContent || len(content) == 0 {
+			return nil
+		}
+
+		if err = json.Unmarshal(content, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		return nil
+	}
+
+	errRsp := &Error{}
+	if err := json.Unmarshal(content, errRsp); err != nil || (errRsp.Detail == "" && errRsp.ScimType == "") {
+		errRsp.Detail = strings.TrimSpace(string(content))
+	}
+
+	// Response status code is more reliable than error body contents.
+	errRsp.StatusCode = rsp.StatusCode
+	return errRsp
+}
+
+func (c Client) call(ctx context.Context, method, reqPath string, query url.Values, data, out interface{}) error {
+	req, err := c.newRequest(ctx, method, reqPath, query, data)
+	if err != nil {
+		return err
+	}
+
+	return c.do(req, out)
+}
+
+func (c Client) list(ctx context.Context, endpoint string, p ListParams, out interface{}) error {
+	return c.call(ctx, http.MethodGet, endpoint, p.Query(), nil, out)
+}
+
+func (c Client) get(ctx context.Context, reqPath string, out interface{}) error {
+	return c.call(ctx, http.MethodGet, reqPath, nil, nil, out)
+}
+
+func (c Client) post(ctx context.Context, reqPath string, data, out interface{}) error {
+	return c.call(ctx, http.MethodPost, reqPath, nil, data, out)
+}
+
+func (c Client) put(ctx context.Context, reqPath string, data, out interface{}) error {
+	return c.call(ctx, http.MethodPut, reqPath, nil, data, out)
+}
+
+func (c Client) patch(ctx context.Context, reqPath string, data, out interface{}) error {
+	return c.call(ctx, http.MethodPatch, reqPath, nil, data, out)
+}
+
+func (c Client) delete(ctx context.Context, reqPath string) error {
+	return c.call(ctx, http.MethodDelete, reqPath, nil, nil, nil)
+}
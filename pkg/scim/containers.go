@@ -0,0 +1,72 @@
+package scim
+
+import "context"
+
+// CyberArk PAM resource schemas
+const (
+	SchemaContainer           = "urn:ietf:params:scim:schemas:pam:1.0:Container"
+	SchemaContainerPermission = "urn:ietf:params:scim:schemas:pam:1.0:ContainerPermission"
+	SchemaPrivilegedData      = "urn:ietf:params:scim:schemas:pam:1.0:PrivilegedData"
+)
+
+// CyberArk PAM resource type names
+const (
+	ResourceTypeContainer           = "Container"
+	ResourceTypeContainerPermission = "ContainerPermission"
+	ResourceTypePrivilegedData      = "PrivilegedData"
+)
+
+// Container is CyberArk PAM safe
+type Container struct {
+	Schemas     []string   `json:"schemas,omitempty"`
+	ID          string     `json:"id,omitempty"`
+	Name        string     `json:"name"`
+	DisplayName string     `json:"displayName,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Type        string     `json:"type,omitempty"`
+	Owner       *Reference `json:"owner,omitempty"`
+	Meta        *Meta      `json:"meta,omitempty"`
+}
+
+// ContainerPermission is a set of safe permissions granted to a user or a group.
+//
+// Exactly one of User and Group is set.
+type ContainerPermission struct {
+	Schemas   []string   `json:"schemas,omitempty"`
+	ID        string     `json:"id,omitempty"`
+	Container Reference  `json:"container"`
+	User      *Reference `json:"user,omitempty"`
+	Group     *Reference `json:"group,omitempty"`
+	Rights    []string   `json:"rights,omitempty"`
+	Meta      *Meta      `json:"meta,omitempty"`
+}
+
+// ContainerList is containers list response
+type ContainerList struct {
+	ListResponse
+	Resources []Container `json:"Resources"`
+}
+
+// ContainerPermissionList is container permissions list response
+type ContainerPermissionList struct {
+	ListResponse
+	Resources []ContainerPermission `json:"Resources"`
+}
+
+// ListContainers returns a page of containers matching list params
+func (c Client) ListContainers(ctx context.Context, p ListParams) (*ContainerList, error) {
+	rsp := new(ContainerList)
+	return rsp, c.list(ctx, EndpointContainers, p, rsp)
+}
+
+// GetContainer returns container by ID
+func (c Client) GetContainer(ctx context.Context, id string) (*Container, error) {
+	rsp := new(Container)
+	return rsp, c.get(ctx, resourcePath(EndpointContainers, id), rsp)
+}
+
+// ListContainerPermissions returns a page of container permissions matching list params
+func (c Client) ListContainerPermissions(ctx context.Context, p ListParams) (*ContainerPermissionList, error) {
+	rsp := new(ContainerPermissionList)
+	return rsp, c.list(ctx, EndpointContainerPermissions, p, rsp)
+}
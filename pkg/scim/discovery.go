@@ -0,0 +1,125 @@
+package scim
+
+import "context"
+
+// Supported is a feature support flag of ServiceProviderConfig
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+// BulkSupport is bulk feature description of ServiceProviderConfig
+type BulkSupport struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+// FilterSupport is filter feature description of ServiceProviderConfig
+type FilterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+// AuthenticationScheme describes supported authentication scheme
+type AuthenticationScheme struct {
+	Type             string `json:"type"`
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	SpecURI          string `json:"specUri,omitempty"`
+	DocumentationURI string `json:"documentationUri,omitempty"`
+	Primary          bool   `json:"primary,omitempty"`
+}
+
+// ServiceProviderConfig describes SCIM service provider features
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas,omitempty"`
+	DocumentationURI      string                 `json:"documentationUri,omitempty"`
+	Patch                 Supported              `json:"patch"`
+	Bulk                  BulkSupport            `json:"bulk"`
+	Filter                FilterSupport          `json:"filter"`
+	ChangePassword        Supported              `json:"changePassword"`
+	Sort                  Supported              `json:"sort"`
+	ETag                  Supported              `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  *Meta                  `json:"meta,omitempty"`
+}
+
+// SchemaExtension is resource type schema extension reference
+type SchemaExtension struct {
+	Schema   string `json:"schema"`
+	Required bool   `json:"required"`
+}
+
+// ResourceType describes resource type supported by service provider
+type ResourceType struct {
+	Schemas          []string          `json:"schemas,omitempty"`
+	ID               string            `json:"id,omitempty"`
+	Name             string            `json:"name"`
+	Endpoint         string            `json:"endpoint"`
+	Description      string            `json:"description,omitempty"`
+	Schema           string            `json:"schema"`
+	SchemaExtensions []SchemaExtension `json:"schemaExtensions,omitempty"`
+	Meta             *Meta             `json:"meta,omitempty"`
+}
+
+// Attribute is schema attribute definition
+type Attribute struct {
+	Name            string      `json:"name"`
+	Type            string      `json:"type"`
+	MultiValued     bool        `json:"multiValued"`
+	Description     string      `json:"description,omitempty"`
+	Required        bool        `json:"required"`
+	CaseExact       bool        `json:"caseExact"`
+	Mutability      string      `json:"mutability"`
+	Returned        string      `json:"returned"`
+	Uniqueness      string      `json:"uniqueness,omitempty"`
+	CanonicalValues []string    `json:"canonicalValues,omitempty"`
+	ReferenceTypes  []string    `json:"referenceTypes,omitempty"`
+	SubAttributes   []Attribute `json:"subAttributes,omitempty"`
+}
+
+// Schema is resource schema definition
+type Schema struct {
+	Schemas     []string    `json:"schemas,omitempty"`
+	ID          string      `json:"id"`
+	Name        string      `json:"name,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Attributes  []Attribute `json:"attributes"`
+	Meta        *Meta       `json:"meta,omitempty"`
+}
+
+// SchemaList is schemas list response
+type SchemaList struct {
+	ListResponse
+	Resources []Schema `json:"Resources"`
+}
+
+// ResourceTypeList is resource types list response
+type ResourceTypeList struct {
+	ListResponse
+	Resources []ResourceType `json:"Resources"`
+}
+
+// ServiceProviderConfig returns service provider configuration
+func (c Client) ServiceProviderConfig(ctx context.Context) (*ServiceProviderConfig, error) {
+	rsp := new(ServiceProviderConfig)
+	return rsp, c.get(ctx, EndpointServiceProviderConfig, rsp)
+}
+
+// Schemas returns list of schemas supported by service provider
+func (c Client) Schemas(ctx context.Context) ([]Schema, error) {
+	rsp := new(SchemaList)
+	return rsp.Resources, c.get(ctx, EndpointSchemas, rsp)
+}
+
+// Schema returns schema by URN
+func (c Client) Schema(ctx context.Context, id string) (*Schema, error) {
+	rsp := new(Schema)
+	return rsp, c.get(ctx, resourcePath(EndpointSchemas, id), rsp)
+}
+
+// ResourceTypes returns list of resource types supported by service provider
+func (c Client) ResourceTypes(ctx context.Context) ([]ResourceType, error) {
+	rsp := new(ResourceTypeList)
+	return rsp.Resources, c.get(ctx, EndpointResourceTypes, rsp)
+}
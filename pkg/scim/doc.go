@@ -0,0 +1,10 @@
+// Package scim contains a SCIM 2.0 (RFC 7643, RFC 7644) protocol types and
+// a typed client for the CyberArk PAM SCIM server.
+//
+// The client covers Users, Groups and discovery resources
+// (ServiceProviderConfig, Schemas, ResourceTypes). SCIM error responses
+// are returned as *Error values which can be matched with errors.Is
+// against predefined errors like ErrNotFound or ErrInvalidFilter.
+//
+// See: https://identity-developer.cyberark.com/docs/manage-pam-objects-with-scim-endpoints
+package scim
@@ -0,0 +1,153 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// SCIM error types (scimType), see RFC 7644 section 3.12.
+const (
+	ErrTypeInvalidFilter = "invalidFilter"
+	ErrTypeTooMany       = "tooMany"
+	ErrTypeUniqueness    = "uniqueness"
+	ErrTypeMutability    = "mutability"
+	ErrTypeInvalidSyntax = "invalidSyntax"
+	ErrTypeInvalidPath   = "invalidPath"
+	ErrTypeNoTarget      = "noTarget"
+	ErrTypeInvalidValue  = "invalidValue"
+	ErrTypeInvalidVers   = "invalidVers"
+	ErrTypeSensitive     = "sensitive"
+
+	// ErrTypeInvalidCursor is returned for invalid or expired cursor, see RFC 9865.
+	ErrTypeInvalidCursor = "invalidCursor"
+)
+
+// Predefined errors to match with errors.Is.
+//
+// Errors with scimType match by type, other errors match by HTTP status code.
+var (
+	ErrInvalidFilter = &Error{ScimType: ErrTypeInvalidFilter}
+	ErrTooMany       = &Error{ScimType: ErrTypeTooMany}
+	ErrUniqueness    = &Error{ScimType: ErrTypeUniqueness}
+	ErrMutability    = &Error{ScimType: ErrTypeMutability}
+	ErrInvalidSyntax = &Error{ScimType: ErrTypeInvalidSyntax}
+	ErrInvalidPath   = &Error{ScimType: ErrTypeInvalidPath}
+	ErrNoTarget      = &Error{ScimType: ErrTypeNoTarget}
+	ErrInvalidValue  = &Error{ScimType: ErrTypeInvalidValue}
+	ErrInvalidVers   = &Error{ScimType: ErrTypeInvalidVers}
+	ErrSensitive     = &Error{ScimType: ErrTypeSensitive}
+	ErrInvalidCursor = &Error{ScimType: ErrTypeInvalidCursor}
+
+	ErrBadRequest         = &Error{StatusCode: http.StatusBadRequest}
+	ErrUnauthorized       = &Error{StatusCode: http.StatusUnauthorized}
+	ErrForbidden          = &Error{StatusCode: http.StatusForbidden}
+	ErrNotFound           = &Error{StatusCode: http.StatusNotFound}
+	ErrConflict           = &Error{StatusCode: http.StatusConflict}
+	ErrPreconditionFailed = &Error{StatusCode: http.StatusPreconditionFailed}
+	ErrTooManyRequests    = &Error{StatusCode: http.StatusTooManyRequests}
+	ErrNotImplemented     = &Error{StatusCode: http.StatusNotImplemented}
+)
+
+// Error is SCIM error response
+type Error struct {
+	// StatusCode is HTTP response status code
+	StatusCode int
+
+	// ScimType is SCIM detail error keyword
+	ScimType string
+
+	// Detail is human-readable error message
+	Detail string
+}
+
+// NewError constructs a new SCIM error
+func NewError(status int, scimType, format string, args ...interface{}) *Error {
+	if len(args) > 0 {
+		format = fmt.Sprintf(format, args...)
+	}
+
+	return &Error{
+		StatusCode: status,
+		ScimType:   scimType,
+		Detail:     format,
+	}
+}
+
+type errorMessage struct {
+	Schemas  []string        `json:"schemas"`
+	Status   json.RawMessage `json:"status"`
+	ScimType string          `json:"scimType,omitempty"`
+	Detail   string          `json:"detail,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler
+func (e Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(errorMessage{
+		Schemas:  []string{SchemaError},
+		Status:   json.RawMessage(strconv.Quote(strconv.Itoa(e.StatusCode))),
+		ScimType: e.ScimType,
+		Detail:   e.Detail,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// Status is accepted both as a string (as RFC requires) and as a number.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var msg errorMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+
+	e.ScimType = msg.ScimType
+	e.Detail = msg.Detail
+	if len(msg.Status) == 0 {
+		return nil
+	}
+
+	var status interface{}
+	if err := json.Unmarshal(msg.Status, &status); err != nil {
+		return err
+	}
+
+	switch v := status.(type) {
+	case float64:
+		e.StatusCode = int(v)
+	case string:
+		e.StatusCode, _ = strconv.Atoi(v)
+	}
+	return nil
+}
+
+// Error implements error interface
+func (e Error) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+
+	if e.ScimType != "" {
+		return fmt.Sprintf("scim: %d %s: %s", e.StatusCode, e.ScimType, msg)
+	}
+	return fmt.Sprintf("scim: %d: %s", e.StatusCode, msg)
+}
+
+// Is implements errors.Is interface.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	if t.ScimType != "" && t.ScimType != e.ScimType {
+		return false
+	}
+
+	if t.StatusCode != 0 && t.StatusCode != e.StatusCode {
+		return false
+	}
+
+	return t.ScimType != "" || t.StatusCode != 0
+}
@@ -0,0 +1,128 @@
+package scim
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Conditional request headers, see RFC 7644 section 3.14.
+const (
+	HeaderETag        = "ETag"
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+// WeakETag returns weak entity tag with provided opaque value, like `W/"3"`.
+func WeakETag(tag string) string {
+	return "W/" + strconv.Quote(tag)
+}
+
+// NewVersion returns weak entity tag computed from resource attributes.
+//
+// Resources with the same JSON representation have the same version,
+// so resource meta version should be empty when version is computed.
+func NewVersion(res interface{}) string {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return WeakETag(hex.EncodeToString(sum[:8]))
+}
+
+// MatchETag reports whether entity tag matches a list of entity tags
+// from If-Match or If-None-Match header. "*" matches any entity tag.
+//
+// Tags are compared using weak comparison, since SCIM versions are weak entity tags.
+func MatchETag(list, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Precondition is conditional request precondition, see RFC 7232.
+type Precondition struct {
+	// IfMatch is a list of entity tags, the request is performed only if resource version matches one of them.
+	IfMatch string
+
+	// IfNoneMatch is a list of entity tags, the request is performed only if resource version matches none of them.
+	IfNoneMatch string
+}
+
+// PreconditionFromHeader returns precondition from request headers
+func PreconditionFromHeader(h http.Header) Precondition {
+	return Precondition{
+		IfMatch:     strings.TrimSpace(h.Get(HeaderIfMatch)),
+		IfNoneMatch: strings.TrimSpace(h.Get(HeaderIfNoneMatch)),
+	}
+}
+
+// IsZero reports whether precondition is empty
+func (p Precondition) IsZero() bool {
+	return p.IfMatch == "" && p.IfNoneMatch == ""
+}
+
+// SetHeader sets precondition headers to request headers
+func (p Precondition) SetHeader(h http.Header) {
+	if p.IfMatch != "" {
+		h.Set(HeaderIfMatch, p.IfMatch)
+	}
+	if p.IfNoneMatch != "" {
+		h.Set(HeaderIfNoneMatch, p.IfNoneMatch)
+	}
+}
+
+// Check checks precondition against current resource version.
+//
+// Empty version means that resource has no current representation,
+// in this case only If-None-Match precondition can be met.
+// Returns "412 Precondition Failed" error if precondition is not met.
+func (p Precondition) Check(version string) error {
+	if p.IfMatch != "" && !MatchETag(p.IfMatch, version) {
+		return NewError(http.StatusPreconditionFailed, "",
+			"resource version %s doesn't match If-Match precondition %s", versionOrNone(version), p.IfMatch)
+	}
+
+	if p.IfNoneMatch != "" && MatchETag(p.IfNoneMatch, version) {
+		return NewError(http.StatusPreconditionFailed, "",
+			"resource version %s matches If-None-Match precondition %s", version, p.IfNoneMatch)
+	}
+	return nil
+}
+
+func versionOrNone(version string) string {
+	if version == "" {
+		return "<none>"
+	}
+	return version
+}
+
+type preconditionKey struct{}
+
+// WithPrecondition returns context with request precondition.
+//
+// Client sends precondition of request context in conditional request headers.
+func WithPrecondition(ctx context.Context, p Precondition) context.Context {
+	return context.WithValue(ctx, preconditionKey{}, p)
+}
+
+// PreconditionFromContext returns request precondition from context
+func PreconditionFromContext(ctx context.Context) Precondition {
+	p, _ := ctx.Value(preconditionKey{}).(Precondition)
+	return p
+}
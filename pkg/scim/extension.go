@@ -0,0 +1,113 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// SchemaEnterpriseUser is enterprise user schema extension, see RFC 7643 section 4.3.
+const SchemaEnterpriseUser = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+
+// EnterpriseUser is enterprise user schema extension attributes
+type EnterpriseUser struct {
+	EmployeeNumber string   `json:"employeeNumber,omitempty"`
+	CostCenter     string   `json:"costCenter,omitempty"`
+	Organization   string   `json:"organization,omitempty"`
+	Division       string   `json:"division,omitempty"`
+	Department     string   `json:"department,omitempty"`
+	Manager        *Manager `json:"manager,omitempty"`
+}
+
+// Manager is a reference to user's manager
+type Manager struct {
+	Value       string `json:"value,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// Extensions are schema extensions of a resource which have no Go representation,
+// like vendor-specific extensions, keyed by schema URI.
+//
+// Extensions are encoded as top-level resource attributes.
+type Extensions map[string]json.RawMessage
+
+// user is User without custom JSON encoding
+type user User
+
+// MarshalJSON implements json.Marshaler.
+//
+// Extensions are added to resource object as attributes named by schema URI.
+func (u User) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(user(u))
+	if err != nil || len(u.Extensions) == 0 {
+		return data, err
+	}
+
+	var obj map[string]json.RawMessage
+	if err = json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+
+	for uri, ext := range u.Extensions {
+		if _, ok := obj[uri]; !ok && isExtension(uri, SchemaUser, SchemaEnterpriseUser) {
+			obj[uri] = ext
+		}
+	}
+	return json.Marshal(obj)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// Schema extensions other than enterprise user extension are stored in Extensions.
+func (u *User) UnmarshalJSON(data []byte) error {
+	var out user
+	if err := json.Unmarshal(data, &out); err != nil {
+		return err
+	}
+
+	exts, err := extensionsFromJSON(data, SchemaUser, SchemaEnterpriseUser)
+	if err != nil {
+		return err
+	}
+
+	out.Extensions = exts
+	*u = User(out)
+	return nil
+}
+
+// extensionsFromJSON returns attributes of resource object which are named by
+// schema URI, except known schemas.
+func extensionsFromJSON(data []byte, known ...string) (Extensions, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+
+	var out Extensions
+	for key, val := range obj {
+		if !isExtension(key, known...) || bytes.Equal(bytes.TrimSpace(val), []byte("null")) {
+			continue
+		}
+
+		if out == nil {
+			out = make(Extensions)
+		}
+		out[key] = val
+	}
+	return out, nil
+}
+
+// isExtension reports whether attribute name is schema URI other than known schemas
+func isExtension(name string, known ...string) bool {
+	if !strings.HasPrefix(strings.ToLower(name), "urn:") {
+		return false
+	}
+
+	for _, uri := range known {
+		if strings.EqualFold(name, uri) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+package filter
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Operator is attribute comparison operator
+type Operator string
+
+// Comparison operators
+const (
+	OpEqual          Operator = "eq"
+	OpNotEqual       Operator = "ne"
+	OpContains       Operator = "co"
+	OpStartsWith     Operator = "sw"
+	OpEndsWith       Operator = "ew"
+	OpPresent        Operator = "pr"
+	OpGreater        Operator = "gt"
+	OpGreaterOrEqual Operator = "ge"
+	OpLess           Operator = "lt"
+	OpLessOrEqual    Operator = "le"
+)
+
+var operators = map[string]Operator{
+	string(OpEqual):          OpEqual,
+	string(OpNotEqual):       OpNotEqual,
+	string(OpContains):       OpContains,
+	string(OpStartsWith):     OpStartsWith,
+	string(OpEndsWith):       OpEndsWith,
+	string(OpPresent):        OpPresent,
+	string(OpGreater):        OpGreater,
+	string(OpGreaterOrEqual): OpGreaterOrEqual,
+	string(OpLess):           OpLess,
+	string(OpLessOrEqual):    OpLessOrEqual,
+}
+
+// LogicalOperator is logical expression operator
+type LogicalOperator string
+
+// Logical operators
+const (
+	OpAnd LogicalOperator = "and"
+	OpOr  LogicalOperator = "or"
+)
+
+// Expression is parsed filter expression.
+//
+// Expression is one of *AttrExpr, *LogicalExpr, *NotExpr or *ValuePathExpr.
+type Expression interface {
+	// String returns filter expression in SCIM filter syntax
+	String() string
+
+	expression()
+}
+
+// AttrPath is attribute path with optional schema URI and sub-attribute.
+//
+// Example: "urn:ietf:params:scim:schemas:core:2.0:User:name.givenName"
+type AttrPath struct {
+	// URI is optional schema URI
+	URI string
+
+	// Name is attribute name
+	Name string
+
+	// SubAttr is optional sub-attribute name
+	SubAttr string
+}
+
+// String implements fmt.Stringer
+func (p AttrPath) String() string {
+	sb := strings.Builder{}
+	if p.URI != "" {
+		sb.WriteString(p.URI)
+		sb.WriteByte(':')
+	}
+
+	sb.WriteString(p.Name)
+	if p.SubAttr != "" {
+		sb.WriteByte('.')
+		sb.WriteString(p.SubAttr)
+	}
+	return sb.String()
+}
+
+// Key returns lower-cased attribute path without schema URI.
+//
+// Attribute names are case-insensitive, so key can be used for attribute lookup.
+func (p AttrPath) Key() string {
+	key := strings.ToLower(p.Name)
+	if p.SubAttr != "" {
+		key += "." + strings.ToLower(p.SubAttr)
+	}
+	return key
+}
+
+// AttrExpr is attribute comparison expression like `userName eq "john"` or `title pr`.
+type AttrExpr struct {
+	Path     AttrPath
+	Operator Operator
+
+	// Value is comparison value, one of string, float64, bool or nil.
+	//
+	// Value is always nil for OpPresent.
+	Value interface{}
+}
+
+func (*AttrExpr) expression() {}
+
+// String implements Expression
+func (e *AttrExpr) String() string {
+	if e.Operator == OpPresent {
+		return e.Path.String() + " " + string(e.Operator)
+	}
+
+	return e.Path.String() + " " + string(e.Operator) + " " + FormatValue(e.Value)
+}
+
+// LogicalExpr is logical "and" or "or" expression
+type LogicalExpr struct {
+	Operator LogicalOperator
+	Left     Expression
+	Right    Expression
+}
+
+func (*LogicalExpr) expression() {}
+
+// String implements Expression
+func (e *LogicalExpr) String() string {
+	return "(" + e.Left.String() + " " + string(e.Operator) + " " + e.Right.String() + ")"
+}
+
+// NotExpr is negated expression
+type NotExpr struct {
+	Expr Expression
+}
+
+func (*NotExpr) expression() {}
+
+// String implements Expression
+func (e *NotExpr) String() string {
+	return "not (" + e.Expr.String() + ")"
+}
+
+// ValuePathExpr is multi-valued attribute filter like `emails[type eq "work"]`.
+//
+// Attribute paths in the filter are relative to the multi-valued attribute.
+type ValuePathExpr struct {
+	Path   AttrPath
+	Filter Expression
+}
+
+func (*ValuePathExpr) expression() {}
+
+// String implements Expression
+func (e *ValuePathExpr) String() string {
+	return e.Path.String() + "[" + e.Filter.String() + "]"
+}
+
+// FormatValue formats comparison value in SCIM filter syntax
+func FormatValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(t)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case string:
+		data, _ := json.Marshal(t)
+		return string(data)
+	default:
+		data, _ := json.Marshal(t)
+		return string(data)
+	}
+}
+
+// And combines expressions with "and" operator.
+//
+// Nil expressions are skipped, returns nil if all expressions are nil.
+func And(exprs ...Expression) Expression {
+	var out Expression
+	for _, e := range exprs {
+		if e == nil {
+			continue
+		}
+
+		if out == nil {
+			out = e
+			continue
+		}
+
+		out = &LogicalExpr{Operator: OpAnd, Left: out, Right: e}
+	}
+	return out
+}
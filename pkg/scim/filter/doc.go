@@ -0,0 +1,11 @@
+// Package filter implements SCIM filter expressions parser.
+//
+// Parser supports filter grammar defined in RFC 7644 section 3.4.2.2:
+// comparison operators (eq, ne, co, sw, ew, gt, ge, lt, le), presence
+// operator (pr), logical operators (and, or, not), grouping with
+// parentheses and value path filters like `emails[type eq "work"]`.
+//
+// Operator precedence from highest to lowest is: not, and, or.
+//
+// Parsed expressions can be evaluated against JSON objects with Matches.
+package filter
@@ -0,0 +1,244 @@
+package filter
+
+import (
+	"strings"
+	"time"
+)
+
+// Matches reports whether resource matches filter expression.
+//
+// Resource is a JSON object decoded to map, like json.Unmarshal does.
+// Attribute names are case-insensitive, strings are compared case-insensitive
+// as most SCIM attributes are not case-exact.
+//
+// Attribute of multi-valued attribute matches if any value matches.
+// Primitive values of multi-valued attributes are accessible as "value" sub-attribute.
+//
+// Nil expression matches any resource.
+func Matches(expr Expression, res map[string]interface{}) bool {
+	switch e := expr.(type) {
+	case nil:
+		return true
+	case *LogicalExpr:
+		if e.Operator == OpAnd {
+			return Matches(e.Left, res) && Matches(e.Right, res)
+		}
+		return Matches(e.Left, res) || Matches(e.Right, res)
+	case *NotExpr:
+		return !Matches(e.Expr, res)
+	case *ValuePathExpr:
+		for _, item := range asList(lookupPath(res, AttrPath{URI: e.Path.URI, Name: e.Path.Name})) {
+			if Matches(e.Filter, asObject(item)) {
+				return true
+			}
+		}
+		return false
+	case *AttrExpr:
+		return matchAttr(e, lookupPath(res, e.Path))
+	default:
+		return false
+	}
+}
+
+// Lookup returns object attribute value by name, ignoring name case.
+func Lookup(obj map[string]interface{}, name string) (key string, val interface{}, ok bool) {
+	if val, ok = obj[name]; ok {
+		return name, val, true
+	}
+
+	for k, v := range obj {
+		if strings.EqualFold(k, name) {
+			return k, v, true
+		}
+	}
+	return "", nil, false
+}
+
+// lookupPath returns attribute value.
+//
+// If attribute is multi-valued and sub-attribute is specified,
+// sub-attribute values of all items are returned.
+func lookupPath(res map[string]interface{}, path AttrPath) interface{} {
+	if path.URI != "" {
+		// extension attributes are stored in object with schema URI key
+		if _, ext, ok := Lookup(res, path.URI); ok {
+			if obj, ok := ext.(map[string]interface{}); ok {
+				res = obj
+			}
+		}
+	}
+
+	_, val, ok := Lookup(res, path.Name)
+	if !ok || path.SubAttr == "" {
+		return val
+	}
+
+	if list, ok := val.([]interface{}); ok {
+		out := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			if _, sub, ok := Lookup(asObject(item), path.SubAttr); ok {
+				out = append(out, sub)
+			}
+		}
+		return out
+	}
+
+	_, sub, _ := Lookup(asObject(val), path.SubAttr)
+	return sub
+}
+
+func matchAttr(e *AttrExpr, val interface{}) bool {
+	if list, ok := val.([]interface{}); ok {
+		if e.Operator == OpNotEqual {
+			return !matchAttr(&AttrExpr{Path: e.Path, Operator: OpEqual, Value: e.Value}, val)
+		}
+
+		for _, item := range list {
+			if matchAttr(e, item) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch e.Operator {
+	case OpPresent:
+		return isPresent(val)
+	case OpEqual:
+		return compare(val, e.Value) == 0
+	case OpNotEqual:
+		return compare(val, e.Value) != 0
+	}
+
+	if val == nil || e.Value == nil {
+		return false
+	}
+
+	switch e.Operator {
+	case OpContains, OpStartsWith, OpEndsWith:
+		s, ok1 := val.(string)
+		sub, ok2 := e.Value.(string)
+		if !ok1 || !ok2 {
+			return false
+		}
+
+		s, sub = strings.ToLower(s), strings.ToLower(sub)
+		switch e.Operator {
+		case OpContains:
+			return strings.Contains(s, sub)
+		case OpStartsWith:
+			return strings.HasPrefix(s, sub)
+		default:
+			return strings.HasSuffix(s, sub)
+		}
+	}
+
+	if _, ok := val.(bool); ok {
+		// booleans can be compared only with eq and ne
+		return false
+	}
+
+	cmp := compare(val, e.Value)
+	switch e.Operator {
+	case OpGreater:
+		return cmp == 1
+	case OpGreaterOrEqual:
+		return cmp == 1 || cmp == 0
+	case OpLess:
+		return cmp == -1
+	case OpLessOrEqual:
+		return cmp == -1 || cmp == 0
+	default:
+		return false
+	}
+}
+
+// incomparable is compare result for values of different types
+const incomparable = 2
+
+// compare compares attribute value with filter value.
+//
+// Returns -1, 0, 1 or incomparable.
+func compare(val, want interface{}) int {
+	if want == nil {
+		if isPresent(val) {
+			return incomparable
+		}
+		return 0
+	}
+
+	switch v := val.(type) {
+	case string:
+		w, ok := want.(string)
+		if !ok {
+			return incomparable
+		}
+
+		t1, err1 := time.Parse(time.RFC3339Nano, v)
+		t2, err2 := time.Parse(time.RFC3339Nano, w)
+		if err1 == nil && err2 == nil {
+			return compareTime(t1, t2)
+		}
+		return strings.Compare(strings.ToLower(v), strings.ToLower(w))
+	case float64:
+		w, ok := want.(float64)
+		if !ok {
+			return incomparable
+		}
+
+		switch {
+		case v < w:
+			return -1
+		case v > w:
+			return 1
+		}
+		return 0
+	case bool:
+		if w, ok := want.(bool); ok && v == w {
+			return 0
+		}
+	}
+	return incomparable
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func isPresent(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+func asList(val interface{}) []interface{} {
+	switch v := val.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	}
+	return []interface{}{val}
+}
+
+// asObject returns object value, primitive values are wrapped to object with "value" attribute.
+func asObject(val interface{}) map[string]interface{} {
+	if obj, ok := val.(map[string]interface{}); ok {
+		return obj
+	}
+	return map[string]interface{}{"value": val}
+}
@@ -0,0 +1,175 @@
+package filter
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokNumber
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokEOF:
+		return "end of filter"
+	case tokWord:
+		return "word"
+	case tokString:
+		return "string"
+	case tokNumber:
+		return "number"
+	case tokLParen:
+		return `"("`
+	case tokRParen:
+		return `")"`
+	case tokLBracket:
+		return `"["`
+	case tokRBracket:
+		return `"]"`
+	default:
+		return "unknown token"
+	}
+}
+
+type token struct {
+	kind tokenKind
+	pos  int
+
+	// text is token source text
+	text string
+
+	// str is decoded string literal value
+	str string
+
+	// num is decoded number literal value
+	num float64
+}
+
+// is reports whether token is a word equal to keyword (case-insensitive)
+func (t token) is(keyword string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, keyword)
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return t.kind.String()
+	}
+	return strconv.Quote(t.text)
+}
+
+// lex splits filter string into tokens
+func lex(src string) ([]token, error) {
+	var out []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			out = append(out, token{kind: tokLParen, pos: i, text: "("})
+			i++
+		case c == ')':
+			out = append(out, token{kind: tokRParen, pos: i, text: ")"})
+			i++
+		case c == '[':
+			out = append(out, token{kind: tokLBracket, pos: i, text: "["})
+			i++
+		case c == ']':
+			out = append(out, token{kind: tokRBracket, pos: i, text: "]"})
+			i++
+		case c == '"':
+			tkn, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, tkn)
+			i += len(tkn.text)
+		case isDigit(c) || (c == '-' && i+1 < len(src) && isDigit(src[i+1])):
+			tkn, err := lexNumber(src, i)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, tkn)
+			i += len(tkn.text)
+		case isWordStart(c):
+			end := i + 1
+			for end < len(src) && isWordChar(src[end]) {
+				end++
+			}
+			out = append(out, token{kind: tokWord, pos: i, text: src[i:end]})
+			i = end
+		default:
+			return nil, syntaxError(i, "unexpected character %q", c)
+		}
+	}
+
+	out = append(out, token{kind: tokEOF, pos: len(src)})
+	return out, nil
+}
+
+func lexString(src string, start int) (token, error) {
+	escaped := false
+	for i := start + 1; i < len(src); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case src[i] == '\\':
+			escaped = true
+		case src[i] == '"':
+			text := src[start : i+1]
+			var str string
+			if err := json.Unmarshal([]byte(text), &str); err != nil {
+				return token{}, syntaxError(start, "invalid string literal")
+			}
+			return token{kind: tokString, pos: start, text: text, str: str}, nil
+		}
+	}
+
+	return token{}, syntaxError(start, "unterminated string literal")
+}
+
+func lexNumber(src string, start int) (token, error) {
+	end := start + 1
+	for end < len(src) && isNumberChar(src[end]) {
+		end++
+	}
+
+	text := src[start:end]
+	num, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return token{}, syntaxError(start, "invalid number %q", text)
+	}
+	return token{kind: tokNumber, pos: start, text: text, num: num}, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isWordStart(c byte) bool {
+	return isAlpha(c) || c == '$' || c == '_'
+}
+
+// isWordChar reports whether character can be a part of attribute path, including schema URI.
+func isWordChar(c byte) bool {
+	return isAlpha(c) || isDigit(c) || c == '_' || c == '-' || c == '$' || c == ':' || c == '.'
+}
+
+func isNumberChar(c byte) bool {
+	return isDigit(c) || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-'
+}
@@ -0,0 +1,275 @@
+package filter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// MaxLength is max allowed filter length
+const MaxLength = 4096
+
+// Error returns SCIM "invalidFilter" error with specified message
+func Error(format string, args ...interface{}) *scim.Error {
+	return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidFilter, format, args...)
+}
+
+func syntaxError(pos int, format string, args ...interface{}) *scim.Error {
+	return Error("invalid filter at position %d: %s", pos+1, fmt.Sprintf(format, args...))
+}
+
+// Parse parses SCIM filter expression.
+//
+// Returns nil expression if filter is empty.
+// Returns *scim.Error with "invalidFilter" type if filter is not valid.
+func Parse(src string) (Expression, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, nil
+	}
+
+	if len(src) > MaxLength {
+		return nil, Error("filter is too long, max length is %d", MaxLength)
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tkn := p.peek(); tkn.kind != tokEOF {
+		return nil, syntaxError(tkn.pos, "unexpected %s", tkn)
+	}
+	return expr, nil
+}
+
+// ParseAttrPath parses attribute path like "name.givenName" or
+// "urn:ietf:params:scim:schemas:core:2.0:User:userName".
+func ParseAttrPath(src string) (AttrPath, error) {
+	return parseAttrPath(src, 0)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+
+	// inValuePath is set when parser is inside of value path filter,
+	// nested value paths are not allowed.
+	inValuePath bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tkn := p.tokens[p.pos]
+	if tkn.kind != tokEOF {
+		p.pos++
+	}
+	return tkn
+}
+
+func (p *parser) expect(kind tokenKind) error {
+	tkn := p.next()
+	if tkn.kind != kind {
+		return syntaxError(tkn.pos, "expected %s but got %s", kind, tkn)
+	}
+	return nil
+}
+
+// parseOr parses "or" expression, which has the lowest precedence
+func (p *parser) parseOr() (Expression, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().is(string(OpOr)) {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &LogicalExpr{Operator: OpOr, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expression, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().is(string(OpAnd)) {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &LogicalExpr{Operator: OpAnd, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expression, error) {
+	if !p.peek().is("not") || p.tokens[p.pos+1].kind != tokLParen {
+		return p.parsePrimary()
+	}
+
+	p.next()
+	expr, err := p.parseGroup()
+	if err != nil {
+		return nil, err
+	}
+	return &NotExpr{Expr: expr}, nil
+}
+
+func (p *parser) parseGroup() (Expression, error) {
+	if err := p.expect(tokLParen); err != nil {
+		return nil, err
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.expect(tokRParen); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+func (p *parser) parsePrimary() (Expression, error) {
+	tkn := p.peek()
+	switch tkn.kind {
+	case tokLParen:
+		return p.parseGroup()
+	case tokWord:
+	default:
+		return nil, syntaxError(tkn.pos, "expected attribute path but got %s", tkn)
+	}
+
+	p.next()
+	path, err := parseAttrPath(tkn.text, tkn.pos)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind == tokLBracket {
+		return p.parseValuePath(path)
+	}
+
+	opTkn := p.next()
+	op, ok := operators[strings.ToLower(opTkn.text)]
+	if opTkn.kind != tokWord || !ok {
+		return nil, syntaxError(opTkn.pos, "expected comparison operator but got %s", opTkn)
+	}
+
+	if op == OpPresent {
+		return &AttrExpr{Path: path, Operator: op}, nil
+	}
+
+	val, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AttrExpr{Path: path, Operator: op, Value: val}, nil
+}
+
+func (p *parser) parseValuePath(path AttrPath) (Expression, error) {
+	tkn := p.next()
+	if p.inValuePath {
+		return nil, syntaxError(tkn.pos, "nested value path filters are not allowed")
+	}
+
+	if path.SubAttr != "" {
+		return nil, syntaxError(tkn.pos, "value path filter can't be applied to sub-attribute %q", path)
+	}
+
+	p.inValuePath = true
+	expr, err := p.parseOr()
+	p.inValuePath = false
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.expect(tokRBracket); err != nil {
+		return nil, err
+	}
+	return &ValuePathExpr{Path: path, Filter: expr}, nil
+}
+
+func (p *parser) parseValue() (interface{}, error) {
+	tkn := p.next()
+	switch tkn.kind {
+	case tokString:
+		return tkn.str, nil
+	case tokNumber:
+		return tkn.num, nil
+	case tokWord:
+		switch {
+		case tkn.is("true"):
+			return true, nil
+		case tkn.is("false"):
+			return false, nil
+		case tkn.is("null"):
+			return nil, nil
+		}
+	}
+
+	return nil, syntaxError(tkn.pos, "expected comparison value but got %s", tkn)
+}
+
+func parseAttrPath(src string, pos int) (AttrPath, error) {
+	var out AttrPath
+	name := src
+	if i := strings.LastIndexByte(src, ':'); i != -1 {
+		out.URI = src[:i]
+		name = src[i+1:]
+	}
+
+	if i := strings.IndexByte(name, '.'); i != -1 {
+		out.SubAttr = name[i+1:]
+		name = name[:i]
+	}
+
+	out.Name = name
+	if !isAttrName(out.Name) || (out.SubAttr != "" && !isAttrName(out.SubAttr)) {
+		return out, syntaxError(pos, "invalid attribute path %q", src)
+	}
+	return out, nil
+}
+
+// isAttrName checks attribute name according to ATTRNAME grammar.
+//
+// "$ref" is allowed as a special case.
+func isAttrName(s string) bool {
+	if s == "$ref" {
+		return true
+	}
+
+	if s == "" || !isAlpha(s[0]) {
+		return false
+	}
+
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if !isAlpha(c) && !isDigit(c) && c != '_' && c != '-' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,176 @@
+package filter
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]struct {
+		src  string
+		want string
+	}{
+		"comparison": {
+			src:  `userName eq "john"`,
+			want: `userName eq "john"`,
+		},
+		"operator is case-insensitive": {
+			src:  `userName EQ "john"`,
+			want: `userName eq "john"`,
+		},
+		"present": {
+			src:  `title pr`,
+			want: `title pr`,
+		},
+		"string operators": {
+			src:  `userName sw "j" and userName ew "n" and userName co "oh"`,
+			want: `((userName sw "j" and userName ew "n") and userName co "oh")`,
+		},
+		"and has precedence over or": {
+			src:  `a eq 1 or b eq 2 and c eq 3`,
+			want: `(a eq 1 or (b eq 2 and c eq 3))`,
+		},
+		"or is left-associative": {
+			src:  `a eq 1 or b eq 2 or c eq 3`,
+			want: `((a eq 1 or b eq 2) or c eq 3)`,
+		},
+		"parentheses": {
+			src:  `(a eq 1 or b eq 2) and c eq 3`,
+			want: `((a eq 1 or b eq 2) and c eq 3)`,
+		},
+		"not": {
+			src:  `not (a eq 1) and b eq 2`,
+			want: `(not (a eq 1) and b eq 2)`,
+		},
+		"not with logical expression": {
+			src:  `not (a eq 1 or b eq 2)`,
+			want: `not ((a eq 1 or b eq 2))`,
+		},
+		"value path": {
+			src:  `emails[type eq "work" and value co "@example.com"]`,
+			want: `emails[(type eq "work" and value co "@example.com")]`,
+		},
+		"value path in logical expression": {
+			src:  `userName eq "john" or emails[primary eq true]`,
+			want: `(userName eq "john" or emails[primary eq true])`,
+		},
+		"sub-attribute": {
+			src:  `name.givenName eq "John"`,
+			want: `name.givenName eq "John"`,
+		},
+		"schema URI": {
+			src:  `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "42"`,
+			want: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber eq "42"`,
+		},
+		"values": {
+			src:  `a eq true and b eq false and c eq null and d gt -1.5 and e le 1e3`,
+			want: `((((a eq true and b eq false) and c eq null) and d gt -1.5) and e le 1000)`,
+		},
+		"string escapes": {
+			src:  `displayName eq "say \"hi\" \\ é"`,
+			want: `displayName eq "say \"hi\" \\ é"`,
+		},
+		"ref attribute": {
+			src:  `members[$ref eq "/Users/1"]`,
+			want: `members[$ref eq "/Users/1"]`,
+		},
+		"whitespace": {
+			src:  "\tuserName  eq\n\"john\" ",
+			want: `userName eq "john"`,
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			expr, err := Parse(c.src)
+			require.NoError(t, err)
+			require.Equal(t, c.want, expr.String())
+
+			// formatted expression is parsed to the same expression
+			again, err := Parse(expr.String())
+			require.NoError(t, err)
+			require.Equal(t, expr, again)
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		expr, err := Parse("  ")
+		require.NoError(t, err)
+		require.Nil(t, expr)
+	})
+
+	t.Run("expression tree", func(t *testing.T) {
+		expr, err := Parse(`not (emails[type eq "work"]) or Meta.LastModified ge "2021-01-01T00:00:00Z"`)
+		require.NoError(t, err)
+		require.Equal(t, &LogicalExpr{
+			Operator: OpOr,
+			Left: &NotExpr{Expr: &ValuePathExpr{
+				Path:   AttrPath{Name: "emails"},
+				Filter: &AttrExpr{Path: AttrPath{Name: "type"}, Operator: OpEqual, Value: "work"},
+			}},
+			Right: &AttrExpr{
+				Path:     AttrPath{Name: "Meta", SubAttr: "LastModified"},
+				Operator: OpGreaterOrEqual,
+				Value:    "2021-01-01T00:00:00Z",
+			},
+		}, expr)
+	})
+}
+
+func TestParse_Invalid(t *testing.T) {
+	cases := map[string]struct {
+		src string
+		msg string
+	}{
+		"missing value":          {src: `userName eq`, msg: "expected comparison value but got end of filter"},
+		"unknown operator":       {src: `userName is "john"`, msg: `expected comparison operator but got "is"`},
+		"missing operator":       {src: `userName`, msg: "expected comparison operator"},
+		"unquoted value":         {src: `userName eq john`, msg: `expected comparison value but got "john"`},
+		"unterminated string":    {src: `userName eq "john`, msg: "position 13: unterminated string literal"},
+		"invalid escape":         {src: `userName eq "\x"`, msg: "invalid string literal"},
+		"unbalanced parens":      {src: `(userName eq "john"`, msg: `expected ")" but got end of filter`},
+		"extra paren":            {src: `userName eq "john")`, msg: `unexpected ")"`},
+		"dangling and":           {src: `userName eq "john" and`, msg: "expected attribute path but got end of filter"},
+		"unexpected character":   {src: `userName eq "john" & title pr`, msg: `unexpected character '&'`},
+		"invalid number":         {src: `age gt 1.2.3`, msg: `invalid number "1.2.3"`},
+		"invalid path":           {src: `name.given.name eq "x"`, msg: `invalid attribute path "name.given.name"`},
+		"path starts with digit": {src: `1abc eq "x"`, msg: "expected attribute path"},
+		"nested value path":      {src: `emails[type[value eq "x"]]`, msg: "nested value path filters are not allowed"},
+		"value path of sub-attribute": {
+			src: `name.givenName[value eq "x"]`,
+			msg: "value path filter can't be applied to sub-attribute",
+		},
+		"unterminated value path": {src: `emails[type eq "work"`, msg: `expected "]" but got end of filter`},
+		"not without parens":      {src: `not userName eq "john"`, msg: `expected comparison operator but got "userName"`},
+		"too long":                {src: `userName eq "` + strings.Repeat("a", MaxLength) + `"`, msg: "filter is too long"},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			_, err := Parse(c.src)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), c.msg)
+			require.True(t, errors.Is(err, scim.ErrInvalidFilter), "invalidFilter error is expected")
+
+			var serr *scim.Error
+			require.True(t, errors.As(err, &serr))
+			require.Equal(t, http.StatusBadRequest, serr.StatusCode)
+		})
+	}
+}
+
+func TestAttrPath_Key(t *testing.T) {
+	path, err := ParseAttrPath("urn:ietf:params:scim:schemas:core:2.0:User:Name.GivenName")
+	require.NoError(t, err)
+	require.Equal(t, "urn:ietf:params:scim:schemas:core:2.0:User", path.URI)
+	require.Equal(t, "name.givenname", path.Key())
+
+	other, err := ParseAttrPath("name.givenName")
+	require.NoError(t, err)
+	require.Equal(t, path.Key(), other.Key(), "attribute names are case-insensitive")
+}
@@ -0,0 +1,55 @@
+package scim
+
+import "context"
+
+// ListGroups returns a page of groups matching list params
+func (c Client) ListGroups(ctx context.Context, p ListParams) (*GroupList, error) {
+	rsp := new(GroupList)
+	return rsp, c.list(ctx, EndpointGroups, p, rsp)
+}
+
+// GetGroup returns group by ID
+func (c Client) GetGroup(ctx context.Context, id string) (*Group, error) {
+	rsp := new(Group)
+	return rsp, c.get(ctx, resourcePath(EndpointGroups, id), rsp)
+}
+
+// CreateGroup creates a new group and returns created resource
+func (c Client) CreateGroup(ctx context.Context, g Group) (*Group, error) {
+	if len(g.Schemas) == 0 {
+		g.Schemas = []string{SchemaGroup}
+	}
+
+	rsp := new(Group)
+	return rsp, c.post(ctx, EndpointGroups, g, rsp)
+}
+
+// ReplaceGroup replaces group with provided ID and returns updated resource
+func (c Client) ReplaceGroup(ctx context.Context, id string, g Group) (*Group, error) {
+	if len(g.Schemas) == 0 {
+		g.Schemas = []string{SchemaGroup}
+	}
+
+	rsp := new(Group)
+	return rsp, c.put(ctx, resourcePath(EndpointGroups, id), g, rsp)
+}
+
+// PatchGroup applies PATCH operations to group.
+//
+// Returns updated resource or nil if server returned no content.
+func (c Client) PatchGroup(ctx context.Context, id string, ops ...PatchOperation) (*Group, error) {
+	rsp := new(Group)
+	if err := c.patch(ctx, resourcePath(EndpointGroups, id), NewPatchRequest(ops...), rsp); err != nil {
+		return nil, err
+	}
+
+	if rsp.ID == "" {
+		return nil, nil
+	}
+	return rsp, nil
+}
+
+// DeleteGroup deletes group by ID
+func (c Client) DeleteGroup(ctx context.Context, id string) error {
+	return c.delete(ctx, resourcePath(EndpointGroups, id))
+}
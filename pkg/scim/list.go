@@ -0,0 +1,119 @@
+package scim
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Sort orders
+const (
+	SortAscending  = "ascending"
+	SortDescending = "descending"
+)
+
+// ListParams is list query parameters.
+//
+// Zero values are omitted from the query.
+type ListParams struct {
+	// Filter is SCIM filter expression
+	Filter string
+
+	// StartIndex is 1-based index of the first result
+	StartIndex int
+
+	// Count is max number of results per page
+	Count int
+
+	// SortBy is attribute name to sort results by
+	SortBy string
+
+	// SortOrder is either SortAscending or SortDescending
+	SortOrder string
+
+	// Attributes is list of attributes to return
+	Attributes []string
+
+	// ExcludedAttributes is list of attributes to exclude from results
+	ExcludedAttributes []string
+
+	// Cursor enables cursor pagination (RFC 9865).
+	//
+	// Should point to an empty string to request the first page.
+	Cursor *string
+}
+
+// Query returns list params as URL query values
+func (p ListParams) Query() url.Values {
+	q := url.Values{}
+	if p.Filter != "" {
+		q.Set("filter", p.Filter)
+	}
+	if p.StartIndex > 0 {
+		q.Set("startIndex", strconv.Itoa(p.StartIndex))
+	}
+	if p.Count > 0 {
+		q.Set("count", strconv.Itoa(p.Count))
+	}
+	if p.SortBy != "" {
+		q.Set("sortBy", p.SortBy)
+	}
+	if p.SortOrder != "" {
+		q.Set("sortOrder", p.SortOrder)
+	}
+	if len(p.Attributes) > 0 {
+		q.Set("attributes", strings.Join(p.Attributes, ","))
+	}
+	if len(p.ExcludedAttributes) > 0 {
+		q.Set("excludedAttributes", strings.Join(p.ExcludedAttributes, ","))
+	}
+	if p.Cursor != nil {
+		q.Set("cursor", *p.Cursor)
+	}
+	return q
+}
+
+// ListResponse is SCIM list response message.
+//
+// Resources contains a slice of returned resources.
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	StartIndex   int         `json:"startIndex,omitempty"`
+	NextCursor   string      `json:"nextCursor,omitempty"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// NewListResponse constructs a new list response
+func NewListResponse(resources interface{}, total, startIndex, itemsPerPage int) *ListResponse {
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		ItemsPerPage: itemsPerPage,
+		StartIndex:   startIndex,
+		Resources:    resources,
+	}
+}
+
+// UserList is users list response
+type UserList struct {
+	ListResponse
+	Resources []User `json:"Resources"`
+}
+
+// GroupList is groups list response
+type GroupList struct {
+	ListResponse
+	Resources []Group `json:"Resources"`
+}
+
+// HasMore reports whether there are more results after the current page
+func (l ListResponse) HasMore(pageLen int) bool {
+	startIndex := l.StartIndex
+	if startIndex < 1 {
+		startIndex = 1
+	}
+
+	return pageLen > 0 && startIndex-1+pageLen < l.TotalResults
+}
@@ -0,0 +1,29 @@
+package scim
+
+// Patch operation types
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+)
+
+// PatchOperation is a single PATCH operation
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// PatchRequest is SCIM PATCH request message
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// NewPatchRequest constructs a new PATCH request from operations list
+func NewPatchRequest(ops ...PatchOperation) *PatchRequest {
+	return &PatchRequest{
+		Schemas:    []string{SchemaPatchOp},
+		Operations: ops,
+	}
+}
@@ -0,0 +1,10 @@
+// Package patch implements SCIM PATCH operations defined in RFC 7644 section 3.5.2.
+//
+// Operations are applied to a resource represented as JSON object
+// (map[string]interface{}). Supported operations are "add", "remove" and
+// "replace" with attribute paths like "name.givenName", schema-qualified
+// paths and value filters like `members[value eq "2819c223"]`.
+//
+// Failed operations are reported as *scim.Error with corresponding
+// scimType (invalidPath, noTarget, invalidValue or mutability).
+package patch
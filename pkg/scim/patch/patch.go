@@ -0,0 +1,600 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+)
+
+// Patcher applies PATCH operations to resources of a single resource type.
+type Patcher struct {
+	// Schema is resource core schema URI.
+	//
+	// Paths qualified with other schema URIs address extension attributes.
+	Schema string
+
+	// ReadOnly is list of attributes which can't be modified.
+	//
+	// Sub-attributes are listed with parent attribute name, like "meta.created".
+	ReadOnly []string
+
+	// Immutable is list of attributes which can be modified only if they have no value.
+	//
+	// Sub-attributes are listed with parent attribute name, like "members.value".
+	// Values of multi-valued attribute with immutable sub-attributes can be added
+	// and removed, but sub-attributes of existing values can't be changed.
+	Immutable []string
+
+	// MultiValued is list of multi-valued attributes.
+	//
+	// Single values added to multi-valued attributes are appended to a list.
+	MultiValued []string
+}
+
+// ValidateRequest checks PATCH request message schema and operations.
+func ValidateRequest(req scim.PatchRequest) error {
+	if !containsFold(req.Schemas, scim.SchemaPatchOp) {
+		return errInvalidSyntax("PATCH request must contain %q schema", scim.SchemaPatchOp)
+	}
+
+	if len(req.Operations) == 0 {
+		return errInvalidValue("PATCH request contains no operations")
+	}
+
+	for i, op := range req.Operations {
+		switch strings.ToLower(op.Op) {
+		case scim.PatchAdd, scim.PatchReplace:
+		case scim.PatchRemove:
+			if op.Path == "" {
+				return withOperation(errNoTarget("path is required for remove operation"), i, op)
+			}
+		default:
+			return withOperation(errInvalidSyntax("unsupported operation %q", op.Op), i, op)
+		}
+	}
+	return nil
+}
+
+// Apply applies operations to resource in order.
+//
+// Resource is modified in place. Operations are not applied atomically,
+// resource should be discarded if error is returned.
+func (p Patcher) Apply(res map[string]interface{}, ops []scim.PatchOperation) error {
+	for i, op := range ops {
+		if err := p.apply(res, op); err != nil {
+			return withOperation(err, i, op)
+		}
+	}
+	return nil
+}
+
+// ApplyTo applies operations to resource struct like scim.User.
+//
+// Resource is converted to JSON object, patched and decoded back.
+// Value v is not changed if any operation failed.
+func (p Patcher) ApplyTo(v interface{}, ops []scim.PatchOperation) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	res := make(map[string]interface{})
+	if err = json.Unmarshal(data, &res); err != nil {
+		return err
+	}
+
+	if err = p.Apply(res, ops); err != nil {
+		return err
+	}
+
+	if data, err = json.Marshal(res); err != nil {
+		return err
+	}
+
+	out := reflect.New(reflect.TypeOf(v).Elem())
+	if err = json.Unmarshal(data, out.Interface()); err != nil {
+		return errInvalidValue("patched resource is invalid: %s", err)
+	}
+
+	reflect.ValueOf(v).Elem().Set(out.Elem())
+	return nil
+}
+
+func (p Patcher) apply(res map[string]interface{}, op scim.PatchOperation) error {
+	name := strings.ToLower(op.Op)
+	value, err := normalize(op.Value)
+	if err != nil {
+		return errInvalidValue("invalid value: %s", err)
+	}
+
+	if op.Path != "" {
+		path, err := ParsePath(op.Path)
+		if err != nil {
+			return err
+		}
+		return p.applyPath(res, name, *path, value)
+	}
+
+	if name == scim.PatchRemove {
+		return errNoTarget("path is required for remove operation")
+	}
+
+	// without path, value contains attributes to add or replace
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return errInvalidValue("value must be an object if path is not specified")
+	}
+
+	for key, val := range obj {
+		if ext, ok := val.(map[string]interface{}); ok && isSchemaURI(key) && !strings.EqualFold(key, p.Schema) {
+			for subKey, subVal := range ext {
+				path := Path{Attr: filter.AttrPath{URI: key, Name: subKey}}
+				if err := p.applyPath(res, name, path, subVal); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		attr, err := filter.ParseAttrPath(key)
+		if err != nil {
+			return errInvalidPath("invalid attribute name %q", key)
+		}
+
+		if err = p.applyPath(res, name, Path{Attr: attr}, val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p Patcher) applyPath(res map[string]interface{}, op string, path Path, value interface{}) error {
+	if op != scim.PatchRemove && value == nil {
+		if op == scim.PatchAdd {
+			return errInvalidValue("value is required for %s operation", op)
+		}
+
+		// replacing with null is equal to removal
+		op = scim.PatchRemove
+	}
+
+	obj := res
+	if path.Attr.URI != "" && !strings.EqualFold(path.Attr.URI, p.Schema) {
+		ext, err := p.extension(res, path.Attr.URI, op != scim.PatchRemove)
+		if err != nil || ext == nil {
+			return err
+		}
+		obj = ext
+	} else if containsFold(p.ReadOnly, path.Attr.Name) {
+		return errMutability("attribute %q is read-only", path.Attr.Name)
+	} else if _, cur, ok := filter.Lookup(obj, path.Attr.Name); ok && cur != nil && containsFold(p.Immutable, path.Attr.Name) {
+		return errMutability("attribute %q is immutable", path.Attr.Name)
+	} else if err := p.checkSubAttrs(obj, op, path, value); err != nil {
+		return err
+	}
+
+	switch {
+	case path.Filter != nil:
+		return p.applyFiltered(obj, op, path, value)
+	case path.Attr.SubAttr != "":
+		return applySubAttr(obj, op, path.Attr, value)
+	default:
+		return p.applyAttr(obj, op, path.Attr.Name, value)
+	}
+}
+
+// checkSubAttrs checks mutability of sub-attributes modified by operation.
+func (p Patcher) checkSubAttrs(obj map[string]interface{}, op string, path Path, value interface{}) error {
+	subAttr := path.Attr.SubAttr
+	if subAttr == "" {
+		subAttr = path.SubAttr
+	}
+
+	if subAttr != "" {
+		name := path.Attr.Name + "." + subAttr
+		if containsFold(p.ReadOnly, name) {
+			return errMutability("attribute %q is read-only", name)
+		}
+		if !containsFold(p.Immutable, name) {
+			return nil
+		}
+
+		for _, item := range targetValues(obj, path) {
+			if _, cur, ok := filter.Lookup(item, subAttr); ok && cur != nil {
+				return errMutability("attribute %q is immutable", name)
+			}
+		}
+		return nil
+	}
+
+	immutable := p.immutableSubAttrs(path.Attr.Name)
+	if op == scim.PatchRemove || len(immutable) == 0 {
+		return nil
+	}
+
+	_, current, _ := filter.Lookup(obj, path.Attr.Name)
+	if _, isList := current.([]interface{}); path.Filter == nil && (isList || containsFold(p.MultiValued, path.Attr.Name)) {
+		// values of multi-valued attribute are added or replaced as a whole
+		return nil
+	}
+
+	valueObj, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	// filtered value is replaced, otherwise value is merged into existing one
+	replaced := op == scim.PatchReplace && path.Filter != nil
+	for _, item := range targetValues(obj, path) {
+		for _, subAttr := range immutable {
+			_, cur, ok := filter.Lookup(item, subAttr)
+			if !ok || cur == nil {
+				continue
+			}
+
+			_, val, ok := filter.Lookup(valueObj, subAttr)
+			if (ok && !reflect.DeepEqual(cur, val)) || (!ok && replaced) {
+				return errMutability("attribute %q is immutable", path.Attr.Name+"."+subAttr)
+			}
+		}
+	}
+	return nil
+}
+
+// immutableSubAttrs returns names of immutable sub-attributes of attribute
+func (p Patcher) immutableSubAttrs(name string) []string {
+	var out []string
+	for _, item := range p.Immutable {
+		if parent, subAttr := splitAttrName(item); subAttr != "" && strings.EqualFold(parent, name) {
+			out = append(out, subAttr)
+		}
+	}
+	return out
+}
+
+// extension returns extension attributes object.
+//
+// If create is set, missing extension is created and its URI is added to resource schemas.
+func (p Patcher) extension(res map[string]interface{}, uri string, create bool) (map[string]interface{}, error) {
+	_, val, ok := filter.Lookup(res, uri)
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+
+		ext := make(map[string]interface{})
+		res[uri] = ext
+		p.addSchema(res, uri)
+		return ext, nil
+	}
+
+	ext, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, errInvalidPath("schema extension %q is not an object", uri)
+	}
+	return ext, nil
+}
+
+func (p Patcher) addSchema(res map[string]interface{}, uri string) {
+	key, val, ok := filter.Lookup(res, "schemas")
+	if !ok {
+		key = "schemas"
+	}
+
+	schemas, _ := val.([]interface{})
+	for _, s := range schemas {
+		if str, ok := s.(string); ok && strings.EqualFold(str, uri) {
+			return
+		}
+	}
+	res[key] = append(schemas, uri)
+}
+
+func (p Patcher) applyAttr(obj map[string]interface{}, op, name string, value interface{}) error {
+	key, current, exists := filter.Lookup(obj, name)
+	if !exists {
+		key = name
+	}
+
+	list, isList := current.([]interface{})
+	multiValued := isList || containsFold(p.MultiValued, name)
+	switch op {
+	case scim.PatchRemove:
+		if value != nil && multiValued {
+			// non-standard but widely used form of values removal,
+			// like {"op": "remove", "path": "members", "value": [{"value": "2819c223"}]}
+			list = removeValues(list, asList(value))
+			setList(obj, key, list)
+			return nil
+		}
+
+		delete(obj, key)
+		return nil
+	case scim.PatchAdd:
+		if multiValued {
+			setList(obj, key, addValues(list, asList(value)))
+			return nil
+		}
+
+		fallthrough
+	case scim.PatchReplace:
+		if multiValued {
+			setList(obj, key, asList(value))
+			return nil
+		}
+
+		// sub-attributes of complex attribute which are not specified are left unchanged
+		currentObj, ok1 := current.(map[string]interface{})
+		valueObj, ok2 := value.(map[string]interface{})
+		if ok1 && ok2 {
+			mergeObject(currentObj, valueObj)
+			return nil
+		}
+
+		obj[key] = value
+		return nil
+	}
+	return nil
+}
+
+// applySubAttr applies operation to sub-attribute of complex attribute.
+//
+// If attribute is multi-valued, sub-attributes of all values are modified.
+func applySubAttr(obj map[string]interface{}, op string, attr filter.AttrPath, value interface{}) error {
+	key, current, exists := filter.Lookup(obj, attr.Name)
+	if !exists {
+		if op == scim.PatchRemove {
+			return nil
+		}
+
+		key, current = attr.Name, make(map[string]interface{})
+		obj[key] = current
+	}
+
+	switch t := current.(type) {
+	case map[string]interface{}:
+		setSubAttr(t, op, attr.SubAttr, value)
+	case []interface{}:
+		for _, item := range t {
+			if itemObj, ok := item.(map[string]interface{}); ok {
+				setSubAttr(itemObj, op, attr.SubAttr, value)
+			}
+		}
+	default:
+		return errInvalidPath("attribute %q is not complex", attr.Name)
+	}
+	return nil
+}
+
+func (p Patcher) applyFiltered(obj map[string]interface{}, op string, path Path, value interface{}) error {
+	key, current, exists := filter.Lookup(obj, path.Attr.Name)
+	list, ok := current.([]interface{})
+	if exists && !ok {
+		return errInvalidPath("attribute %q is not multi-valued", path.Attr.Name)
+	}
+
+	matched := 0
+	out := list[:0:0]
+	for _, item := range list {
+		itemObj, isObj := item.(map[string]interface{})
+		if !filter.Matches(path.Filter, asObject(item)) {
+			out = append(out, item)
+			continue
+		}
+
+		matched++
+		switch {
+		case op == scim.PatchRemove && path.SubAttr == "":
+			// value is removed
+			continue
+		case path.SubAttr == "" && op == scim.PatchReplace:
+			item = value
+		case path.SubAttr == "":
+			valueObj, ok := value.(map[string]interface{})
+			if !isObj || !ok {
+				return errInvalidValue("value of %q must be an object", path)
+			}
+			mergeObject(itemObj, valueObj)
+		case isObj:
+			setSubAttr(itemObj, op, path.SubAttr, value)
+		case strings.EqualFold(path.SubAttr, "value"):
+			// primitive values are addressed by "value" sub-attribute
+			if op == scim.PatchRemove {
+				continue
+			}
+			item = value
+		default:
+			return errInvalidPath("attribute %q values have no sub-attributes", path.Attr.Name)
+		}
+		out = append(out, item)
+	}
+
+	if matched == 0 && op != scim.PatchRemove {
+		return errNoTarget("no values of %q match filter", path.Attr.String())
+	}
+
+	setList(obj, key, out)
+	return nil
+}
+
+// targetValues returns existing complex values addressed by path
+func targetValues(obj map[string]interface{}, path Path) []map[string]interface{} {
+	_, current, _ := filter.Lookup(obj, path.Attr.Name)
+	switch t := current.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{t}
+	case []interface{}:
+		out := make([]map[string]interface{}, 0, len(t))
+		for _, item := range t {
+			itemObj, ok := item.(map[string]interface{})
+			if ok && (path.Filter == nil || filter.Matches(path.Filter, itemObj)) {
+				out = append(out, itemObj)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func setSubAttr(obj map[string]interface{}, op, name string, value interface{}) {
+	key, current, exists := filter.Lookup(obj, name)
+	if !exists {
+		key = name
+	}
+
+	switch op {
+	case scim.PatchRemove:
+		delete(obj, key)
+	case scim.PatchAdd:
+		if list, ok := current.([]interface{}); ok {
+			obj[key] = addValues(list, asList(value))
+			return
+		}
+		obj[key] = value
+	default:
+		obj[key] = value
+	}
+}
+
+// setList sets multi-valued attribute, empty attribute is removed.
+func setList(obj map[string]interface{}, key string, list []interface{}) {
+	if len(list) == 0 {
+		delete(obj, key)
+		return
+	}
+	obj[key] = list
+}
+
+// addValues appends values to multi-valued attribute.
+//
+// Value with the same "value" sub-attribute as existing one replaces it.
+func addValues(list, values []interface{}) []interface{} {
+	out := append(make([]interface{}, 0, len(list)+len(values)), list...)
+	for _, v := range values {
+		if i := indexOfValue(out, v); i != -1 {
+			out[i] = v
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// removeValues removes values from multi-valued attribute.
+func removeValues(list, values []interface{}) []interface{} {
+	out := list[:0:0]
+	for _, item := range list {
+		if indexOfValue(values, item) == -1 {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// indexOfValue returns index of equal value in the list.
+//
+// Complex values are equal if they have the same "value" sub-attribute.
+func indexOfValue(list []interface{}, v interface{}) int {
+	id, hasID := valueID(v)
+	for i, item := range list {
+		if itemID, ok := valueID(item); hasID && ok {
+			if itemID == id {
+				return i
+			}
+			continue
+		}
+
+		if reflect.DeepEqual(item, v) {
+			return i
+		}
+	}
+	return -1
+}
+
+func valueID(v interface{}) (interface{}, bool) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	_, id, ok := filter.Lookup(obj, "value")
+	return id, ok && id != nil
+}
+
+func mergeObject(dst, src map[string]interface{}) {
+	for k, v := range src {
+		key, _, ok := filter.Lookup(dst, k)
+		if !ok {
+			key = k
+		}
+		dst[key] = v
+	}
+}
+
+// normalize converts value to generic JSON representation
+func normalize(v interface{}) (interface{}, error) {
+	switch v.(type) {
+	case nil, string, float64, bool, map[string]interface{}, []interface{}:
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out interface{}
+	return out, json.Unmarshal(data, &out)
+}
+
+func asList(v interface{}) []interface{} {
+	if list, ok := v.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{v}
+}
+
+func asObject(v interface{}) map[string]interface{} {
+	if obj, ok := v.(map[string]interface{}); ok {
+		return obj
+	}
+	return map[string]interface{}{"value": v}
+}
+
+func splitAttrName(name string) (string, string) {
+	if i := strings.IndexByte(name, '.'); i != -1 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+func isSchemaURI(s string) bool {
+	return strings.HasPrefix(strings.ToLower(s), "urn:")
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// withOperation adds operation position to error message
+func withOperation(err error, i int, op scim.PatchOperation) error {
+	scimErr, ok := err.(*scim.Error)
+	if !ok {
+		return err
+	}
+
+	return &scim.Error{
+		StatusCode: scimErr.StatusCode,
+		ScimType:   scimErr.ScimType,
+		Detail:     fmt.Sprintf("operation %d (%s %q): %s", i+1, op.Op, op.Path, scimErr.Detail),
+	}
+}
@@ -0,0 +1,554 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+var testPatcher = Patcher{
+	Schema:      scim.SchemaUser,
+	ReadOnly:    []string{"id", "meta", "members.display"},
+	Immutable:   []string{"externalId", "members.value"},
+	MultiValued: []string{"emails", "members", "roles", "phoneNumbers"},
+}
+
+const testResource = `{
+	"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+	"id": "1",
+	"userName": "john",
+	"name": {"givenName": "John", "familyName": "Doe"},
+	"emails": [
+		{"value": "john@work.example.com", "type": "work", "primary": true},
+		{"value": "john@home.example.com", "type": "home"}
+	],
+	"roles": ["admin", "user"],
+	"members": [{"value": "10", "display": "Jane"}]
+}`
+
+func TestPatcher_Apply(t *testing.T) {
+	cases := map[string]struct {
+		ops  []scim.PatchOperation
+		want string
+	}{
+		"add attribute": {
+			ops: []scim.PatchOperation{{Op: "add", Path: "title", Value: "Engineer"}},
+			want: `{
+				"title": "Engineer"
+			}`,
+		},
+		"add sub-attribute of nonexistent attribute": {
+			ops: []scim.PatchOperation{{Op: "add", Path: "address.locality", Value: "Berlin"}},
+			want: `{
+				"address": {"locality": "Berlin"}
+			}`,
+		},
+		"add value to nonexistent multi-valued attribute": {
+			ops: []scim.PatchOperation{{Op: "add", Path: "phoneNumbers", Value: map[string]interface{}{"value": "555"}}},
+			want: `{
+				"phoneNumbers": [{"value": "555"}]
+			}`,
+		},
+		"add immutable attribute without value": {
+			ops: []scim.PatchOperation{{Op: "add", Path: "externalId", Value: "ext"}},
+			want: `{
+				"externalId": "ext"
+			}`,
+		},
+		"add value to multi-valued attribute": {
+			ops: []scim.PatchOperation{{Op: "Add", Path: "emails", Value: []interface{}{
+				map[string]interface{}{"value": "john@other.example.com"},
+			}}},
+			want: `{
+				"emails": [
+					{"value": "john@work.example.com", "type": "work", "primary": true},
+					{"value": "john@home.example.com", "type": "home"},
+					{"value": "john@other.example.com"}
+				]
+			}`,
+		},
+		"add existing value to multi-valued attribute": {
+			ops: []scim.PatchOperation{{Op: "add", Path: "emails", Value: map[string]interface{}{
+				"value": "john@home.example.com", "type": "other",
+			}}},
+			want: `{
+				"emails": [
+					{"value": "john@work.example.com", "type": "work", "primary": true},
+					{"value": "john@home.example.com", "type": "other"}
+				]
+			}`,
+		},
+		"add without path": {
+			ops: []scim.PatchOperation{{Op: "add", Value: map[string]interface{}{
+				"nickName": "Johnny",
+				"name":     map[string]interface{}{"middleName": "M"},
+				"roles":    "guest",
+			}}},
+			want: `{
+				"nickName": "Johnny",
+				"name": {"givenName": "John", "familyName": "Doe", "middleName": "M"},
+				"roles": ["admin", "user", "guest"]
+			}`,
+		},
+		"add struct value": {
+			ops: []scim.PatchOperation{{Op: "add", Path: "members", Value: []scim.Reference{{Value: "11"}}}},
+			want: `{
+				"members": [{"value": "10", "display": "Jane"}, {"value": "11"}]
+			}`,
+		},
+		"replace attribute": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: "userName", Value: "johnny"}},
+			want: `{
+				"userName": "johnny"
+			}`,
+		},
+		"replace attribute in other case": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: "USERNAME", Value: "johnny"}},
+			want: `{
+				"userName": "johnny"
+			}`,
+		},
+		"replace attribute with core schema URI": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: "urn:ietf:params:scim:schemas:core:2.0:User:userName", Value: "johnny"}},
+			want: `{
+				"userName": "johnny"
+			}`,
+		},
+		"replace complex attribute merges sub-attributes": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: "name", Value: map[string]interface{}{"givenName": "Johnny"}}},
+			want: `{
+				"name": {"givenName": "Johnny", "familyName": "Doe"}
+			}`,
+		},
+		"replace sub-attribute": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: "name.familyName", Value: "Smith"}},
+			want: `{
+				"name": {"givenName": "John", "familyName": "Smith"}
+			}`,
+		},
+		"replace sub-attribute of all values": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: "emails.primary", Value: false}},
+			want: `{
+				"emails": [
+					{"value": "john@work.example.com", "type": "work", "primary": false},
+					{"value": "john@home.example.com", "type": "home", "primary": false}
+				]
+			}`,
+		},
+		"replace multi-valued attribute": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: "roles", Value: []interface{}{"guest"}}},
+			want: `{
+				"roles": ["guest"]
+			}`,
+		},
+		"replace multi-valued attribute with immutable sub-attributes": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: "members", Value: []interface{}{
+				map[string]interface{}{"value": "11"},
+			}}},
+			want: `{
+				"members": [{"value": "11"}]
+			}`,
+		},
+		"replace without path": {
+			ops: []scim.PatchOperation{{Op: "replace", Value: map[string]interface{}{
+				"userName": "johnny",
+				"roles":    []interface{}{"guest"},
+			}}},
+			want: `{
+				"userName": "johnny",
+				"roles": ["guest"]
+			}`,
+		},
+		"replace with null removes attribute": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: "name", Value: nil}},
+			want: `{
+				"name": null
+			}`,
+		},
+		"replace filtered value": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: `emails[type eq "home"]`, Value: map[string]interface{}{
+				"value": "john@new.example.com", "type": "home",
+			}}},
+			want: `{
+				"emails": [
+					{"value": "john@work.example.com", "type": "work", "primary": true},
+					{"value": "john@new.example.com", "type": "home"}
+				]
+			}`,
+		},
+		"add to filtered value merges sub-attributes": {
+			ops: []scim.PatchOperation{{Op: "add", Path: `emails[type eq "home"]`, Value: map[string]interface{}{
+				"display": "Home",
+			}}},
+			want: `{
+				"emails": [
+					{"value": "john@work.example.com", "type": "work", "primary": true},
+					{"value": "john@home.example.com", "type": "home", "display": "Home"}
+				]
+			}`,
+		},
+		"replace filtered sub-attribute": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: `emails[type eq "work" and primary eq true].value`, Value: "j@work.example.com"}},
+			want: `{
+				"emails": [
+					{"value": "j@work.example.com", "type": "work", "primary": true},
+					{"value": "john@home.example.com", "type": "home"}
+				]
+			}`,
+		},
+		"replace filtered sub-attribute of primitive values": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: `roles[value eq "user"].value`, Value: "guest"}},
+			want: `{
+				"roles": ["admin", "guest"]
+			}`,
+		},
+		"remove filtered sub-attribute of primitive values": {
+			ops: []scim.PatchOperation{{Op: "remove", Path: `roles[value eq "admin"].value`}},
+			want: `{
+				"roles": ["user"]
+			}`,
+		},
+		"remove filtered primitive values": {
+			ops: []scim.PatchOperation{{Op: "remove", Path: `roles[value sw "a" or value sw "u"]`}},
+			want: `{
+				"roles": null
+			}`,
+		},
+		"remove attribute": {
+			ops: []scim.PatchOperation{{Op: "remove", Path: "name"}},
+			want: `{
+				"name": null
+			}`,
+		},
+		"remove sub-attribute": {
+			ops: []scim.PatchOperation{{Op: "remove", Path: "name.familyName"}},
+			want: `{
+				"name": {"givenName": "John"}
+			}`,
+		},
+		"remove filtered value": {
+			ops: []scim.PatchOperation{{Op: "remove", Path: `emails[type eq "home"]`}},
+			want: `{
+				"emails": [{"value": "john@work.example.com", "type": "work", "primary": true}]
+			}`,
+		},
+		"remove filtered sub-attribute": {
+			ops: []scim.PatchOperation{{Op: "remove", Path: `emails[type eq "work"].primary`}},
+			want: `{
+				"emails": [
+					{"value": "john@work.example.com", "type": "work"},
+					{"value": "john@home.example.com", "type": "home"}
+				]
+			}`,
+		},
+		"remove last value removes attribute": {
+			ops: []scim.PatchOperation{{Op: "remove", Path: `members[value eq "10"]`}},
+			want: `{
+				"members": null
+			}`,
+		},
+		"remove with filter matching nothing": {
+			ops:  []scim.PatchOperation{{Op: "remove", Path: `emails[type eq "other"]`}},
+			want: `{}`,
+		},
+		"remove nonexistent attribute": {
+			ops:  []scim.PatchOperation{{Op: "remove", Path: "nickName"}},
+			want: `{}`,
+		},
+		"remove complex values by value": {
+			ops: []scim.PatchOperation{{Op: "remove", Path: "emails", Value: []interface{}{
+				map[string]interface{}{"value": "john@home.example.com"},
+			}}},
+			want: `{
+				"emails": [{"value": "john@work.example.com", "type": "work", "primary": true}]
+			}`,
+		},
+		"remove primitive values by value": {
+			ops: []scim.PatchOperation{{Op: "remove", Path: "roles", Value: []interface{}{"user", "guest"}}},
+			want: `{
+				"roles": ["admin"]
+			}`,
+		},
+		"add extension attribute": {
+			ops: []scim.PatchOperation{{
+				Op:    "add",
+				Path:  "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber",
+				Value: "42",
+			}},
+			want: `{
+				"schemas": [
+					"urn:ietf:params:scim:schemas:core:2.0:User",
+					"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+				],
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "42"}
+			}`,
+		},
+		"merge extension attributes": {
+			ops: []scim.PatchOperation{
+				{
+					Op:    "add",
+					Path:  "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value",
+					Value: "10",
+				},
+				{Op: "replace", Value: map[string]interface{}{
+					"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{
+						"department": "IT",
+						"manager":    map[string]interface{}{"displayName": "Jane"},
+					},
+				}},
+			},
+			want: `{
+				"schemas": [
+					"urn:ietf:params:scim:schemas:core:2.0:User",
+					"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+				],
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+					"department": "IT",
+					"manager": {"value": "10", "displayName": "Jane"}
+				}
+			}`,
+		},
+		"remove attribute of missing extension": {
+			ops: []scim.PatchOperation{{
+				Op:   "remove",
+				Path: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber",
+			}},
+			want: `{}`,
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			res := decodeObject(t, testResource)
+			require.NoError(t, testPatcher.Apply(res, c.ops))
+
+			// expected resource is test resource with changed attributes, null removes attribute
+			want := decodeObject(t, testResource)
+			for k, v := range decodeObject(t, c.want) {
+				if v == nil {
+					delete(want, k)
+					continue
+				}
+				want[k] = v
+			}
+			require.Equal(t, want, res)
+		})
+	}
+}
+
+func TestPatcher_Apply_Error(t *testing.T) {
+	cases := map[string]struct {
+		ops      []scim.PatchOperation
+		scimType string
+		msg      string
+	}{
+		"read-only attribute": {
+			ops:      []scim.PatchOperation{{Op: "replace", Path: "id", Value: "2"}},
+			scimType: scim.ErrTypeMutability,
+			msg:      `operation 1 (replace "id"): attribute "id" is read-only`,
+		},
+		"read-only attribute without path": {
+			ops:      []scim.PatchOperation{{Op: "replace", Value: map[string]interface{}{"meta": map[string]interface{}{}}}},
+			scimType: scim.ErrTypeMutability,
+			msg:      `attribute "meta" is read-only`,
+		},
+		"read-only sub-attribute": {
+			ops:      []scim.PatchOperation{{Op: "replace", Path: `members[value eq "10"].display`, Value: "Joan"}},
+			scimType: scim.ErrTypeMutability,
+			msg:      `attribute "members.display" is read-only`,
+		},
+		"immutable attribute with value": {
+			ops: []scim.PatchOperation{
+				{Op: "add", Path: "externalId", Value: "ext"},
+				{Op: "replace", Path: "externalId", Value: "other"},
+			},
+			scimType: scim.ErrTypeMutability,
+			msg:      `operation 2 (replace "externalId"): attribute "externalId" is immutable`,
+		},
+		"immutable sub-attribute": {
+			ops:      []scim.PatchOperation{{Op: "replace", Path: `members[value eq "10"].value`, Value: "11"}},
+			scimType: scim.ErrTypeMutability,
+			msg:      `attribute "members.value" is immutable`,
+		},
+		"immutable sub-attribute of replaced value": {
+			ops: []scim.PatchOperation{{Op: "replace", Path: `members[value eq "10"]`, Value: map[string]interface{}{
+				"display": "Joan",
+			}}},
+			scimType: scim.ErrTypeMutability,
+			msg:      `attribute "members.value" is immutable`,
+		},
+		"immutable sub-attribute of merged value": {
+			ops: []scim.PatchOperation{{Op: "add", Path: `members[value eq "10"]`, Value: map[string]interface{}{
+				"value": "11",
+			}}},
+			scimType: scim.ErrTypeMutability,
+			msg:      `attribute "members.value" is immutable`,
+		},
+		"replace with filter matching nothing": {
+			ops:      []scim.PatchOperation{{Op: "replace", Path: `emails[type eq "other"].value`, Value: "x"}},
+			scimType: scim.ErrTypeNoTarget,
+			msg:      `no values of "emails" match filter`,
+		},
+		"add to filter matching nothing": {
+			ops:      []scim.PatchOperation{{Op: "add", Path: `phoneNumbers[type eq "work"]`, Value: map[string]interface{}{"value": "555"}}},
+			scimType: scim.ErrTypeNoTarget,
+			msg:      `no values of "phoneNumbers" match filter`,
+		},
+		"remove without path": {
+			ops:      []scim.PatchOperation{{Op: "remove"}},
+			scimType: scim.ErrTypeNoTarget,
+			msg:      "path is required for remove operation",
+		},
+		"add null": {
+			ops:      []scim.PatchOperation{{Op: "add", Path: "title"}},
+			scimType: scim.ErrTypeInvalidValue,
+			msg:      "value is required for add operation",
+		},
+		"value without path isn't object": {
+			ops:      []scim.PatchOperation{{Op: "replace", Value: "john"}},
+			scimType: scim.ErrTypeInvalidValue,
+			msg:      "value must be an object if path is not specified",
+		},
+		"filtered value isn't object": {
+			ops:      []scim.PatchOperation{{Op: "add", Path: `emails[type eq "work"]`, Value: "x"}},
+			scimType: scim.ErrTypeInvalidValue,
+			msg:      `value of "emails[type eq \"work\"]" must be an object`,
+		},
+		"invalid path": {
+			ops:      []scim.PatchOperation{{Op: "replace", Path: `emails[type eq]`, Value: "x"}},
+			scimType: scim.ErrTypeInvalidPath,
+			msg:      "invalid value filter in path",
+		},
+		"invalid attribute name without path": {
+			ops:      []scim.PatchOperation{{Op: "add", Value: map[string]interface{}{"a.b.c": "x"}}},
+			scimType: scim.ErrTypeInvalidPath,
+			msg:      `invalid attribute name "a.b.c"`,
+		},
+		"filter of single-valued attribute": {
+			ops:      []scim.PatchOperation{{Op: "replace", Path: `userName[value eq "john"]`, Value: "x"}},
+			scimType: scim.ErrTypeInvalidPath,
+			msg:      `attribute "userName" is not multi-valued`,
+		},
+		"sub-attribute of simple attribute": {
+			ops:      []scim.PatchOperation{{Op: "replace", Path: "userName.first", Value: "x"}},
+			scimType: scim.ErrTypeInvalidPath,
+			msg:      `attribute "userName" is not complex`,
+		},
+		"other sub-attribute of primitive values": {
+			ops:      []scim.PatchOperation{{Op: "replace", Path: `roles[value eq "admin"].display`, Value: "x"}},
+			scimType: scim.ErrTypeInvalidPath,
+			msg:      `attribute "roles" values have no sub-attributes`,
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			err := testPatcher.Apply(decodeObject(t, testResource), c.ops)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), c.msg)
+			require.True(t, errors.Is(err, &scim.Error{ScimType: c.scimType}), "%s error is expected, got %s", c.scimType, err)
+		})
+	}
+}
+
+func TestPatcher_ApplyTo(t *testing.T) {
+	patcher := Patcher{Schema: scim.SchemaUser, ReadOnly: []string{"id"}, MultiValued: []string{"emails"}}
+
+	t.Run("round-trip", func(t *testing.T) {
+		user := scim.User{
+			Schemas:  []string{scim.SchemaUser},
+			ID:       "1",
+			UserName: "john",
+			Name:     &scim.Name{GivenName: "John", FamilyName: "Doe"},
+			Emails:   []scim.MultiValue{{Value: "john@work.example.com", Type: "work"}},
+		}
+
+		err := patcher.ApplyTo(&user, []scim.PatchOperation{
+			{Op: "replace", Path: "name.givenName", Value: "Johnny"},
+			{Op: "add", Path: "emails", Value: scim.MultiValue{Value: "john@home.example.com", Type: "home"}},
+			{Op: "add", Path: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber", Value: "42"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, scim.User{
+			Schemas:  []string{scim.SchemaUser, scim.SchemaEnterpriseUser},
+			ID:       "1",
+			UserName: "john",
+			Name:     &scim.Name{GivenName: "Johnny", FamilyName: "Doe"},
+			Emails: []scim.MultiValue{
+				{Value: "john@work.example.com", Type: "work"},
+				{Value: "john@home.example.com", Type: "home"},
+			},
+			Enterprise: &scim.EnterpriseUser{EmployeeNumber: "42"},
+		}, user)
+	})
+
+	t.Run("failed operation", func(t *testing.T) {
+		user := scim.User{ID: "1", UserName: "john"}
+		err := patcher.ApplyTo(&user, []scim.PatchOperation{
+			{Op: "replace", Path: "userName", Value: "johnny"},
+			{Op: "replace", Path: "id", Value: "2"},
+		})
+		require.True(t, errors.Is(err, scim.ErrMutability))
+		require.Equal(t, scim.User{ID: "1", UserName: "john"}, user, "user isn't changed")
+	})
+
+	t.Run("invalid patched resource", func(t *testing.T) {
+		user := scim.User{ID: "1", UserName: "john"}
+		err := patcher.ApplyTo(&user, []scim.PatchOperation{{Op: "replace", Path: "active", Value: "yes"}})
+		require.True(t, errors.Is(err, scim.ErrInvalidValue))
+		require.Contains(t, err.Error(), "patched resource is invalid")
+		require.Equal(t, scim.User{ID: "1", UserName: "john"}, user, "user isn't changed")
+	})
+}
+
+func TestValidateRequest(t *testing.T) {
+	cases := map[string]struct {
+		req      scim.PatchRequest
+		scimType string
+	}{
+		"valid": {
+			req: scim.PatchRequest{Schemas: []string{scim.SchemaPatchOp}, Operations: []scim.PatchOperation{
+				{Op: "Add", Path: "title", Value: "x"},
+				{Op: "REPLACE", Value: map[string]interface{}{"title": "x"}},
+				{Op: "remove", Path: "title"},
+			}},
+		},
+		"missing schema": {
+			req:      scim.PatchRequest{Operations: []scim.PatchOperation{{Op: "remove", Path: "title"}}},
+			scimType: scim.ErrTypeInvalidSyntax,
+		},
+		"no operations": {
+			req:      scim.PatchRequest{Schemas: []string{scim.SchemaPatchOp}},
+			scimType: scim.ErrTypeInvalidValue,
+		},
+		"unsupported operation": {
+			req:      scim.PatchRequest{Schemas: []string{scim.SchemaPatchOp}, Operations: []scim.PatchOperation{{Op: "move"}}},
+			scimType: scim.ErrTypeInvalidSyntax,
+		},
+		"remove without path": {
+			req:      scim.PatchRequest{Schemas: []string{scim.SchemaPatchOp}, Operations: []scim.PatchOperation{{Op: "remove"}}},
+			scimType: scim.ErrTypeNoTarget,
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			err := ValidateRequest(c.req)
+			if c.scimType == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, errors.Is(err, &scim.Error{ScimType: c.scimType}), "%s error is expected, got %v", c.scimType, err)
+		})
+	}
+}
+
+func decodeObject(t *testing.T, src string) map[string]interface{} {
+	t.Helper()
+	out := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal([]byte(src), &out))
+	return out
+}
@@ -0,0 +1,112 @@
+package patch
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+)
+
+// Path is PATCH operation target path, see RFC 7644 section 3.5.2 "PATH" grammar.
+//
+// Examples:
+//
+//	name.givenName
+//	urn:ietf:params:scim:schemas:core:2.0:User:userName
+//	members[value eq "2819c223"]
+//	emails[type eq "work"].display
+type Path struct {
+	// Attr is target attribute.
+	//
+	// Sub-attribute is set only for paths without value filter.
+	Attr filter.AttrPath
+
+	// Filter is optional value filter of multi-valued attribute
+	Filter filter.Expression
+
+	// SubAttr is optional sub-attribute of filtered values
+	SubAttr string
+}
+
+// String returns path in SCIM syntax
+func (p Path) String() string {
+	if p.Filter == nil {
+		return p.Attr.String()
+	}
+
+	out := p.Attr.String() + "[" + p.Filter.String() + "]"
+	if p.SubAttr != "" {
+		out += "." + p.SubAttr
+	}
+	return out
+}
+
+// ParsePath parses PATCH operation path.
+//
+// Returns *scim.Error with "invalidPath" type if path is not valid.
+func ParsePath(src string) (*Path, error) {
+	start := strings.IndexByte(src, '[')
+	if start == -1 {
+		attr, err := filter.ParseAttrPath(src)
+		if err != nil {
+			return nil, errInvalidPath("invalid path %q", src)
+		}
+		return &Path{Attr: attr}, nil
+	}
+
+	end := strings.LastIndexByte(src, ']')
+	if end < start {
+		return nil, errInvalidPath("invalid path %q: missing closing bracket", src)
+	}
+
+	attr, err := filter.ParseAttrPath(src[:start])
+	if err != nil || attr.SubAttr != "" {
+		return nil, errInvalidPath("invalid path %q", src)
+	}
+
+	expr, err := filter.Parse(src[start+1 : end])
+	if err != nil {
+		return nil, errInvalidPath("invalid value filter in path %q: %s", src, scimDetail(err))
+	}
+	if expr == nil {
+		return nil, errInvalidPath("invalid path %q: empty value filter", src)
+	}
+
+	out := &Path{Attr: attr, Filter: expr}
+	if rest := src[end+1:]; rest != "" {
+		sub, err := filter.ParseAttrPath(strings.TrimPrefix(rest, "."))
+		if !strings.HasPrefix(rest, ".") || err != nil || sub.URI != "" || sub.SubAttr != "" {
+			return nil, errInvalidPath("invalid sub-attribute in path %q", src)
+		}
+		out.SubAttr = sub.Name
+	}
+	return out, nil
+}
+
+func scimDetail(err error) string {
+	if scimErr, ok := err.(*scim.Error); ok {
+		return scimErr.Detail
+	}
+	return err.Error()
+}
+
+func errInvalidPath(format string, args ...interface{}) *scim.Error {
+	return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidPath, format, args...)
+}
+
+func errNoTarget(format string, args ...interface{}) *scim.Error {
+	return scim.NewError(http.StatusBadRequest, scim.ErrTypeNoTarget, format, args...)
+}
+
+func errInvalidValue(format string, args ...interface{}) *scim.Error {
+	return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidValue, format, args...)
+}
+
+func errInvalidSyntax(format string, args ...interface{}) *scim.Error {
+	return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidSyntax, format, args...)
+}
+
+func errMutability(format string, args ...interface{}) *scim.Error {
+	return scim.NewError(http.StatusBadRequest, scim.ErrTypeMutability, format, args...)
+}
@@ -0,0 +1,96 @@
+package patch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+)
+
+func TestParsePath(t *testing.T) {
+	cases := map[string]struct {
+		src  string
+		want Path
+	}{
+		"attribute": {
+			src:  "userName",
+			want: Path{Attr: filter.AttrPath{Name: "userName"}},
+		},
+		"sub-attribute": {
+			src:  "name.givenName",
+			want: Path{Attr: filter.AttrPath{Name: "name", SubAttr: "givenName"}},
+		},
+		"schema URI": {
+			src: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value",
+			want: Path{Attr: filter.AttrPath{
+				URI:     scim.SchemaEnterpriseUser,
+				Name:    "manager",
+				SubAttr: "value",
+			}},
+		},
+		"value filter": {
+			src: `members[value eq "2819c223"]`,
+			want: Path{
+				Attr:   filter.AttrPath{Name: "members"},
+				Filter: &filter.AttrExpr{Path: filter.AttrPath{Name: "value"}, Operator: filter.OpEqual, Value: "2819c223"},
+			},
+		},
+		"value filter with sub-attribute": {
+			src: `emails[(type eq "work" and primary eq true)].display`,
+			want: Path{
+				Attr: filter.AttrPath{Name: "emails"},
+				Filter: &filter.LogicalExpr{
+					Operator: filter.OpAnd,
+					Left:     &filter.AttrExpr{Path: filter.AttrPath{Name: "type"}, Operator: filter.OpEqual, Value: "work"},
+					Right:    &filter.AttrExpr{Path: filter.AttrPath{Name: "primary"}, Operator: filter.OpEqual, Value: true},
+				},
+				SubAttr: "display",
+			},
+		},
+		"value filter with brackets in value": {
+			src: `emails[value eq "a]b"]`,
+			want: Path{
+				Attr:   filter.AttrPath{Name: "emails"},
+				Filter: &filter.AttrExpr{Path: filter.AttrPath{Name: "value"}, Operator: filter.OpEqual, Value: "a]b"},
+			},
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			got, err := ParsePath(c.src)
+			require.NoError(t, err)
+			require.Equal(t, c.want, *got)
+			require.Equal(t, c.src, got.String())
+		})
+	}
+}
+
+func TestParsePath_Invalid(t *testing.T) {
+	cases := map[string]struct {
+		src string
+		msg string
+	}{
+		"empty":                      {src: "", msg: `invalid path ""`},
+		"too many sub-attributes":    {src: "name.given.name", msg: `invalid path "name.given.name"`},
+		"missing closing bracket":    {src: `emails[type eq "work"`, msg: "missing closing bracket"},
+		"invalid filter":             {src: `emails[type eq]`, msg: "invalid value filter in path"},
+		"empty filter":               {src: `emails[ ]`, msg: "empty value filter"},
+		"filter of sub-attribute":    {src: `name.givenName[value eq "x"]`, msg: "invalid path"},
+		"sub-attribute without dot":  {src: `emails[type eq "work"]display`, msg: "invalid sub-attribute"},
+		"nested sub-attribute":       {src: `emails[type eq "work"].a.b`, msg: "invalid sub-attribute"},
+		"sub-attribute with URI":     {src: `emails[type eq "work"].urn:x:value`, msg: "invalid sub-attribute"},
+		"invalid attribute of value": {src: `1emails[type eq "work"]`, msg: "invalid path"},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			_, err := ParsePath(c.src)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), c.msg)
+			require.True(t, errors.Is(err, scim.ErrInvalidPath), "invalidPath error is expected")
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package scim
+
+import "context"
+
+// PrivilegedData is CyberArk PAM account stored in a safe.
+//
+// Secret value of account is intentionally not declared,
+// so it's never decoded from PAM SCIM server responses.
+type PrivilegedData struct {
+	Schemas   []string   `json:"schemas,omitempty"`
+	ID        string     `json:"id,omitempty"`
+	Name      string     `json:"name"`
+	Type      string     `json:"type,omitempty"`
+	Container *Reference `json:"container,omitempty"`
+	Address   string     `json:"address,omitempty"`
+	Platform  string     `json:"platform,omitempty"`
+	UserName  string     `json:"userName,omitempty"`
+	Meta      *Meta      `json:"meta,omitempty"`
+}
+
+// PrivilegedDataList is privileged data list response
+type PrivilegedDataList struct {
+	ListResponse
+	Resources []PrivilegedData `json:"Resources"`
+}
+
+// ListPrivilegedData returns a page of accounts matching list params
+func (c Client) ListPrivilegedData(ctx context.Context, p ListParams) (*PrivilegedDataList, error) {
+	rsp := new(PrivilegedDataList)
+	return rsp, c.list(ctx, EndpointPrivilegedData, p, rsp)
+}
+
+// GetPrivilegedData returns account by ID
+func (c Client) GetPrivilegedData(ctx context.Context, id string) (*PrivilegedData, error) {
+	rsp := new(PrivilegedData)
+	return rsp, c.get(ctx, resourcePath(EndpointPrivilegedData, id), rsp)
+}
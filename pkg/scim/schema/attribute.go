@@ -0,0 +1,173 @@
+package schema
+
+import "github.com/strick-j/scimfe/pkg/scim"
+
+// Attribute data types, see RFC 7643 section 2.3.
+const (
+	TypeString    = "string"
+	TypeBoolean   = "boolean"
+	TypeDecimal   = "decimal"
+	TypeInteger   = "integer"
+	TypeDateTime  = "dateTime"
+	TypeBinary    = "binary"
+	TypeReference = "reference"
+	TypeComplex   = "complex"
+)
+
+// Attribute mutability values
+const (
+	MutabilityReadOnly  = "readOnly"
+	MutabilityReadWrite = "readWrite"
+	MutabilityImmutable = "immutable"
+	MutabilityWriteOnly = "writeOnly"
+)
+
+// Attribute returned values
+const (
+	ReturnedAlways  = "always"
+	ReturnedNever   = "never"
+	ReturnedDefault = "default"
+	ReturnedRequest = "request"
+)
+
+// Attribute uniqueness values
+const (
+	UniquenessNone   = "none"
+	UniquenessServer = "server"
+	UniquenessGlobal = "global"
+)
+
+// AttrOption is attribute definition option
+type AttrOption func(a *scim.Attribute)
+
+// Attr returns attribute definition.
+//
+// Attribute is single-valued, optional, case-insensitive, read-write
+// and returned by default unless options specify otherwise.
+func Attr(name, typ, description string, opts ...AttrOption) scim.Attribute {
+	a := scim.Attribute{
+		Name:        name,
+		Type:        typ,
+		Description: description,
+		Mutability:  MutabilityReadWrite,
+		Returned:    ReturnedDefault,
+	}
+
+	if typ == TypeString || typ == TypeReference || typ == TypeBinary {
+		a.Uniqueness = UniquenessNone
+	}
+
+	for _, opt := range opts {
+		opt(&a)
+	}
+	return a
+}
+
+// Required marks attribute as required
+func Required(a *scim.Attribute) {
+	a.Required = true
+}
+
+// MultiValued marks attribute as multi-valued
+func MultiValued(a *scim.Attribute) {
+	a.MultiValued = true
+}
+
+// CaseExact marks string attribute as case-sensitive
+func CaseExact(a *scim.Attribute) {
+	a.CaseExact = true
+}
+
+// Mutability sets attribute mutability
+func Mutability(m string) AttrOption {
+	return func(a *scim.Attribute) {
+		a.Mutability = m
+	}
+}
+
+// Returned sets when attribute is returned in response
+func Returned(r string) AttrOption {
+	return func(a *scim.Attribute) {
+		a.Returned = r
+	}
+}
+
+// Uniqueness sets attribute uniqueness
+func Uniqueness(u string) AttrOption {
+	return func(a *scim.Attribute) {
+		a.Uniqueness = u
+	}
+}
+
+// CanonicalValues sets suggested attribute values
+func CanonicalValues(values ...string) AttrOption {
+	return func(a *scim.Attribute) {
+		a.CanonicalValues = values
+	}
+}
+
+// ReferenceTypes sets resource types which can be referenced by attribute
+func ReferenceTypes(types ...string) AttrOption {
+	return func(a *scim.Attribute) {
+		a.ReferenceTypes = types
+	}
+}
+
+// SubAttributes sets sub-attributes of complex attribute
+func SubAttributes(attrs ...scim.Attribute) AttrOption {
+	return func(a *scim.Attribute) {
+		a.SubAttributes = attrs
+	}
+}
+
+// Select returns copy of schema with listed attributes only.
+//
+// Can be used to publish a subset of core schema supported by service provider.
+func Select(s scim.Schema, names ...string) scim.Schema {
+	out := s
+	out.Attributes = make([]scim.Attribute, 0, len(names))
+	for _, name := range names {
+		if a := findAttr(s.Attributes, name); a != nil {
+			out.Attributes = append(out.Attributes, *a)
+		}
+	}
+	return out
+}
+
+// Modify returns copy of schema with attribute definition changed by options.
+//
+// Attribute name can be sub-attribute path, like "name.givenName".
+func Modify(s scim.Schema, name string, opts ...AttrOption) scim.Schema {
+	out := s
+	out.Attributes = copyAttrs(s.Attributes)
+
+	attrs := out.Attributes
+	parent, sub := splitAttrName(name)
+	if sub != "" {
+		a := findAttr(attrs, parent)
+		if a == nil {
+			return out
+		}
+		attrs, name = a.SubAttributes, sub
+	}
+
+	if a := findAttr(attrs, name); a != nil {
+		for _, opt := range opts {
+			opt(a)
+		}
+	}
+	return out
+}
+
+func copyAttrs(attrs []scim.Attribute) []scim.Attribute {
+	if attrs == nil {
+		return nil
+	}
+
+	out := make([]scim.Attribute, len(attrs))
+	for i, a := range attrs {
+		out[i] = a
+		out[i].SubAttributes = copyAttrs(a.SubAttributes)
+	}
+	return out
+}
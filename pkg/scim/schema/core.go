@@ -0,0 +1,137 @@
+package schema
+
+import "github.com/strick-j/scimfe/pkg/scim"
+
+// Common attributes of all resources, see RFC 7643 section 3.1.
+//
+// Common attributes are not published in schema definitions,
+// but are processed by Registry as part of any resource.
+var commonAttributes = []scim.Attribute{
+	Attr("id", TypeString, "Unique identifier for the resource, assigned by the service provider.",
+		CaseExact, Mutability(MutabilityReadOnly), Returned(ReturnedAlways), Uniqueness(UniquenessServer)),
+	Attr("externalId", TypeString, "Identifier for the resource as defined by the provisioning client.",
+		CaseExact),
+	Attr("meta", TypeComplex, "Resource metadata.",
+		Mutability(MutabilityReadOnly),
+		SubAttributes(
+			Attr("resourceType", TypeString, "Name of the resource type of the resource.",
+				CaseExact, Mutability(MutabilityReadOnly)),
+			Attr("created", TypeDateTime, "Date and time the resource was added to the service provider.",
+				Mutability(MutabilityReadOnly)),
+			Attr("lastModified", TypeDateTime, "Most recent date and time the resource was modified.",
+				Mutability(MutabilityReadOnly)),
+			Attr("location", TypeReference, "URI of the resource being returned.",
+				CaseExact, Mutability(MutabilityReadOnly)),
+			Attr("version", TypeString, "Version of the resource being returned.",
+				CaseExact, Mutability(MutabilityReadOnly)),
+		)),
+}
+
+// multiValue returns sub-attributes of multi-valued attribute like emails
+func multiValue(valueDescription string, types ...string) AttrOption {
+	return SubAttributes(
+		Attr("value", TypeString, valueDescription),
+		Attr("display", TypeString, "A human-readable name, primarily used for display purposes."),
+		Attr("type", TypeString, "A label indicating the attribute's function.",
+			CanonicalValues(types...)),
+		Attr("primary", TypeBoolean, "A Boolean value indicating the preferred value for this attribute."),
+	)
+}
+
+// User is core user schema, see RFC 7643 section 4.1.
+//
+// Only attributes supported by scim.User are defined.
+var User = scim.Schema{
+	ID:          scim.SchemaUser,
+	Name:        "User",
+	Description: "User Account",
+	Attributes: []scim.Attribute{
+		Attr("userName", TypeString, "Unique identifier for the User, used to authenticate to the service provider.",
+			Required, Uniqueness(UniquenessServer)),
+		Attr("name", TypeComplex, "The components of the user's real name.",
+			SubAttributes(
+				Attr("formatted", TypeString, "The full name, including all middle names, titles, and suffixes."),
+				Attr("familyName", TypeString, "The family name of the User."),
+				Attr("givenName", TypeString, "The given name of the User."),
+				Attr("middleName", TypeString, "The middle name(s) of the User."),
+				Attr("honorificPrefix", TypeString, "The honorific prefix(es) of the User."),
+				Attr("honorificSuffix", TypeString, "The honorific suffix(es) of the User."),
+			)),
+		Attr("displayName", TypeString, "The name of the User, suitable for display to end-users."),
+		Attr("nickName", TypeString, "The casual way to address the user in real life."),
+		Attr("profileUrl", TypeReference, "A fully qualified URL pointing to a page representing the User's online profile.",
+			ReferenceTypes("external")),
+		Attr("title", TypeString, "The user's title, such as \"Vice President\"."),
+		Attr("userType", TypeString, "Used to identify the relationship between the organization and the user."),
+		Attr("locale", TypeString, "Used to indicate the User's default location for purposes of localizing items."),
+		Attr("timezone", TypeString, "The User's time zone in the 'Olson' time zone database format."),
+		Attr("active", TypeBoolean, "A Boolean value indicating the User's administrative status."),
+		Attr("password", TypeString, "The User's cleartext password.",
+			Mutability(MutabilityWriteOnly), Returned(ReturnedNever)),
+		Attr("emails", TypeComplex, "Email addresses for the user.",
+			MultiValued, multiValue("Email addresses for the user.", "work", "home", "other")),
+		Attr("phoneNumbers", TypeComplex, "Phone numbers for the User.",
+			MultiValued, multiValue("Phone number of the User.", "work", "home", "mobile", "fax", "pager", "other")),
+		Attr("groups", TypeComplex, "A list of groups to which the user belongs.",
+			MultiValued, Mutability(MutabilityReadOnly),
+			SubAttributes(
+				Attr("value", TypeString, "The identifier of the User's group.",
+					Mutability(MutabilityReadOnly)),
+				Attr("$ref", TypeReference, "The URI of the corresponding 'Group' resource to which the user belongs.",
+					ReferenceTypes("User", "Group"), Mutability(MutabilityReadOnly)),
+				Attr("display", TypeString, "A human-readable name, primarily used for display purposes.",
+					Mutability(MutabilityReadOnly)),
+				Attr("type", TypeString, "A label indicating the attribute's function.",
+					CanonicalValues("direct", "indirect"), Mutability(MutabilityReadOnly)),
+			)),
+		Attr("entitlements", TypeComplex, "A list of entitlements for the User that represent a thing the User has.",
+			MultiValued, multiValue("The value of an entitlement.")),
+	},
+}
+
+// Group is core group schema, see RFC 7643 section 4.2.
+var Group = scim.Schema{
+	ID:          scim.SchemaGroup,
+	Name:        "Group",
+	Description: "Group",
+	Attributes: []scim.Attribute{
+		Attr("displayName", TypeString, "A human-readable name for the Group.",
+			Required),
+		Attr("members", TypeComplex, "A list of members of the Group.",
+			MultiValued,
+			SubAttributes(
+				Attr("value", TypeString, "Identifier of the member of this Group.",
+					Mutability(MutabilityImmutable)),
+				Attr("$ref", TypeReference, "The URI corresponding to a SCIM resource that is a member of this Group.",
+					ReferenceTypes("User", "Group"), Mutability(MutabilityImmutable)),
+				Attr("display", TypeString, "A human-readable name, primarily used for display purposes.",
+					Mutability(MutabilityReadOnly)),
+				Attr("type", TypeString, "A label indicating the type of resource.",
+					CanonicalValues("User", "Group"), Mutability(MutabilityImmutable)),
+			)),
+		Attr("entitlements", TypeComplex, "A list of entitlements for the Group.",
+			MultiValued, multiValue("The value of an entitlement.")),
+	},
+}
+
+// EnterpriseUser is enterprise user schema extension, see RFC 7643 section 4.3.
+var EnterpriseUser = scim.Schema{
+	ID:          scim.SchemaEnterpriseUser,
+	Name:        "EnterpriseUser",
+	Description: "Enterprise User",
+	Attributes: []scim.Attribute{
+		Attr("employeeNumber", TypeString, "Numeric or alphanumeric identifier assigned to a person."),
+		Attr("costCenter", TypeString, "Identifies the name of a cost center."),
+		Attr("organization", TypeString, "Identifies the name of an organization."),
+		Attr("division", TypeString, "Identifies the name of a division."),
+		Attr("department", TypeString, "Identifies the name of a department."),
+		Attr("manager", TypeComplex, "The User's manager.",
+			SubAttributes(
+				Attr("value", TypeString, "The id of the SCIM resource representing the User's manager."),
+				Attr("$ref", TypeReference, "The URI of the SCIM resource representing the User's manager.",
+					ReferenceTypes("User")),
+				Attr("displayName", TypeString, "The displayName of the User's manager.",
+					Mutability(MutabilityReadOnly)),
+			)),
+	},
+}
@@ -0,0 +1,400 @@
+package schema
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// Decode decodes resource sent by client to dst value, like scim.User.
+//
+// Resource is checked against resource type schemas:
+//
+//   - schemas should contain resource type core schema and can contain
+//     registered schema extensions only, missing schemas are set to core schema;
+//     other extensions are accepted as is if resource type allows them, see AllowExtensions;
+//   - read-only and unknown attributes are ignored, null values are treated as unassigned;
+//   - attribute values should match attribute types;
+//   - required attributes should be present.
+//
+// Returns *scim.Error with "invalidSyntax" or "invalidValue" type if resource is not valid.
+func (r Registry) Decode(resourceType string, data []byte, dst interface{}) error {
+	rt := r.resourceType(resourceType)
+	if rt == nil {
+		return scim.NewError(http.StatusNotFound, "", "resource type %q not found", resourceType)
+	}
+
+	var res map[string]interface{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "cannot read resource: %s", err)
+	}
+
+	if res == nil {
+		return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "empty resource")
+	}
+
+	out, err := rt.check(res, true)
+	if err != nil {
+		return err
+	}
+
+	if data, err = json.Marshal(out); err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(data, dst); err != nil {
+		return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidValue, "invalid resource: %s", err)
+	}
+	return nil
+}
+
+// Validate checks that resource value, like scim.User, matches resource type schemas.
+//
+// Unlike Decode, read-only attributes are accepted, since resource
+// may be already processed by service provider, e.g. patched.
+func (r Registry) Validate(resourceType string, v interface{}) error {
+	rt := r.mustResourceType(resourceType)
+	res, err := toObject(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = rt.check(res, false)
+	return err
+}
+
+// CheckImmutable checks that replacement of resource, like scim.User, doesn't change
+// immutable attributes of current resource, see RFC 7644 section 3.5.1.
+//
+// Immutable attributes which have no value in current resource can be set,
+// immutable attributes omitted in replacement are not checked.
+// Values of multi-valued attributes with immutable sub-attributes are matched
+// by "value" sub-attribute, such values can be added and removed.
+//
+// Returns *scim.Error with "mutability" type if immutable attribute is changed.
+func (r Registry) CheckImmutable(resourceType string, current, replacement interface{}) error {
+	rt := r.mustResourceType(resourceType)
+	cur, err := toObject(current)
+	if err != nil {
+		return err
+	}
+
+	repl, err := toObject(replacement)
+	if err != nil {
+		return err
+	}
+
+	if err = checkImmutable(rt.attributes(), cur, repl, ""); err != nil {
+		return err
+	}
+
+	for _, ext := range rt.extensions {
+		_, curExt, _ := lookup(cur, ext.ID)
+		_, replExt, _ := lookup(repl, ext.ID)
+		curObj, _ := curExt.(map[string]interface{})
+		replObj, _ := replExt.(map[string]interface{})
+		if err = checkImmutable(ext.Attributes, curObj, replObj, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkImmutable(attrs []scim.Attribute, cur, repl map[string]interface{}, prefix string) error {
+	for _, a := range attrs {
+		_, curVal, _ := lookup(cur, a.Name)
+		if !isAssigned(curVal) {
+			continue
+		}
+
+		_, replVal, _ := lookup(repl, a.Name)
+		if a.Mutability == MutabilityImmutable {
+			if isAssigned(replVal) && !reflect.DeepEqual(curVal, replVal) {
+				return immutable(prefix + a.Name)
+			}
+			continue
+		}
+
+		if a.Type != TypeComplex {
+			continue
+		}
+
+		if !a.MultiValued {
+			curObj, _ := curVal.(map[string]interface{})
+			replObj, _ := replVal.(map[string]interface{})
+			if err := checkImmutable(a.SubAttributes, curObj, replObj, prefix+a.Name+"."); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// values are matched by "value" sub-attribute, unmatched values are added or removed
+		curList, _ := curVal.([]interface{})
+		replList, _ := replVal.([]interface{})
+		for _, replItem := range replList {
+			replObj, _ := replItem.(map[string]interface{})
+			curObj := findValue(curList, replObj)
+			if curObj == nil {
+				continue
+			}
+
+			if err := checkImmutable(a.SubAttributes, curObj, replObj, prefix+a.Name+"."); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findValue returns complex value from list with the same "value" sub-attribute
+func findValue(list []interface{}, v map[string]interface{}) map[string]interface{} {
+	_, id, ok := lookup(v, "value")
+	if !ok || id == nil {
+		return nil
+	}
+
+	for _, item := range list {
+		obj, _ := item.(map[string]interface{})
+		if _, itemID, ok := lookup(obj, "value"); ok && reflect.DeepEqual(itemID, id) {
+			return obj
+		}
+	}
+	return nil
+}
+
+// check validates resource and returns resource with canonical attribute names.
+//
+// If strip is set, read-only attributes are removed.
+func (rt resourceType) check(res map[string]interface{}, strip bool) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(res))
+	schemas, err := rt.checkSchemas(res)
+	if err != nil {
+		return nil, err
+	}
+	out["schemas"] = schemas
+
+	for key, val := range res {
+		if strings.EqualFold(key, "schemas") {
+			continue
+		}
+
+		if ext := rt.extension(key); ext != nil {
+			obj, ok := val.(map[string]interface{})
+			if !ok {
+				if val == nil {
+					continue
+				}
+				return nil, invalidValue("extension %q must be an object", ext.ID)
+			}
+
+			if obj, err = checkObject(ext.Attributes, obj, "", strip); err != nil {
+				return nil, err
+			}
+			out[ext.ID] = obj
+			continue
+		}
+
+		if rt.unregisteredExtension(key) {
+			switch val.(type) {
+			case nil:
+			case map[string]interface{}:
+				out[key] = val
+			default:
+				return nil, invalidValue("extension %q must be an object", key)
+			}
+			continue
+		}
+
+		a := findAttr(rt.attributes(), key)
+		if a == nil || val == nil || (strip && a.Mutability == MutabilityReadOnly) {
+			continue
+		}
+
+		if out[a.Name], err = checkValue(*a, val, a.Name, strip); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = checkRequired(rt.core.Attributes, out, ""); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (rt resourceType) checkSchemas(res map[string]interface{}) ([]interface{}, error) {
+	_, val, ok := lookup(res, "schemas")
+	if !ok || val == nil {
+		return []interface{}{rt.core.ID}, nil
+	}
+
+	list, ok := val.([]interface{})
+	if !ok {
+		return nil, invalidValue("schemas must be a list of schema URIs")
+	}
+
+	hasCore := false
+	out := make([]interface{}, 0, len(list))
+	for _, item := range list {
+		uri, ok := item.(string)
+		switch {
+		case !ok:
+			return nil, invalidValue("schemas must be a list of schema URIs")
+		case strings.EqualFold(uri, rt.core.ID):
+			hasCore = true
+			uri = rt.core.ID
+		case rt.extension(uri) != nil:
+			uri = rt.extension(uri).ID
+		case rt.unregisteredExtension(uri):
+		default:
+			return nil, invalidValue("schema %q is not supported by %s resource type", uri, rt.Name)
+		}
+		out = append(out, uri)
+	}
+
+	if !hasCore {
+		return nil, invalidValue("schemas must contain %q", rt.core.ID)
+	}
+	return out, nil
+}
+
+func checkValue(a scim.Attribute, val interface{}, path string, strip bool) (interface{}, error) {
+	if !a.MultiValued {
+		return checkSingleValue(a, val, path, strip)
+	}
+
+	list, ok := val.([]interface{})
+	if !ok {
+		return nil, invalidValue("attribute %q must be multi-valued", path)
+	}
+
+	out := make([]interface{}, 0, len(list))
+	for _, item := range list {
+		if item == nil {
+			continue
+		}
+
+		v, err := checkSingleValue(a, item, path, strip)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func checkSingleValue(a scim.Attribute, val interface{}, path string, strip bool) (interface{}, error) {
+	ok := false
+	switch a.Type {
+	case TypeString, TypeReference, TypeBinary:
+		_, ok = val.(string)
+	case TypeBoolean:
+		_, ok = val.(bool)
+	case TypeDecimal:
+		_, ok = val.(float64)
+	case TypeInteger:
+		var f float64
+		f, ok = val.(float64)
+		ok = ok && f == math.Trunc(f)
+	case TypeDateTime:
+		var s string
+		if s, ok = val.(string); ok {
+			_, err := time.Parse(time.RFC3339Nano, s)
+			ok = err == nil
+		}
+	case TypeComplex:
+		obj, isObj := val.(map[string]interface{})
+		if !isObj {
+			break
+		}
+		return checkObject(a.SubAttributes, obj, path+".", strip)
+	default:
+		ok = true
+	}
+
+	if !ok {
+		return nil, invalidValue("attribute %q must be %s value", path, a.Type)
+	}
+	return val, nil
+}
+
+// checkObject checks attributes of complex value or extension
+func checkObject(attrs []scim.Attribute, obj map[string]interface{}, prefix string, strip bool) (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(obj))
+	for key, val := range obj {
+		a := findAttr(attrs, key)
+		if a == nil || val == nil || (strip && a.Mutability == MutabilityReadOnly) {
+			continue
+		}
+
+		v, err := checkValue(*a, val, prefix+a.Name, strip)
+		if err != nil {
+			return nil, err
+		}
+		out[a.Name] = v
+	}
+
+	if err := checkRequired(attrs, out, prefix); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func checkRequired(attrs []scim.Attribute, obj map[string]interface{}, prefix string) error {
+	for _, a := range attrs {
+		if !a.Required || a.Mutability == MutabilityReadOnly {
+			continue
+		}
+
+		if !isAssigned(obj[a.Name]) {
+			return invalidValue("attribute %q is required", prefix+a.Name)
+		}
+	}
+	return nil
+}
+
+// isAssigned reports whether value is not null or empty, see RFC 7643 section 2.5.
+func isAssigned(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(v) != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+func toObject(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]interface{})
+	return out, json.Unmarshal(data, &out)
+}
+
+func lookup(obj map[string]interface{}, name string) (string, interface{}, bool) {
+	for k, v := range obj {
+		if strings.EqualFold(k, name) {
+			return k, v, true
+		}
+	}
+	return "", nil, false
+}
+
+func immutable(name string) *scim.Error {
+	return scim.NewError(http.StatusBadRequest, scim.ErrTypeMutability, "attribute %q is immutable", name)
+}
+
+func invalidValue(format string, args ...interface{}) *scim.Error {
+	return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidValue, format, args...)
+}
@@ -0,0 +1,314 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+var testRegistry = NewRegistry("/scim/v2", scim.ServiceProviderConfig{}).
+	Register(scim.ResourceType{Name: "User", Endpoint: "/Users"}, User, EnterpriseUser).
+	Register(scim.ResourceType{Name: "Group", Endpoint: "/Groups"}, Group).
+	AllowExtensions("Group")
+
+func TestRegistry_Decode(t *testing.T) {
+	cases := map[string]struct {
+		resourceType string
+		src          string
+		want         string
+	}{
+		"read-only attributes are ignored": {
+			resourceType: "User",
+			src: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+				"id": "1",
+				"meta": {"version": "W/\"1\""},
+				"userName": "john",
+				"groups": [{"value": "10", "display": "Admins"}]
+			}`,
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+				"userName": "john"
+			}`,
+		},
+		"read-only sub-attributes are ignored": {
+			resourceType: "User",
+			src: `{
+				"schemas": [
+					"urn:ietf:params:scim:schemas:core:2.0:User",
+					"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+				],
+				"userName": "john",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+					"employeeNumber": "42",
+					"manager": {"value": "2", "displayName": "Jane"}
+				}
+			}`,
+			want: `{
+				"schemas": [
+					"urn:ietf:params:scim:schemas:core:2.0:User",
+					"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+				],
+				"userName": "john",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+					"employeeNumber": "42",
+					"manager": {"value": "2"}
+				}
+			}`,
+		},
+		"immutable attributes are accepted": {
+			resourceType: "Group",
+			src: `{
+				"displayName": "Admins",
+				"members": [{"value": "1", "type": "User", "display": "John"}]
+			}`,
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+				"displayName": "Admins",
+				"members": [{"value": "1", "type": "User"}]
+			}`,
+		},
+		"write-only attributes are accepted": {
+			resourceType: "User",
+			src:          `{"userName": "john", "password": "secret"}`,
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+				"userName": "john",
+				"password": "secret"
+			}`,
+		},
+		"attribute names are canonical": {
+			resourceType: "User",
+			src: `{
+				"SCHEMAS": ["URN:IETF:PARAMS:SCIM:SCHEMAS:CORE:2.0:USER"],
+				"USERNAME": "john",
+				"name": {"GivenName": "John"}
+			}`,
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+				"userName": "john",
+				"name": {"givenName": "John"}
+			}`,
+		},
+		"unknown and null attributes are ignored": {
+			resourceType: "User",
+			src:          `{"userName": "john", "nickName": null, "shoeSize": 42, "emails": [null]}`,
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+				"userName": "john",
+				"emails": []
+			}`,
+		},
+		"unregistered extension is allowed": {
+			resourceType: "Group",
+			src: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group", "urn:example:scim:Group"],
+				"displayName": "Admins",
+				"urn:example:scim:Group": {"level": 3}
+			}`,
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group", "urn:example:scim:Group"],
+				"displayName": "Admins",
+				"urn:example:scim:Group": {"level": 3}
+			}`,
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			var got map[string]interface{}
+			require.NoError(t, testRegistry.Decode(c.resourceType, []byte(c.src), &got))
+			require.Equal(t, decodeObject(t, c.want), got)
+		})
+	}
+
+	t.Run("struct", func(t *testing.T) {
+		var got scim.User
+		err := testRegistry.Decode("User", []byte(`{"id": "1", "userName": "john", "password": "secret"}`), &got)
+		require.NoError(t, err)
+		require.Equal(t, scim.User{
+			Schemas:  []string{scim.SchemaUser},
+			UserName: "john",
+			Password: "secret",
+		}, got)
+	})
+}
+
+func TestRegistry_Decode_Invalid(t *testing.T) {
+	cases := map[string]struct {
+		resourceType string
+		src          string
+		status       int
+		scimType     string
+		msg          string
+	}{
+		"unknown resource type": {
+			resourceType: "Device",
+			src:          `{}`,
+			status:       http.StatusNotFound,
+			msg:          `resource type "Device" not found`,
+		},
+		"invalid JSON": {
+			src:      `{"userName":`,
+			scimType: scim.ErrTypeInvalidSyntax,
+			msg:      "cannot read resource",
+		},
+		"null resource": {
+			src:      `null`,
+			scimType: scim.ErrTypeInvalidSyntax,
+			msg:      "empty resource",
+		},
+		"missing required attribute": {
+			src:      `{"displayName": "John"}`,
+			scimType: scim.ErrTypeInvalidValue,
+			msg:      `attribute "userName" is required`,
+		},
+		"empty required attribute": {
+			src:      `{"userName": " "}`,
+			scimType: scim.ErrTypeInvalidValue,
+			msg:      `attribute "userName" is required`,
+		},
+		"invalid attribute type": {
+			src:      `{"userName": "john", "active": "yes"}`,
+			scimType: scim.ErrTypeInvalidValue,
+			msg:      `attribute "active" must be boolean value`,
+		},
+		"invalid sub-attribute type": {
+			src:      `{"userName": "john", "name": {"givenName": 1}}`,
+			scimType: scim.ErrTypeInvalidValue,
+			msg:      `attribute "name.givenName" must be string value`,
+		},
+		"single value of multi-valued attribute": {
+			src:      `{"userName": "john", "emails": {"value": "john@example.com"}}`,
+			scimType: scim.ErrTypeInvalidValue,
+			msg:      `attribute "emails" must be multi-valued`,
+		},
+		"invalid extension attribute type": {
+			src:      `{"userName": "john", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"manager": "2"}}`,
+			scimType: scim.ErrTypeInvalidValue,
+			msg:      `attribute "manager" must be complex value`,
+		},
+		"extension isn't object": {
+			src:      `{"userName": "john", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": "42"}`,
+			scimType: scim.ErrTypeInvalidValue,
+			msg:      "must be an object",
+		},
+		"unsupported schema": {
+			src:      `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:example:scim:User"], "userName": "john"}`,
+			scimType: scim.ErrTypeInvalidValue,
+			msg:      `schema "urn:example:scim:User" is not supported by User resource type`,
+		},
+		"missing core schema": {
+			src:      `{"schemas": ["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"], "userName": "john"}`,
+			scimType: scim.ErrTypeInvalidValue,
+			msg:      `schemas must contain "urn:ietf:params:scim:schemas:core:2.0:User"`,
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			if c.resourceType == "" {
+				c.resourceType = "User"
+			}
+			if c.status == 0 {
+				c.status = http.StatusBadRequest
+			}
+
+			var got map[string]interface{}
+			err := testRegistry.Decode(c.resourceType, []byte(c.src), &got)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), c.msg)
+
+			var scimErr *scim.Error
+			require.True(t, errors.As(err, &scimErr))
+			require.Equal(t, c.status, scimErr.StatusCode)
+			require.Equal(t, c.scimType, scimErr.ScimType)
+		})
+	}
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	t.Run("read-only attributes are accepted", func(t *testing.T) {
+		err := testRegistry.Validate("User", scim.User{
+			ID:       "1",
+			UserName: "john",
+			Groups:   []scim.Reference{{Value: "10"}},
+			Meta:     &scim.Meta{Version: `W/"1"`},
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("missing required attribute", func(t *testing.T) {
+		err := testRegistry.Validate("User", scim.User{ID: "1"})
+		require.True(t, errors.Is(err, scim.ErrInvalidValue))
+	})
+}
+
+func TestRegistry_CheckImmutable(t *testing.T) {
+	const current = `{
+		"displayName": "Admins",
+		"members": [
+			{"value": "1", "type": "User"},
+			{"value": "2"}
+		]
+	}`
+
+	cases := map[string]struct {
+		replacement string
+		err         string
+	}{
+		"unchanged": {
+			replacement: current,
+		},
+		"mutable attribute is changed": {
+			replacement: `{"displayName": "Users", "members": [{"value": "1", "type": "User"}]}`,
+		},
+		"values are added and removed": {
+			replacement: `{"members": [{"value": "2"}, {"value": "3", "type": "Group"}]}`,
+		},
+		"unassigned immutable sub-attribute is set": {
+			replacement: `{"members": [{"value": "2", "type": "User"}]}`,
+		},
+		"immutable sub-attribute is omitted": {
+			replacement: `{"members": [{"value": "1"}]}`,
+		},
+		"immutable sub-attribute is changed": {
+			replacement: `{"members": [{"value": "1", "type": "Group"}]}`,
+			err:         `attribute "members.type" is immutable`,
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			err := testRegistry.CheckImmutable("Group", decodeObject(t, current), decodeObject(t, c.replacement))
+			if c.err == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			require.Contains(t, err.Error(), c.err)
+			require.True(t, errors.Is(err, scim.ErrMutability), "mutability error is expected")
+		})
+	}
+}
+
+func TestRegistry_Patcher(t *testing.T) {
+	p := testRegistry.Patcher("Group")
+	require.Equal(t, scim.SchemaGroup, p.Schema)
+	require.Equal(t, []string{"id", "meta", "meta.resourceType", "meta.created", "meta.lastModified",
+		"meta.location", "meta.version", "members.display"}, p.ReadOnly)
+	require.Equal(t, []string{"members.value", "members.$ref", "members.type"}, p.Immutable)
+	require.Equal(t, []string{"members", "entitlements", "schemas"}, p.MultiValued)
+}
+
+func decodeObject(t *testing.T, src string) map[string]interface{} {
+	t.Helper()
+	out := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal([]byte(src), &out))
+	return out
+}
@@ -0,0 +1,13 @@
+// Package schema implements SCIM schema registry.
+//
+// Registry contains resource types and schemas supported by service provider,
+// defined in Go (see User and Group), and is used to:
+//
+//   - serve discovery documents: ServiceProviderConfig, ResourceTypes and Schemas
+//     (RFC 7644 section 4);
+//   - decode and validate resources sent by clients, according to attribute
+//     types, "required" and "mutability" characteristics (RFC 7643 section 2);
+//   - build PATCH engine for resource type (see package patch);
+//   - prepare responses according to "returned" characteristic
+//     and "attributes" / "excludedAttributes" request parameters.
+package schema
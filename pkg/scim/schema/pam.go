@@ -0,0 +1,79 @@
+package schema
+
+import "github.com/strick-j/scimfe/pkg/scim"
+
+// reference returns sub-attributes of read-only reference to other resource
+func reference(valueDescription string, types ...string) AttrOption {
+	return SubAttributes(
+		Attr("value", TypeString, valueDescription,
+			CaseExact, Mutability(MutabilityReadOnly)),
+		Attr("display", TypeString, "A human-readable name, primarily used for display purposes.",
+			Mutability(MutabilityReadOnly)),
+		Attr("$ref", TypeReference, "The URI of the referenced resource.",
+			ReferenceTypes(types...), Mutability(MutabilityReadOnly)),
+	)
+}
+
+// Container is CyberArk PAM safe schema.
+//
+// Containers are synchronized from PAM SCIM server and are read-only.
+var Container = scim.Schema{
+	ID:          scim.SchemaContainer,
+	Name:        "Container",
+	Description: "CyberArk PAM safe",
+	Attributes: []scim.Attribute{
+		Attr("name", TypeString, "Unique name of the safe.",
+			Required, CaseExact, Mutability(MutabilityReadOnly), Uniqueness(UniquenessServer)),
+		Attr("displayName", TypeString, "A human-readable name of the safe.",
+			Mutability(MutabilityReadOnly)),
+		Attr("description", TypeString, "Description of the safe.",
+			Mutability(MutabilityReadOnly)),
+		Attr("type", TypeString, "Type of the safe.",
+			Mutability(MutabilityReadOnly)),
+		Attr("owner", TypeComplex, "Owner of the safe.",
+			Mutability(MutabilityReadOnly), reference("Identifier of the safe owner.", "User", "Group")),
+	},
+}
+
+// PrivilegedData is CyberArk PAM account schema.
+//
+// Accounts are synchronized from PAM SCIM server and are read-only.
+// Account secret is not part of the schema, since it's never stored.
+var PrivilegedData = scim.Schema{
+	ID:          scim.SchemaPrivilegedData,
+	Name:        "PrivilegedData",
+	Description: "CyberArk PAM account stored in a safe",
+	Attributes: []scim.Attribute{
+		Attr("name", TypeString, "Name of the account.",
+			Required, CaseExact, Mutability(MutabilityReadOnly)),
+		Attr("type", TypeString, "Type of the account secret.",
+			CanonicalValues("password", "key"), Mutability(MutabilityReadOnly)),
+		Attr("container", TypeComplex, "Safe which stores the account.",
+			Required, Mutability(MutabilityReadOnly), reference("Name of the safe.", "Container")),
+		Attr("address", TypeString, "Address of the target system.",
+			Mutability(MutabilityReadOnly)),
+		Attr("platform", TypeString, "Identifier of the platform which manages the account.",
+			Mutability(MutabilityReadOnly)),
+		Attr("userName", TypeString, "Name of the user on the target system.",
+			Mutability(MutabilityReadOnly)),
+	},
+}
+
+// ContainerPermission is CyberArk PAM safe permission schema.
+//
+// Permissions are synchronized from PAM SCIM server and are read-only.
+var ContainerPermission = scim.Schema{
+	ID:          scim.SchemaContainerPermission,
+	Name:        "ContainerPermission",
+	Description: "Set of CyberArk PAM safe permissions granted to a user or a group",
+	Attributes: []scim.Attribute{
+		Attr("container", TypeComplex, "Safe which permissions are granted to.",
+			Required, Mutability(MutabilityReadOnly), reference("Name of the safe.", "Container")),
+		Attr("user", TypeComplex, "User which permissions are granted to.",
+			Mutability(MutabilityReadOnly), reference("Identifier of the user.", "User")),
+		Attr("group", TypeComplex, "Group which permissions are granted to.",
+			Mutability(MutabilityReadOnly), reference("Identifier of the group.", "Group")),
+		Attr("rights", TypeString, "Granted safe permissions.",
+			MultiValued, Mutability(MutabilityReadOnly)),
+	},
+}
@@ -0,0 +1,244 @@
+package schema
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+)
+
+// Project returns resource value, like scim.User, with attributes
+// selected according to attribute "returned" rules and
+// "attributes" and "excludedAttributes" request parameters.
+//
+//   - attributes with "never" returned rule are always removed;
+//   - attributes with "always" returned rule are always kept;
+//   - attributes with "request" returned rule are kept only if listed in attributes;
+//   - if attributes are passed, only listed attributes are kept,
+//     otherwise attributes listed in excluded attributes are removed.
+//
+// Attribute names can contain schema URI and sub-attribute, like "name.givenName".
+// Returns *scim.Error with "invalidValue" type if attribute name is not valid.
+func (r Registry) Project(resourceType string, v interface{}, attributes, excluded []string) (map[string]interface{}, error) {
+	rt := r.mustResourceType(resourceType)
+	res, err := toObject(v)
+	if err != nil {
+		return nil, err
+	}
+
+	p := projection{core: rt.core.ID}
+	if p.attributes, err = parseAttrPaths("attributes", attributes); err != nil {
+		return nil, err
+	}
+	if p.excluded, err = parseAttrPaths("excludedAttributes", excluded); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]interface{}, len(res))
+	for key, val := range res {
+		if strings.EqualFold(key, "schemas") {
+			out[key] = val
+			continue
+		}
+
+		if ext := rt.extension(key); ext != nil {
+			if obj, ok := val.(map[string]interface{}); ok && p.includesExtension(ext.ID) {
+				if obj = p.project(ext.Attributes, ext.ID, obj); len(obj) > 0 {
+					out[key] = obj
+				}
+			}
+			continue
+		}
+
+		if rt.unregisteredExtension(key) {
+			if obj, ok := val.(map[string]interface{}); ok && p.includesExtension(key) {
+				if obj = p.projectUnregistered(key, obj); len(obj) > 0 {
+					out[key] = obj
+				}
+			}
+			continue
+		}
+
+		if a := findAttr(rt.attributes(), key); a != nil {
+			if v, ok := p.value(*a, p.core, val); ok {
+				out[key] = v
+			}
+		}
+	}
+	return out, nil
+}
+
+// projection holds parsed "attributes" and "excludedAttributes" parameters
+type projection struct {
+	core       string
+	attributes []filter.AttrPath
+	excluded   []filter.AttrPath
+}
+
+// project returns object with projected attributes
+func (p projection) project(attrs []scim.Attribute, uri string, obj map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(obj))
+	for key, val := range obj {
+		a := findAttr(attrs, key)
+		if a == nil {
+			continue
+		}
+
+		if v, ok := p.value(*a, uri, val); ok {
+			out[key] = v
+		}
+	}
+	return out
+}
+
+// projectUnregistered returns object of extension which is not registered.
+//
+// Extension attributes have no schema, so they are selected by name only.
+func (p projection) projectUnregistered(uri string, obj map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(obj))
+	for key, val := range obj {
+		switch {
+		case len(p.attributes) > 0:
+			if !p.extensionListed(uri) && !p.listed(p.attributes, uri, key, "", false) {
+				continue
+			}
+		case p.listed(p.excluded, uri, key, "", true):
+			continue
+		}
+		out[key] = val
+	}
+	return out
+}
+
+// value returns projected attribute value and whether attribute should be returned
+func (p projection) value(a scim.Attribute, uri string, val interface{}) (interface{}, bool) {
+	if !p.includes(a.Returned, uri, a.Name, "") {
+		return nil, false
+	}
+
+	if a.Type != TypeComplex || len(a.SubAttributes) == 0 {
+		return val, true
+	}
+
+	switch v := val.(type) {
+	case map[string]interface{}:
+		return p.subAttributes(a, uri, v), true
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if obj, ok := item.(map[string]interface{}); ok {
+				out = append(out, p.subAttributes(a, uri, obj))
+			}
+		}
+		return out, true
+	}
+	return val, true
+}
+
+func (p projection) subAttributes(a scim.Attribute, uri string, obj map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(obj))
+	for key, val := range obj {
+		sub := findAttr(a.SubAttributes, key)
+		if sub != nil && p.includes(sub.Returned, uri, a.Name, sub.Name) {
+			out[key] = val
+		}
+	}
+	return out
+}
+
+// includes reports whether attribute or sub-attribute should be returned
+func (p projection) includes(returned, uri, name, subAttr string) bool {
+	switch returned {
+	case ReturnedNever:
+		return false
+	case ReturnedAlways:
+		return true
+	}
+
+	if len(p.attributes) > 0 {
+		if returned != ReturnedRequest && p.extensionListed(uri) {
+			return true
+		}
+
+		if subAttr == "" {
+			return p.listed(p.attributes, uri, name, "", false)
+		}
+
+		// all sub-attributes are returned if parent attribute is requested
+		return p.listed(p.attributes, uri, name, "", true) ||
+			p.listed(p.attributes, uri, name, subAttr, true)
+	}
+
+	if returned == ReturnedRequest {
+		return false
+	}
+	return !p.listed(p.excluded, uri, name, "", true) &&
+		(subAttr == "" || !p.listed(p.excluded, uri, name, subAttr, true))
+}
+
+// includesExtension reports whether extension object should be returned.
+//
+// Extension attributes are checked separately, so extension is
+// removed only if it's listed in excluded attributes.
+func (p projection) includesExtension(uri string) bool {
+	for _, path := range p.excluded {
+		if strings.EqualFold(path.String(), uri) {
+			return false
+		}
+	}
+	return true
+}
+
+// extensionListed reports whether whole extension is listed in attributes
+func (p projection) extensionListed(uri string) bool {
+	if uri == p.core {
+		return false
+	}
+
+	for _, path := range p.attributes {
+		if strings.EqualFold(path.String(), uri) {
+			return true
+		}
+	}
+	return false
+}
+
+// listed reports whether attribute is listed in paths.
+//
+// If subAttr is empty and exact is false, attribute is listed
+// also if any of its sub-attributes is listed.
+func (p projection) listed(paths []filter.AttrPath, uri, name, subAttr string, exact bool) bool {
+	for _, path := range paths {
+		if !strings.EqualFold(path.Name, name) {
+			continue
+		}
+
+		if !strings.EqualFold(path.URI, uri) && (path.URI != "" || uri != p.core) {
+			continue
+		}
+
+		if strings.EqualFold(path.SubAttr, subAttr) || (!exact && subAttr == "") {
+			return true
+		}
+	}
+	return false
+}
+
+func parseAttrPaths(param string, names []string) ([]filter.AttrPath, error) {
+	out := make([]filter.AttrPath, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		path, err := filter.ParseAttrPath(name)
+		if err != nil {
+			return nil, scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidValue,
+				"invalid %s value %q", param, name)
+		}
+		out = append(out, path)
+	}
+	return out, nil
+}
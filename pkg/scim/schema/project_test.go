@@ -0,0 +1,189 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+const testUser = `{
+	"schemas": [
+		"urn:ietf:params:scim:schemas:core:2.0:User",
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	],
+	"id": "1",
+	"userName": "john",
+	"password": "secret",
+	"name": {"givenName": "John", "familyName": "Doe"},
+	"emails": [{"value": "john@example.com", "type": "work"}],
+	"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+		"employeeNumber": "42",
+		"manager": {"value": "2", "displayName": "Jane"}
+	}
+}`
+
+func TestRegistry_Project(t *testing.T) {
+	cases := map[string]struct {
+		attributes []string
+		excluded   []string
+		want       string
+	}{
+		"password is never returned": {
+			want: `{
+				"schemas": [
+					"urn:ietf:params:scim:schemas:core:2.0:User",
+					"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+				],
+				"id": "1",
+				"userName": "john",
+				"name": {"givenName": "John", "familyName": "Doe"},
+				"emails": [{"value": "john@example.com", "type": "work"}],
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+					"employeeNumber": "42",
+					"manager": {"value": "2", "displayName": "Jane"}
+				}
+			}`,
+		},
+		"requested password isn't returned": {
+			attributes: []string{"password"},
+			want:       `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"], "id": "1"}`,
+		},
+		"attribute": {
+			attributes: []string{"USERNAME"},
+			want:       `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"], "id": "1", "userName": "john"}`,
+		},
+		"attribute with core schema URI": {
+			attributes: []string{"urn:ietf:params:scim:schemas:core:2.0:User:userName"},
+			want:       `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"], "id": "1", "userName": "john"}`,
+		},
+		"complex attribute": {
+			attributes: []string{"name"},
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+				"id": "1",
+				"name": {"givenName": "John", "familyName": "Doe"}
+			}`,
+		},
+		"sub-attribute": {
+			attributes: []string{"name.givenName"},
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+				"id": "1",
+				"name": {"givenName": "John"}
+			}`,
+		},
+		"sub-attribute of multi-valued attribute": {
+			attributes: []string{"emails.value"},
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+				"id": "1",
+				"emails": [{"value": "john@example.com"}]
+			}`,
+		},
+		"extension": {
+			attributes: []string{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"},
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+				"id": "1",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+					"employeeNumber": "42",
+					"manager": {"value": "2", "displayName": "Jane"}
+				}
+			}`,
+		},
+		"extension attribute": {
+			attributes: []string{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber"},
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+				"id": "1",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"employeeNumber": "42"}
+			}`,
+		},
+		"extension sub-attribute": {
+			attributes: []string{"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value"},
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+				"id": "1",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"manager": {"value": "2"}}
+			}`,
+		},
+		"extension attribute without schema URI isn't matched": {
+			attributes: []string{"employeeNumber"},
+			want:       `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"], "id": "1"}`,
+		},
+		"excluded attribute": {
+			excluded: []string{"name", "emails", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"},
+			want:     `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"], "id": "1", "userName": "john"}`,
+		},
+		"excluded always returned attribute": {
+			excluded: []string{"id", "userName", "name", "emails", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"},
+			want:     `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"], "id": "1"}`,
+		},
+		"excluded sub-attribute": {
+			excluded: []string{"name.familyName", "emails.type", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"},
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+				"id": "1",
+				"userName": "john",
+				"name": {"givenName": "John"},
+				"emails": [{"value": "john@example.com"}]
+			}`,
+		},
+		"excluded extension attributes": {
+			excluded: []string{
+				"userName", "name", "emails",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:employeeNumber",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.displayName",
+			},
+			want: `{
+				"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+				"id": "1",
+				"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"manager": {"value": "2"}}
+			}`,
+		},
+		"attributes have precedence over excluded attributes": {
+			attributes: []string{"userName"},
+			excluded:   []string{"userName"},
+			want:       `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"], "id": "1", "userName": "john"}`,
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			got, err := testRegistry.Project("User", decodeObject(t, testUser), c.attributes, c.excluded)
+			require.NoError(t, err)
+			require.Equal(t, decodeObject(t, c.want), got)
+		})
+	}
+
+	t.Run("struct", func(t *testing.T) {
+		got, err := testRegistry.Project("User", scim.User{ID: "1", UserName: "john", Password: "secret"}, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"id": "1", "userName": "john"}, got)
+	})
+
+	t.Run("unregistered extension", func(t *testing.T) {
+		group := decodeObject(t, `{
+			"id": "1",
+			"displayName": "Admins",
+			"urn:example:scim:Group": {"level": 3, "owner": "john"}
+		}`)
+
+		got, err := testRegistry.Project("Group", group, []string{"urn:example:scim:Group:level"}, nil)
+		require.NoError(t, err)
+		require.Equal(t, decodeObject(t, `{"id": "1", "urn:example:scim:Group": {"level": 3}}`), got)
+
+		got, err = testRegistry.Project("Group", group, nil, []string{"urn:example:scim:Group"})
+		require.NoError(t, err)
+		require.Equal(t, decodeObject(t, `{"id": "1", "displayName": "Admins"}`), got)
+	})
+
+	t.Run("invalid attribute", func(t *testing.T) {
+		_, err := testRegistry.Project("User", decodeObject(t, testUser), nil, []string{"name.given.name"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `invalid excludedAttributes value "name.given.name"`)
+		require.True(t, errors.Is(err, scim.ErrInvalidValue), "invalidValue error is expected")
+	})
+}
@@ -0,0 +1,252 @@
+package schema
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/patch"
+)
+
+// Registry is a set of resource types and schemas supported by service provider.
+//
+// Resource types should be registered before registry is used,
+// registry is not safe for concurrent modification.
+type Registry struct {
+	basePath string
+	config   scim.ServiceProviderConfig
+	types    []*resourceType
+	schemas  []scim.Schema
+}
+
+// resourceType is registered resource type with its schemas
+type resourceType struct {
+	scim.ResourceType
+
+	core       scim.Schema
+	extensions []scim.Schema
+
+	// allowExtensions enables schema extensions which are not registered
+	allowExtensions bool
+}
+
+// NewRegistry constructs a new registry.
+//
+// Base path is SCIM service root path like "/scim/v2",
+// used as location of discovery documents.
+func NewRegistry(basePath string, cfg scim.ServiceProviderConfig) *Registry {
+	return &Registry{
+		basePath: strings.TrimSuffix(basePath, "/"),
+		config:   cfg,
+	}
+}
+
+// Register adds resource type with core schema and optional schema extensions.
+//
+// Resource type schema and schema extensions are filled from passed schemas.
+// Schemas shared by several resource types are published once.
+//
+// Register panics if resource type is already registered.
+func (r *Registry) Register(rt scim.ResourceType, core scim.Schema, extensions ...scim.Schema) *Registry {
+	if r.resourceType(rt.Name) != nil {
+		panic(fmt.Sprintf("scim/schema: resource type %q is already registered", rt.Name))
+	}
+
+	if rt.ID == "" {
+		rt.ID = rt.Name
+	}
+
+	rt.Schema = core.ID
+	rt.SchemaExtensions = make([]scim.SchemaExtension, 0, len(extensions))
+	for _, ext := range extensions {
+		rt.SchemaExtensions = append(rt.SchemaExtensions, scim.SchemaExtension{Schema: ext.ID})
+	}
+
+	r.types = append(r.types, &resourceType{ResourceType: rt, core: core, extensions: extensions})
+	for _, s := range append([]scim.Schema{core}, extensions...) {
+		if _, err := r.Schema(s.ID); err != nil {
+			r.schemas = append(r.schemas, s)
+		}
+	}
+	return r
+}
+
+// AllowExtensions makes resource type accept schema extensions which are not
+// registered, like vendor-specific extensions.
+//
+// Attributes of such extensions are not validated and are returned as is,
+// extensions are not published in discovery documents.
+//
+// AllowExtensions panics if resource type is not registered.
+func (r *Registry) AllowExtensions(name string) *Registry {
+	r.mustResourceType(name).allowExtensions = true
+	return r
+}
+
+// ServiceProviderConfig returns service provider configuration document
+func (r Registry) ServiceProviderConfig() scim.ServiceProviderConfig {
+	out := r.config
+	out.Schemas = []string{scim.SchemaServiceProviderConfig}
+	out.Meta = r.meta("ServiceProviderConfig", scim.EndpointServiceProviderConfig)
+	if out.AuthenticationSchemes == nil {
+		out.AuthenticationSchemes = []scim.AuthenticationScheme{}
+	}
+	return out
+}
+
+// ResourceTypes returns all registered resource types
+func (r Registry) ResourceTypes() []scim.ResourceType {
+	out := make([]scim.ResourceType, 0, len(r.types))
+	for _, rt := range r.types {
+		out = append(out, r.resourceTypeDocument(rt))
+	}
+	return out
+}
+
+// ResourceType returns resource type by name.
+//
+// Returns *scim.Error with "404 Not Found" status if resource type is not registered.
+func (r Registry) ResourceType(name string) (*scim.ResourceType, error) {
+	rt := r.resourceType(name)
+	if rt == nil {
+		return nil, scim.NewError(http.StatusNotFound, "", "resource type %q not found", name)
+	}
+
+	out := r.resourceTypeDocument(rt)
+	return &out, nil
+}
+
+// Schemas returns all schemas of registered resource types
+func (r Registry) Schemas() []scim.Schema {
+	out := make([]scim.Schema, 0, len(r.schemas))
+	for _, s := range r.schemas {
+		out = append(out, r.schemaDocument(s))
+	}
+	return out
+}
+
+// Schema returns schema by URI.
+//
+// Returns *scim.Error with "404 Not Found" status if schema is not registered.
+func (r Registry) Schema(id string) (*scim.Schema, error) {
+	for _, s := range r.schemas {
+		if strings.EqualFold(s.ID, id) {
+			out := r.schemaDocument(s)
+			return &out, nil
+		}
+	}
+	return nil, scim.NewError(http.StatusNotFound, "", "schema %q not found", id)
+}
+
+// Patcher returns PATCH engine for resource type.
+//
+// Read-only, immutable and multi-valued attributes are taken from resource type core schema,
+// mutability of sub-attributes is included as well.
+// Patcher panics if resource type is not registered.
+func (r Registry) Patcher(name string) patch.Patcher {
+	rt := r.mustResourceType(name)
+	p := patch.Patcher{Schema: rt.core.ID}
+	for _, a := range rt.attributes() {
+		addMutability(&p, a.Name, a.Mutability)
+		for _, sa := range a.SubAttributes {
+			addMutability(&p, a.Name+"."+sa.Name, sa.Mutability)
+		}
+
+		if a.MultiValued {
+			p.MultiValued = append(p.MultiValued, a.Name)
+		}
+	}
+
+	p.MultiValued = append(p.MultiValued, "schemas")
+	return p
+}
+
+// addMutability adds read-only or immutable attribute to patcher
+func addMutability(p *patch.Patcher, name, mutability string) {
+	switch mutability {
+	case MutabilityReadOnly:
+		p.ReadOnly = append(p.ReadOnly, name)
+	case MutabilityImmutable:
+		p.Immutable = append(p.Immutable, name)
+	}
+}
+
+func (r Registry) resourceType(name string) *resourceType {
+	for _, rt := range r.types {
+		if strings.EqualFold(rt.Name, name) {
+			return rt
+		}
+	}
+	return nil
+}
+
+func (r Registry) mustResourceType(name string) *resourceType {
+	rt := r.resourceType(name)
+	if rt == nil {
+		panic(fmt.Sprintf("scim/schema: resource type %q is not registered", name))
+	}
+	return rt
+}
+
+func (r Registry) resourceTypeDocument(rt *resourceType) scim.ResourceType {
+	out := rt.ResourceType
+	out.Schemas = []string{scim.SchemaResourceType}
+	out.Meta = r.meta("ResourceType", scim.EndpointResourceTypes+"/"+out.ID)
+	return out
+}
+
+func (r Registry) schemaDocument(s scim.Schema) scim.Schema {
+	s.Schemas = []string{scim.SchemaSchema}
+	s.Meta = r.meta("Schema", scim.EndpointSchemas+"/"+s.ID)
+	return s
+}
+
+func (r Registry) meta(resourceType, endpoint string) *scim.Meta {
+	return &scim.Meta{
+		ResourceType: resourceType,
+		Location:     r.basePath + endpoint,
+	}
+}
+
+// extension returns resource type schema extension by URI
+func (rt resourceType) extension(uri string) *scim.Schema {
+	for i := range rt.extensions {
+		if strings.EqualFold(rt.extensions[i].ID, uri) {
+			return &rt.extensions[i]
+		}
+	}
+	return nil
+}
+
+// unregisteredExtension reports whether attribute is schema extension
+// which is not registered, but accepted by resource type.
+func (rt resourceType) unregisteredExtension(name string) bool {
+	return rt.allowExtensions && isSchemaURI(name) &&
+		!strings.EqualFold(name, rt.core.ID) && rt.extension(name) == nil
+}
+
+// attributes returns common and core schema attributes
+func (rt resourceType) attributes() []scim.Attribute {
+	return append(append([]scim.Attribute{}, commonAttributes...), rt.core.Attributes...)
+}
+
+func findAttr(attrs []scim.Attribute, name string) *scim.Attribute {
+	for i := range attrs {
+		if strings.EqualFold(attrs[i].Name, name) {
+			return &attrs[i]
+		}
+	}
+	return nil
+}
+
+func splitAttrName(name string) (string, string) {
+	if i := strings.IndexByte(name, '.'); i != -1 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+func isSchemaURI(s string) bool {
+	return strings.HasPrefix(strings.ToLower(s), "urn:")
+}
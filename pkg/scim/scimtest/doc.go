@@ -0,0 +1,21 @@
+// Package scimtest provides an in-memory fake of CyberArk PAM SCIM server
+// for tests and local development.
+//
+// Handler keeps users, groups, containers, container permissions and accounts
+// in memory and serves them at the same endpoints as PAM SCIM server does.
+// Lists support filters, sorting, "startIndex" paging and attribute projection,
+// users and groups can be created, replaced, patched and deleted with
+// ETag preconditions. Containers, permissions and accounts are read-only
+// and are seeded with Add methods or a Fixture.
+//
+// OAuth2 client credentials token endpoint is served at TokenPath,
+// SCIM requests require an issued token if client credentials are configured.
+//
+// Latency, throttling and errors can be injected into responses, see Fault:
+//
+//	srv := scimtest.NewServer(scimtest.Options{})
+//	defer srv.Close()
+//
+//	srv.Inject(scimtest.Throttle(time.Second, 2))
+//	client := srv.SCIMClient()
+package scimtest
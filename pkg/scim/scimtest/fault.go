@@ -0,0 +1,148 @@
+package scimtest
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// Fault is a failure injected into responses of matching requests
+type Fault struct {
+	// Method is request method, fault matches any method if empty
+	Method string
+
+	// Path is request path prefix like "/Users", fault matches any path if empty
+	Path string
+
+	// Latency delays response
+	Latency time.Duration
+
+	// Status is error response status code, request is served normally after latency if zero
+	Status int
+
+	// RetryAfter is sent in "Retry-After" header of error response if set
+	RetryAfter time.Duration
+
+	// Times is number of requests affected by fault, fault is never removed if zero
+	Times int
+
+	// Probability is chance of matching request to be affected, every request is affected if zero
+	Probability float64
+}
+
+// Latency returns fault which delays all responses
+func Latency(d time.Duration) Fault {
+	return Fault{Latency: d}
+}
+
+// Throttle returns fault which fails a number of requests with "429 Too Many Requests"
+func Throttle(retryAfter time.Duration, times int) Fault {
+	return Fault{Status: http.StatusTooManyRequests, RetryAfter: retryAfter, Times: times}
+}
+
+// Failure returns fault which fails a number of requests with status code
+func Failure(status, times int) Fault {
+	return Fault{Status: status, Times: times}
+}
+
+func (f Fault) matches(r *http.Request) bool {
+	if f.Method != "" && !strings.EqualFold(f.Method, r.Method) {
+		return false
+	}
+	if f.Path != "" && !strings.HasPrefix(strings.ToLower(r.URL.Path), strings.ToLower(f.Path)) {
+		return false
+	}
+	return f.Probability <= 0 || rand.Float64() < f.Probability
+}
+
+// injectedFault is fault with remaining number of affected requests
+type injectedFault struct {
+	Fault
+	left int
+}
+
+// Inject adds faults, faults are applied in order of injection.
+//
+// Latencies of all matching faults are summed up,
+// error response is sent by the first matching fault with status code,
+// other faults with status code are not applied to the request.
+func (h *Handler) Inject(faults ...Fault) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, f := range faults {
+		h.faults = append(h.faults, &injectedFault{Fault: f, left: f.Times})
+	}
+}
+
+// ClearFaults removes all injected faults
+func (h *Handler) ClearFaults() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.faults = nil
+}
+
+// injectFault applies faults matching request.
+//
+// Returns true if error response was sent.
+func (h *Handler) injectFault(w http.ResponseWriter, r *http.Request) bool {
+	var latency time.Duration
+	var failure *Fault
+
+	h.mu.Lock()
+	active := h.faults[:0]
+	for _, f := range h.faults {
+		// status faults after the first matching one don't affect request
+		if (f.Status == 0 || failure == nil) && f.matches(r) {
+			latency += f.Latency
+			if f.Status != 0 {
+				failure = &Fault{Status: f.Status, RetryAfter: f.RetryAfter}
+			}
+
+			if f.Times > 0 {
+				if f.left--; f.left <= 0 {
+					continue
+				}
+			}
+		}
+		active = append(active, f)
+	}
+	h.faults = active
+	h.mu.Unlock()
+
+	if err := sleep(r.Context(), latency); err != nil {
+		return true
+	}
+
+	if failure == nil {
+		return false
+	}
+
+	if failure.RetryAfter > 0 {
+		secs := (failure.RetryAfter + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.Itoa(int(secs)))
+	}
+
+	writeError(w, scim.NewError(failure.Status, "", "injected fault"))
+	return true
+}
+
+// sleep waits for duration or until context is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
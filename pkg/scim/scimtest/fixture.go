@@ -0,0 +1,68 @@
+package scimtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// Fixture is a set of resources to seed fake server with.
+//
+// Resources without ID get assigned IDs, group members and
+// permission subjects can reference resources by IDs set in fixture.
+type Fixture struct {
+	Users                []scim.User                `json:"users"`
+	Groups               []scim.Group               `json:"groups"`
+	Containers           []scim.Container           `json:"containers"`
+	ContainerPermissions []scim.ContainerPermission `json:"containerPermissions"`
+	PrivilegedData       []scim.PrivilegedData      `json:"privilegedData"`
+}
+
+// ReadFixture reads fixture from JSON file
+func ReadFixture(path string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	out := new(Fixture)
+	if err = json.Unmarshal(data, out); err != nil {
+		return nil, fmt.Errorf("invalid fixture %q: %w", path, err)
+	}
+	return out, nil
+}
+
+// Load adds fixture resources.
+//
+// Resources are added in order: users, groups, containers, container permissions and accounts.
+// Resources added before failure are kept.
+func (h *Handler) Load(f Fixture) error {
+	for _, u := range f.Users {
+		if _, err := h.AddUser(u); err != nil {
+			return fmt.Errorf("user %q: %w", u.UserName, err)
+		}
+	}
+	for _, g := range f.Groups {
+		if _, err := h.AddGroup(g); err != nil {
+			return fmt.Errorf("group %q: %w", g.DisplayName, err)
+		}
+	}
+	for _, c := range f.Containers {
+		if _, err := h.AddContainer(c); err != nil {
+			return fmt.Errorf("container %q: %w", c.Name, err)
+		}
+	}
+	for _, p := range f.ContainerPermissions {
+		if _, err := h.AddContainerPermission(p); err != nil {
+			return fmt.Errorf("permission of container %q: %w", p.Container.Value, err)
+		}
+	}
+	for _, d := range f.PrivilegedData {
+		if _, err := h.AddPrivilegedData(d); err != nil {
+			return fmt.Errorf("account %q: %w", d.Name, err)
+		}
+	}
+	return nil
+}
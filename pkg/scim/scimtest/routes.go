@@ -0,0 +1,355 @@
+package scimtest
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/filter"
+	"github.com/strick-j/scimfe/pkg/scim/patch"
+)
+
+// maxBodySize is max size of request body
+const maxBodySize = 1 << 20
+
+// route serves SCIM request and returns response status and body
+func (h *Handler) route(header http.Header, r *http.Request) (int, interface{}, error) {
+	endpoint, id := splitPath(r.URL.Path)
+	switch endpoint {
+	case scim.EndpointServiceProviderConfig, scim.EndpointResourceTypes, scim.EndpointSchemas:
+		if r.Method != http.MethodGet {
+			return 0, nil, errMethodNotAllowed(r)
+		}
+
+		out, err := h.discovery(endpoint, id)
+		return http.StatusOK, out, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c := h.collectionAt(endpoint)
+	if c == nil {
+		return 0, nil, scim.NewError(http.StatusNotFound, "", "endpoint %q not found", r.URL.Path)
+	}
+
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		out, err := h.list(c, r.URL.Query())
+		return http.StatusOK, out, err
+	case id == "" && r.Method == http.MethodPost && c.writable:
+		out, err := h.create(c, header, r)
+		return http.StatusCreated, out, err
+	case id != "" && r.Method == http.MethodGet:
+		out, err := h.get(c, id, header, r.URL.Query())
+		return http.StatusOK, out, err
+	case id != "" && r.Method == http.MethodPut && c.writable:
+		out, err := h.replace(c, id, header, r)
+		return http.StatusOK, out, err
+	case id != "" && r.Method == http.MethodPatch && c.writable:
+		out, err := h.patch(c, id, header, r)
+		return http.StatusOK, out, err
+	case id != "" && r.Method == http.MethodDelete && c.writable:
+		return http.StatusNoContent, nil, h.delete(c, id, r)
+	}
+	return 0, nil, errMethodNotAllowed(r)
+}
+
+func (h *Handler) discovery(endpoint, id string) (interface{}, error) {
+	switch {
+	case endpoint == scim.EndpointServiceProviderConfig:
+		return h.schemas.ServiceProviderConfig(), nil
+	case endpoint == scim.EndpointResourceTypes && id != "":
+		return h.schemas.ResourceType(id)
+	case endpoint == scim.EndpointResourceTypes:
+		list := h.schemas.ResourceTypes()
+		return scim.NewListResponse(list, len(list), 1, len(list)), nil
+	case id != "":
+		return h.schemas.Schema(id)
+	default:
+		list := h.schemas.Schemas()
+		return scim.NewListResponse(list, len(list), 1, len(list)), nil
+	}
+}
+
+// list returns a page of resources matching list query, see RFC 7644 section 3.4.2
+func (h *Handler) list(c *collection, q url.Values) (*scim.ListResponse, error) {
+	expr, err := filter.Parse(q.Get("filter"))
+	if err != nil {
+		return nil, err
+	}
+
+	startIndex, err := intParam(q, "startIndex", 1)
+	if err != nil {
+		return nil, err
+	}
+	if startIndex < 1 {
+		startIndex = 1
+	}
+
+	count, err := intParam(q, "count", h.opts.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > h.opts.PageSize {
+		count = h.opts.PageSize
+	}
+
+	var matched []map[string]interface{}
+	for _, id := range c.order {
+		if res := h.render(c, c.items[id]); filter.Matches(expr, res) {
+			matched = append(matched, res)
+		}
+	}
+
+	if sortBy := q.Get("sortBy"); sortBy != "" {
+		path, err := filter.ParseAttrPath(sortBy)
+		if err != nil {
+			return nil, scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidValue, "invalid sortBy: %s", sortBy)
+		}
+
+		desc := strings.EqualFold(q.Get("sortOrder"), scim.SortDescending)
+		sort.SliceStable(matched, func(i, j int) bool {
+			a, b := sortValue(matched[i], path), sortValue(matched[j], path)
+			if desc {
+				return a > b
+			}
+			return a < b
+		})
+	}
+
+	total := len(matched)
+	from, to := startIndex-1, startIndex-1+count
+	if from > total {
+		from = total
+	}
+	if to > total {
+		to = total
+	}
+
+	attrs, excluded := listParam(q, "attributes"), listParam(q, "excludedAttributes")
+	out := make([]interface{}, 0, to-from)
+	for _, res := range matched[from:to] {
+		res, err := h.schemas.Project(c.resourceType, res, attrs, excluded)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res)
+	}
+	return scim.NewListResponse(out, total, startIndex, len(out)), nil
+}
+
+func (h *Handler) get(c *collection, id string, header http.Header, q url.Values) (interface{}, error) {
+	res, err := h.find(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res = h.render(c, res)
+	header.Set(scim.HeaderETag, version(res))
+	return h.schemas.Project(c.resourceType, res, listParam(q, "attributes"), listParam(q, "excludedAttributes"))
+}
+
+func (h *Handler) create(c *collection, header http.Header, r *http.Request) (interface{}, error) {
+	res, err := h.decode(c, r)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(res, "id")
+	if res, err = h.insert(c, res); err != nil {
+		return nil, err
+	}
+
+	header.Set("Location", stringAttr(res["meta"].(map[string]interface{}), "location"))
+	return h.respond(c, res, header, r)
+}
+
+func (h *Handler) replace(c *collection, id string, header http.Header, r *http.Request) (interface{}, error) {
+	cur, err := h.find(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = scim.PreconditionFromHeader(r.Header).Check(version(cur)); err != nil {
+		return nil, err
+	}
+
+	res, err := h.decode(c, r)
+	if err != nil {
+		return nil, err
+	}
+
+	res["id"] = id
+	if res, err = h.save(c, id, res, createdAt(cur)); err != nil {
+		return nil, err
+	}
+	return h.respond(c, res, header, r)
+}
+
+func (h *Handler) patch(c *collection, id string, header http.Header, r *http.Request) (interface{}, error) {
+	cur, err := h.find(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = scim.PreconditionFromHeader(r.Header).Check(version(cur)); err != nil {
+		return nil, err
+	}
+
+	data, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var req scim.PatchRequest
+	if err = json.Unmarshal(data, &req); err != nil {
+		return nil, scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "cannot read PATCH request: %s", err)
+	}
+	if err = patch.ValidateRequest(req); err != nil {
+		return nil, err
+	}
+
+	res := copyObject(cur)
+	if err = h.schemas.Patcher(c.resourceType).Apply(res, req.Operations); err != nil {
+		return nil, err
+	}
+	if err = h.schemas.Validate(c.resourceType, res); err != nil {
+		return nil, err
+	}
+
+	if res, err = h.save(c, id, res, createdAt(cur)); err != nil {
+		return nil, err
+	}
+	return h.respond(c, res, header, r)
+}
+
+func (h *Handler) delete(c *collection, id string, r *http.Request) error {
+	cur, err := h.find(c, id)
+	if err != nil {
+		return err
+	}
+	if err = scim.PreconditionFromHeader(r.Header).Check(version(cur)); err != nil {
+		return err
+	}
+
+	h.remove(c, id)
+	return nil
+}
+
+func (h *Handler) find(c *collection, id string) (map[string]interface{}, error) {
+	res, ok := c.items[id]
+	if !ok {
+		return nil, scim.NewError(http.StatusNotFound, "", "%s %q not found", c.resourceType, id)
+	}
+	return res, nil
+}
+
+// decode reads resource from request body and validates it against resource type schemas
+func (h *Handler) decode(c *collection, r *http.Request) (map[string]interface{}, error) {
+	data, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]interface{})
+	return res, h.schemas.Decode(c.resourceType, data, &res)
+}
+
+// respond returns modified resource projected according to request parameters
+func (h *Handler) respond(c *collection, res map[string]interface{}, header http.Header, r *http.Request) (interface{}, error) {
+	header.Set(scim.HeaderETag, version(res))
+	q := r.URL.Query()
+	return h.schemas.Project(c.resourceType, res, listParam(q, "attributes"), listParam(q, "excludedAttributes"))
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return nil, scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "cannot read request body: %s", err)
+	}
+	return data, nil
+}
+
+// splitPath returns endpoint and resource ID from request path like "/Users/2819c223"
+func splitPath(p string) (string, string) {
+	p = strings.Trim(p, "/")
+	if i := strings.IndexByte(p, '/'); i != -1 {
+		return "/" + p[:i], p[i+1:]
+	}
+	return "/" + p, ""
+}
+
+func intParam(q url.Values, name string, defaultValue int) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return defaultValue, nil
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidValue, "invalid %s: %q is not a number", name, v)
+	}
+	return n, nil
+}
+
+// listParam returns comma-separated list parameter
+func listParam(q url.Values, name string) []string {
+	var out []string
+	for _, v := range strings.Split(q.Get(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// sortValue returns lower-cased string value of attribute to sort resources by.
+//
+// The first value is used for multi-valued attributes.
+func sortValue(res map[string]interface{}, path filter.AttrPath) string {
+	obj := res
+	if path.URI != "" {
+		if _, ext, ok := filter.Lookup(res, path.URI); ok {
+			obj, _ = ext.(map[string]interface{})
+		}
+	}
+
+	_, val, _ := filter.Lookup(obj, path.Name)
+	if list, ok := val.([]interface{}); ok {
+		if len(list) == 0 {
+			return ""
+		}
+		val = list[0]
+	}
+
+	if path.SubAttr != "" {
+		sub, _ := val.(map[string]interface{})
+		_, val, _ = filter.Lookup(sub, path.SubAttr)
+	}
+
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.ToLower(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+func version(res map[string]interface{}) string {
+	meta, _ := res["meta"].(map[string]interface{})
+	return stringAttr(meta, "version")
+}
+
+func errMethodNotAllowed(r *http.Request) error {
+	return scim.NewError(http.StatusMethodNotAllowed, "", "method %s is not allowed for %s", r.Method, r.URL.Path)
+}
@@ -0,0 +1,283 @@
+package scimtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/schema"
+)
+
+// TokenPath is path of OAuth2 client credentials token endpoint
+const TokenPath = "/oauth2/token"
+
+const (
+	// DefaultPageSize is default max number of list results per page
+	DefaultPageSize = 100
+
+	defaultTokenTTL = time.Hour
+
+	// maxRequests is number of recent requests kept in request log
+	maxRequests = 1000
+)
+
+// Options configures fake server
+type Options struct {
+	// ClientID and ClientSecret are OAuth2 client credentials accepted by token endpoint.
+	//
+	// Authentication is disabled if client ID is empty: token endpoint
+	// accepts any credentials and SCIM requests don't require a token.
+	ClientID     string
+	ClientSecret string
+
+	// PageSize is max number of list results per page, DefaultPageSize is used if zero
+	PageSize int
+
+	// TokenTTL is lifetime of issued access tokens, one hour if zero
+	TokenTTL time.Duration
+
+	// Now returns time of resource modifications, time.Now is used if nil.
+	//
+	// Can be used to emulate servers with second precision of "meta.lastModified".
+	Now func() time.Time
+}
+
+// Request is served request recorded in request log
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Status int
+	Time   time.Time
+}
+
+// Handler is http.Handler of fake PAM SCIM server.
+//
+// Handler is safe for concurrent use.
+type Handler struct {
+	opts    Options
+	schemas *schema.Registry
+
+	mu       sync.Mutex
+	types    []*collection
+	lastID   int
+	tokens   map[string]time.Time
+	faults   []*injectedFault
+	requests []Request
+}
+
+// NewHandler is Handler constructor
+func NewHandler(opts Options) *Handler {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+	if opts.TokenTTL <= 0 {
+		opts.TokenTTL = defaultTokenTTL
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	return &Handler{
+		opts:    opts,
+		schemas: newRegistry(opts.PageSize),
+		types:   newCollections(),
+		tokens:  make(map[string]time.Time),
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	defer h.record(r, rec)
+
+	if h.injectFault(rec, r) {
+		return
+	}
+
+	if r.URL.Path == TokenPath {
+		h.serveToken(rec, r)
+		return
+	}
+
+	if err := h.authorize(r); err != nil {
+		writeError(rec, err)
+		return
+	}
+
+	status, out, err := h.route(rec.Header(), r)
+	if err != nil {
+		writeError(rec, err)
+		return
+	}
+
+	writeJSON(rec, status, contentType(r), out)
+}
+
+// Requests returns recent served requests in order, including requests failed by faults
+func (h *Handler) Requests() []Request {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Request(nil), h.requests...)
+}
+
+// IssueToken returns a new valid access token
+func (h *Handler) IssueToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	tkn := hex.EncodeToString(buf)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tokens[tkn] = time.Now().Add(h.opts.TokenTTL)
+	return tkn
+}
+
+func (h *Handler) record(r *http.Request, rec *statusRecorder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.requests) >= maxRequests {
+		h.requests = append(h.requests[:0], h.requests[1:]...)
+	}
+
+	h.requests = append(h.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.RawQuery,
+		Status: rec.status,
+		Time:   time.Now(),
+	})
+}
+
+// tokenResponse is OAuth2 token endpoint response, see RFC 6749 section 5
+type tokenResponse struct {
+	AccessToken string `json:"access_token,omitempty"`
+	TokenType   string `json:"token_type,omitempty"`
+	ExpiresIn   int64  `json:"expires_in,omitempty"`
+
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func (h *Handler) serveToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, "application/json",
+			tokenResponse{Error: "invalid_request", ErrorDescription: "POST method is expected"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+		writeJSON(w, http.StatusBadRequest, "application/json",
+			tokenResponse{Error: "unsupported_grant_type", ErrorDescription: "client_credentials grant is expected"})
+		return
+	}
+
+	if h.opts.ClientID != "" {
+		id, secret, _ := r.BasicAuth()
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+		if id != h.opts.ClientID || secret != h.opts.ClientSecret {
+			writeJSON(w, http.StatusUnauthorized, "application/json",
+				tokenResponse{Error: "invalid_client", ErrorDescription: "invalid client credentials"})
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, "application/json", tokenResponse{
+		AccessToken: h.IssueToken(),
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(h.opts.TokenTTL / time.Second),
+	})
+}
+
+// authorize checks request access token
+func (h *Handler) authorize(r *http.Request) error {
+	if h.opts.ClientID == "" {
+		return nil
+	}
+
+	tkn := r.Header.Get("Authorization")
+	if len(tkn) < 7 || !strings.EqualFold(tkn[:7], "bearer ") {
+		return scim.NewError(http.StatusUnauthorized, "", "bearer token is required")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	expires, ok := h.tokens[strings.TrimSpace(tkn[7:])]
+	if !ok || time.Now().After(expires) {
+		return scim.NewError(http.StatusUnauthorized, "", "invalid or expired access token")
+	}
+	return nil
+}
+
+// contentType returns response content type accepted by request,
+// SCIM media type is used unless only JSON is accepted.
+func contentType(r *http.Request) string {
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "application/json") && !strings.Contains(accept, scim.ContentType) {
+		return "application/json"
+	}
+	return scim.ContentType
+}
+
+func writeJSON(w http.ResponseWriter, status int, mediaType string, v interface{}) {
+	if v == nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var serr *scim.Error
+	if !errors.As(err, &serr) {
+		serr = scim.NewError(http.StatusInternalServerError, "", err.Error())
+	}
+
+	writeJSON(w, serr.StatusCode, scim.ContentType, serr)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Server is fake PAM SCIM server listening on a system-chosen port of local loopback interface
+type Server struct {
+	*Handler
+	*httptest.Server
+}
+
+// NewServer starts and returns a new fake server.
+//
+// Caller should call Close when finished, to shut it down.
+func NewServer(opts Options) *Server {
+	h := NewHandler(opts)
+	return &Server{Handler: h, Server: httptest.NewServer(h)}
+}
+
+// TokenURL returns URL of token endpoint
+func (s *Server) TokenURL() string {
+	return s.URL + TokenPath
+}
+
+// SCIMClient returns SCIM client of fake server which uses a valid access token
+func (s *Server) SCIMClient() *scim.Client {
+	return scim.NewClient(s.Client(), s.URL, scim.StaticToken(s.IssueToken()))
+}
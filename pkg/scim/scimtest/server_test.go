@@ -0,0 +1,265 @@
+package scimtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+func TestHandler_List(t *testing.T) {
+	h := NewHandler(Options{PageSize: 2})
+	require.NoError(t, h.Load(Fixture{Users: []scim.User{
+		{UserName: "alice"},
+		{UserName: "bob"},
+		{UserName: "carol"},
+	}}))
+
+	cases := map[string]struct {
+		query     string
+		total     int
+		userNames []string
+	}{
+		"first page is limited by page size": {
+			query:     "",
+			total:     3,
+			userNames: []string{"alice", "bob"},
+		},
+		"start index": {
+			query:     "startIndex=3",
+			total:     3,
+			userNames: []string{"carol"},
+		},
+		"count": {
+			query:     "count=1&startIndex=2",
+			total:     3,
+			userNames: []string{"bob"},
+		},
+		"start index after last result": {
+			query: "startIndex=10",
+			total: 3,
+		},
+		"filter": {
+			query:     "filter=" + url.QueryEscape(`userName eq "Bob"`),
+			total:     1,
+			userNames: []string{"bob"},
+		},
+		"sort": {
+			query:     "sortBy=userName&sortOrder=descending",
+			total:     3,
+			userNames: []string{"carol", "bob"},
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			rsp := serve(h, http.MethodGet, scim.EndpointUsers+"?"+c.query, nil, nil)
+			require.Equal(t, http.StatusOK, rsp.Code, rsp.Body.String())
+
+			var list struct {
+				TotalResults int
+				Resources    []scim.User
+			}
+			require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &list))
+			require.Equal(t, c.total, list.TotalResults)
+
+			var got []string
+			for _, u := range list.Resources {
+				got = append(got, u.UserName)
+			}
+			require.Equal(t, c.userNames, got)
+		})
+	}
+
+	t.Run("invalid filter", func(t *testing.T) {
+		rsp := serve(h, http.MethodGet, scim.EndpointUsers+"?filter=userName", nil, nil)
+		require.Equal(t, http.StatusBadRequest, rsp.Code)
+	})
+}
+
+func TestHandler_LastModifiedFilter(t *testing.T) {
+	now := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	h := NewHandler(Options{Now: func() time.Time { return now }})
+	_, err := h.AddUser(scim.User{UserName: "alice"})
+	require.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	_, err = h.AddUser(scim.User{UserName: "bob"})
+	require.NoError(t, err)
+
+	q := url.QueryEscape(`meta.lastModified ge "` + now.Format(time.RFC3339) + `"`)
+	rsp := serve(h, http.MethodGet, scim.EndpointUsers+"?filter="+q, nil, nil)
+	require.Equal(t, http.StatusOK, rsp.Code, rsp.Body.String())
+	require.Contains(t, rsp.Body.String(), `"bob"`)
+	require.NotContains(t, rsp.Body.String(), `"alice"`)
+}
+
+func TestHandler_Patch(t *testing.T) {
+	h := NewHandler(Options{})
+	require.NoError(t, h.Load(Fixture{
+		Users:  []scim.User{{ID: "1", UserName: "alice"}, {ID: "2", UserName: "bob"}},
+		Groups: []scim.Group{{ID: "10", DisplayName: "admins", Members: []scim.Reference{{Value: "1"}}}},
+	}))
+
+	path := scim.EndpointGroups + "/10"
+	rsp := serve(h, http.MethodGet, path, nil, nil)
+	require.Equal(t, http.StatusOK, rsp.Code)
+	etag := rsp.Header().Get(scim.HeaderETag)
+	require.NotEmpty(t, etag)
+
+	req := scim.NewPatchRequest(
+		scim.PatchOperation{Op: "add", Path: "members", Value: []interface{}{map[string]interface{}{"value": "2"}}},
+		scim.PatchOperation{Op: "remove", Path: `members[value eq "1"]`},
+	)
+	rsp = serve(h, http.MethodPatch, path, req, http.Header{scim.HeaderIfMatch: {etag}})
+	require.Equal(t, http.StatusOK, rsp.Code, rsp.Body.String())
+
+	groups := h.Groups()
+	require.Len(t, groups, 1)
+	require.Len(t, groups[0].Members, 1)
+	require.Equal(t, "2", groups[0].Members[0].Value)
+	require.Equal(t, "bob", groups[0].Members[0].Display)
+
+	t.Run("user groups are computed from members", func(t *testing.T) {
+		rsp := serve(h, http.MethodGet, scim.EndpointUsers+"/2", nil, nil)
+		require.Equal(t, http.StatusOK, rsp.Code)
+
+		var u scim.User
+		require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &u))
+		require.Len(t, u.Groups, 1)
+		require.Equal(t, "10", u.Groups[0].Value)
+	})
+
+	t.Run("stale version is rejected", func(t *testing.T) {
+		req := scim.NewPatchRequest(scim.PatchOperation{Op: "replace", Path: "displayName", Value: "ops"})
+		rsp := serve(h, http.MethodPatch, path, req, http.Header{scim.HeaderIfMatch: {etag}})
+		require.Equal(t, http.StatusPreconditionFailed, rsp.Code)
+		require.Equal(t, "admins", h.Groups()[0].DisplayName)
+	})
+
+	t.Run("unknown member is rejected", func(t *testing.T) {
+		req := scim.NewPatchRequest(scim.PatchOperation{
+			Op: "add", Path: "members", Value: []interface{}{map[string]interface{}{"value": "99"}},
+		})
+		rsp := serve(h, http.MethodPatch, path, req, nil)
+		require.Equal(t, http.StatusBadRequest, rsp.Code, rsp.Body.String())
+	})
+
+	t.Run("deleted user is removed from members", func(t *testing.T) {
+		rsp := serve(h, http.MethodDelete, scim.EndpointUsers+"/2", nil, nil)
+		require.Equal(t, http.StatusNoContent, rsp.Code)
+		require.Empty(t, h.Groups()[0].Members)
+	})
+}
+
+func TestHandler_Inject(t *testing.T) {
+	h := NewHandler(Options{})
+
+	t.Run("throttle", func(t *testing.T) {
+		h.Inject(Throttle(1500*time.Millisecond, 2))
+		for i := 0; i < 2; i++ {
+			rsp := serve(h, http.MethodGet, scim.EndpointUsers, nil, nil)
+			require.Equal(t, http.StatusTooManyRequests, rsp.Code)
+			require.Equal(t, "2", rsp.Header().Get("Retry-After"))
+		}
+
+		rsp := serve(h, http.MethodGet, scim.EndpointUsers, nil, nil)
+		require.Equal(t, http.StatusOK, rsp.Code)
+	})
+
+	t.Run("method and path", func(t *testing.T) {
+		h.Inject(Fault{Method: http.MethodGet, Path: scim.EndpointGroups, Status: http.StatusBadGateway})
+		defer h.ClearFaults()
+
+		require.Equal(t, http.StatusOK, serve(h, http.MethodGet, scim.EndpointUsers, nil, nil).Code)
+		require.Equal(t, http.StatusBadGateway, serve(h, http.MethodGet, scim.EndpointGroups, nil, nil).Code)
+		require.Equal(t, http.StatusBadGateway, serve(h, http.MethodGet, scim.EndpointGroups+"/1", nil, nil).Code)
+		require.NotEqual(t, http.StatusBadGateway, serve(h, http.MethodDelete, scim.EndpointGroups+"/1", nil, nil).Code)
+	})
+
+	t.Run("first failure is applied", func(t *testing.T) {
+		h.Inject(Failure(http.StatusServiceUnavailable, 1), Failure(http.StatusInternalServerError, 1))
+		require.Equal(t, http.StatusServiceUnavailable, serve(h, http.MethodGet, scim.EndpointUsers, nil, nil).Code)
+		require.Equal(t, http.StatusInternalServerError, serve(h, http.MethodGet, scim.EndpointUsers, nil, nil).Code)
+		require.Equal(t, http.StatusOK, serve(h, http.MethodGet, scim.EndpointUsers, nil, nil).Code)
+	})
+
+	t.Run("clear faults", func(t *testing.T) {
+		h.Inject(Failure(http.StatusInternalServerError, 0))
+		require.Equal(t, http.StatusInternalServerError, serve(h, http.MethodGet, scim.EndpointUsers, nil, nil).Code)
+		h.ClearFaults()
+		require.Equal(t, http.StatusOK, serve(h, http.MethodGet, scim.EndpointUsers, nil, nil).Code)
+	})
+
+	t.Run("requests are recorded", func(t *testing.T) {
+		h.Inject(Failure(http.StatusInternalServerError, 1))
+		serve(h, http.MethodGet, scim.EndpointUsers+"?count=1", nil, nil)
+
+		reqs := h.Requests()
+		last := reqs[len(reqs)-1]
+		require.Equal(t, http.MethodGet, last.Method)
+		require.Equal(t, scim.EndpointUsers, last.Path)
+		require.Equal(t, "count=1", last.Query)
+		require.Equal(t, http.StatusInternalServerError, last.Status)
+	})
+}
+
+func TestHandler_Authorize(t *testing.T) {
+	h := NewHandler(Options{ClientID: "scimfe", ClientSecret: "secret"})
+
+	rsp := serve(h, http.MethodGet, scim.EndpointUsers, nil, nil)
+	require.Equal(t, http.StatusUnauthorized, rsp.Code)
+
+	rsp = serve(h, http.MethodGet, scim.EndpointUsers, nil, http.Header{"Authorization": {"Bearer invalid"}})
+	require.Equal(t, http.StatusUnauthorized, rsp.Code)
+
+	rsp = requestToken(h, "scimfe", "wrong")
+	require.Equal(t, http.StatusUnauthorized, rsp.Code)
+
+	rsp = requestToken(h, "scimfe", "secret")
+	require.Equal(t, http.StatusOK, rsp.Code, rsp.Body.String())
+
+	var tkn tokenResponse
+	require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &tkn))
+	require.Equal(t, "Bearer", tkn.TokenType)
+	require.Equal(t, int64(time.Hour/time.Second), tkn.ExpiresIn)
+
+	rsp = serve(h, http.MethodGet, scim.EndpointUsers, nil, http.Header{"Authorization": {"Bearer " + tkn.AccessToken}})
+	require.Equal(t, http.StatusOK, rsp.Code)
+}
+
+// serve sends request with JSON body to handler
+func serve(h http.Handler, method, target string, body interface{}, header http.Header) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+
+	req := httptest.NewRequest(method, target, &buf)
+	req.Header.Set("Content-Type", scim.ContentType)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	rsp := httptest.NewRecorder()
+	h.ServeHTTP(rsp, req)
+	return rsp
+}
+
+func requestToken(h http.Handler, clientID, clientSecret string) *httptest.ResponseRecorder {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req := httptest.NewRequest(http.MethodPost, TokenPath, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, clientSecret)
+
+	rsp := httptest.NewRecorder()
+	h.ServeHTTP(rsp, req)
+	return rsp
+}
@@ -0,0 +1,428 @@
+package scimtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scim/schema"
+)
+
+// newRegistry returns schema registry of fake server resource types
+func newRegistry(pageSize int) *schema.Registry {
+	return schema.NewRegistry("", scim.ServiceProviderConfig{
+		Patch:  scim.Supported{Supported: true},
+		Filter: scim.FilterSupport{Supported: true, MaxResults: pageSize},
+		Sort:   scim.Supported{Supported: true},
+		ETag:   scim.Supported{Supported: true},
+		AuthenticationSchemes: []scim.AuthenticationScheme{
+			{
+				Type:        "oauthbearertoken",
+				Name:        "OAuth Bearer Token",
+				Description: "Access token obtained with OAuth2 client credentials grant",
+				SpecURI:     "https://www.rfc-editor.org/info/rfc6750",
+				Primary:     true,
+			},
+		},
+	}).
+		Register(scim.ResourceType{Name: scim.ResourceTypeUser, Endpoint: scim.EndpointUsers},
+			schema.User, schema.EnterpriseUser).
+		AllowExtensions(scim.ResourceTypeUser).
+		Register(scim.ResourceType{Name: scim.ResourceTypeGroup, Endpoint: scim.EndpointGroups},
+			schema.Group).
+		Register(scim.ResourceType{Name: scim.ResourceTypeContainer, Endpoint: scim.EndpointContainers},
+			schema.Container).
+		Register(scim.ResourceType{Name: scim.ResourceTypeContainerPermission, Endpoint: scim.EndpointContainerPermissions},
+			schema.ContainerPermission).
+		Register(scim.ResourceType{Name: scim.ResourceTypePrivilegedData, Endpoint: scim.EndpointPrivilegedData},
+			schema.PrivilegedData)
+}
+
+// collection is in-memory storage of resources of a single resource type.
+//
+// Resources are stored as JSON objects, so they can be filtered and patched as is.
+type collection struct {
+	resourceType string
+	endpoint     string
+	schema       string
+
+	// unique is attribute which value is unique within collection, compared case-insensitive
+	unique string
+
+	// writable collections can be modified with SCIM requests
+	writable bool
+
+	order []string
+	items map[string]map[string]interface{}
+}
+
+func newCollections() []*collection {
+	out := []*collection{
+		{resourceType: scim.ResourceTypeUser, endpoint: scim.EndpointUsers, schema: scim.SchemaUser, unique: "userName", writable: true},
+		{resourceType: scim.ResourceTypeGroup, endpoint: scim.EndpointGroups, schema: scim.SchemaGroup, unique: "displayName", writable: true},
+		{resourceType: scim.ResourceTypeContainer, endpoint: scim.EndpointContainers, schema: scim.SchemaContainer, unique: "name"},
+		{resourceType: scim.ResourceTypeContainerPermission, endpoint: scim.EndpointContainerPermissions, schema: scim.SchemaContainerPermission},
+		{resourceType: scim.ResourceTypePrivilegedData, endpoint: scim.EndpointPrivilegedData, schema: scim.SchemaPrivilegedData},
+	}
+
+	for _, c := range out {
+		c.items = make(map[string]map[string]interface{})
+	}
+	return out
+}
+
+// collection returns collection of resource type, lock should be held
+func (h *Handler) collection(resourceType string) *collection {
+	for _, c := range h.types {
+		if c.resourceType == resourceType {
+			return c
+		}
+	}
+	return nil
+}
+
+// collectionAt returns collection served at endpoint, lock should be held
+func (h *Handler) collectionAt(endpoint string) *collection {
+	for _, c := range h.types {
+		if strings.EqualFold(c.endpoint, endpoint) {
+			return c
+		}
+	}
+	return nil
+}
+
+// AddUser adds user and returns stored resource with assigned ID and metadata.
+//
+// User ID is assigned if empty. Read-only "groups" attribute is ignored,
+// user groups are computed from group members.
+func (h *Handler) AddUser(u scim.User) (*scim.User, error) {
+	out := new(scim.User)
+	return out, h.add(scim.ResourceTypeUser, u, out)
+}
+
+// AddGroup adds group and returns stored resource with assigned ID and metadata.
+//
+// Group members should be already added.
+func (h *Handler) AddGroup(g scim.Group) (*scim.Group, error) {
+	out := new(scim.Group)
+	return out, h.add(scim.ResourceTypeGroup, g, out)
+}
+
+// AddContainer adds container and returns stored resource with assigned ID and metadata
+func (h *Handler) AddContainer(c scim.Container) (*scim.Container, error) {
+	out := new(scim.Container)
+	return out, h.add(scim.ResourceTypeContainer, c, out)
+}
+
+// AddContainerPermission adds container permission and returns stored resource with assigned ID and metadata
+func (h *Handler) AddContainerPermission(p scim.ContainerPermission) (*scim.ContainerPermission, error) {
+	out := new(scim.ContainerPermission)
+	return out, h.add(scim.ResourceTypeContainerPermission, p, out)
+}
+
+// AddPrivilegedData adds account and returns stored resource with assigned ID and metadata
+func (h *Handler) AddPrivilegedData(d scim.PrivilegedData) (*scim.PrivilegedData, error) {
+	out := new(scim.PrivilegedData)
+	return out, h.add(scim.ResourceTypePrivilegedData, d, out)
+}
+
+// Users returns all stored users
+func (h *Handler) Users() []scim.User {
+	var out []scim.User
+	h.all(scim.ResourceTypeUser, &out)
+	return out
+}
+
+// Groups returns all stored groups
+func (h *Handler) Groups() []scim.Group {
+	var out []scim.Group
+	h.all(scim.ResourceTypeGroup, &out)
+	return out
+}
+
+// Containers returns all stored containers
+func (h *Handler) Containers() []scim.Container {
+	var out []scim.Container
+	h.all(scim.ResourceTypeContainer, &out)
+	return out
+}
+
+// Reset removes all stored resources
+func (h *Handler) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.types = newCollections()
+	h.lastID = 0
+}
+
+func (h *Handler) add(resourceType string, v, out interface{}) error {
+	res, err := toObject(v)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	stored, err := h.insert(h.collection(resourceType), res)
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return fromObject(stored, out)
+}
+
+func (h *Handler) all(resourceType string, out interface{}) {
+	h.mu.Lock()
+	c := h.collection(resourceType)
+	list := make([]map[string]interface{}, 0, len(c.order))
+	for _, id := range c.order {
+		list = append(list, h.render(c, c.items[id]))
+	}
+	h.mu.Unlock()
+
+	_ = fromObject(list, out)
+}
+
+// insert stores a new resource and returns rendered resource, lock should be held.
+//
+// Resource ID is assigned if empty.
+func (h *Handler) insert(c *collection, res map[string]interface{}) (map[string]interface{}, error) {
+	id, _ := res["id"].(string)
+	lastID := h.lastID
+	if id == "" {
+		lastID++
+		id = strconv.Itoa(lastID)
+	} else if _, ok := c.items[id]; ok {
+		return nil, scim.NewError(http.StatusConflict, scim.ErrTypeUniqueness,
+			"%s %q already exists", c.resourceType, id)
+	} else if n, err := strconv.Atoi(id); err == nil && n > lastID {
+		lastID = n
+	}
+
+	res["id"] = id
+	if _, ok := res["schemas"]; !ok {
+		res["schemas"] = []interface{}{c.schema}
+	}
+
+	out, err := h.save(c, id, res, h.opts.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	h.lastID = lastID
+	return out, nil
+}
+
+// save stores resource and returns rendered resource, lock should be held.
+//
+// Resource metadata is replaced, version is computed from resource attributes.
+func (h *Handler) save(c *collection, id string, res map[string]interface{}, created time.Time) (map[string]interface{}, error) {
+	if err := h.checkUnique(c, id, res); err != nil {
+		return nil, err
+	}
+	if err := h.checkMembers(c, res); err != nil {
+		return nil, err
+	}
+
+	// computed attributes
+	delete(res, "meta")
+	if c.resourceType == scim.ResourceTypeUser {
+		delete(res, "groups")
+	}
+
+	version := scim.NewVersion(res)
+	res["meta"] = map[string]interface{}{
+		"resourceType": c.resourceType,
+		"created":      created.UTC().Format(time.RFC3339Nano),
+		"lastModified": h.opts.Now().UTC().Format(time.RFC3339Nano),
+		"location":     c.endpoint + "/" + id,
+		"version":      version,
+	}
+
+	if _, ok := c.items[id]; !ok {
+		c.order = append(c.order, id)
+	}
+	c.items[id] = res
+	return h.render(c, res), nil
+}
+
+// remove deletes resource and its group memberships, lock should be held
+func (h *Handler) remove(c *collection, id string) {
+	delete(c.items, id)
+	for i := range c.order {
+		if c.order[i] == id {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+
+	if c.resourceType != scim.ResourceTypeUser && c.resourceType != scim.ResourceTypeGroup {
+		return
+	}
+
+	groups := h.collection(scim.ResourceTypeGroup)
+	for _, gid := range groups.order {
+		g := groups.items[gid]
+		members := asList(g["members"])
+		kept := make([]interface{}, 0, len(members))
+		for _, m := range members {
+			if refValue(m) != id {
+				kept = append(kept, m)
+			}
+		}
+
+		if len(kept) != len(members) {
+			g["members"] = kept
+			_, _ = h.save(groups, gid, g, createdAt(g))
+		}
+	}
+}
+
+// render returns copy of stored resource with computed attributes, lock should be held.
+//
+// User groups are computed from group members, member display names are filled from members.
+func (h *Handler) render(c *collection, res map[string]interface{}) map[string]interface{} {
+	out := copyObject(res)
+	switch c.resourceType {
+	case scim.ResourceTypeUser:
+		var groups []interface{}
+		gc := h.collection(scim.ResourceTypeGroup)
+		for _, gid := range gc.order {
+			g := gc.items[gid]
+			for _, m := range asList(g["members"]) {
+				if refValue(m) == out["id"] {
+					groups = append(groups, map[string]interface{}{
+						"value":   gid,
+						"display": g["displayName"],
+						"$ref":    gc.endpoint + "/" + gid,
+						"type":    "direct",
+					})
+					break
+				}
+			}
+		}
+		if len(groups) > 0 {
+			out["groups"] = groups
+		}
+	case scim.ResourceTypeGroup:
+		for _, m := range asList(out["members"]) {
+			obj, ok := m.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			mc, member := h.member(refValue(obj))
+			if member == nil {
+				continue
+			}
+
+			obj["display"] = displayName(member)
+			obj["$ref"] = mc.endpoint + "/" + refValue(obj)
+			if obj["type"] == nil {
+				obj["type"] = mc.resourceType
+			}
+		}
+	}
+	return out
+}
+
+// member returns user or group referenced by group member, lock should be held
+func (h *Handler) member(id string) (*collection, map[string]interface{}) {
+	for _, resourceType := range []string{scim.ResourceTypeUser, scim.ResourceTypeGroup} {
+		c := h.collection(resourceType)
+		if res, ok := c.items[id]; ok {
+			return c, res
+		}
+	}
+	return nil, nil
+}
+
+// checkUnique checks that unique attribute value is not taken by other resource
+func (h *Handler) checkUnique(c *collection, id string, res map[string]interface{}) error {
+	if c.unique == "" {
+		return nil
+	}
+
+	val := stringAttr(res, c.unique)
+	for otherID, other := range c.items {
+		if otherID != id && val != "" && strings.EqualFold(stringAttr(other, c.unique), val) {
+			return scim.NewError(http.StatusConflict, scim.ErrTypeUniqueness,
+				"%s with %s %q already exists", c.resourceType, c.unique, val)
+		}
+	}
+	return nil
+}
+
+// checkMembers checks that group members exist
+func (h *Handler) checkMembers(c *collection, res map[string]interface{}) error {
+	if c.resourceType != scim.ResourceTypeGroup {
+		return nil
+	}
+
+	for _, m := range asList(res["members"]) {
+		id := refValue(m)
+		if _, member := h.member(id); member == nil {
+			return scim.NewError(http.StatusBadRequest, scim.ErrTypeInvalidValue, "member %q not found", id)
+		}
+	}
+	return nil
+}
+
+func displayName(res map[string]interface{}) string {
+	if v := stringAttr(res, "displayName"); v != "" {
+		return v
+	}
+	return stringAttr(res, "userName")
+}
+
+func stringAttr(res map[string]interface{}, name string) string {
+	v, _ := res[name].(string)
+	return v
+}
+
+func refValue(v interface{}) string {
+	obj, _ := v.(map[string]interface{})
+	return stringAttr(obj, "value")
+}
+
+func createdAt(res map[string]interface{}) time.Time {
+	meta, _ := res["meta"].(map[string]interface{})
+	t, err := time.Parse(time.RFC3339Nano, stringAttr(meta, "created"))
+	if err != nil {
+		return time.Now()
+	}
+	return t
+}
+
+func asList(v interface{}) []interface{} {
+	list, _ := v.([]interface{})
+	return list
+}
+
+// toObject converts value to JSON object
+func toObject(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]interface{})
+	return out, json.Unmarshal(data, &out)
+}
+
+// fromObject decodes JSON value to out value
+func fromObject(v, out interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func copyObject(res map[string]interface{}) map[string]interface{} {
+	out, _ := toObject(res)
+	return out
+}
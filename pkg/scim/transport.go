@@ -0,0 +1,413 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by Transport while circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// RetryPolicy configures retries of idempotent requests
+type RetryPolicy struct {
+	// MaxRetries is maximum number of retries, requests are not retried if zero
+	MaxRetries int
+
+	// MinBackoff is delay before the first retry, delay is doubled on each retry
+	MinBackoff time.Duration
+
+	// MaxBackoff limits delay between retries
+	MaxBackoff time.Duration
+
+	// MaxRetryAfter limits delay requested by "Retry-After" response header.
+	//
+	// Response is returned as is if server asks to wait longer.
+	MaxRetryAfter time.Duration
+
+	// Timeout limits duration of each attempt, attempts are not limited if zero
+	Timeout time.Duration
+}
+
+// backoff returns jittered delay before retry.
+//
+// Delay is picked randomly between half and full exponential delay, so
+// concurrent clients don't retry at the same moment.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.MaxBackoff
+	if shift := retry - 1; shift < 32 {
+		if exp := p.MinBackoff << uint(shift); exp > 0 && exp < d {
+			d = exp
+		}
+	}
+
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)))
+}
+
+// Transport is http.RoundTripper which retries failed idempotent requests
+// and stops sending requests while remote server is failing.
+//
+// Requests are retried after transport errors and "429 Too Many Requests",
+// "502 Bad Gateway", "503 Service Unavailable" and "504 Gateway Timeout" responses.
+// Delay requested by "Retry-After" header is honored.
+//
+// Transport errors, "429 Too Many Requests" and server errors are reported as failures
+// to circuit breaker. Requests fail with ErrCircuitOpen while breaker is open.
+type Transport struct {
+	base    http.RoundTripper
+	policy  RetryPolicy
+	breaker *CircuitBreaker
+
+	// OnRetry is called before request is retried, reason is either error or response status
+	OnRetry func(req *http.Request, retry int, delay time.Duration, reason string)
+}
+
+// NewTransport is Transport constructor.
+//
+// Base transport and breaker are optional, http.DefaultTransport is used if base is nil.
+func NewTransport(base http.RoundTripper, policy RetryPolicy, breaker *CircuitBreaker) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if policy.MaxBackoff < policy.MinBackoff {
+		policy.MaxBackoff = policy.MinBackoff
+	}
+
+	return &Transport{base: base, policy: policy, breaker: breaker}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	retries := 0
+	if isIdempotent(req.Method) && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil) {
+		retries = t.policy.MaxRetries
+	}
+
+	ctx := req.Context()
+	for retry := 0; ; retry++ {
+		if err := t.breaker.allow(); err != nil {
+			return nil, err
+		}
+
+		attemptReq, err := rewindRequest(req, retry)
+		if err != nil {
+			t.breaker.release()
+			return nil, err
+		}
+
+		rsp, err := t.attempt(attemptReq)
+		switch {
+		case ctx.Err() != nil:
+			// request was cancelled by caller, remote server is not at fault
+			t.breaker.release()
+		case err != nil || isFailureStatus(rsp.StatusCode):
+			t.breaker.failure()
+		default:
+			t.breaker.success()
+		}
+
+		if retry >= retries || ctx.Err() != nil || !isRetryable(rsp, err) {
+			return rsp, err
+		}
+
+		delay := t.policy.backoff(retry + 1)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = rsp.Status
+			if after, ok := retryAfter(rsp.Header, time.Now()); ok {
+				if after > t.policy.MaxRetryAfter {
+					return rsp, nil
+				}
+				if after > delay {
+					delay = after
+				}
+			}
+			drainBody(rsp.Body)
+		}
+
+		if t.OnRetry != nil {
+			t.OnRetry(req, retry+1, delay, reason)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends request once, attempt timeout is released when response body is closed
+func (t *Transport) attempt(req *http.Request) (*http.Response, error) {
+	if t.policy.Timeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.policy.Timeout)
+	rsp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	rsp.Body = &cancelBody{ReadCloser: rsp.Body, cancel: cancel}
+	return rsp, nil
+}
+
+// rewindRequest returns request for retry with a fresh copy of request body
+func rewindRequest(req *http.Request, retry int) (*http.Request, error) {
+	if retry == 0 || req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %w", err)
+	}
+
+	out := req.Clone(req.Context())
+	out.Body = body
+	return out, nil
+}
+
+// isIdempotent reports whether request method is idempotent, see RFC 7231 section 4.2.2
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func isRetryable(rsp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+
+	switch rsp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func isFailureStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// retryAfter returns delay requested by "Retry-After" header, see RFC 7231 section 7.1.3
+func retryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// drainBody reads the rest of response body so connection can be reused
+func drainBody(body io.ReadCloser) {
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(body, 4096))
+	_ = body.Close()
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// BreakerState is circuit breaker state
+type BreakerState string
+
+// Circuit breaker states
+const (
+	// BreakerClosed passes all requests
+	BreakerClosed BreakerState = "closed"
+
+	// BreakerOpen rejects all requests until cool down period is over
+	BreakerOpen BreakerState = "open"
+
+	// BreakerHalfOpen passes a single probe request, breaker is closed if probe succeeds
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// BreakerPolicy configures circuit breaker
+type BreakerPolicy struct {
+	// Threshold is number of consecutive failures which opens the breaker, breaker is disabled if zero
+	Threshold int
+
+	// Cooldown is time during which open breaker rejects requests
+	Cooldown time.Duration
+}
+
+// BreakerStatus is circuit breaker state snapshot
+type BreakerStatus struct {
+	State BreakerState `json:"state"`
+
+	// Failures is number of consecutive failures
+	Failures int `json:"failures"`
+
+	// OpenedAt is time when breaker was opened
+	OpenedAt *time.Time `json:"openedAt,omitempty"`
+
+	// RetryAt is time when open breaker lets a probe request through
+	RetryAt *time.Time `json:"retryAt,omitempty"`
+}
+
+// CircuitBreaker stops requests to failing remote server.
+//
+// Breaker is opened after a number of consecutive failures and rejects requests
+// during cool down period. After that a single probe request is let through,
+// breaker is closed if probe succeeds and opened again otherwise.
+//
+// Nil breaker passes all requests.
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	policy   BreakerPolicy
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+
+	// OnStateChange is called after breaker state is changed
+	OnStateChange func(from, to BreakerState)
+}
+
+// NewCircuitBreaker is CircuitBreaker constructor
+func NewCircuitBreaker(policy BreakerPolicy) *CircuitBreaker {
+	return &CircuitBreaker{policy: policy, state: BreakerClosed}
+}
+
+// Status returns breaker state snapshot
+func (b *CircuitBreaker) Status() BreakerStatus {
+	if b == nil {
+		return BreakerStatus{State: BreakerClosed}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := BreakerStatus{State: b.state, Failures: b.failures}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		retryAt := openedAt.Add(b.policy.Cooldown)
+		out.OpenedAt, out.RetryAt = &openedAt, &retryAt
+	}
+	return out
+}
+
+// allow returns ErrCircuitOpen if request should not be sent
+func (b *CircuitBreaker) allow() error {
+	if b == nil || b.policy.Threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	from := b.state
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.policy.Cooldown {
+			b.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		b.state, b.probing = BreakerHalfOpen, true
+	case BreakerHalfOpen:
+		if b.probing {
+			b.mu.Unlock()
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return nil
+}
+
+// success records successful request
+func (b *CircuitBreaker) success() {
+	if b == nil || b.policy.Threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	from := b.state
+	b.state, b.failures, b.probing = BreakerClosed, 0, false
+	b.mu.Unlock()
+
+	b.notify(from, BreakerClosed)
+}
+
+// failure records failed request
+func (b *CircuitBreaker) failure() {
+	if b == nil || b.policy.Threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	from := b.state
+	b.failures++
+	b.probing = false
+	if b.state == BreakerHalfOpen || b.failures >= b.policy.Threshold {
+		b.state, b.openedAt = BreakerOpen, time.Now()
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// release records request which outcome is unknown, so another probe can be sent
+func (b *CircuitBreaker) release() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+func (b *CircuitBreaker) notify(from, to BreakerState) {
+	if from != to && b.OnStateChange != nil {
+		b.OnStateChange(from, to)
+	}
+}
@@ -0,0 +1,299 @@
+package scim
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport_Retry(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond, MaxRetryAfter: 2 * time.Second}
+
+	t.Run("retries idempotent request", func(t *testing.T) {
+		srv := newStatusServer(http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK)
+		defer srv.Close()
+
+		var retries []string
+		tr := NewTransport(nil, policy, nil)
+		tr.OnRetry = func(_ *http.Request, _ int, _ time.Duration, reason string) {
+			retries = append(retries, reason)
+		}
+
+		rsp := roundTrip(t, tr, http.MethodGet, srv.URL, nil)
+		require.Equal(t, http.StatusOK, rsp.StatusCode)
+		require.Equal(t, 3, srv.attempts())
+		require.Equal(t, []string{"503 Service Unavailable", "502 Bad Gateway"}, retries)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		srv := newStatusServer(http.StatusServiceUnavailable)
+		defer srv.Close()
+
+		rsp := roundTrip(t, NewTransport(nil, policy, nil), http.MethodGet, srv.URL, nil)
+		require.Equal(t, http.StatusServiceUnavailable, rsp.StatusCode)
+		require.Equal(t, 4, srv.attempts())
+	})
+
+	t.Run("doesn't retry client errors", func(t *testing.T) {
+		srv := newStatusServer(http.StatusInternalServerError, http.StatusBadRequest)
+		defer srv.Close()
+
+		rsp := roundTrip(t, NewTransport(nil, policy, nil), http.MethodGet, srv.URL, nil)
+		require.Equal(t, http.StatusInternalServerError, rsp.StatusCode)
+		require.Equal(t, 1, srv.attempts())
+	})
+
+	t.Run("doesn't retry POST", func(t *testing.T) {
+		srv := newStatusServer(http.StatusServiceUnavailable, http.StatusOK)
+		defer srv.Close()
+
+		rsp := roundTrip(t, NewTransport(nil, policy, nil), http.MethodPost, srv.URL, []byte(`{}`))
+		require.Equal(t, http.StatusServiceUnavailable, rsp.StatusCode)
+		require.Equal(t, 1, srv.attempts())
+	})
+
+	t.Run("resends body of PUT", func(t *testing.T) {
+		srv := newStatusServer(http.StatusServiceUnavailable, http.StatusOK)
+		defer srv.Close()
+
+		rsp := roundTrip(t, NewTransport(nil, policy, nil), http.MethodPut, srv.URL, []byte(`{"userName":"alice"}`))
+		require.Equal(t, http.StatusOK, rsp.StatusCode)
+		require.Equal(t, []string{`{"userName":"alice"}`, `{"userName":"alice"}`}, srv.bodies())
+	})
+
+	t.Run("honors Retry-After", func(t *testing.T) {
+		srv := newStatusServer(http.StatusTooManyRequests, http.StatusOK)
+		srv.header.Set("Retry-After", "1")
+		defer srv.Close()
+
+		var delay time.Duration
+		tr := NewTransport(nil, policy, nil)
+		tr.OnRetry = func(_ *http.Request, _ int, d time.Duration, _ string) {
+			delay = d
+		}
+
+		start := time.Now()
+		rsp := roundTrip(t, tr, http.MethodGet, srv.URL, nil)
+		require.Equal(t, http.StatusOK, rsp.StatusCode)
+		require.Equal(t, 2, srv.attempts())
+		require.Equal(t, time.Second, delay)
+		require.GreaterOrEqual(t, int64(time.Since(start)), int64(time.Second))
+	})
+
+	t.Run("returns response if Retry-After exceeds limit", func(t *testing.T) {
+		srv := newStatusServer(http.StatusTooManyRequests, http.StatusOK)
+		srv.header.Set("Retry-After", "120")
+		defer srv.Close()
+
+		start := time.Now()
+		rsp := roundTrip(t, NewTransport(nil, policy, nil), http.MethodGet, srv.URL, nil)
+		require.Equal(t, http.StatusTooManyRequests, rsp.StatusCode)
+		require.Equal(t, 1, srv.attempts())
+		require.Less(t, int64(time.Since(start)), int64(time.Second))
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Run("opens after consecutive failures", func(t *testing.T) {
+		srv := newStatusServer(http.StatusInternalServerError, http.StatusInternalServerError,
+			http.StatusOK, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+		defer srv.Close()
+
+		var changes []BreakerState
+		breaker := NewCircuitBreaker(BreakerPolicy{Threshold: 3, Cooldown: time.Hour})
+		breaker.OnStateChange = func(from, to BreakerState) {
+			changes = append(changes, to)
+		}
+		tr := NewTransport(nil, RetryPolicy{}, breaker)
+
+		// success resets failures counter
+		for i := 0; i < 5; i++ {
+			roundTrip(t, tr, http.MethodGet, srv.URL, nil)
+			require.Equal(t, BreakerClosed, breaker.Status().State, "request %d", i+1)
+		}
+
+		roundTrip(t, tr, http.MethodGet, srv.URL, nil)
+		status := breaker.Status()
+		require.Equal(t, BreakerOpen, status.State)
+		require.Equal(t, 3, status.Failures)
+		require.NotNil(t, status.RetryAt)
+		require.Equal(t, []BreakerState{BreakerOpen}, changes)
+
+		_, err := roundTripErr(tr, http.MethodGet, srv.URL)
+		require.True(t, errors.Is(err, ErrCircuitOpen))
+		require.Equal(t, 6, srv.attempts(), "request is not sent while breaker is open")
+	})
+
+	t.Run("lets single probe through and recovers", func(t *testing.T) {
+		release := make(chan struct{})
+		var probes int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/fail" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			atomic.AddInt32(&probes, 1)
+			<-release
+		}))
+		defer srv.Close()
+
+		breaker := NewCircuitBreaker(BreakerPolicy{Threshold: 1, Cooldown: 50 * time.Millisecond})
+		tr := NewTransport(nil, RetryPolicy{}, breaker)
+		roundTrip(t, tr, http.MethodGet, srv.URL+"/fail", nil)
+		require.Equal(t, BreakerOpen, breaker.Status().State)
+
+		time.Sleep(60 * time.Millisecond)
+		done := make(chan *http.Response)
+		go func() {
+			rsp, _ := roundTripErr(tr, http.MethodGet, srv.URL)
+			done <- rsp
+		}()
+
+		require.Eventually(t, func() bool { return atomic.LoadInt32(&probes) == 1 }, time.Second, 5*time.Millisecond)
+		require.Equal(t, BreakerHalfOpen, breaker.Status().State)
+		_, err := roundTripErr(tr, http.MethodGet, srv.URL)
+		require.True(t, errors.Is(err, ErrCircuitOpen), "only one probe is let through")
+
+		close(release)
+		rsp := <-done
+		require.NotNil(t, rsp)
+		require.Equal(t, http.StatusOK, rsp.StatusCode)
+		require.Equal(t, BreakerClosed, breaker.Status().State)
+
+		rsp = roundTrip(t, tr, http.MethodGet, srv.URL, nil)
+		require.Equal(t, http.StatusOK, rsp.StatusCode)
+		require.Equal(t, int32(2), atomic.LoadInt32(&probes))
+	})
+
+	t.Run("failed probe opens breaker again", func(t *testing.T) {
+		srv := newStatusServer(http.StatusServiceUnavailable)
+		defer srv.Close()
+
+		breaker := NewCircuitBreaker(BreakerPolicy{Threshold: 2, Cooldown: 50 * time.Millisecond})
+		tr := NewTransport(nil, RetryPolicy{}, breaker)
+		roundTrip(t, tr, http.MethodGet, srv.URL, nil)
+		roundTrip(t, tr, http.MethodGet, srv.URL, nil)
+		openedAt := *breaker.Status().OpenedAt
+
+		time.Sleep(60 * time.Millisecond)
+		roundTrip(t, tr, http.MethodGet, srv.URL, nil)
+		status := breaker.Status()
+		require.Equal(t, BreakerOpen, status.State)
+		require.True(t, status.OpenedAt.After(openedAt))
+		require.Equal(t, 3, srv.attempts())
+	})
+
+	t.Run("nil breaker passes requests", func(t *testing.T) {
+		var breaker *CircuitBreaker
+		require.NoError(t, breaker.allow())
+		breaker.failure()
+		require.Equal(t, BreakerClosed, breaker.Status().State)
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		"empty":    {value: "", ok: false},
+		"seconds":  {value: "30", want: 30 * time.Second, ok: true},
+		"negative": {value: "-1", ok: false},
+		"date":     {value: now.Add(time.Minute).Format(http.TimeFormat), want: time.Minute, ok: true},
+		"past":     {value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0, ok: true},
+		"invalid":  {value: "soon", ok: false},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			h := http.Header{}
+			h.Set("Retry-After", c.value)
+			got, ok := retryAfter(h, now)
+			require.Equal(t, c.ok, ok)
+			require.Equal(t, c.want, got)
+		})
+	}
+}
+
+// statusServer responds with statuses in order, the last status is repeated
+type statusServer struct {
+	*httptest.Server
+	header http.Header
+
+	mu       sync.Mutex
+	statuses []int
+	received []string
+}
+
+func newStatusServer(statuses ...int) *statusServer {
+	s := &statusServer{statuses: statuses, header: http.Header{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		s.mu.Lock()
+		status := s.statuses[0]
+		if len(s.statuses) > 1 {
+			s.statuses = s.statuses[1:]
+		}
+		s.received = append(s.received, string(body))
+		s.mu.Unlock()
+
+		for k, v := range s.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+	}))
+	return s
+}
+
+func (s *statusServer) attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.received)
+}
+
+func (s *statusServer) bodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.received...)
+}
+
+func roundTrip(t *testing.T, tr http.RoundTripper, method, url string, body []byte) *http.Response {
+	t.Helper()
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, url, r)
+	require.NoError(t, err)
+
+	rsp, err := tr.RoundTrip(req)
+	require.NoError(t, err)
+	drainBody(rsp.Body)
+	return rsp
+}
+
+func roundTripErr(tr http.RoundTripper, method, url string) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp, err := tr.RoundTrip(req)
+	if err == nil {
+		drainBody(rsp.Body)
+	}
+	return rsp, err
+}
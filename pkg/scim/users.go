@@ -0,0 +1,55 @@
+package scim
+
+import "context"
+
+// ListUsers returns a page of users matching list params
+func (c Client) ListUsers(ctx context.Context, p ListParams) (*UserList, error) {
+	rsp := new(UserList)
+	return rsp, c.list(ctx, EndpointUsers, p, rsp)
+}
+
+// GetUser returns user by ID
+func (c Client) GetUser(ctx context.Context, id string) (*User, error) {
+	rsp := new(User)
+	return rsp, c.get(ctx, resourcePath(EndpointUsers, id), rsp)
+}
+
+// CreateUser creates a new user and returns created resource
+func (c Client) CreateUser(ctx context.Context, u User) (*User, error) {
+	if len(u.Schemas) == 0 {
+		u.Schemas = []string{SchemaUser}
+	}
+
+	rsp := new(User)
+	return rsp, c.post(ctx, EndpointUsers, u, rsp)
+}
+
+// ReplaceUser replaces user with provided ID and returns updated resource
+func (c Client) ReplaceUser(ctx context.Context, id string, u User) (*User, error) {
+	if len(u.Schemas) == 0 {
+		u.Schemas = []string{SchemaUser}
+	}
+
+	rsp := new(User)
+	return rsp, c.put(ctx, resourcePath(EndpointUsers, id), u, rsp)
+}
+
+// PatchUser applies PATCH operations to user.
+//
+// Returns updated resource or nil if server returned no content.
+func (c Client) PatchUser(ctx context.Context, id string, ops ...PatchOperation) (*User, error) {
+	rsp := new(User)
+	if err := c.patch(ctx, resourcePath(EndpointUsers, id), NewPatchRequest(ops...), rsp); err != nil {
+		return nil, err
+	}
+
+	if rsp.ID == "" {
+		return nil, nil
+	}
+	return rsp, nil
+}
+
+// DeleteUser deletes user by ID
+func (c Client) DeleteUser(ctx context.Context, id string) error {
+	return c.delete(ctx, resourcePath(EndpointUsers, id))
+}
@@ -0,0 +1,66 @@
+package scimfe
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+type Action struct {
+	ID           string    `json:"id"`
+	Action       string    `json:"action"`
+	ResourceType string    `json:"resourceType"`
+	ResourceID   string    `json:"resourceId,omitempty"`
+	Actor        string    `json:"actor"`
+	Success      bool      `json:"success"`
+	Digest       string    `json:"digest,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Duration     int64     `json:"duration"`
+	Time         time.Time `json:"time"`
+}
+
+type ActionsResponse struct {
+	scim.ListResponse
+	Resources []Action `json:"Resources"`
+}
+
+// ActionsQuery is audit log query
+type ActionsQuery struct {
+	scim.ListParams
+
+	ResourceType string
+	Success      *bool
+	From         time.Time
+	To           time.Time
+}
+
+func (q ActionsQuery) query() url.Values {
+	v := q.ListParams.Query()
+	if q.ResourceType != "" {
+		v.Set("resourceType", q.ResourceType)
+	}
+	if q.Success != nil {
+		if *q.Success {
+			v.Set("success", "true")
+		} else {
+			v.Set("success", "false")
+		}
+	}
+	if !q.From.IsZero() {
+		v.Set("from", q.From.Format(time.RFC3339Nano))
+	}
+	if !q.To.IsZero() {
+		v.Set("to", q.To.Format(time.RFC3339Nano))
+	}
+	return v
+}
+
+func (c Client) Actions(q ActionsQuery, t Token) (*ActionsResponse, error) {
+	rsp := new(ActionsResponse)
+	reqPath := "/actions"
+	if v := q.query(); len(v) > 0 {
+		reqPath += "?" + v.Encode()
+	}
+	return rsp, c.get(reqPath, rsp, t)
+}
@@ -8,6 +8,9 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+
+	"github.com/strick-j/scimfe/pkg/scim"
 )
 
 const msgOk = "pong"
@@ -60,7 +63,7 @@ func (c Client) do(req *http.Request, out interface{}) error {
 	}
 
 	switch rsp.StatusCode {
-	case http.StatusOK:
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
 		if out == nil {
 			return fmt.Errorf("got response but passed output is nil")
 		}
@@ -89,6 +92,24 @@ func (c Client) post(reqPath string, data interface{}, out interface{}, auth Tok
 	return c.do(req, out)
 }
 
+func (c Client) put(reqPath string, data interface{}, out interface{}, auth Token) error {
+	req, err := c.newRequest(http.MethodPut, reqPath, data, auth)
+	if err != nil {
+		return err
+	}
+
+	return c.do(req, out)
+}
+
+func (c Client) patch(reqPath string, data interface{}, out interface{}, auth Token) error {
+	req, err := c.newRequest(http.MethodPatch, reqPath, data, auth)
+	if err != nil {
+		return err
+	}
+
+	return c.do(req, out)
+}
+
 func (c Client) get(reqPath string, out interface{}, auth Token) error {
 	req, err := c.newRequest(http.MethodGet, reqPath, nil, auth)
 	if err != nil {
@@ -118,3 +139,30 @@ func (c Client) Ping() error {
 	}
 	return nil
 }
+
+// HealthResponse is service health status
+type HealthResponse struct {
+	Status string `json:"status"`
+	PAM    struct {
+		Status  string             `json:"status"`
+		Breaker scim.BreakerStatus `json:"breaker"`
+	} `json:"pam"`
+}
+
+// Health returns service health status
+func (c Client) Health() (*HealthResponse, error) {
+	out := new(HealthResponse)
+	return out, c.get("/health", out, "")
+}
+
+// withQuery appends list query parameters to request path
+func withQuery(reqPath string, params scim.ListParams) string {
+	return withValues(reqPath, params.Query())
+}
+
+func withValues(reqPath string, q url.Values) string {
+	if len(q) == 0 {
+		return reqPath
+	}
+	return reqPath + "?" + q.Encode()
+}
@@ -0,0 +1,97 @@
+package scimfe
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// Change is a recorded change of PAM user or group
+type Change struct {
+	ID           int             `json:"id"`
+	ResourceType string          `json:"resourceType"`
+	ResourceID   int             `json:"resourceId"`
+	Operation    string          `json:"operation"`
+	Before       json.RawMessage `json:"before,omitempty"`
+	After        json.RawMessage `json:"after,omitempty"`
+	JobID        string          `json:"jobId,omitempty"`
+	Time         time.Time       `json:"time"`
+}
+
+type ChangesResponse struct {
+	scim.ListResponse
+	Resources []Change `json:"Resources"`
+}
+
+// HistoryQuery is resource history query
+type HistoryQuery struct {
+	scim.ListParams
+
+	Operation string
+	JobID     string
+	From      string
+	To        string
+}
+
+func (q HistoryQuery) query() url.Values {
+	v := q.ListParams.Query()
+	for name, val := range map[string]string{
+		"operation": q.Operation,
+		"jobId":     q.JobID,
+		"from":      q.From,
+		"to":        q.To,
+	} {
+		if val != "" {
+			v.Set(name, val)
+		}
+	}
+	return v
+}
+
+// MembershipPeriod is time period during which user was a member of a group
+type MembershipPeriod struct {
+	UserID       int        `json:"userId"`
+	UserDisplay  string     `json:"userDisplay,omitempty"`
+	GroupID      int        `json:"groupId"`
+	GroupDisplay string     `json:"groupDisplay,omitempty"`
+	From         time.Time  `json:"from"`
+	To           *time.Time `json:"to,omitempty"`
+	JobID        string     `json:"jobId,omitempty"`
+}
+
+type MembershipPeriodsResponse struct {
+	scim.ListResponse
+	Resources []MembershipPeriod `json:"Resources"`
+}
+
+func (c Client) PamUserHistory(id string, q HistoryQuery, t Token) (*ChangesResponse, error) {
+	rsp := new(ChangesResponse)
+	return rsp, c.get(withValues("/pam/users/"+url.PathEscape(id)+"/history", q.query()), rsp, t)
+}
+
+func (c Client) PamGroupHistory(id string, q HistoryQuery, t Token) (*ChangesResponse, error) {
+	rsp := new(ChangesResponse)
+	return rsp, c.get(withValues("/pam/groups/"+url.PathEscape(id)+"/history", q.query()), rsp, t)
+}
+
+// PamUserGroups returns group memberships of user at asOf time, or current memberships if asOf is empty
+func (c Client) PamUserGroups(id, asOf string, t Token) (*MembershipPeriodsResponse, error) {
+	rsp := new(MembershipPeriodsResponse)
+	return rsp, c.get(withValues("/pam/users/"+url.PathEscape(id)+"/groups", asOfQuery(asOf)), rsp, t)
+}
+
+// PamGroupMembers returns members of group at asOf time, or current members if asOf is empty
+func (c Client) PamGroupMembers(id, asOf string, t Token) (*MembershipPeriodsResponse, error) {
+	rsp := new(MembershipPeriodsResponse)
+	return rsp, c.get(withValues("/pam/groups/"+url.PathEscape(id)+"/members", asOfQuery(asOf)), rsp, t)
+}
+
+func asOfQuery(asOf string) url.Values {
+	v := url.Values{}
+	if asOf != "" {
+		v.Set("asOf", asOf)
+	}
+	return v
+}
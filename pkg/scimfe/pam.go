@@ -0,0 +1,129 @@
+package scimfe
+
+import (
+	"net/url"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+func (c Client) PamUsers(params scim.ListParams, t Token) (*scim.UserList, error) {
+	rsp := new(scim.UserList)
+	return rsp, c.get(withQuery("/pam/users", params), rsp, t)
+}
+
+func (c Client) PamUserByID(id string, t Token) (*scim.User, error) {
+	rsp := new(scim.User)
+	return rsp, c.get("/pam/users/"+id, rsp, t)
+}
+
+func (c Client) PamGroups(params scim.ListParams, t Token) (*scim.GroupList, error) {
+	rsp := new(scim.GroupList)
+	return rsp, c.get(withQuery("/pam/groups", params), rsp, t)
+}
+
+func (c Client) PamGroupByID(id string, t Token) (*scim.Group, error) {
+	rsp := new(scim.Group)
+	return rsp, c.get("/pam/groups/"+id, rsp, t)
+}
+
+func (c Client) CreatePamUser(u scim.User, t Token) (*scim.User, error) {
+	rsp := new(scim.User)
+	return rsp, c.post("/pam/users", u, rsp, t)
+}
+
+func (c Client) ReplacePamUser(id string, u scim.User, t Token) (*scim.User, error) {
+	rsp := new(scim.User)
+	return rsp, c.put("/pam/users/"+id, u, rsp, t)
+}
+
+func (c Client) PatchPamUser(id string, req scim.PatchRequest, t Token) (*scim.User, error) {
+	rsp := new(scim.User)
+	return rsp, c.patch("/pam/users/"+id, req, rsp, t)
+}
+
+// DeletePamUser deactivates PAM user
+func (c Client) DeletePamUser(id string, t Token) error {
+	return c.delete("/pam/users/"+id, t)
+}
+
+func (c Client) CreatePamGroup(g scim.Group, t Token) (*scim.Group, error) {
+	rsp := new(scim.Group)
+	return rsp, c.post("/pam/groups", g, rsp, t)
+}
+
+func (c Client) ReplacePamGroup(id string, g scim.Group, t Token) (*scim.Group, error) {
+	rsp := new(scim.Group)
+	return rsp, c.put("/pam/groups/"+id, g, rsp, t)
+}
+
+func (c Client) PatchPamGroup(id string, req scim.PatchRequest, t Token) (*scim.Group, error) {
+	rsp := new(scim.Group)
+	return rsp, c.patch("/pam/groups/"+id, req, rsp, t)
+}
+
+func (c Client) DeletePamGroup(id string, t Token) error {
+	return c.delete("/pam/groups/"+id, t)
+}
+
+func (c Client) PamBulk(req scim.BulkRequest, t Token) (*scim.BulkResponse, error) {
+	rsp := new(scim.BulkResponse)
+	return rsp, c.post("/pam/Bulk", req, rsp, t)
+}
+
+func (c Client) PamServiceProviderConfig(t Token) (*scim.ServiceProviderConfig, error) {
+	rsp := new(scim.ServiceProviderConfig)
+	return rsp, c.get("/pam"+scim.EndpointServiceProviderConfig, rsp, t)
+}
+
+func (c Client) PamResourceTypes(t Token) (*scim.ResourceTypeList, error) {
+	rsp := new(scim.ResourceTypeList)
+	return rsp, c.get("/pam"+scim.EndpointResourceTypes, rsp, t)
+}
+
+func (c Client) PamSchemas(t Token) (*scim.SchemaList, error) {
+	rsp := new(scim.SchemaList)
+	return rsp, c.get("/pam"+scim.EndpointSchemas, rsp, t)
+}
+
+// ContainerAccess is effective access of a user to PAM container
+type ContainerAccess struct {
+	User   scim.Reference   `json:"user"`
+	Rights []string         `json:"rights"`
+	Direct bool             `json:"direct"`
+	Groups []scim.Reference `json:"groups,omitempty"`
+}
+
+type ContainerAccessList struct {
+	scim.ListResponse
+	Resources []ContainerAccess `json:"Resources"`
+}
+
+func (c Client) PamContainers(params scim.ListParams, t Token) (*scim.ContainerList, error) {
+	rsp := new(scim.ContainerList)
+	return rsp, c.get(withQuery("/pam/containers", params), rsp, t)
+}
+
+func (c Client) PamContainerByID(id string, t Token) (*scim.Container, error) {
+	rsp := new(scim.Container)
+	return rsp, c.get("/pam/containers/"+url.PathEscape(id), rsp, t)
+}
+
+func (c Client) PamContainerPermissions(id string, t Token) (*scim.ContainerPermissionList, error) {
+	rsp := new(scim.ContainerPermissionList)
+	return rsp, c.get("/pam/containers/"+url.PathEscape(id)+"/permissions", rsp, t)
+}
+
+func (c Client) PamContainerAccess(id string, t Token) (*ContainerAccessList, error) {
+	rsp := new(ContainerAccessList)
+	return rsp, c.get("/pam/containers/"+url.PathEscape(id)+"/access", rsp, t)
+}
+
+func (c Client) PamAccounts(params scim.ListParams, t Token) (*scim.PrivilegedDataList, error) {
+	rsp := new(scim.PrivilegedDataList)
+	return rsp, c.get(withQuery("/pam/accounts", params), rsp, t)
+}
+
+func (c Client) PamAccountByID(id string, t Token) (*scim.PrivilegedData, error) {
+	rsp := new(scim.PrivilegedData)
+	return rsp, c.get("/pam/accounts/"+url.PathEscape(id), rsp, t)
+}
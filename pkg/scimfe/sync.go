@@ -0,0 +1,116 @@
+package scimfe
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/strick-j/scimfe/pkg/scim"
+)
+
+// SyncJob is PAM synchronization job
+type SyncJob struct {
+	ID              string     `json:"id"`
+	ResourceType    string     `json:"resourceType"`
+	Status          string     `json:"status"`
+	Actor           string     `json:"actor"`
+	CancelRequested bool       `json:"cancelRequested"`
+	Error           string     `json:"error,omitempty"`
+	Mode            string     `json:"mode"`
+	Pages           int        `json:"pages"`
+	Upserted        int        `json:"upserted"`
+	Deleted         int        `json:"deleted"`
+	Failed          int        `json:"failed"`
+	Memberships     int        `json:"memberships"`
+	Permissions     int        `json:"permissions"`
+	Unresolved      int        `json:"unresolved"`
+	Created         time.Time  `json:"created"`
+	Updated         time.Time  `json:"updated"`
+	Finished        *time.Time `json:"finished,omitempty"`
+}
+
+type SyncJobsResponse struct {
+	scim.ListResponse
+	Resources []SyncJob `json:"Resources"`
+}
+
+// SyncJobRequest is synchronization job start request
+type SyncJobRequest struct {
+	ResourceType string `json:"resourceType,omitempty"`
+	Mode         string `json:"mode,omitempty"`
+}
+
+// SyncJobsQuery is synchronization jobs query
+type SyncJobsQuery struct {
+	scim.ListParams
+
+	ResourceType string
+	Status       string
+}
+
+func (q SyncJobsQuery) query() url.Values {
+	v := q.ListParams.Query()
+	if q.ResourceType != "" {
+		v.Set("resourceType", q.ResourceType)
+	}
+	if q.Status != "" {
+		v.Set("status", q.Status)
+	}
+	return v
+}
+
+func (c Client) StartSyncJob(req SyncJobRequest, t Token) (*SyncJob, error) {
+	rsp := new(SyncJob)
+	return rsp, c.post("/sync/jobs", req, rsp, t)
+}
+
+func (c Client) SyncJobs(q SyncJobsQuery, t Token) (*SyncJobsResponse, error) {
+	rsp := new(SyncJobsResponse)
+	reqPath := "/sync/jobs"
+	if v := q.query(); len(v) > 0 {
+		reqPath += "?" + v.Encode()
+	}
+	return rsp, c.get(reqPath, rsp, t)
+}
+
+func (c Client) SyncJobByID(id string, t Token) (*SyncJob, error) {
+	rsp := new(SyncJob)
+	return rsp, c.get("/sync/jobs/"+url.PathEscape(id), rsp, t)
+}
+
+func (c Client) CancelSyncJob(id string, t Token) (*SyncJob, error) {
+	rsp := new(SyncJob)
+	return rsp, c.post("/sync/jobs/"+url.PathEscape(id)+"/cancel", nil, rsp, t)
+}
+
+// DriftReport is report of differences between PAM mirror and PAM SCIM server
+type DriftReport struct {
+	Generated time.Time `json:"generated"`
+	Summary   struct {
+		Added              int `json:"added"`
+		Removed            int `json:"removed"`
+		Changed            int `json:"changed"`
+		MembershipsAdded   int `json:"membershipsAdded"`
+		MembershipsRemoved int `json:"membershipsRemoved"`
+	} `json:"summary"`
+	Resources []struct {
+		Kind         string `json:"kind"`
+		ResourceType string `json:"resourceType"`
+		ID           string `json:"id"`
+		Name         string `json:"name"`
+		Attributes   []struct {
+			Path   string      `json:"path"`
+			Mirror interface{} `json:"mirror"`
+			Remote interface{} `json:"remote"`
+		} `json:"attributes,omitempty"`
+	} `json:"resources"`
+	Memberships []struct {
+		Kind    string `json:"kind"`
+		GroupID int    `json:"groupId"`
+		UserID  int    `json:"userId"`
+	} `json:"memberships"`
+}
+
+func (c Client) DriftReport(t Token) (*DriftReport, error) {
+	rsp := new(DriftReport)
+	return rsp, c.get("/sync/drift", rsp, t)
+}
@@ -1,5 +1,7 @@
 package scimfe
 
+import "github.com/strick-j/scimfe/pkg/scim"
+
 type User struct {
 	ID    string `json:"id"`
 	Email string `json:"email"`
@@ -7,12 +9,13 @@ type User struct {
 }
 
 type UsersResponse struct {
-	Users []User `json:"users"`
+	scim.ListResponse
+	Resources []User `json:"Resources"`
 }
 
-func (c Client) Users(t Token) ([]User, error) {
+func (c Client) Users(params scim.ListParams, t Token) (*UsersResponse, error) {
 	rsp := new(UsersResponse)
-	return rsp.Users, c.get("/users", rsp, t)
+	return rsp, c.get(withQuery("/users", params), rsp, t)
 }
 
 func (c Client) UserByID(uid string, t Token) (*User, error) {
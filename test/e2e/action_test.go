@@ -0,0 +1,81 @@
+package e2e
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scimfe"
+)
+
+func TestActions_List(t *testing.T) {
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	sess, err := Client.Register(scimfe.RegisterRequest{
+		Email:    "testactions@mail.com",
+		Name:     "testactions",
+		Password: "123456",
+	})
+	require.NoError(t, err, "failed to create a user for test case")
+
+	_, err = DB.Exec(`INSERT INTO actions ("action", "resourceType", "resourceId", "actor", "success", "error", "duration", "time") VALUES
+		('sync', 'All', NULL, 'system', true, NULL, 1500, '2021-01-01 10:00:00'),
+		('create', 'User', '101', 'system', true, NULL, 20, '2021-01-02 10:00:00'),
+		('delete', 'Group', '201', 'system', false, 'not found', 10, '2021-01-03 10:00:00')`)
+	require.NoError(t, err, "failed to seed audit log")
+
+	failed := false
+	cases := map[string]struct {
+		query   scimfe.ActionsQuery
+		want    []string
+		wantErr string
+	}{
+		"all": {
+			want: []string{"delete", "create", "sync"},
+		},
+		"resource type": {
+			query: scimfe.ActionsQuery{ResourceType: "user"},
+			want:  []string{"create"},
+		},
+		"failed": {
+			query: scimfe.ActionsQuery{Success: &failed},
+			want:  []string{"delete"},
+		},
+		"time range": {
+			query: scimfe.ActionsQuery{
+				From: time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC),
+				To:   time.Date(2021, 1, 3, 0, 0, 0, 0, time.UTC),
+			},
+			want: []string{"create", "sync"},
+		},
+		"filter": {
+			query: scimfe.ActionsQuery{ListParams: scim.ListParams{Filter: `duration gt 15`}},
+			want:  []string{"create", "sync"},
+		},
+		"invalid filter": {
+			query:   scimfe.ActionsQuery{ListParams: scim.ListParams{Filter: `actor eq`}},
+			wantErr: "400 Bad Request",
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			got, err := Client.Actions(c.query, sess.Token)
+			if c.wantErr != "" {
+				shouldContainError(t, err, c.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, len(c.want), got.TotalResults)
+			actions := make([]string, 0, len(got.Resources))
+			for _, a := range got.Resources {
+				actions = append(actions, a.Action)
+			}
+			require.Equal(t, c.want, actions)
+		})
+	}
+
+	_, err = Client.Actions(scimfe.ActionsQuery{}, "")
+	shouldContainError(t, err, "401 Unauthorized: authorization required")
+}
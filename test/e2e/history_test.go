@@ -0,0 +1,145 @@
+package e2e
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scimfe"
+)
+
+const historyJobID = "6f1c2d4e-0000-4000-8000-000000000010"
+
+func TestPam_History(t *testing.T) {
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	sess, err := Client.Register(scimfe.RegisterRequest{
+		Email:    "testhistory@mail.com",
+		Name:     "testhistory",
+		Password: "123456",
+	})
+	require.NoError(t, err, "failed to create a user for test case")
+
+	queries := []string{
+		`INSERT INTO pamhistory ("resourceType", "resourceId", "operation", "before", "after", "jobId", "time") VALUES
+			('User', 101, 'created', NULL, '{"id": "101", "userName": "jdoe", "title": "Engineer"}', $1, '2021-01-01 10:00:00'),
+			('User', 101, 'updated', '{"id": "101", "userName": "jdoe", "title": "Engineer"}',
+				'{"id": "101", "userName": "jdoe", "title": "Manager"}', $1, '2021-01-05 10:00:00'),
+			('User', 101, 'deleted', '{"id": "101", "userName": "jdoe", "title": "Manager"}', NULL, NULL, '2021-01-10 10:00:00'),
+			('Group', 101, 'created', NULL, '{"id": "101", "displayName": "Auditors"}', $1, '2021-01-01 10:00:00')`,
+		`INSERT INTO pamhistory_memberships ("userId", "userDisplay", "groupId", "groupDisplay", "validFrom", "validTo", "jobId") VALUES
+			(101, 'John Doe', 201, 'Vault Admins', '2021-01-01 10:00:00', '2021-01-10 10:00:00', $1),
+			(101, 'John Doe', 202, 'Auditors', '2021-01-05 10:00:00', NULL, $1),
+			(102, 'Alice Smith', 201, 'Vault Admins', '2021-01-03 10:00:00', NULL, NULL)`,
+	}
+	for _, q := range queries {
+		_, err = DB.Exec(q, historyJobID)
+		require.NoError(t, err, "failed to seed history")
+	}
+
+	t.Run("user history", func(t *testing.T) {
+		got, err := Client.PamUserHistory("101", scimfe.HistoryQuery{}, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 3, got.TotalResults)
+
+		ops := make([]string, 0, len(got.Resources))
+		for _, c := range got.Resources {
+			require.Equal(t, "User", c.ResourceType)
+			require.Equal(t, 101, c.ResourceID)
+			ops = append(ops, c.Operation)
+		}
+		require.Equal(t, []string{"deleted", "updated", "created"}, ops)
+		require.JSONEq(t, `{"id": "101", "userName": "jdoe", "title": "Engineer"}`, string(got.Resources[1].Before))
+		require.JSONEq(t, `{"id": "101", "userName": "jdoe", "title": "Manager"}`, string(got.Resources[1].After))
+		require.Equal(t, historyJobID, got.Resources[1].JobID)
+		require.Empty(t, got.Resources[0].After)
+		require.Empty(t, got.Resources[0].JobID)
+
+		got, err = Client.PamUserHistory("101", scimfe.HistoryQuery{Operation: "updated"}, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 1, got.TotalResults)
+
+		got, err = Client.PamUserHistory("101", scimfe.HistoryQuery{
+			From: "2021-01-02T00:00:00Z",
+			To:   "2021-01-10T10:00:00Z",
+		}, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 1, got.TotalResults)
+		require.Equal(t, "updated", got.Resources[0].Operation)
+
+		got, err = Client.PamUserHistory("101", scimfe.HistoryQuery{
+			ListParams: scim.ListParams{SortBy: "time", SortOrder: scim.SortAscending, Count: 1},
+		}, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 3, got.TotalResults)
+		require.Len(t, got.Resources, 1)
+		require.Equal(t, "created", got.Resources[0].Operation)
+
+		_, err = Client.PamUserHistory("101", scimfe.HistoryQuery{From: "yesterday"}, sess.Token)
+		shouldContainError(t, err, "400 Bad Request")
+	})
+
+	t.Run("group history", func(t *testing.T) {
+		got, err := Client.PamGroupHistory("101", scimfe.HistoryQuery{}, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 1, got.TotalResults)
+		require.Equal(t, "Group", got.Resources[0].ResourceType)
+
+		got, err = Client.PamGroupHistory("999", scimfe.HistoryQuery{}, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 0, got.TotalResults)
+	})
+
+	t.Run("memberships as of", func(t *testing.T) {
+		cases := map[string]struct {
+			asOf string
+			want []int
+		}{
+			"current": {
+				want: []int{202},
+			},
+			"before first membership": {
+				asOf: "2020-12-31T00:00:00Z",
+				want: []int{},
+			},
+			"at start": {
+				asOf: "2021-01-01T10:00:00Z",
+				want: []int{201},
+			},
+			"both": {
+				asOf: "2021-01-06T00:00:00Z",
+				want: []int{201, 202},
+			},
+			"at end": {
+				asOf: "2021-01-10T10:00:00Z",
+				want: []int{202},
+			},
+		}
+
+		for n, c := range cases {
+			t.Run(n, func(t *testing.T) {
+				got, err := Client.PamUserGroups("101", c.asOf, sess.Token)
+				require.NoError(t, err)
+				ids := make([]int, 0, len(got.Resources))
+				for _, m := range got.Resources {
+					ids = append(ids, m.GroupID)
+				}
+				require.Equal(t, c.want, ids)
+			})
+		}
+
+		got, err := Client.PamGroupMembers("201", "2021-01-04T00:00:00+02:00", sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 2, got.TotalResults)
+		require.Equal(t, 101, got.Resources[0].UserID)
+		require.Equal(t, "John Doe", got.Resources[0].UserDisplay)
+		require.NotNil(t, got.Resources[0].To)
+		require.Equal(t, 102, got.Resources[1].UserID)
+		require.Nil(t, got.Resources[1].To)
+
+		_, err = Client.PamGroupMembers("201", "last tuesday", sess.Token)
+		shouldContainError(t, err, "400 Bad Request")
+	})
+
+	_, err = Client.PamUserHistory("101", scimfe.HistoryQuery{}, "")
+	shouldContainError(t, err, "401 Unauthorized: authorization required")
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/require"
 	"github.com/strick-j/scimfe/internal/app"
+	"github.com/strick-j/scimfe/pkg/scim"
 	"github.com/strick-j/scimfe/pkg/scimfe"
 )
 
@@ -21,9 +22,11 @@ import (
 //
 
 var (
-	Client *scimfe.Client
-	DB     *sqlx.DB
-	Redis  *redis.Client
+	Client      *scimfe.Client
+	SCIMClient  *scim.Client
+	SCIMBaseURL string
+	DB          *sqlx.DB
+	Redis       *redis.Client
 )
 
 func formatClientUrl(addr string) string {
@@ -41,6 +44,10 @@ func TestMain(m *testing.M) {
 	}
 
 	Client = scimfe.NewClient(&http.Client{}, formatClientUrl(cfg.Server.ListenAddress))
+	SCIMBaseURL = formatClientUrl(cfg.Server.ListenAddress) + "/scim/v2"
+	if len(cfg.SCIM.Tokens) > 0 {
+		SCIMClient = scim.NewClient(&http.Client{}, SCIMBaseURL, scim.StaticToken(cfg.SCIM.Tokens[0]))
+	}
 	if err := Client.Ping(); err != nil {
 		log.Fatalf("Failed to ping test scimfe API: %s. Run 'make run' to start test API", err)
 	}
@@ -73,6 +80,10 @@ func TruncateData() error {
 
 	queries := []string{
 		"TRUNCATE TABLE users CASCADE",
+		"TRUNCATE TABLE pamuser, pamgroup, pamcontainer, pamaccount CASCADE",
+		"TRUNCATE TABLE actions",
+		"TRUNCATE TABLE pamsync_state, pamsync_jobs",
+		"TRUNCATE TABLE pamhistory, pamhistory_memberships",
 	}
 
 	for _, q := range queries {
@@ -87,6 +98,12 @@ func TestPing(t *testing.T) {
 	require.NoError(t, Client.Ping())
 }
 
+func TestHealth(t *testing.T) {
+	rsp, err := Client.Health()
+	require.NoError(t, err)
+	require.Equal(t, scim.BreakerClosed, rsp.PAM.Breaker.State)
+}
+
 func shouldContainError(t *testing.T, err error, part string) {
 	if err == nil {
 		t.Fatalf("got no error, but expected %q", part)
@@ -0,0 +1,800 @@
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/internal/service"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scimfe"
+)
+
+// seedPamData populates PAM mirror tables the same way as synchronization does
+func seedPamData(t *testing.T) {
+	queries := []string{
+		`INSERT INTO pamuser (id, username, displayname, active, schemas) VALUES
+			(101, 'jdoe', 'John Doe', true, '{urn:ietf:params:scim:schemas:core:2.0:User}'),
+			(102, 'asmith', 'Alice Smith', false, '{urn:ietf:params:scim:schemas:core:2.0:User}')`,
+		`INSERT INTO pamuser_name (id, givenname, familyname) VALUES (101, 'John', 'Doe')`,
+		`INSERT INTO pamuser_emails (id, name, "primary", value) VALUES (101, 'work', true, 'jdoe@example.com')`,
+		`INSERT INTO pamuser_phonenumbers (id, name, "primary", value) VALUES (101, 'mobile', false, '+100000000')`,
+		`INSERT INTO pamgroup (id, displayname, schemas) VALUES
+			(201, 'Vault Admins', '{urn:ietf:params:scim:schemas:core:2.0:Group}')`,
+		`INSERT INTO pamgroup_members (id, value, display) VALUES (201, 101, 'John Doe')`,
+		`INSERT INTO pamuser_groups (id, value, display, type) VALUES (101, 201, 'Vault Admins', 'direct')`,
+		`INSERT INTO pamuser_meta (id, "resourceType", version) VALUES (101, 'User', 'W/"u101-1"')`,
+		`INSERT INTO pamgroup_meta (id, "resourceType", version) VALUES (201, 'Group', 'W/"g201-1"')`,
+		`INSERT INTO pamuser_enterprise (id, employeenumber, department, manager_value, manager_display) VALUES
+			(102, 'E-102', 'Security', '101', 'John Doe')`,
+		`UPDATE pamuser SET
+			schemas = '{urn:ietf:params:scim:schemas:core:2.0:User,urn:ietf:params:scim:schemas:cyberark:1.0:User}',
+			extensions = '{"urn:ietf:params:scim:schemas:cyberark:1.0:User": {"source": "LDAP", "vaultAuthorization": ["AuditUsers"]}}'
+			WHERE id = 102`,
+		`INSERT INTO pamcontainer (id, name, description, owner_value, owner_display, schemas) VALUES
+			('VaultInternal', 'VaultInternal', 'Vault internal accounts', '101', 'John Doe',
+				'{urn:ietf:params:scim:schemas:pam:1.0:Container}'),
+			('Unused', 'Unused', NULL, NULL, NULL, '{urn:ietf:params:scim:schemas:pam:1.0:Container}')`,
+		`INSERT INTO pamcontainer_meta (id, "resourceType", version) VALUES ('VaultInternal', 'Container', 'W/"c1"')`,
+		`INSERT INTO pamcontainer_permissions (id, remote_id, user_id, group_id, display, rights) VALUES
+			('VaultInternal', 'VaultInternal:jdoe', 101, NULL, 'John Doe', '{ListAccounts,UseAccounts}'),
+			('VaultInternal', 'VaultInternal:asmith', 102, NULL, 'Alice Smith', '{ListAccounts}'),
+			('VaultInternal', 'VaultInternal:Vault Admins', NULL, 201, 'Vault Admins', '{ListAccounts,ManageSafe}')`,
+		`INSERT INTO pamaccount (id, name, type, container_id, container_display, address, platform, username, schemas) VALUES
+			('1_1', 'Operating System-UnixSSH-db01-root', 'password', 'VaultInternal', 'VaultInternal',
+				'db01.example.com', 'UnixSSH', 'root', '{urn:ietf:params:scim:schemas:pam:1.0:PrivilegedData}'),
+			('1_2', 'Operating System-WinDomain-dc01-admin', 'password', 'VaultInternal', 'VaultInternal',
+				'DC01.example.com', 'WinDomain', 'admin', '{urn:ietf:params:scim:schemas:pam:1.0:PrivilegedData}'),
+			('2_1', 'Key-UnixSSHKeys-web01-deploy', 'key', 'Unused', 'Unused',
+				'web01.example.com', 'UnixSSHKeys', 'deploy', '{urn:ietf:params:scim:schemas:pam:1.0:PrivilegedData}')`,
+		`INSERT INTO pamaccount_meta (id, "resourceType", version) VALUES ('1_1', 'PrivilegedData', 'W/"a1"')`,
+	}
+
+	for _, q := range queries {
+		_, err := DB.Exec(q)
+		require.NoError(t, err, "failed to seed PAM data")
+	}
+}
+
+func TestPam_Users(t *testing.T) {
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	seedPamData(t)
+	sess, err := Client.Register(scimfe.RegisterRequest{
+		Email:    "testpamusers@mail.com",
+		Name:     "testpamusers",
+		Password: "123456",
+	})
+	require.NoError(t, err, "failed to create a user for test case")
+
+	active := true
+	jdoe := scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          "101",
+		UserName:    "jdoe",
+		DisplayName: "John Doe",
+		Active:      &active,
+		Name:        &scim.Name{GivenName: "John", FamilyName: "Doe"},
+		Emails: []scim.MultiValue{
+			{Type: "work", Primary: true, Value: "jdoe@example.com"},
+		},
+		PhoneNumbers: []scim.MultiValue{
+			{Type: "mobile", Value: "+100000000"},
+		},
+		Groups: []scim.Reference{
+			{Value: "201", Display: "Vault Admins", Type: "direct"},
+		},
+		Meta: &scim.Meta{ResourceType: scim.ResourceTypeUser, Version: `W/"u101-1"`},
+	}
+
+	cases := map[string]struct {
+		id      string
+		token   scimfe.Token
+		want    *scim.User
+		wantErr string
+	}{
+		"empty token": {
+			id:      "101",
+			wantErr: "401 Unauthorized: authorization required",
+		},
+		"invalid token": {
+			id:      "101",
+			token:   scimfe.Token(uuid.New().String()),
+			wantErr: "401 Unauthorized: authorization required",
+		},
+		"invalid id": {
+			id:      "abc",
+			token:   sess.Token,
+			wantErr: "400 Bad Request",
+		},
+		"no such user": {
+			id:      "999",
+			token:   sess.Token,
+			wantErr: "404 Not Found: user not found",
+		},
+		"valid user": {
+			id:    "101",
+			token: sess.Token,
+			want:  &jdoe,
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			got, err := Client.PamUserByID(c.id, c.token)
+			if c.wantErr != "" {
+				shouldContainError(t, err, c.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, c.want, got)
+		})
+	}
+
+	t.Run("list", func(t *testing.T) {
+		got, err := Client.PamUsers(scim.ListParams{}, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 2, got.TotalResults)
+		require.Len(t, got.Resources, 2)
+		require.Equal(t, jdoe, got.Resources[0])
+		require.Equal(t, "asmith", got.Resources[1].UserName)
+	})
+
+	t.Run("page", func(t *testing.T) {
+		got, err := Client.PamUsers(scim.ListParams{
+			StartIndex: 2, Count: 1, SortBy: "userName", SortOrder: scim.SortDescending,
+		}, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 2, got.TotalResults)
+		require.Equal(t, 2, got.StartIndex)
+		require.Equal(t, 1, got.ItemsPerPage)
+		require.Equal(t, "asmith", got.Resources[0].UserName)
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		cursor := ""
+		got, err := Client.PamUsers(scim.ListParams{Count: 1, Cursor: &cursor}, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, "jdoe", got.Resources[0].UserName)
+		require.NotEmpty(t, got.NextCursor)
+
+		got, err = Client.PamUsers(scim.ListParams{Count: 1, Cursor: &got.NextCursor}, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, "asmith", got.Resources[0].UserName)
+		require.Empty(t, got.NextCursor)
+
+		invalid := "???"
+		_, err = Client.PamUsers(scim.ListParams{Count: 1, Cursor: &invalid}, sess.Token)
+		shouldContainError(t, err, "400 Bad Request")
+	})
+
+	filterCases := map[string]struct {
+		filter  string
+		want    []string
+		wantErr string
+	}{
+		"equal": {
+			filter: `userName eq "JDOE"`,
+			want:   []string{"jdoe"},
+		},
+		"logical": {
+			filter: `active eq false or name.givenName sw "jo"`,
+			want:   []string{"jdoe", "asmith"},
+		},
+		"value path": {
+			filter: `emails[type eq "work" and value co "@example.com"]`,
+			want:   []string{"jdoe"},
+		},
+		"not present": {
+			filter: `not (emails pr)`,
+			want:   []string{"asmith"},
+		},
+		"unknown attribute": {
+			filter:  `foo eq "bar"`,
+			wantErr: "400 Bad Request",
+		},
+		"invalid filter": {
+			filter:  `userName eq`,
+			wantErr: "400 Bad Request",
+		},
+	}
+
+	for n, c := range filterCases {
+		t.Run("filter "+n, func(t *testing.T) {
+			got, err := Client.PamUsers(scim.ListParams{Filter: c.filter}, sess.Token)
+			if c.wantErr != "" {
+				shouldContainError(t, err, c.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			names := make([]string, 0, len(got.Resources))
+			for _, u := range got.Resources {
+				names = append(names, u.UserName)
+			}
+			require.ElementsMatch(t, c.want, names)
+		})
+	}
+}
+
+func TestPam_Groups(t *testing.T) {
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	seedPamData(t)
+	sess, err := Client.Register(scimfe.RegisterRequest{
+		Email:    "testpamgroups@mail.com",
+		Name:     "testpamgroups",
+		Password: "123456",
+	})
+	require.NoError(t, err, "failed to create a user for test case")
+
+	want := scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          "201",
+		DisplayName: "Vault Admins",
+		Members: []scim.Reference{
+			{Value: "101", Display: "John Doe", Type: "User"},
+		},
+		Meta: &scim.Meta{ResourceType: scim.ResourceTypeGroup, Version: `W/"g201-1"`},
+	}
+
+	got, err := Client.PamGroupByID("201", sess.Token)
+	require.NoError(t, err)
+	require.Equal(t, want, *got)
+
+	list, err := Client.PamGroups(scim.ListParams{}, sess.Token)
+	require.NoError(t, err)
+	require.Equal(t, 1, list.TotalResults)
+	require.Equal(t, []scim.Group{want}, list.Resources)
+
+	list, err = Client.PamGroups(scim.ListParams{Filter: `members.value eq "101"`}, sess.Token)
+	require.NoError(t, err)
+	require.Equal(t, []scim.Group{want}, list.Resources)
+
+	_, err = Client.PamGroupByID("999", sess.Token)
+	shouldContainError(t, err, "404 Not Found: group not found")
+}
+
+func TestPam_ProvisioningValidation(t *testing.T) {
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	sess, err := Client.Register(scimfe.RegisterRequest{
+		Email:    "testpamprovision@mail.com",
+		Name:     "testpamprovision",
+		Password: "123456",
+	})
+	require.NoError(t, err, "failed to create a user for test case")
+
+	userCases := map[string]struct {
+		user    scim.User
+		token   scimfe.Token
+		wantErr string
+	}{
+		"empty token": {
+			user:    scim.User{UserName: "jdoe"},
+			wantErr: "401 Unauthorized: authorization required",
+		},
+		"no user name": {
+			user:    scim.User{DisplayName: "John Doe"},
+			token:   sess.Token,
+			wantErr: `400 Bad Request: attribute "userName" is required`,
+		},
+		"invalid email": {
+			user: scim.User{
+				UserName: "jdoe",
+				Emails:   []scim.MultiValue{{Value: "jdoe"}},
+			},
+			token:   sess.Token,
+			wantErr: "400 Bad Request: invalid request payload",
+		},
+		"multiple primary emails": {
+			user: scim.User{
+				UserName: "jdoe",
+				Emails: []scim.MultiValue{
+					{Value: "jdoe@example.com", Primary: true},
+					{Value: "john@example.com", Primary: true},
+				},
+			},
+			token:   sess.Token,
+			wantErr: "400 Bad Request: invalid request payload",
+		},
+		"invalid schema": {
+			user:    scim.User{Schemas: []string{scim.SchemaGroup}, UserName: "jdoe"},
+			token:   sess.Token,
+			wantErr: "400 Bad Request: schema \"" + scim.SchemaGroup + "\" is not supported by User resource type",
+		},
+	}
+
+	for n, c := range userCases {
+		t.Run("user "+n, func(t *testing.T) {
+			_, err := Client.CreatePamUser(c.user, c.token)
+			shouldContainError(t, err, c.wantErr)
+
+			_, err = Client.ReplacePamUser("101", c.user, c.token)
+			shouldContainError(t, err, c.wantErr)
+		})
+	}
+
+	t.Run("group without name", func(t *testing.T) {
+		_, err := Client.CreatePamGroup(scim.Group{}, sess.Token)
+		shouldContainError(t, err, `400 Bad Request: attribute "displayName" is required`)
+	})
+
+	t.Run("nested group member", func(t *testing.T) {
+		_, err := Client.CreatePamGroup(scim.Group{
+			DisplayName: "Vault Admins",
+			Members:     []scim.Reference{{Value: "201", Type: scim.ResourceTypeGroup}},
+		}, sess.Token)
+		shouldContainError(t, err, "400 Bad Request: invalid request payload")
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		err := Client.DeletePamGroup("abc", sess.Token)
+		shouldContainError(t, err, "400 Bad Request")
+	})
+}
+
+func TestPam_PatchValidation(t *testing.T) {
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	seedPamData(t)
+	sess, err := Client.Register(scimfe.RegisterRequest{
+		Email:    "testpampatch@mail.com",
+		Name:     "testpampatch",
+		Password: "123456",
+	})
+	require.NoError(t, err, "failed to create a user for test case")
+
+	cases := map[string]struct {
+		req     scim.PatchRequest
+		token   scimfe.Token
+		wantErr string
+	}{
+		"empty token": {
+			req:     *scim.NewPatchRequest(scim.PatchOperation{Op: "replace", Path: "title", Value: "CTO"}),
+			wantErr: "401 Unauthorized: authorization required",
+		},
+		"missing schema": {
+			req: scim.PatchRequest{
+				Operations: []scim.PatchOperation{{Op: "replace", Path: "title", Value: "CTO"}},
+			},
+			token:   sess.Token,
+			wantErr: "400 Bad Request",
+		},
+		"unknown operation": {
+			req:     *scim.NewPatchRequest(scim.PatchOperation{Op: "move", Path: "title", Value: "CTO"}),
+			token:   sess.Token,
+			wantErr: "400 Bad Request",
+		},
+		"remove without path": {
+			req:     *scim.NewPatchRequest(scim.PatchOperation{Op: "remove"}),
+			token:   sess.Token,
+			wantErr: "400 Bad Request",
+		},
+		"invalid path": {
+			req:     *scim.NewPatchRequest(scim.PatchOperation{Op: "replace", Path: "emails[type eq", Value: "x"}),
+			token:   sess.Token,
+			wantErr: "400 Bad Request",
+		},
+		"read-only attribute": {
+			req:     *scim.NewPatchRequest(scim.PatchOperation{Op: "replace", Path: "id", Value: "102"}),
+			token:   sess.Token,
+			wantErr: "400 Bad Request",
+		},
+		"remove required attribute": {
+			req:     *scim.NewPatchRequest(scim.PatchOperation{Op: "remove", Path: "userName"}),
+			token:   sess.Token,
+			wantErr: "400 Bad Request",
+		},
+		"invalid enterprise attribute": {
+			req: *scim.NewPatchRequest(scim.PatchOperation{
+				Op: "replace", Path: scim.SchemaEnterpriseUser + ":employeeNumber", Value: 102,
+			}),
+			token:   sess.Token,
+			wantErr: "400 Bad Request",
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			_, err := Client.PatchPamUser("101", c.req, c.token)
+			shouldContainError(t, err, c.wantErr)
+		})
+	}
+
+	t.Run("user not found", func(t *testing.T) {
+		_, err := Client.PatchPamUser("999", *scim.NewPatchRequest(
+			scim.PatchOperation{Op: "replace", Path: "title", Value: "CTO"},
+		), sess.Token)
+		shouldContainError(t, err, "404 Not Found")
+	})
+
+	t.Run("remove group name", func(t *testing.T) {
+		_, err := Client.PatchPamGroup("201", *scim.NewPatchRequest(
+			scim.PatchOperation{Op: "remove", Path: "displayName"},
+		), sess.Token)
+		shouldContainError(t, err, "400 Bad Request")
+	})
+}
+
+func TestPam_Bulk(t *testing.T) {
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	sess, err := Client.Register(scimfe.RegisterRequest{
+		Email:    "testpambulk@mail.com",
+		Name:     "testpambulk",
+		Password: "123456",
+	})
+	require.NoError(t, err, "failed to create a user for test case")
+
+	t.Run("empty token", func(t *testing.T) {
+		_, err := Client.PamBulk(*scim.NewBulkRequest(0), "")
+		shouldContainError(t, err, "401 Unauthorized: authorization required")
+	})
+
+	t.Run("missing schema", func(t *testing.T) {
+		_, err := Client.PamBulk(scim.BulkRequest{}, sess.Token)
+		shouldContainError(t, err, "400 Bad Request")
+	})
+
+	t.Run("too many operations", func(t *testing.T) {
+		ops := make([]scim.BulkOperation, service.BulkMaxOperations+1)
+		for i := range ops {
+			ops[i] = scim.BulkOperation{Method: http.MethodDelete, Path: "/Users/abc"}
+		}
+
+		_, err := Client.PamBulk(*scim.NewBulkRequest(0, ops...), sess.Token)
+		shouldContainError(t, err, "413 Request Entity Too Large")
+	})
+
+	t.Run("duplicate bulkId", func(t *testing.T) {
+		_, err := Client.PamBulk(*scim.NewBulkRequest(0,
+			scim.BulkOperation{Method: http.MethodPost, Path: "/Users", BulkID: "u1", Data: json.RawMessage(`{"userName":"a"}`)},
+			scim.BulkOperation{Method: http.MethodPost, Path: "/Users", BulkID: "u1", Data: json.RawMessage(`{"userName":"b"}`)},
+		), sess.Token)
+		shouldContainError(t, err, "400 Bad Request")
+	})
+
+	invalidOps := []scim.BulkOperation{
+		{Method: http.MethodPost, Path: "/Users", Data: json.RawMessage(`{"userName":"jdoe"}`)},
+		{Method: http.MethodGet, Path: "/Users/101"},
+		{Method: http.MethodDelete, Path: "/Containers/1"},
+		{Method: http.MethodDelete, Path: "/Users/abc"},
+		{Method: http.MethodPost, Path: "/Users", BulkID: "u1", Data: json.RawMessage(`{"displayName":"John Doe"}`)},
+		{Method: http.MethodDelete, Path: "/Users/bulkId:u1"},
+		{Method: http.MethodDelete, Path: "/Groups/bulkId:unknown"},
+	}
+	wantStatus := []string{"400", "400", "400", "400", "400", "409", "409"}
+
+	t.Run("invalid operations", func(t *testing.T) {
+		rsp, err := Client.PamBulk(*scim.NewBulkRequest(0, invalidOps...), sess.Token)
+		require.NoError(t, err)
+		require.Len(t, rsp.Operations, len(invalidOps))
+		for i, res := range rsp.Operations {
+			require.Equal(t, wantStatus[i], res.Status, "operation %d", i)
+			require.NotNil(t, res.Response, "operation %d", i)
+		}
+	})
+
+	t.Run("fail on errors", func(t *testing.T) {
+		rsp, err := Client.PamBulk(*scim.NewBulkRequest(2, invalidOps...), sess.Token)
+		require.NoError(t, err)
+		require.Len(t, rsp.Operations, 2)
+	})
+}
+
+func TestPam_Discovery(t *testing.T) {
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	seedPamData(t)
+	sess, err := Client.Register(scimfe.RegisterRequest{
+		Email:    "testpamdiscovery@mail.com",
+		Name:     "testpamdiscovery",
+		Password: "123456",
+	})
+	require.NoError(t, err, "failed to create a user for test case")
+
+	t.Run("empty token", func(t *testing.T) {
+		_, err := Client.PamServiceProviderConfig("")
+		shouldContainError(t, err, "401 Unauthorized: authorization required")
+	})
+
+	t.Run("service provider config", func(t *testing.T) {
+		cfg, err := Client.PamServiceProviderConfig(sess.Token)
+		require.NoError(t, err)
+		require.True(t, cfg.Patch.Supported)
+		require.True(t, cfg.Bulk.Supported)
+		require.Equal(t, service.BulkMaxOperations, cfg.Bulk.MaxOperations)
+		require.Equal(t, service.BulkMaxPayloadSize, cfg.Bulk.MaxPayloadSize)
+		require.True(t, cfg.ETag.Supported)
+	})
+
+	t.Run("resource types", func(t *testing.T) {
+		list, err := Client.PamResourceTypes(sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 4, list.TotalResults)
+		require.Equal(t, scim.SchemaUser, list.Resources[0].Schema)
+		require.Equal(t, "/users", list.Resources[0].Endpoint)
+		require.Equal(t, scim.SchemaGroup, list.Resources[1].Schema)
+		require.Equal(t, "/groups", list.Resources[1].Endpoint)
+		require.Equal(t, scim.SchemaContainer, list.Resources[2].Schema)
+		require.Equal(t, "/containers", list.Resources[2].Endpoint)
+		require.Equal(t, scim.SchemaPrivilegedData, list.Resources[3].Schema)
+		require.Equal(t, "/accounts", list.Resources[3].Endpoint)
+	})
+
+	t.Run("schemas", func(t *testing.T) {
+		list, err := Client.PamSchemas(sess.Token)
+		require.NoError(t, err)
+		require.Len(t, list.Resources, 4)
+		require.Equal(t, scim.SchemaUser, list.Resources[0].ID)
+		require.Equal(t, "/pam/Schemas/"+scim.SchemaUser, list.Resources[0].Meta.Location)
+	})
+
+	t.Run("attributes", func(t *testing.T) {
+		list, err := Client.PamUsers(scim.ListParams{Attributes: []string{"userName"}}, sess.Token)
+		require.NoError(t, err)
+		require.NotEmpty(t, list.Resources)
+		for _, u := range list.Resources {
+			require.NotEmpty(t, u.ID)
+			require.NotEmpty(t, u.UserName)
+			require.Empty(t, u.DisplayName)
+			require.Empty(t, u.Emails)
+		}
+	})
+
+	t.Run("excluded attributes", func(t *testing.T) {
+		list, err := Client.PamGroups(scim.ListParams{ExcludedAttributes: []string{"members"}}, sess.Token)
+		require.NoError(t, err)
+		require.NotEmpty(t, list.Resources)
+		for _, g := range list.Resources {
+			require.NotEmpty(t, g.DisplayName)
+			require.Empty(t, g.Members)
+		}
+	})
+}
+
+func TestPam_Containers(t *testing.T) {
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	seedPamData(t)
+	sess, err := Client.Register(scimfe.RegisterRequest{
+		Email:    "testpamcontainers@mail.com",
+		Name:     "testpamcontainers",
+		Password: "123456",
+	})
+	require.NoError(t, err, "failed to create a user for test case")
+
+	t.Run("empty token", func(t *testing.T) {
+		_, err := Client.PamContainers(scim.ListParams{}, "")
+		shouldContainError(t, err, "401 Unauthorized: authorization required")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		got, err := Client.PamContainers(scim.ListParams{SortBy: "name"}, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 2, got.TotalResults)
+		require.Equal(t, "Unused", got.Resources[0].ID)
+		require.Nil(t, got.Resources[0].Owner)
+		require.Equal(t, "VaultInternal", got.Resources[1].ID)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		got, err := Client.PamContainers(scim.ListParams{Filter: `owner.value eq "101"`}, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 1, got.TotalResults)
+		require.Equal(t, "VaultInternal", got.Resources[0].Name)
+	})
+
+	t.Run("by id", func(t *testing.T) {
+		got, err := Client.PamContainerByID("VaultInternal", sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, &scim.Container{
+			Schemas:     []string{scim.SchemaContainer},
+			ID:          "VaultInternal",
+			Name:        "VaultInternal",
+			Description: "Vault internal accounts",
+			Owner:       &scim.Reference{Value: "101", Display: "John Doe"},
+			Meta:        &scim.Meta{ResourceType: scim.ResourceTypeContainer, Version: `W/"c1"`},
+		}, got)
+	})
+
+	t.Run("no such container", func(t *testing.T) {
+		_, err := Client.PamContainerByID("Missing", sess.Token)
+		shouldContainError(t, err, "404 Not Found: container not found")
+
+		_, err = Client.PamContainerAccess("Missing", sess.Token)
+		shouldContainError(t, err, "404 Not Found: container not found")
+	})
+
+	t.Run("permissions", func(t *testing.T) {
+		got, err := Client.PamContainerPermissions("VaultInternal", sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, 3, got.TotalResults)
+		require.Equal(t, &scim.Reference{Value: "101", Display: "John Doe"}, got.Resources[0].User)
+		require.Equal(t, []string{"ListAccounts", "UseAccounts"}, got.Resources[0].Rights)
+		require.Equal(t, &scim.Reference{Value: "201", Display: "Vault Admins"}, got.Resources[2].Group)
+		require.Nil(t, got.Resources[2].User)
+	})
+
+	t.Run("access", func(t *testing.T) {
+		got, err := Client.PamContainerAccess("VaultInternal", sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, []scimfe.ContainerAccess{
+			{
+				User:   scim.Reference{Value: "101", Display: "John Doe", Type: scim.ResourceTypeUser},
+				Rights: []string{"ListAccounts", "UseAccounts", "ManageSafe"},
+				Direct: true,
+				Groups: []scim.Reference{{Value: "201", Display: "Vault Admins", Type: scim.ResourceTypeGroup}},
+			},
+			{
+				User:   scim.Reference{Value: "102", Display: "Alice Smith", Type: scim.ResourceTypeUser},
+				Rights: []string{"ListAccounts"},
+				Direct: true,
+			},
+		}, got.Resources)
+	})
+
+	t.Run("no access", func(t *testing.T) {
+		got, err := Client.PamContainerAccess("Unused", sess.Token)
+		require.NoError(t, err)
+		require.Empty(t, got.Resources)
+	})
+}
+
+func TestPam_Accounts(t *testing.T) {
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	seedPamData(t)
+	sess, err := Client.Register(scimfe.RegisterRequest{
+		Email:    "testpamaccounts@mail.com",
+		Name:     "testpamaccounts",
+		Password: "123456",
+	})
+	require.NoError(t, err, "failed to create a user for test case")
+
+	t.Run("empty token", func(t *testing.T) {
+		_, err := Client.PamAccounts(scim.ListParams{}, "")
+		shouldContainError(t, err, "401 Unauthorized: authorization required")
+	})
+
+	t.Run("by id", func(t *testing.T) {
+		got, err := Client.PamAccountByID("1_1", sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, &scim.PrivilegedData{
+			Schemas:   []string{scim.SchemaPrivilegedData},
+			ID:        "1_1",
+			Name:      "Operating System-UnixSSH-db01-root",
+			Type:      "password",
+			Container: &scim.Reference{Value: "VaultInternal", Display: "VaultInternal"},
+			Address:   "db01.example.com",
+			Platform:  "UnixSSH",
+			UserName:  "root",
+			Meta:      &scim.Meta{ResourceType: scim.ResourceTypePrivilegedData, Version: `W/"a1"`},
+		}, got)
+	})
+
+	t.Run("computed version", func(t *testing.T) {
+		// account without version from remote server gets version computed from its attributes
+		got, err := Client.PamAccountByID("1_2", sess.Token)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(got.Meta.Version, `W/"`), "unexpected version %q", got.Meta.Version)
+
+		again, err := Client.PamAccountByID("1_2", sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, got.Meta.Version, again.Meta.Version)
+
+		other, err := Client.PamAccountByID("2_1", sess.Token)
+		require.NoError(t, err)
+		require.NotEqual(t, got.Meta.Version, other.Meta.Version)
+	})
+
+	t.Run("no such account", func(t *testing.T) {
+		_, err := Client.PamAccountByID("9_9", sess.Token)
+		shouldContainError(t, err, "404 Not Found: account not found")
+	})
+
+	cases := map[string]struct {
+		filter string
+		want   []string
+	}{
+		"by safe": {
+			filter: `container.value eq "VaultInternal"`,
+			want:   []string{"1_1", "1_2"},
+		},
+		"by platform": {
+			filter: `platform eq "unixsshkeys"`,
+			want:   []string{"2_1"},
+		},
+		"by address": {
+			filter: `address ew "example.com" and not (address sw "db")`,
+			want:   []string{"1_2", "2_1"},
+		},
+		"by safe and platform": {
+			filter: `container.value eq "VaultInternal" and platform eq "WinDomain"`,
+			want:   []string{"1_2"},
+		},
+		"no match": {
+			filter: `container.value eq "vaultinternal"`,
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			got, err := Client.PamAccounts(scim.ListParams{Filter: c.filter, SortBy: "id"}, sess.Token)
+			require.NoError(t, err)
+			require.Equal(t, len(c.want), got.TotalResults)
+
+			ids := make([]string, 0, len(got.Resources))
+			for _, a := range got.Resources {
+				ids = append(ids, a.ID)
+			}
+			require.ElementsMatch(t, c.want, ids)
+		})
+	}
+}
+
+func TestPam_UserExtensions(t *testing.T) {
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	seedPamData(t)
+	sess, err := Client.Register(scimfe.RegisterRequest{
+		Email:    "testpamextensions@mail.com",
+		Name:     "testpamextensions",
+		Password: "123456",
+	})
+	require.NoError(t, err, "failed to create a user for test case")
+
+	const cyberArkUser = "urn:ietf:params:scim:schemas:cyberark:1.0:User"
+
+	t.Run("read", func(t *testing.T) {
+		got, err := Client.PamUserByID("102", sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, []string{scim.SchemaUser, cyberArkUser, scim.SchemaEnterpriseUser}, got.Schemas)
+		require.Equal(t, &scim.EnterpriseUser{
+			EmployeeNumber: "E-102",
+			Department:     "Security",
+			Manager:        &scim.Manager{Value: "101", DisplayName: "John Doe"},
+		}, got.Enterprise)
+		require.JSONEq(t, `{"source": "LDAP", "vaultAuthorization": ["AuditUsers"]}`, string(got.Extensions[cyberArkUser]))
+	})
+
+	t.Run("no extensions", func(t *testing.T) {
+		got, err := Client.PamUserByID("101", sess.Token)
+		require.NoError(t, err)
+		require.Nil(t, got.Enterprise)
+		require.Empty(t, got.Extensions)
+	})
+
+	t.Run("attributes", func(t *testing.T) {
+		got, err := Client.PamUsers(scim.ListParams{
+			Attributes: []string{"userName", scim.SchemaEnterpriseUser + ":department", cyberArkUser + ":source"},
+			Filter:     `id eq "102"`,
+		}, sess.Token)
+		require.NoError(t, err)
+		require.Len(t, got.Resources, 1)
+		require.Equal(t, &scim.EnterpriseUser{Department: "Security"}, got.Resources[0].Enterprise)
+		require.JSONEq(t, `{"source": "LDAP"}`, string(got.Resources[0].Extensions[cyberArkUser]))
+	})
+
+	t.Run("excluded attributes", func(t *testing.T) {
+		list, err := Client.PamUsers(scim.ListParams{
+			ExcludedAttributes: []string{scim.SchemaEnterpriseUser, cyberArkUser},
+			Filter:             `id eq "102"`,
+		}, sess.Token)
+		require.NoError(t, err)
+		require.Len(t, list.Resources, 1)
+		require.Nil(t, list.Resources[0].Enterprise)
+		require.Empty(t, list.Resources[0].Extensions)
+	})
+
+	filters := map[string]string{
+		"employee number": scim.SchemaEnterpriseUser + `:employeeNumber eq "E-102"`,
+		"department":      scim.SchemaEnterpriseUser + `:department eq "security"`,
+		"manager":         scim.SchemaEnterpriseUser + `:manager.value eq "101"`,
+	}
+
+	for n, f := range filters {
+		t.Run("filter by "+n, func(t *testing.T) {
+			got, err := Client.PamUsers(scim.ListParams{Filter: f}, sess.Token)
+			require.NoError(t, err)
+			require.Equal(t, 1, got.TotalResults)
+			require.Equal(t, "asmith", got.Resources[0].UserName)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scim"
+	"github.com/strick-j/scimfe/pkg/scimfe"
+)
+
+func requireSCIMClient(t *testing.T) {
+	if SCIMClient == nil {
+		t.Skip("SCIM tokens are not configured")
+	}
+}
+
+func TestSCIM_Auth(t *testing.T) {
+	requireSCIMClient(t)
+	invalidClient := scim.NewClient(&http.Client{}, SCIMBaseURL, scim.StaticToken("invalid"))
+	_, err := invalidClient.ListUsers(context.Background(), scim.ListParams{})
+	require.True(t, errors.Is(err, scim.ErrUnauthorized), "unexpected error: %v", err)
+
+	noAuthClient := scim.NewClient(&http.Client{}, SCIMBaseURL, nil)
+	_, err = noAuthClient.ListUsers(context.Background(), scim.ListParams{})
+	require.True(t, errors.Is(err, scim.ErrUnauthorized), "unexpected error: %v", err)
+}
+
+func TestSCIM_Discovery(t *testing.T) {
+	requireSCIMClient(t)
+	ctx := context.Background()
+
+	cfg, err := SCIMClient.ServiceProviderConfig(ctx)
+	require.NoError(t, err)
+	require.True(t, cfg.Patch.Supported)
+	require.False(t, cfg.Bulk.Supported)
+	require.True(t, cfg.ETag.Supported)
+	require.Len(t, cfg.AuthenticationSchemes, 1)
+	require.Equal(t, "oauthbearertoken", cfg.AuthenticationSchemes[0].Type)
+
+	types, err := SCIMClient.ResourceTypes(ctx)
+	require.NoError(t, err)
+	require.Len(t, types, 1)
+	require.Equal(t, scim.ResourceTypeUser, types[0].Name)
+	require.Equal(t, scim.EndpointUsers, types[0].Endpoint)
+
+	s, err := SCIMClient.Schema(ctx, scim.SchemaUser)
+	require.NoError(t, err)
+	attrs := make(map[string]scim.Attribute, len(s.Attributes))
+	for _, a := range s.Attributes {
+		attrs[a.Name] = a
+	}
+	require.True(t, attrs["userName"].Required)
+	require.Equal(t, "never", attrs["password"].Returned)
+	require.Equal(t, "readOnly", attrs["emails"].Mutability)
+	require.NotContains(t, attrs, "phoneNumbers")
+
+	_, err = SCIMClient.Schema(ctx, scim.SchemaGroup)
+	require.True(t, errors.Is(err, scim.ErrNotFound), "unexpected error: %v", err)
+}
+
+func TestSCIM_Users(t *testing.T) {
+	requireSCIMClient(t)
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	ctx := context.Background()
+
+	created, err := SCIMClient.CreateUser(ctx, scim.User{
+		Schemas:  []string{scim.SchemaUser},
+		UserName: "TestSCIMUser@mail.com",
+		Name:     &scim.Name{GivenName: "Test", FamilyName: "Operator"},
+		Password: "123456",
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.ID)
+	require.Equal(t, "testscimuser@mail.com", created.UserName)
+	require.Equal(t, "Test Operator", created.DisplayName)
+	require.True(t, *created.Active)
+
+	t.Run("login", func(t *testing.T) {
+		_, err := Client.Login(scimfe.Credentials{Email: "testscimuser@mail.com", Password: "123456"})
+		require.NoError(t, err)
+	})
+
+	t.Run("uniqueness", func(t *testing.T) {
+		_, err := SCIMClient.CreateUser(ctx, scim.User{UserName: "testscimuser@mail.com", DisplayName: "Duplicate"})
+		require.True(t, errors.Is(err, scim.ErrUniqueness), "unexpected error: %v", err)
+	})
+
+	t.Run("invalid userName", func(t *testing.T) {
+		_, err := SCIMClient.CreateUser(ctx, scim.User{UserName: "operator", DisplayName: "Operator"})
+		require.True(t, errors.Is(err, scim.ErrBadRequest), "unexpected error: %v", err)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		list, err := SCIMClient.ListUsers(ctx, scim.ListParams{Filter: `userName eq "testscimuser@mail.com"`})
+		require.NoError(t, err)
+		require.Len(t, list.Resources, 1)
+		require.Equal(t, created.ID, list.Resources[0].ID)
+
+		list, err = SCIMClient.ListUsers(ctx, scim.ListParams{Filter: `userName eq "nobody@mail.com"`})
+		require.NoError(t, err)
+		require.Empty(t, list.Resources)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		u, err := SCIMClient.ReplaceUser(ctx, created.ID, scim.User{
+			UserName:    "testscimuser@mail.com",
+			DisplayName: "Replaced Operator",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Replaced Operator", u.DisplayName)
+
+		got, err := SCIMClient.GetUser(ctx, created.ID)
+		require.NoError(t, err)
+		require.Equal(t, "Replaced Operator", got.DisplayName)
+
+		// password is kept if it's not set
+		_, err = Client.Login(scimfe.Credentials{Email: "testscimuser@mail.com", Password: "123456"})
+		require.NoError(t, err)
+	})
+
+	t.Run("deactivate", func(t *testing.T) {
+		u, err := SCIMClient.PatchUser(ctx, created.ID,
+			scim.PatchOperation{Op: scim.PatchReplace, Path: "active", Value: false})
+		require.NoError(t, err)
+		require.False(t, *u.Active)
+
+		_, err = Client.Login(scimfe.Credentials{Email: "testscimuser@mail.com", Password: "123456"})
+		shouldContainError(t, err, "invalid username or password")
+	})
+
+	t.Run("version", func(t *testing.T) {
+		got, err := SCIMClient.GetUser(ctx, created.ID)
+		require.NoError(t, err)
+		require.NotEmpty(t, got.Meta.Version)
+		require.NotEqual(t, created.Meta.Version, got.Meta.Version)
+
+		stale := scim.WithPrecondition(ctx, scim.Precondition{IfMatch: created.Meta.Version})
+		_, err = SCIMClient.PatchUser(stale, created.ID,
+			scim.PatchOperation{Op: scim.PatchReplace, Path: "displayName", Value: "Stale Operator"})
+		require.True(t, errors.Is(err, scim.ErrPreconditionFailed), "unexpected error: %v", err)
+
+		err = SCIMClient.DeleteUser(stale, created.ID)
+		require.True(t, errors.Is(err, scim.ErrPreconditionFailed), "unexpected error: %v", err)
+
+		current := scim.WithPrecondition(ctx, scim.Precondition{IfMatch: got.Meta.Version})
+		u, err := SCIMClient.PatchUser(current, created.ID,
+			scim.PatchOperation{Op: scim.PatchReplace, Path: "displayName", Value: "Versioned Operator"})
+		require.NoError(t, err)
+		require.Equal(t, "Versioned Operator", u.DisplayName)
+		require.NotEqual(t, got.Meta.Version, u.Meta.Version)
+
+		_, err = SCIMClient.ReplaceUser(scim.WithPrecondition(ctx, scim.Precondition{IfNoneMatch: "*"}), created.ID, scim.User{
+			UserName:    "testscimuser@mail.com",
+			DisplayName: "Replaced Operator",
+		})
+		require.True(t, errors.Is(err, scim.ErrPreconditionFailed), "unexpected error: %v", err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, SCIMClient.DeleteUser(ctx, created.ID))
+
+		_, err := SCIMClient.GetUser(ctx, created.ID)
+		require.True(t, errors.Is(err, scim.ErrNotFound), "unexpected error: %v", err)
+
+		err = SCIMClient.DeleteUser(ctx, created.ID)
+		require.True(t, errors.Is(err, scim.ErrNotFound), "unexpected error: %v", err)
+	})
+
+	t.Run("invalid id", func(t *testing.T) {
+		_, err := SCIMClient.GetUser(ctx, "abc")
+		require.True(t, errors.Is(err, scim.ErrNotFound), "unexpected error: %v", err)
+	})
+}
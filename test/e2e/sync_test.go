@@ -0,0 +1,121 @@
+package e2e
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scimfe"
+)
+
+const (
+	runningJobID  = "6f1c2d4e-0000-4000-8000-000000000001"
+	finishedJobID = "6f1c2d4e-0000-4000-8000-000000000002"
+	failedJobID   = "6f1c2d4e-0000-4000-8000-000000000003"
+)
+
+func TestSync_Jobs(t *testing.T) {
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	sess, err := Client.Register(scimfe.RegisterRequest{
+		Email:    "testsyncjobs@mail.com",
+		Name:     "testsyncjobs",
+		Password: "123456",
+	})
+	require.NoError(t, err, "failed to create a user for test case")
+
+	_, err = DB.Exec(`INSERT INTO pamsync_jobs ("id", "resourceType", "mode", "status", "actor", "error", "pages", "upserted", "deleted", "failed", "created", "updated", "finished") VALUES
+		($1, 'User', 'delta', 'running', 'system', NULL, 2, 150, 0, 1, NOW() AT TIME ZONE 'utc', NOW() AT TIME ZONE 'utc', NULL),
+		($2, 'Group', 'full', 'succeeded', 'system', NULL, 1, 20, 3, 0, '2021-01-02 10:00:00', '2021-01-02 10:01:00', '2021-01-02 10:01:00'),
+		($3, 'All', 'full', 'failed', 'system', 'remote is unavailable', 0, 0, 0, 0, '2021-01-01 10:00:00', '2021-01-01 10:00:05', '2021-01-01 10:00:05')`,
+		runningJobID, finishedJobID, failedJobID)
+	require.NoError(t, err, "failed to seed synchronization jobs")
+
+	t.Run("list", func(t *testing.T) {
+		cases := map[string]struct {
+			query scimfe.SyncJobsQuery
+			want  []string
+		}{
+			"all": {
+				want: []string{runningJobID, finishedJobID, failedJobID},
+			},
+			"status": {
+				query: scimfe.SyncJobsQuery{Status: "running"},
+				want:  []string{runningJobID},
+			},
+			"resource type": {
+				query: scimfe.SyncJobsQuery{ResourceType: "group"},
+				want:  []string{finishedJobID},
+			},
+		}
+
+		for n, c := range cases {
+			t.Run(n, func(t *testing.T) {
+				got, err := Client.SyncJobs(c.query, sess.Token)
+				require.NoError(t, err)
+				require.Equal(t, len(c.want), got.TotalResults)
+				ids := make([]string, 0, len(got.Resources))
+				for _, j := range got.Resources {
+					ids = append(ids, j.ID)
+				}
+				require.Equal(t, c.want, ids)
+			})
+		}
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		got, err := Client.SyncJobByID(runningJobID, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, "running", got.Status)
+		require.Equal(t, "delta", got.Mode)
+		require.Equal(t, 2, got.Pages)
+		require.Equal(t, 150, got.Upserted)
+		require.Equal(t, 1, got.Failed)
+		require.Nil(t, got.Finished)
+
+		got, err = Client.SyncJobByID(failedJobID, sess.Token)
+		require.NoError(t, err)
+		require.Equal(t, "failed", got.Status)
+		require.Equal(t, "remote is unavailable", got.Error)
+		require.NotNil(t, got.Finished)
+
+		_, err = Client.SyncJobByID("6f1c2d4e-0000-4000-8000-0000000000ff", sess.Token)
+		shouldContainError(t, err, "404 Not Found")
+
+		_, err = Client.SyncJobByID("invalid", sess.Token)
+		shouldContainError(t, err, "400 Bad Request")
+	})
+
+	t.Run("start", func(t *testing.T) {
+		_, err := Client.StartSyncJob(scimfe.SyncJobRequest{ResourceType: "User"}, sess.Token)
+		shouldContainError(t, err, "409 Conflict")
+
+		// only one job can be running, including jobs of other resource types
+		for _, rt := range []string{"All", "Container"} {
+			_, err = Client.StartSyncJob(scimfe.SyncJobRequest{ResourceType: rt}, sess.Token)
+			shouldContainError(t, err, "409 Conflict")
+		}
+
+		_, err = Client.StartSyncJob(scimfe.SyncJobRequest{ResourceType: "Group", Mode: "partial"}, sess.Token)
+		shouldContainError(t, err, "400 Bad Request")
+
+		_, err = Client.StartSyncJob(scimfe.SyncJobRequest{ResourceType: "Unknown"}, sess.Token)
+		shouldContainError(t, err, "400 Bad Request")
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		got, err := Client.CancelSyncJob(runningJobID, sess.Token)
+		require.NoError(t, err)
+		require.True(t, got.CancelRequested)
+
+		_, err = Client.CancelSyncJob(finishedJobID, sess.Token)
+		shouldContainError(t, err, "409 Conflict")
+
+		_, err = Client.CancelSyncJob("6f1c2d4e-0000-4000-8000-0000000000ff", sess.Token)
+		shouldContainError(t, err, "404 Not Found")
+	})
+
+	_, err = Client.SyncJobs(scimfe.SyncJobsQuery{}, "")
+	shouldContainError(t, err, "401 Unauthorized: authorization required")
+
+	_, err = Client.DriftReport("")
+	shouldContainError(t, err, "401 Unauthorized: authorization required")
+}
@@ -2,10 +2,12 @@ package e2e
 
 import (
 	"fmt"
+	"sort"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"github.com/strick-j/scimfe/pkg/scim"
 	"github.com/strick-j/scimfe/pkg/scimfe"
 )
 
@@ -28,6 +30,11 @@ func TestUser_GetUsersList(t *testing.T) {
 		expects[i] = sess.User
 	}
 
+	// users are ordered by id by default
+	sort.Slice(expects, func(i, j int) bool {
+		return expects[i].ID < expects[j].ID
+	})
+
 	cases := map[string]struct {
 		want        []scimfe.User
 		wantErr     string
@@ -67,16 +74,94 @@ func TestUser_GetUsersList(t *testing.T) {
 				c.onBeforeRun(t, &token)
 			}
 
-			got, err := Client.Users(token)
+			got, err := Client.Users(scim.ListParams{}, token)
 			if c.wantErr != "" {
 				shouldContainError(t, err, c.wantErr)
 				return
 			}
 			require.NoError(t, err)
 			require.NotNil(t, got)
-			require.Equal(t, c.want, got)
+			require.Equal(t, len(c.want), got.TotalResults)
+			require.Equal(t, c.want, got.Resources)
+		})
+	}
+}
+
+func TestUser_GetUsersListPagination(t *testing.T) {
+	const usersCount = 5
+	require.NoError(t, TruncateData(), "failed to truncate data from the test")
+	var tkn scimfe.Token
+	users := make([]scimfe.User, usersCount)
+	for i := 0; i < usersCount; i++ {
+		sess, err := Client.Register(scimfe.RegisterRequest{
+			Email:    fmt.Sprintf("userspaging%d@mail.com", i),
+			Name:     fmt.Sprintf("userspaging%d", i),
+			Password: "123456",
 		})
+		require.NoError(t, err, "failed to create a user for test case")
+		tkn = sess.Token
+		users[i] = sess.User
 	}
+
+	byID := append([]scimfe.User{}, users...)
+	sort.Slice(byID, func(i, j int) bool {
+		return byID[i].ID < byID[j].ID
+	})
+
+	cases := map[string]struct {
+		params  scim.ListParams
+		want    []scimfe.User
+		wantErr string
+	}{
+		"page": {
+			params: scim.ListParams{StartIndex: 2, Count: 2},
+			want:   byID[1:3],
+		},
+		"sort descending": {
+			params: scim.ListParams{SortBy: "email", SortOrder: scim.SortDescending, Count: 2},
+			want:   []scimfe.User{users[4], users[3]},
+		},
+		"not sortable": {
+			params:  scim.ListParams{SortBy: "password"},
+			wantErr: "400 Bad Request",
+		},
+		"invalid sort order": {
+			params:  scim.ListParams{SortOrder: "random"},
+			wantErr: "400 Bad Request",
+		},
+	}
+
+	for n, c := range cases {
+		t.Run(n, func(t *testing.T) {
+			got, err := Client.Users(c.params, tkn)
+			if c.wantErr != "" {
+				shouldContainError(t, err, c.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, usersCount, got.TotalResults)
+			require.Equal(t, c.want, got.Resources)
+		})
+	}
+
+	t.Run("cursor", func(t *testing.T) {
+		var (
+			cursor string
+			got    []scimfe.User
+		)
+		for {
+			rsp, err := Client.Users(scim.ListParams{Count: 2, Cursor: &cursor}, tkn)
+			require.NoError(t, err)
+			require.Equal(t, usersCount, rsp.TotalResults)
+			got = append(got, rsp.Resources...)
+			if rsp.NextCursor == "" {
+				break
+			}
+			cursor = rsp.NextCursor
+		}
+		require.Equal(t, byID, got)
+	})
 }
 
 func TestUser_Current(t *testing.T) {